@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventFilter selects events of the event log for the analyze command
+type eventFilter struct {
+	From      time.Duration  // Requests started before are skipped
+	To        time.Duration  // Requests started after are skipped, 0 - until the end of the run
+	Operation *regexp.Regexp // Matches operation (PUT /api/products/{id}) or step name (update-product)
+	Scenario  string
+	Status    []statusRange // Any of the ranges, empty - any status
+}
+
+// statusRange is a range of status codes, 0 - no response
+type statusRange struct {
+	From, To int
+}
+
+// match reports whether the event passes the filter
+func (f eventFilter) match(event RequestEvent) bool {
+	if event.Offset < f.From || (f.To > 0 && event.Offset >= f.To) {
+		return false
+	}
+	if f.Scenario != "" && event.Scenario != f.Scenario {
+		return false
+	}
+	if f.Operation != nil && !f.Operation.MatchString(event.Operation) && !f.Operation.MatchString(event.Step) {
+		return false
+	}
+	if len(f.Status) == 0 {
+		return true
+	}
+	for _, r := range f.Status {
+		if event.Status >= r.From && event.Status <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the filter, e.g. "op ~ ^PUT, status 2xx,404"
+func (f eventFilter) String(status string) string {
+	var parts []string
+	if f.Operation != nil {
+		parts = append(parts, "op ~ "+f.Operation.String())
+	}
+	if f.Scenario != "" {
+		parts = append(parts, "scenario "+f.Scenario)
+	}
+	if status != "" {
+		parts = append(parts, "status "+status)
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseStatusFilter parses comma separated status codes and classes, e.g. "2xx,404,0" (0 - no response)
+func parseStatusFilter(spec string) ([]statusRange, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	var ranges []statusRange
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if len(field) == 3 && strings.HasSuffix(field, "xx") && field[0] >= '1' && field[0] <= '5' {
+			class := int(field[0]-'0') * 100
+			ranges = append(ranges, statusRange{From: class, To: class + 99})
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 0 || code > 599 {
+			return nil, fmt.Errorf("invalid status %q: expected a code, a class like 5xx or 0 for no response", field)
+		}
+		ranges = append(ranges, statusRange{From: code, To: code})
+	}
+	return ranges, nil
+}
+
+// runAnalyzeCommand implements "benchmark-runner analyze [flags] FILE"
+func runAnalyzeCommand(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s analyze [flags] FILE\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "Recomputes the report of a run from its event log (-events), for a time window\n")
+		fmt.Fprintf(fs.Output(), "and a subset of requests.\n\n")
+		fs.PrintDefaults()
+	}
+	var filter eventFilter
+	var asserts stringList
+	var warmUp WarmUpConfig
+	fs.DurationVar(&filter.From, "from", 0, "Start of the window from the start of the run, e.g. 1m to exclude the warm-up")
+	fs.DurationVar(&filter.To, "to", 0, "End of the window from the start of the run (default: end of the run)")
+	op := fs.String("op", "", "Regular expression of the operations to keep, matched against 'METHOD /path' and the step name, e.g. '^PUT'")
+	fs.StringVar(&filter.Scenario, "scenario", "", "Keep requests of the concurrent scenario")
+	status := fs.String("status", "", "Keep requests with these comma separated status codes or classes, e.g. 2xx,404; 0 - no response")
+	slowest := fs.Int("slowest", 10, "Number of the slowest requests listed in the report")
+	fs.Var(&asserts, "assert", "SLO assertion evaluated on the selected requests, as for a run; can be repeated, exit code 3 if any fails")
+	warmUpFlags(fs, &warmUp)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if filter.To > 0 && filter.To <= filter.From {
+		log.Printf("-to must be after -from")
+		return 2
+	}
+	if err := warmUp.validate(); err != nil {
+		log.Print(err)
+		return 2
+	}
+	var err error
+	if *op != "" {
+		if filter.Operation, err = regexp.Compile(*op); err != nil {
+			log.Printf("Invalid -op: %v", err)
+			return 2
+		}
+	}
+	if filter.Status, err = parseStatusFilter(*status); err != nil {
+		log.Printf("Invalid -status: %v", err)
+		return 2
+	}
+
+	path := fs.Arg(0)
+	result, info, total, err := analyzeEventLog(path, filter, *slowest)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	to := "end of the run"
+	if filter.To > 0 {
+		to = filter.To.String()
+	}
+	log.Printf("Event Log: %s (%s, %d events)", path, info.Format, total)
+	log.Printf("  Run: %s against %s, %d RPS for %s, started %s", info.Header.Type, info.Header.URL, info.Header.RPS,
+		time.Duration(info.Header.Duration*float64(time.Second)), info.Header.StartTime.Format(time.RFC3339))
+	log.Printf("  Window: %s - %s", filter.From, to)
+	log.Printf("  Filter: %s", filter.String(*status))
+	log.Printf("  Selected: %d of %d requests", result.TotalRequests, total)
+	if !info.Complete {
+		log.Printf("  WARNING: the log has no end record (the run was killed), its duration is taken from the last request")
+	}
+	if info.Dropped > 0 {
+		log.Printf("  WARNING: %d events were dropped while the log was written, counts are lower than in the run", info.Dropped)
+	}
+	if result.TotalRequests == 0 {
+		log.Printf("No requests match the filter")
+		return 1
+	}
+
+	targetRPS := info.Header.RPS
+	if info.Header.Type == UserSession {
+		targetRPS = 0
+	}
+	var assertions []Assertion
+	for _, spec := range asserts {
+		assertion, err := parseAssertion(spec, targetRPS)
+		if err != nil {
+			log.Print(err)
+			return 2
+		}
+		assertions = append(assertions, assertion)
+	}
+	result.WarmUp = analyzeWarmUp(result.TimeSeries, result.TotalDuration, warmUp)
+	var passed bool
+	result.Assertions, passed = evaluateAssertions(assertions, result)
+	printResults(result, false)
+
+	if !passed {
+		return exitAssertionFailed
+	}
+	return 0
+}
+
+// analyzeEventLog builds the result of the requests of the event log selected by the filter.
+// Requests are counted individually, also for multi-step scenarios, and the time-series is by request start.
+// Returns the result, the log's run information and the total number of events in the log
+func analyzeEventLog(path string, filter eventFilter, slowest int) (*Result, *EventLogInfo, int64, error) {
+	var (
+		total    int64
+		start    time.Time // Start of the run from the header
+		lastEnd  time.Duration
+		seconds  [][]time.Duration
+		failures []int64
+	)
+	result := &Result{Errors: NewErrorStats()}
+	steps := newStepRecorder()
+	slowestRequests := newSlowestRecorder(slowest)
+	stepNames := make(map[string]bool)
+
+	onHeader := func(header EventLogHeader) {
+		start = header.StartTime
+		result.StartTime = start.Add(filter.From)
+		result.Errors.Start = result.StartTime
+	}
+	info, err := readEventLog(path, onHeader, func(event RequestEvent) {
+		total++
+		if end := event.Offset + event.Latency; end > lastEnd {
+			lastEnd = end
+		}
+		if !filter.match(event) {
+			return
+		}
+		result.TotalRequests++
+		result.Latencies = append(result.Latencies, event.Latency)
+		var stepErr error
+		if event.Error != "" {
+			result.FailedRequests++
+			stepErr = errors.New(event.Error)
+		} else {
+			result.SuccessRequests++
+		}
+
+		name := event.Step
+		if event.Scenario != "" {
+			name = event.Scenario + "/" + event.Step
+		}
+		stepNames[name] = true
+		steps.record(name, event.Latency, stepErr)
+
+		startedAt := start.Add(event.Offset)
+		slowestRequests.Record(SlowRequest{Operation: event.Operation, StatusCode: event.Status, Start: startedAt, Latency: event.Latency})
+		if event.Error != "" {
+			message := "no response"
+			if event.Status > 0 {
+				message = fmt.Sprintf("status %d", event.Status)
+			}
+			result.Errors.RecordErrorSample(event.Operation, event.Error, message,
+				ErrorSample{Time: startedAt, Latency: event.Latency, StatusCode: event.Status})
+		}
+
+		second := int((event.Offset - filter.From) / time.Second)
+		for len(seconds) <= second {
+			seconds = append(seconds, nil)
+			failures = append(failures, 0)
+		}
+		seconds[second] = append(seconds[second], event.Latency)
+		if event.Error != "" {
+			failures[second]++
+		}
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	end := info.Duration
+	if !info.Complete {
+		end = lastEnd
+	}
+	if filter.To > 0 && filter.To < end {
+		end = filter.To
+	}
+	if end <= filter.From {
+		return nil, nil, 0, fmt.Errorf("event log %s: the window starts at %s, after the end of the run at %s", path, filter.From, end.Round(time.Millisecond))
+	}
+
+	result.TotalDuration = end - filter.From
+	result.TargetRPS = info.Header.RPS
+	result.SlowestRequests = slowestRequests.Slowest()
+	if len(stepNames) > 1 {
+		result.Steps = steps.results()
+		sort.SliceStable(result.Steps, func(i, j int) bool { return result.Steps[i].Name < result.Steps[j].Name })
+	}
+	for second, latencies := range seconds {
+		point := TimeSeriesPoint{Second: second, Requests: int64(len(latencies)), Errors: failures[second]}
+		if len(latencies) > 0 {
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			var sum time.Duration
+			for _, latency := range latencies {
+				sum += latency
+			}
+			point.AvgLatency = sum / time.Duration(len(latencies))
+			point.P50Latency = percentile(latencies, 0.50)
+			point.P95Latency = percentile(latencies, 0.95)
+			point.P99Latency = percentile(latencies, 0.99)
+			point.MaxLatency = latencies[len(latencies)-1]
+		}
+		result.TimeSeries = append(result.TimeSeries, point)
+	}
+	calculateLatencyStats(result)
+	return result, info, total, nil
+}
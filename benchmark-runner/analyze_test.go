@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestParseStatusFilter(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []statusRange
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: "404", want: []statusRange{{404, 404}}},
+		{spec: "2xx, 404,0", want: []statusRange{{200, 299}, {404, 404}, {0, 0}}},
+		{spec: "5XX", want: []statusRange{{500, 599}}},
+		{spec: "6xx", wantErr: true},
+		{spec: "0xx", wantErr: true},
+		{spec: "600", wantErr: true},
+		{spec: "-1", wantErr: true},
+		{spec: "ok", wantErr: true},
+		{spec: "200,", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseStatusFilter(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStatusFilter(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseStatusFilter(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestEventFilterMatch(t *testing.T) {
+	event := RequestEvent{Offset: 90 * time.Second, Scenario: "writes", Step: "update-product", Operation: "PUT /api/products/{id}", Status: 404}
+	tests := []struct {
+		name   string
+		filter eventFilter
+		want   bool
+	}{
+		{name: "empty", filter: eventFilter{}, want: true},
+		{name: "window", filter: eventFilter{From: time.Minute, To: 2 * time.Minute}, want: true},
+		{name: "before the window", filter: eventFilter{From: 2 * time.Minute}, want: false},
+		{name: "window end is exclusive", filter: eventFilter{To: 90 * time.Second}, want: false},
+		{name: "scenario", filter: eventFilter{Scenario: "writes"}, want: true},
+		{name: "other scenario", filter: eventFilter{Scenario: "reads"}, want: false},
+		{name: "operation", filter: eventFilter{Operation: regexp.MustCompile("^PUT")}, want: true},
+		{name: "step name", filter: eventFilter{Operation: regexp.MustCompile("^update-")}, want: true},
+		{name: "other operation", filter: eventFilter{Operation: regexp.MustCompile("^GET")}, want: false},
+		{name: "status class", filter: eventFilter{Status: []statusRange{{200, 299}, {400, 499}}}, want: true},
+		{name: "other status", filter: eventFilter{Status: []statusRange{{200, 299}, {0, 0}}}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.match(event); got != tt.want {
+			t.Errorf("%s: match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEventFilterString(t *testing.T) {
+	if got := (eventFilter{}).String(""); got != "none" {
+		t.Errorf("empty filter = %q, want none", got)
+	}
+	filter := eventFilter{Operation: regexp.MustCompile("^PUT"), Scenario: "writes"}
+	if got := filter.String("2xx,404"); got != "op ~ ^PUT, scenario writes, status 2xx,404" {
+		t.Errorf("filter = %q", got)
+	}
+}
+
+func TestAnalyzeEventLog(t *testing.T) {
+	path := writeEventLog(t, t.TempDir(), "run.events", testEvents)
+
+	result, info, total, err := analyzeEventLog(path, eventFilter{Scenario: "reads"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Complete || total != int64(len(testEvents)) {
+		t.Errorf("info = %+v, total = %d, want a complete log of %d events", info, total, len(testEvents))
+	}
+	if result.TotalRequests != 2 || result.FailedRequests != 1 || result.SuccessRequests != 1 {
+		t.Errorf("requests = %d total, %d failed, %d successful, want 2, 1 and 1",
+			result.TotalRequests, result.FailedRequests, result.SuccessRequests)
+	}
+
+	result, _, _, err = analyzeEventLog(path, eventFilter{From: time.Second}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TotalRequests != 2 || result.FailedRequests != 2 {
+		t.Errorf("requests after 1s = %d total, %d failed, want 2 failed", result.TotalRequests, result.FailedRequests)
+	}
+}
+
+func TestRunAnalyzeCommandRejectsWarmUpFlags(t *testing.T) {
+	for _, flag := range []string{"-steady-tolerance=-0.1", "-steady-tolerance=1", "-steady-window=500ms"} {
+		if code := runAnalyzeCommand([]string{flag, "run.events"}); code != 2 {
+			t.Errorf("%s: exit code %d, want 2", flag, code)
+		}
+	}
+}
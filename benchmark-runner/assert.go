@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exitAssertionFailed is the exit code when at least one -assert fails
+const exitAssertionFailed = 3
+
+// metricKind defines how threshold values of a metric are parsed and printed
+type metricKind int
+
+const (
+	kindDuration metricKind = iota // Seconds, thresholds like 50ms
+	kindRatio                      // Fraction, thresholds like 0.1% or 0.001
+	kindNumber                     // Plain number, thresholds like 1000 or 0.98*target
+)
+
+// assertMetric is a metric available in -assert expressions
+type assertMetric struct {
+	kind  metricKind
+	value func(r *Result) float64
+}
+
+// assertMetrics are metrics available in -assert expressions.
+// Counts are HTTP requests, not scenario cycles
+var assertMetrics = map[string]assertMetric{
+	"min": {kindDuration, func(r *Result) float64 { return r.MinLatency.Seconds() }},
+	"avg": {kindDuration, func(r *Result) float64 { return r.AvgLatency.Seconds() }},
+	"max": {kindDuration, func(r *Result) float64 { return r.MaxLatency.Seconds() }},
+	"p50": {kindDuration, func(r *Result) float64 { return r.P50Latency.Seconds() }},
+	"p95": {kindDuration, func(r *Result) float64 { return r.P95Latency.Seconds() }},
+	"p99": {kindDuration, func(r *Result) float64 { return r.P99Latency.Seconds() }},
+	"rps": {kindNumber, func(r *Result) float64 {
+		if r.TotalDuration <= 0 {
+			return 0
+		}
+		return float64(httpRequests(r)) / r.TotalDuration.Seconds()
+	}},
+	"requests": {kindNumber, func(r *Result) float64 { return float64(httpRequests(r)) }},
+	"errors":   {kindNumber, func(r *Result) float64 { return float64(httpFailed(r)) }},
+	"error_rate": {kindRatio, func(r *Result) float64 {
+		if httpRequests(r) == 0 {
+			return 0
+		}
+		return float64(httpFailed(r)) / float64(httpRequests(r))
+	}},
+	"success_rate": {kindRatio, func(r *Result) float64 {
+		if httpRequests(r) == 0 {
+			return 0
+		}
+		return float64(httpRequests(r)-httpFailed(r)) / float64(httpRequests(r))
+	}},
+}
+
+// assertOperators are comparison operators, two-character operators first
+var assertOperators = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// Assertion is a parsed -assert expression, e.g. p99<50ms or rps>=0.98*target
+type Assertion struct {
+	Spec      string
+	Metric    string
+	Operator  string
+	Threshold float64
+	metric    assertMetric
+}
+
+// AssertionResult is the outcome of an assertion evaluated against the result
+type AssertionResult struct {
+	Spec      string
+	Passed    bool
+	Actual    string
+	Threshold string
+}
+
+// parseAssertion parses "<metric><op><value>". targetRPS is the value of "target" in rps thresholds,
+// 0 if the run has no target rate (closed model)
+func parseAssertion(spec string, targetRPS int) (Assertion, error) {
+	expr := strings.ReplaceAll(spec, " ", "")
+
+	a := Assertion{Spec: spec}
+	var value string
+	for _, op := range assertOperators {
+		if i := strings.Index(expr, op); i > 0 {
+			a.Metric, a.Operator, value = expr[:i], op, expr[i+len(op):]
+			break
+		}
+	}
+	if a.Operator == "" || value == "" {
+		return a, fmt.Errorf("invalid assertion %q: expected <metric><op><value>, e.g. p99<50ms", spec)
+	}
+
+	metric, ok := assertMetrics[a.Metric]
+	if !ok {
+		return a, fmt.Errorf("invalid assertion %q: unknown metric %q", spec, a.Metric)
+	}
+	a.metric = metric
+
+	var err error
+	switch metric.kind {
+	case kindDuration:
+		var d time.Duration
+		if d, err = time.ParseDuration(value); err == nil {
+			a.Threshold = d.Seconds()
+		}
+	case kindRatio:
+		if percent, ok := strings.CutSuffix(value, "%"); ok {
+			a.Threshold, err = strconv.ParseFloat(percent, 64)
+			a.Threshold /= 100
+		} else {
+			a.Threshold, err = strconv.ParseFloat(value, 64)
+		}
+	case kindNumber:
+		a.Threshold, err = parseTargetValue(value, targetRPS)
+	}
+	if err != nil {
+		return a, fmt.Errorf("invalid assertion %q: invalid value %q: %v", spec, value, err)
+	}
+	return a, nil
+}
+
+// parseTargetValue parses a number, "target" or "<factor>*target"
+func parseTargetValue(value string, targetRPS int) (float64, error) {
+	if strings.HasSuffix(value, "target") && targetRPS <= 0 {
+		return 0, fmt.Errorf("the run has no target rate: virtual users (-type=user-session) run a closed model, use a number")
+	}
+	if factor, ok := strings.CutSuffix(value, "*target"); ok {
+		f, err := strconv.ParseFloat(factor, 64)
+		return f * float64(targetRPS), err
+	}
+	if value == "target" {
+		return float64(targetRPS), nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// Evaluate checks the assertion against the result
+func (a Assertion) Evaluate(r *Result) AssertionResult {
+	actual := a.metric.value(r)
+
+	var passed bool
+	switch a.Operator {
+	case "<":
+		passed = actual < a.Threshold
+	case "<=":
+		passed = actual <= a.Threshold
+	case ">":
+		passed = actual > a.Threshold
+	case ">=":
+		passed = actual >= a.Threshold
+	case "==":
+		passed = actual == a.Threshold
+	case "!=":
+		passed = actual != a.Threshold
+	}
+
+	return AssertionResult{
+		Spec:      a.Spec,
+		Passed:    passed,
+		Actual:    a.metric.kind.format(actual),
+		Threshold: a.metric.kind.format(a.Threshold),
+	}
+}
+
+// format prints metric value in the units of the metric
+func (k metricKind) format(value float64) string {
+	switch k {
+	case kindDuration:
+		return time.Duration(value * float64(time.Second)).Round(time.Microsecond).String()
+	case kindRatio:
+		return strconv.FormatFloat(value*100, 'g', 4, 64) + "%"
+	default:
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+}
+
+// evaluateAssertions evaluates all assertions and reports whether all passed
+func evaluateAssertions(assertions []Assertion, r *Result) ([]AssertionResult, bool) {
+	results := make([]AssertionResult, 0, len(assertions))
+	passed := true
+	for _, a := range assertions {
+		result := a.Evaluate(r)
+		passed = passed && result.Passed
+		results = append(results, result)
+	}
+	return results, passed
+}
+
+// httpRequests returns the number of HTTP requests of the run
+// (multi-step scenarios count cycles in TotalRequests)
+func httpRequests(r *Result) int64 {
+	if r.StepsPerCycle <= 1 {
+		return r.TotalRequests
+	}
+	var total int64
+	for _, step := range r.Steps {
+		total += step.Requests
+	}
+	return total
+}
+
+// httpFailed returns the number of failed HTTP requests of the run
+func httpFailed(r *Result) int64 {
+	if r.StepsPerCycle <= 1 {
+		return r.FailedRequests
+	}
+	var failed int64
+	for _, step := range r.Steps {
+		failed += step.Failed
+	}
+	return failed
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		spec      string
+		metric    string
+		operator  string
+		threshold float64
+		err       string
+	}{
+		{spec: "p99<50ms", metric: "p99", operator: "<", threshold: 0.05},
+		{spec: "avg <= 1.5s", metric: "avg", operator: "<=", threshold: 1.5},
+		{spec: "error_rate<0.1%", metric: "error_rate", operator: "<", threshold: 0.001},
+		{spec: "success_rate>=0.999", metric: "success_rate", operator: ">=", threshold: 0.999},
+		{spec: "rps>=0.98*target", metric: "rps", operator: ">=", threshold: 980},
+		{spec: "rps>target", metric: "rps", operator: ">", threshold: 1000},
+		{spec: "rps>=500", metric: "rps", operator: ">=", threshold: 500},
+		{spec: "errors==0", metric: "errors", operator: "==", threshold: 0},
+		{spec: "requests!=0", metric: "requests", operator: "!=", threshold: 0},
+		{spec: "p99", err: "expected <metric><op><value>"},
+		{spec: "p99<", err: "expected <metric><op><value>"},
+		{spec: "<50ms", err: "expected <metric><op><value>"},
+		{spec: "p42<50ms", err: `unknown metric "p42"`},
+		{spec: "p99<50", err: `invalid value "50"`},
+		{spec: "error_rate<abc%", err: `invalid value "abc%"`},
+		{spec: "rps>=x*target", err: `invalid value "x*target"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			a, err := parseAssertion(tt.spec, 1000)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if a.Metric != tt.metric || a.Operator != tt.operator || a.Threshold != tt.threshold || a.Spec != tt.spec {
+				t.Errorf("assertion = %+v, want %s %s %v", a, tt.metric, tt.operator, tt.threshold)
+			}
+		})
+	}
+}
+
+func TestParseAssertionWithoutTargetRate(t *testing.T) {
+	for _, spec := range []string{"rps>=target", "rps>=0.9*target"} {
+		if _, err := parseAssertion(spec, 0); err == nil || !strings.Contains(err.Error(), "no target rate") {
+			t.Errorf("parseAssertion(%q) error = %v, want no target rate", spec, err)
+		}
+	}
+	if _, err := parseAssertion("rps>=100", 0); err != nil {
+		t.Errorf("numeric rps threshold rejected: %v", err)
+	}
+	if got := scheduledRPS(Config{BenchmarkType: UserSession, RPS: 100, VirtualUsers: 10}); got != 0 {
+		t.Errorf("scheduledRPS of virtual users = %d, want 0", got)
+	}
+}
+
+func TestAssertionEvaluate(t *testing.T) {
+	result := &Result{
+		TotalRequests:   1000,
+		SuccessRequests: 990,
+		FailedRequests:  10,
+		TotalDuration:   10 * time.Second,
+		P99Latency:      40 * time.Millisecond,
+		AvgLatency:      5 * time.Millisecond,
+	}
+
+	tests := []struct {
+		spec      string
+		passed    bool
+		actual    string
+		threshold string
+	}{
+		{spec: "p99<50ms", passed: true, actual: "40ms", threshold: "50ms"},
+		{spec: "p99<40ms", passed: false, actual: "40ms", threshold: "40ms"},
+		{spec: "p99<=40ms", passed: true},
+		{spec: "avg>5ms", passed: false},
+		{spec: "avg>=5ms", passed: true},
+		{spec: "error_rate<0.1%", passed: false, actual: "1%", threshold: "0.1%"},
+		{spec: "error_rate<=1%", passed: true},
+		{spec: "success_rate>=99%", passed: true, actual: "99%"},
+		{spec: "rps>=0.98*target", passed: true, actual: "100.00", threshold: "98.00"},
+		{spec: "rps>target", passed: false},
+		{spec: "errors==10", passed: true, actual: "10.00"},
+		{spec: "requests!=1000", passed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			a, err := parseAssertion(tt.spec, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := a.Evaluate(result)
+			if got.Passed != tt.passed || got.Spec != tt.spec {
+				t.Errorf("result = %+v, want passed %v", got, tt.passed)
+			}
+			if tt.actual != "" && got.Actual != tt.actual {
+				t.Errorf("actual = %s, want %s", got.Actual, tt.actual)
+			}
+			if tt.threshold != "" && got.Threshold != tt.threshold {
+				t.Errorf("threshold = %s, want %s", got.Threshold, tt.threshold)
+			}
+		})
+	}
+}
+
+func TestAssertionsCountHTTPRequests(t *testing.T) {
+	// 100 cycles of a two-step scenario, 5 failed cycles with one failed step each
+	result := &Result{
+		TotalRequests:   100,
+		SuccessRequests: 95,
+		FailedRequests:  5,
+		TotalDuration:   10 * time.Second,
+		StepsPerCycle:   2,
+		Steps:           []*StepStats{{Name: "create", Requests: 100, Failed: 5}, {Name: "delete", Requests: 100}},
+	}
+
+	want := map[string]float64{
+		"requests":     200,
+		"errors":       5,
+		"rps":          20,
+		"error_rate":   0.025,
+		"success_rate": 0.975,
+	}
+	for name, value := range want {
+		if got := assertMetrics[name].value(result); got != value {
+			t.Errorf("%s = %v, want %v", name, got, value)
+		}
+	}
+}
+
+func TestAssertionsOfEmptyRun(t *testing.T) {
+	for name, metric := range assertMetrics {
+		if got := metric.value(&Result{}); got != 0 {
+			t.Errorf("%s of an empty run = %v, want 0", name, got)
+		}
+	}
+}
+
+func TestEvaluateAssertions(t *testing.T) {
+	result := &Result{TotalRequests: 10, TotalDuration: time.Second, P99Latency: 10 * time.Millisecond}
+	pass, _ := parseAssertion("p99<50ms", 10)
+	fail, _ := parseAssertion("rps>target", 10)
+
+	if results, passed := evaluateAssertions([]Assertion{pass}, result); !passed || len(results) != 1 {
+		t.Errorf("evaluateAssertions = %+v, %v, want passed", results, passed)
+	}
+	if results, passed := evaluateAssertions([]Assertion{fail, pass}, result); passed || len(results) != 2 || results[0].Passed || !results[1].Passed {
+		t.Errorf("evaluateAssertions = %+v, %v, want failed", results, passed)
+	}
+	if _, passed := evaluateAssertions(nil, result); !passed {
+		t.Error("no assertions failed")
+	}
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// calibrationReliableShare - share of the target RPS a reliable step must achieve
+const calibrationReliableShare = 0.98
+
+// CalibrationConfig is the configuration of the calibrate command
+type CalibrationConfig struct {
+	Type           BenchmarkType
+	Latency        time.Duration // Fixed latency of the mock server
+	Jitter         time.Duration // Random latency added to Latency, uniform in [0, Jitter]
+	Products       int           // Products in the mock server at start
+	Steps          []int         // Target RPS of the steps, ascending
+	StepDuration   time.Duration
+	Concurrency    int
+	MaxConcurrency int
+	MaxError       time.Duration // Maximum p99 measurement error of a reliable step
+}
+
+// LatencyString describes latency of the mock server, e.g. "1ms + random 0-500µs"
+func (c CalibrationConfig) LatencyString() string {
+	if c.Jitter <= 0 {
+		return c.Latency.String()
+	}
+	return fmt.Sprintf("%s + random 0-%s", c.Latency, c.Jitter)
+}
+
+// CalibrationStep is the outcome of the run against the mock server at one rate.
+// The measurement error is the client-side latency minus the time the server spent on requests
+type CalibrationStep struct {
+	TargetRPS      int
+	RPS            float64 // Achieved
+	Requests       int64   // Client-side
+	ServerRequests int64
+	Failed         int64
+	ServerAvg      time.Duration
+	ServerP50      time.Duration
+	ServerP99      time.Duration
+	ClientAvg      time.Duration
+	ClientP50      time.Duration
+	ClientP99      time.Duration
+	RunnerCPU      float64  // Average CPU usage in percent of one core, mock server included
+	Warnings       []string // Runner's bottleneck warnings
+	Reliable       bool
+	Reasons        []string // Why the step is not reliable
+}
+
+// ErrorAvg returns the measurement error of the average latency
+func (s *CalibrationStep) ErrorAvg() time.Duration { return s.ClientAvg - s.ServerAvg }
+
+// ErrorP50 returns the measurement error of the median latency
+func (s *CalibrationStep) ErrorP50() time.Duration { return s.ClientP50 - s.ServerP50 }
+
+// ErrorP99 returns the measurement error of the 99th percentile latency
+func (s *CalibrationStep) ErrorP99() time.Duration { return s.ClientP99 - s.ServerP99 }
+
+// CalibrationResult is the outcome of the calibrate command
+type CalibrationResult struct {
+	Config      CalibrationConfig
+	CPUCores    int
+	Steps       []*CalibrationStep
+	MaxReliable *CalibrationStep // Last reliable step before the first unreliable one, nil if none
+	AllReliable bool             // Every step was reliable, the limit is above the last step
+	Interrupted bool
+}
+
+// runCalibrateCommand implements "benchmark-runner calibrate [flags]"
+func runCalibrateCommand(args []string) int {
+	fs := flag.NewFlagSet("calibrate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s calibrate [flags]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "Runs the benchmark against a built-in mock of the /api/products contract with known latency\n")
+		fmt.Fprintf(fs.Output(), "at increasing rates and reports the runner's measurement error and maximum reliable RPS.\n\n")
+		fs.PrintDefaults()
+	}
+	var config CalibrationConfig
+	benchType := fs.String("type", string(GetProducts), "Benchmark type: "+strings.Join(ScenarioNames(), ", "))
+	fs.DurationVar(&config.Latency, "latency", time.Millisecond, "Fixed latency of the mock server")
+	fs.DurationVar(&config.Jitter, "jitter", 0, "Random latency added to -latency, uniform from 0 to the value")
+	fs.IntVar(&config.Products, "products", 20, "Number of products in the mock server (size of the GET /api/products response)")
+	steps := fs.String("rps", "500,1000,2000,5000,10000,20000,50000", "Comma separated target RPS of the steps, ascending; calibration stops at the first unreliable step")
+	fs.DurationVar(&config.StepDuration, "duration", 10*time.Second, "Duration of every step")
+	fs.IntVar(&config.Concurrency, "concurrency", 10, "Initial number of concurrent workers")
+	fs.IntVar(&config.MaxConcurrency, "max-concurrency", 1000, "Maximum number of workers the pool may grow to")
+	fs.DurationVar(&config.MaxError, "max-error", time.Millisecond, "Maximum p99 measurement error of a reliable step")
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	config.Type = BenchmarkType(*benchType)
+	if _, err := LookupScenario(string(config.Type)); err != nil {
+		log.Printf("Invalid -type: %v, calibration supports %s", err, strings.Join(ScenarioNames(), ", "))
+		return 2
+	}
+	var err error
+	if config.Steps, err = parseRPSSteps(*steps); err != nil {
+		log.Printf("Invalid -rps: %v", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Printf("Interrupted: stopping the current step, press Ctrl+C again to exit immediately")
+	}()
+
+	result, err := runCalibration(ctx, config)
+	if err != nil {
+		log.Printf("Calibration failed: %v", err)
+		return 1
+	}
+	printCalibration(result)
+	return 0
+}
+
+// parseRPSSteps parses a comma separated ascending list of positive rates
+func parseRPSSteps(spec string) ([]int, error) {
+	var steps []int
+	for _, field := range strings.Split(spec, ",") {
+		rps, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("%q is not a positive number", field)
+		}
+		if len(steps) > 0 && rps <= steps[len(steps)-1] {
+			return nil, fmt.Errorf("steps must be ascending, %d after %d", rps, steps[len(steps)-1])
+		}
+		steps = append(steps, rps)
+	}
+	return steps, nil
+}
+
+// runCalibration starts the mock server and runs the steps until the first unreliable one
+func runCalibration(ctx context.Context, config CalibrationConfig) (*CalibrationResult, error) {
+	server, err := startMockServer(config.Latency, config.Jitter, config.Products)
+	if err != nil {
+		return nil, err
+	}
+	defer server.Stop()
+
+	log.Printf("Calibration:")
+	log.Printf("  Mock Server: %s, latency %s, %d products", server.URL, config.LatencyString(), config.Products)
+	log.Printf("  Type: %s", config.Type)
+	log.Printf("  Steps: %v RPS, %s each", config.Steps, config.StepDuration)
+	log.Printf("  Concurrency: %d (max %d)", config.Concurrency, config.MaxConcurrency)
+	log.Printf("")
+
+	result := &CalibrationResult{Config: config, CPUCores: runtime.GOMAXPROCS(0), AllReliable: true}
+	for _, rps := range config.Steps {
+		request := RunRequest{
+			URL:            server.URL,
+			Type:           string(config.Type),
+			RPS:            rps,
+			Duration:       config.StepDuration.String(),
+			Concurrency:    config.Concurrency,
+			MaxConcurrency: config.MaxConcurrency,
+			KeepCreated:    true, // The mock server is thrown away, teardown requests would only skew its timings
+		}
+		runConfig, _, err := request.config()
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("Step %d RPS", rps)
+		server.TakeTimes()
+		r, err := runOnce(ctx, runConfig)
+		if err != nil {
+			return nil, err
+		}
+		step := calibrationStep(rps, r, server.TakeTimes(), config.MaxError)
+		if ctx.Err() != nil {
+			step.Reliable = false
+			step.Reasons = append(step.Reasons, "interrupted")
+			result.Interrupted = true
+		}
+		result.Steps = append(result.Steps, step)
+		log.Printf("Step %d RPS: %.1f req/s, measurement error p50 %s, p99 %s, reliable: %v",
+			rps, step.RPS, formatSignedDuration(step.ErrorP50()), formatSignedDuration(step.ErrorP99()), step.Reliable)
+
+		if !step.Reliable {
+			result.AllReliable = false
+			break
+		}
+		result.MaxReliable = step
+	}
+	return result, nil
+}
+
+// calibrationStep compares the client-side result of the step with the server-side request times
+func calibrationStep(rps int, r *Result, serverTimes []time.Duration, maxError time.Duration) *CalibrationStep {
+	runner := r.Runner
+	// The rate is checked in cycles the worker pool schedules (rps / steps, at least 1),
+	// so cycles cut short by a failed step don't hide or fake a slow runner
+	cycles, scheduled := r.TotalRequests, float64(rps)
+	if r.StepsPerCycle > 1 {
+		scheduled = float64(max(rps/r.StepsPerCycle, 1))
+		// Multi-step scenarios count cycles, the server sees their HTTP requests
+		r = combineResults([]*Result{r})
+	}
+	step := &CalibrationStep{
+		TargetRPS:      rps,
+		Requests:       r.TotalRequests,
+		ServerRequests: int64(len(serverTimes)),
+		Failed:         r.FailedRequests,
+		ClientAvg:      r.AvgLatency,
+		ClientP50:      r.P50Latency,
+		ClientP99:      r.P99Latency,
+	}
+	if r.TotalDuration > 0 {
+		step.RPS = float64(r.TotalRequests) / r.TotalDuration.Seconds()
+	}
+	if runner != nil {
+		step.RunnerCPU = runner.AvgCPUPercent
+		step.Warnings = runner.Warnings
+	}
+
+	if len(serverTimes) > 0 {
+		slices.Sort(serverTimes)
+		var sum time.Duration
+		for _, t := range serverTimes {
+			sum += t
+		}
+		step.ServerAvg = sum / time.Duration(len(serverTimes))
+		step.ServerP50 = percentile(serverTimes, 0.50)
+		step.ServerP99 = percentile(serverTimes, 0.99)
+	}
+
+	var share float64
+	if r.TotalDuration > 0 {
+		share = float64(cycles) / r.TotalDuration.Seconds() / scheduled
+	}
+	if share < calibrationReliableShare {
+		step.Reasons = append(step.Reasons, fmt.Sprintf("achieved %.0f RPS (%.0f%% of the target)", step.RPS, share*100))
+	}
+	if step.Failed > 0 {
+		step.Reasons = append(step.Reasons, fmt.Sprintf("%d failed requests", step.Failed))
+	}
+	if step.ErrorP99() > maxError {
+		step.Reasons = append(step.Reasons, fmt.Sprintf("p99 measurement error %s is over %s", step.ErrorP99().Round(time.Microsecond), maxError))
+	}
+	step.Reliable = len(step.Reasons) == 0
+	return step
+}
+
+// formatSignedDuration formats duration with an explicit sign, e.g. "+152µs"
+func formatSignedDuration(d time.Duration) string {
+	d = d.Round(time.Microsecond)
+	if d < 0 {
+		return d.String()
+	}
+	return "+" + d.String()
+}
+
+// printCalibration prints measurement error of every calibration step and the maximum reliable RPS
+func printCalibration(c *CalibrationResult) {
+	fmt.Println("")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("                     CALIBRATION RESULTS")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Printf("Mock Server:         latency %s, %d products\n", c.Config.LatencyString(), c.Config.Products)
+	fmt.Printf("Benchmark Type:      %s\n", c.Config.Type)
+	fmt.Printf("Runner CPU Cores:    %d\n", c.CPUCores)
+	fmt.Println("")
+	fmt.Println("Measurement error is the client-side latency minus the time the server spent on the request.")
+	fmt.Println("┌──────────┬──────────┬────────────┬────────────┬────────────┬────────────┬─────────┬────────┐")
+	fmt.Println("│  TARGET  │  REQ/S   │ SERVER P50 │ SERVER P99 │ ERROR P50  │ ERROR P99  │   CPU   │ RESULT │")
+	fmt.Println("├──────────┼──────────┼────────────┼────────────┼────────────┼────────────┼─────────┼────────┤")
+
+	stepList := make([]map[string]interface{}, 0, len(c.Steps))
+	for _, step := range c.Steps {
+		status := "OK"
+		if !step.Reliable {
+			status = "FAIL"
+		}
+		fmt.Printf("│ %8d │ %8.1f │ %10s │ %10s │ %10s │ %10s │ %6.0f%% │ %-6s │\n",
+			step.TargetRPS, step.RPS, step.ServerP50.Round(time.Microsecond), step.ServerP99.Round(time.Microsecond),
+			formatSignedDuration(step.ErrorP50()), formatSignedDuration(step.ErrorP99()), step.RunnerCPU, status)
+
+		stepList = append(stepList, map[string]interface{}{
+			"target_rps":         step.TargetRPS,
+			"rps":                step.RPS,
+			"requests":           step.Requests,
+			"server_requests":    step.ServerRequests,
+			"failed_requests":    step.Failed,
+			"server_avg_ms":      durationMs(step.ServerAvg),
+			"server_p50_ms":      durationMs(step.ServerP50),
+			"server_p99_ms":      durationMs(step.ServerP99),
+			"client_avg_ms":      durationMs(step.ClientAvg),
+			"client_p50_ms":      durationMs(step.ClientP50),
+			"client_p99_ms":      durationMs(step.ClientP99),
+			"error_avg_ms":       durationMs(step.ErrorAvg()),
+			"error_p50_ms":       durationMs(step.ErrorP50()),
+			"error_p99_ms":       durationMs(step.ErrorP99()),
+			"runner_cpu_percent": step.RunnerCPU,
+			"reliable":           step.Reliable,
+		})
+		if len(step.Reasons) > 0 {
+			stepList[len(stepList)-1]["reasons"] = step.Reasons
+		}
+		if len(step.Warnings) > 0 {
+			stepList[len(stepList)-1]["runner_warnings"] = step.Warnings
+		}
+	}
+	fmt.Println("└──────────┴──────────┴────────────┴────────────┴────────────┴────────────┴─────────┴────────┘")
+
+	for _, step := range c.Steps {
+		for _, reason := range step.Reasons {
+			fmt.Printf("  %d RPS: %s\n", step.TargetRPS, reason)
+		}
+		for _, warning := range step.Warnings {
+			fmt.Printf("  %d RPS: WARNING: %s\n", step.TargetRPS, warning)
+		}
+	}
+
+	fmt.Println("")
+	jsonData := map[string]interface{}{
+		"type":                  c.Config.Type,
+		"latency_ms":            durationMs(c.Config.Latency),
+		"jitter_ms":             durationMs(c.Config.Jitter),
+		"products":              c.Config.Products,
+		"step_duration_seconds": c.Config.StepDuration.Seconds(),
+		"max_error_ms":          durationMs(c.Config.MaxError),
+		"cpu_cores":             c.CPUCores,
+		"interrupted":           c.Interrupted,
+		"steps":                 stepList,
+	}
+	if step := c.MaxReliable; step != nil {
+		limit := fmt.Sprintf("%d", step.TargetRPS)
+		if c.AllReliable {
+			limit = fmt.Sprintf("at least %d (every step was reliable, add higher -rps steps to find the limit)", step.TargetRPS)
+		}
+		fmt.Printf("Max Reliable RPS:    %s\n", limit)
+		fmt.Printf("Measurement Error:   avg %s, p50 %s, p99 %s at %d RPS\n",
+			formatSignedDuration(step.ErrorAvg()), formatSignedDuration(step.ErrorP50()), formatSignedDuration(step.ErrorP99()), step.TargetRPS)
+		fmt.Printf("Latency differences below %s at p99 are within the runner's own error up to this rate\n", step.ErrorP99().Round(time.Microsecond))
+		jsonData["max_reliable_rps"] = step.TargetRPS
+		jsonData["measurement_error"] = map[string]interface{}{
+			"rps":    step.TargetRPS,
+			"avg_ms": durationMs(step.ErrorAvg()),
+			"p50_ms": durationMs(step.ErrorP50()),
+			"p99_ms": durationMs(step.ErrorP99()),
+		}
+	} else {
+		fmt.Printf("Max Reliable RPS:    none of the steps was reliable, try lower -rps steps\n")
+		jsonData["max_reliable_rps"] = 0
+	}
+
+	jsonResult, _ := json.MarshalIndent(map[string]interface{}{"calibration": jsonData}, "", "  ")
+	fmt.Println("")
+	fmt.Println("JSON Results:")
+	fmt.Println(string(jsonResult))
+}
+
+// mockServer implements the /api/products contract in process with configurable latency.
+// It records how long it handled every request: the latency a perfect client would measure
+type mockServer struct {
+	URL string
+
+	latency  time.Duration
+	jitter   time.Duration
+	listener net.Listener
+	server   *http.Server
+
+	mu       sync.RWMutex
+	products map[int64]Product
+	nextID   int64
+	list     []byte // Cached GET /api/products response, nil after changes
+
+	timesMu sync.Mutex
+	times   []time.Duration
+}
+
+// startMockServer starts the mock server on a random loopback port with the given number of products
+func startMockServer(latency, jitter time.Duration, products int) (*mockServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start mock server: %v", err)
+	}
+	m := &mockServer{
+		URL:      "http://" + listener.Addr().String(),
+		latency:  latency,
+		jitter:   jitter,
+		listener: listener,
+		products: make(map[int64]Product, products),
+	}
+	for i := 0; i < products; i++ {
+		m.nextID++
+		m.products[m.nextID] = Product{
+			ID:          m.nextID,
+			Name:        fmt.Sprintf("Product %d", m.nextID),
+			Description: "Mock product of the calibration server",
+			Price:       float64(mathrand.Intn(10000)) / 100,
+			Quantity:    mathrand.Intn(1000),
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", m.handle(func(r *http.Request) (int, interface{}) {
+		return http.StatusOK, map[string]string{"status": "UP"}
+	}))
+	mux.HandleFunc("GET /api/products", m.handle(m.listProducts))
+	mux.HandleFunc("POST /api/products", m.handle(m.createProduct))
+	mux.HandleFunc("GET /api/products/{id}", m.handle(m.getProduct))
+	mux.HandleFunc("PUT /api/products/{id}", m.handle(m.updateProduct))
+	mux.HandleFunc("DELETE /api/products/{id}", m.handle(m.deleteProduct))
+
+	m.server = &http.Server{Handler: mux}
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// Stop stops the mock server
+func (m *mockServer) Stop() {
+	m.server.Close()
+}
+
+// TakeTimes returns request times recorded since the previous call
+func (m *mockServer) TakeTimes() []time.Duration {
+	m.timesMu.Lock()
+	defer m.timesMu.Unlock()
+	times := m.times
+	m.times = nil
+	return times
+}
+
+// handle wraps a handler with the latency and timing. The handler returns status and a JSON body
+// (nil - no body, []byte - encoded body)
+func (m *mockServer) handle(handler func(r *http.Request) (int, interface{})) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		io.Copy(io.Discard, r.Body)
+
+		delay := m.latency
+		if m.jitter > 0 {
+			delay += time.Duration(mathrand.Int63n(int64(m.jitter) + 1))
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+
+		status, body := handler(r)
+		var data []byte
+		switch body := body.(type) {
+		case nil:
+		case []byte:
+			data = body
+		default:
+			data, _ = json.Marshal(body)
+		}
+		elapsed := time.Since(start)
+
+		m.timesMu.Lock()
+		m.times = append(m.times, elapsed)
+		m.timesMu.Unlock()
+
+		if data != nil {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		w.Write(data)
+	}
+}
+
+var errProductNotFound = map[string]string{"error": "Product not found"}
+
+func (m *mockServer) listProducts(r *http.Request) (int, interface{}) {
+	m.mu.RLock()
+	list := m.list
+	m.mu.RUnlock()
+	if list != nil {
+		return http.StatusOK, list
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.list == nil {
+		products := make([]Product, 0, len(m.products))
+		for _, product := range m.products {
+			products = append(products, product)
+		}
+		sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+		m.list, _ = json.Marshal(products)
+	}
+	return http.StatusOK, m.list
+}
+
+func (m *mockServer) createProduct(r *http.Request) (int, interface{}) {
+	// The body was drained by handle, the contract only needs a new ID
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	product := Product{ID: m.nextID, Name: "Benchmark Product", Description: "Created by benchmark tool", Price: 99.99, Quantity: 100}
+	m.products[product.ID] = product
+	m.list = nil
+	return http.StatusCreated, product
+}
+
+func (m *mockServer) getProduct(r *http.Request) (int, interface{}) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "Invalid product ID"}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	product, ok := m.products[id]
+	if !ok {
+		return http.StatusNotFound, errProductNotFound
+	}
+	return http.StatusOK, product
+}
+
+func (m *mockServer) updateProduct(r *http.Request) (int, interface{}) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "Invalid product ID"}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[id]
+	if !ok {
+		return http.StatusNotFound, errProductNotFound
+	}
+	product.Name, product.Description, product.Price, product.Quantity = "Updated Product", "Updated by benchmark tool", 149.99, 200
+	m.products[id] = product
+	m.list = nil
+	return http.StatusOK, product
+}
+
+func (m *mockServer) deleteProduct(r *http.Request) (int, interface{}) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "Invalid product ID"}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.products[id]; !ok {
+		return http.StatusNotFound, errProductNotFound
+	}
+	delete(m.products, id)
+	m.list = nil
+	return http.StatusNoContent, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRPSSteps(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []int
+		wantErr string
+	}{
+		{spec: "100", want: []int{100}},
+		{spec: "100, 500,1000", want: []int{100, 500, 1000}},
+		{spec: "100,abc", wantErr: `"abc" is not a positive number`},
+		{spec: "0,100", wantErr: `"0" is not a positive number`},
+		{spec: "-5", wantErr: `"-5" is not a positive number`},
+		{spec: "100,,200", wantErr: `"" is not a positive number`},
+		{spec: "500,100", wantErr: "steps must be ascending, 100 after 500"},
+		{spec: "100,100", wantErr: "steps must be ascending, 100 after 100"},
+	}
+	for _, tt := range tests {
+		got, err := parseRPSSteps(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseRPSSteps(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("parseRPSSteps(%q) = %v, %v, want %v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatSignedDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "+0s"},
+		{d: 152 * time.Microsecond, want: "+152µs"},
+		{d: 152*time.Microsecond + 400*time.Nanosecond, want: "+152µs"},
+		{d: -3 * time.Millisecond, want: "-3ms"},
+		{d: 1500 * time.Millisecond, want: "+1.5s"},
+	}
+	for _, tt := range tests {
+		if got := formatSignedDuration(tt.d); got != tt.want {
+			t.Errorf("formatSignedDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestCalibrationLatencyString(t *testing.T) {
+	if got := (CalibrationConfig{Latency: time.Millisecond}).LatencyString(); got != "1ms" {
+		t.Errorf("without jitter = %q, want 1ms", got)
+	}
+	if got := (CalibrationConfig{Latency: time.Millisecond, Jitter: 500 * time.Microsecond}).LatencyString(); got != "1ms + random 0-500µs" {
+		t.Errorf("with jitter = %q", got)
+	}
+}
+
+func TestCalibrationStep(t *testing.T) {
+	serverTimes := []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond}
+	result := func(requests, failed int64, p99 time.Duration) *Result {
+		return &Result{
+			TotalRequests:  requests,
+			FailedRequests: failed,
+			TotalDuration:  10 * time.Second,
+			AvgLatency:     1200 * time.Microsecond,
+			P50Latency:     1100 * time.Microsecond,
+			P99Latency:     p99,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		result  *Result
+		reasons []string
+	}{
+		{name: "reliable", result: result(1000, 0, 1500*time.Microsecond)},
+		{name: "slow", result: result(900, 0, 1500*time.Microsecond), reasons: []string{"achieved 90 RPS (90% of the target)"}},
+		{name: "failed", result: result(1000, 3, 1500*time.Microsecond), reasons: []string{"3 failed requests"}},
+		{name: "inaccurate", result: result(1000, 0, 3*time.Millisecond), reasons: []string{"p99 measurement error 2ms is over 1ms"}},
+	}
+	for _, tt := range tests {
+		step := calibrationStep(100, tt.result, append([]time.Duration(nil), serverTimes...), time.Millisecond)
+		if step.Reliable != (len(tt.reasons) == 0) || strings.Join(step.Reasons, "; ") != strings.Join(tt.reasons, "; ") {
+			t.Errorf("%s: reliable = %v, reasons = %q, want %q", tt.name, step.Reliable, step.Reasons, tt.reasons)
+		}
+		if step.ServerRequests != 4 || step.ServerAvg != time.Millisecond || step.ServerP50 != time.Millisecond || step.ServerP99 != time.Millisecond {
+			t.Errorf("%s: server stats = %d requests, avg %s, p50 %s, p99 %s", tt.name, step.ServerRequests, step.ServerAvg, step.ServerP50, step.ServerP99)
+		}
+		if step.ErrorAvg() != 200*time.Microsecond || step.ErrorP50() != 100*time.Microsecond {
+			t.Errorf("%s: measurement error avg %s, p50 %s, want 200µs and 100µs", tt.name, step.ErrorAvg(), step.ErrorP50())
+		}
+	}
+}
+
+func TestCalibrationStepMultiStep(t *testing.T) {
+	// mixed-operations at 100 RPS schedules 25 four-request cycles per second
+	result := func(cycles int64) *Result {
+		steps := make([]*StepStats, 4)
+		for i := range steps {
+			steps[i] = &StepStats{Name: fmt.Sprintf("step-%d", i), Requests: cycles}
+		}
+		return &Result{TotalRequests: cycles, SuccessRequests: cycles, TotalDuration: 10 * time.Second, StepsPerCycle: 4, Steps: steps, Errors: NewErrorStats()}
+	}
+
+	tests := []struct {
+		name     string
+		cycles   int64
+		requests int64
+		reasons  []string
+	}{
+		{name: "on schedule", cycles: 250, requests: 1000},
+		{name: "quarter of the cycles", cycles: 62, requests: 248, reasons: []string{"achieved 25 RPS (25% of the target)"}},
+	}
+	for _, tt := range tests {
+		step := calibrationStep(100, result(tt.cycles), nil, time.Second)
+		if step.Requests != tt.requests || step.RPS != float64(tt.requests)/10 {
+			t.Errorf("%s: requests = %d, rps %v, want HTTP requests %d", tt.name, step.Requests, step.RPS, tt.requests)
+		}
+		if step.Reliable != (len(tt.reasons) == 0) || strings.Join(step.Reasons, "; ") != strings.Join(tt.reasons, "; ") {
+			t.Errorf("%s: reliable = %v, reasons = %q, want %q", tt.name, step.Reliable, step.Reasons, tt.reasons)
+		}
+	}
+}
+
+func TestMockServer(t *testing.T) {
+	server, err := startMockServer(time.Millisecond, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	call := func(method, path string) (int, []byte) {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	status, body := call("GET", "/api/products")
+	var products []Product
+	if status != http.StatusOK || json.Unmarshal(body, &products) != nil || len(products) != 2 || products[0].ID != 1 {
+		t.Fatalf("list = %d %s, want 2 products", status, body)
+	}
+
+	status, body = call("POST", "/api/products")
+	var created Product
+	if status != http.StatusCreated || json.Unmarshal(body, &created) != nil || created.ID != 3 {
+		t.Fatalf("create = %d %s, want product 3", status, body)
+	}
+	if _, body = call("GET", "/api/products"); json.Unmarshal(body, &products) != nil || len(products) != 3 {
+		t.Errorf("list after create = %s, want 3 products", body)
+	}
+
+	tests := []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/api/products/3", http.StatusOK},
+		{"PUT", "/api/products/3", http.StatusOK},
+		{"DELETE", "/api/products/3", http.StatusNoContent},
+		{"GET", "/api/products/3", http.StatusNotFound},
+		{"PUT", "/api/products/3", http.StatusNotFound},
+		{"DELETE", "/api/products/3", http.StatusNotFound},
+		{"GET", "/api/products/abc", http.StatusBadRequest},
+		{"GET", "/health", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if status, body := call(tt.method, tt.path); status != tt.status {
+			t.Errorf("%s %s = %d %s, want %d", tt.method, tt.path, status, body, tt.status)
+		}
+	}
+
+	times := server.TakeTimes()
+	if len(times) != 11 {
+		t.Fatalf("recorded %d request times, want 11", len(times))
+	}
+	for _, d := range times {
+		if d < time.Millisecond {
+			t.Errorf("request time %s is under the latency of 1ms", d)
+		}
+	}
+	if times := server.TakeTimes(); len(times) != 0 {
+		t.Errorf("second TakeTimes returned %d times, want 0", len(times))
+	}
+}
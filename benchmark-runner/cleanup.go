@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// bodySizeWindow is the number of seconds at the start and at the end of the run
+	// whose response sizes are compared
+	bodySizeWindow = 10
+
+	// bodyGrowthThreshold - growth of the average response size reported as drift
+	bodyGrowthThreshold = 0.10
+)
+
+// ResourceOperation is implemented by operations that create or delete resources on the target.
+// Created resources are tracked and deleted in the teardown phase, so runs don't grow the database
+type ResourceOperation interface {
+	// CreatedResource returns URL of the resource created by the response, "" if nothing was created
+	CreatedResource(ctx *RequestContext, resp *Response, state *State) string
+
+	// DeletedResource returns URL of the resource deleted by the response, "" if nothing was deleted
+	DeletedResource(ctx *RequestContext, resp *Response, state *State) string
+}
+
+// resourceTracker keeps URLs of resources created during the run and not deleted yet
+type resourceTracker struct {
+	mu      sync.Mutex
+	urls    map[string]struct{}
+	created int64
+}
+
+// newResourceTracker creates empty tracker
+func newResourceTracker() *resourceTracker {
+	return &resourceTracker{urls: make(map[string]struct{})}
+}
+
+// track updates the tracker with the response of a resource operation
+func (t *resourceTracker) track(ctx *RequestContext, op Operation, resp *Response, state *State) {
+	resourceOp, ok := op.(ResourceOperation)
+	if !ok {
+		return
+	}
+	if url := resourceOp.CreatedResource(ctx, resp, state); url != "" {
+		t.mu.Lock()
+		t.urls[url] = struct{}{}
+		t.created++
+		t.mu.Unlock()
+	}
+	if url := resourceOp.DeletedResource(ctx, resp, state); url != "" {
+		t.mu.Lock()
+		delete(t.urls, url)
+		t.mu.Unlock()
+	}
+}
+
+// CleanupStats is the outcome of the teardown phase
+type CleanupStats struct {
+	Enabled  bool          // Teardown was run (-cleanup)
+	Created  int64         // Resources created during the run
+	Left     int           // Resources not deleted by the run itself
+	Deleted  int           // Resources deleted by the teardown
+	Failed   int           // Resources the teardown failed to delete
+	Duration time.Duration // Duration of the teardown
+	Errors   []string      // First errors of the teardown
+}
+
+// teardown deletes resources left by the run with the given number of workers.
+// Without enabled it only reports how many resources were left
+func (t *resourceTracker) teardown(client *http.Client, workers int, enabled bool) *CleanupStats {
+	t.mu.Lock()
+	urls := make([]string, 0, len(t.urls))
+	for url := range t.urls {
+		urls = append(urls, url)
+	}
+	stats := &CleanupStats{Enabled: enabled, Created: t.created, Left: len(urls)}
+	t.mu.Unlock()
+
+	if !enabled || len(urls) == 0 {
+		return stats
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	sort.Strings(urls)
+
+	log.Printf("Teardown: deleting %d resources created during the run", len(urls))
+	start := time.Now()
+	queue := make(chan string)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for url := range queue {
+				// One retry, so a transient error doesn't leave the resource behind
+				err := deleteResource(client, url)
+				if err != nil {
+					err = deleteResource(client, url)
+				}
+
+				mu.Lock()
+				if err != nil {
+					stats.Failed++
+					if len(stats.Errors) < 5 {
+						stats.Errors = append(stats.Errors, err.Error())
+					}
+				} else {
+					stats.Deleted++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, url := range urls {
+		queue <- url
+	}
+	close(queue)
+	wg.Wait()
+
+	stats.Duration = time.Since(start)
+	log.Printf("Teardown: deleted %d, failed %d in %s", stats.Deleted, stats.Failed, stats.Duration.Round(time.Millisecond))
+	return stats
+}
+
+// deleteResource deletes resource by URL. A resource that is already gone counts as deleted
+func deleteResource(client *http.Client, url string) error {
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return fmt.Errorf("DELETE %s: %v", url, err)
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("DELETE %s: status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// BodySizeStats is the response size of one operation over the run
+type BodySizeStats struct {
+	Operation string
+	Series    []float64 // Average response size per second, bytes (0 - no responses)
+	FirstAvg  float64   // Average over the first seconds of the run
+	LastAvg   float64   // Average over the last seconds of the run
+	Max       int
+	Growth    float64 // LastAvg / FirstAvg - 1
+}
+
+// bodySizeRecorder collects response sizes per operation and second of the run
+type bodySizeRecorder struct {
+	mu    sync.Mutex
+	start time.Time
+	ops   map[string]*bodySizeSeries
+}
+
+type bodySizeSeries struct {
+	bytes  []int64
+	counts []int64
+	max    int
+}
+
+// newBodySizeRecorder creates recorder, seconds are counted from now
+func newBodySizeRecorder() *bodySizeRecorder {
+	return &bodySizeRecorder{start: time.Now(), ops: make(map[string]*bodySizeSeries)}
+}
+
+// Start resets the start of the run
+func (r *bodySizeRecorder) Start() {
+	r.mu.Lock()
+	r.start = time.Now()
+	r.mu.Unlock()
+}
+
+// Record records size of the response of operation completed now
+func (r *bodySizeRecorder) Record(operation string, size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	second := int(time.Since(r.start) / time.Second)
+	series, ok := r.ops[operation]
+	if !ok {
+		series = &bodySizeSeries{}
+		r.ops[operation] = series
+	}
+	for len(series.bytes) <= second {
+		series.bytes = append(series.bytes, 0)
+		series.counts = append(series.counts, 0)
+	}
+	series.bytes[second] += int64(size)
+	series.counts[second]++
+	if size > series.max {
+		series.max = size
+	}
+}
+
+// Results returns response sizes of every operation sorted by operation
+func (r *bodySizeRecorder) Results() []BodySizeStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]BodySizeStats, 0, len(r.ops))
+	for operation, series := range r.ops {
+		stats := BodySizeStats{Operation: operation, Max: series.max, Series: make([]float64, len(series.bytes))}
+		for i := range series.bytes {
+			if series.counts[i] > 0 {
+				stats.Series[i] = float64(series.bytes[i]) / float64(series.counts[i])
+			}
+		}
+		// Short runs compare their first and last thirds
+		window := min(bodySizeWindow, max(1, len(series.bytes)/3))
+		stats.FirstAvg = windowAverage(series, 0, window)
+		stats.LastAvg = windowAverage(series, len(series.bytes)-window, len(series.bytes))
+		if stats.FirstAvg > 0 {
+			stats.Growth = stats.LastAvg/stats.FirstAvg - 1
+		}
+		results = append(results, stats)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Operation < results[j].Operation })
+	return results
+}
+
+// windowAverage returns average response size over seconds [from, to)
+func windowAverage(series *bodySizeSeries, from, to int) float64 {
+	if from < 0 {
+		from = 0
+	}
+	var bytes, count int64
+	for i := from; i < to && i < len(series.bytes); i++ {
+		bytes += series.bytes[i]
+		count += series.counts[i]
+	}
+	if count == 0 {
+		return 0
+	}
+	return float64(bytes) / float64(count)
+}
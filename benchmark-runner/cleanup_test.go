@@ -0,0 +1,180 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResourceTrackerTrack(t *testing.T) {
+	lookup := func(name BenchmarkType) Operation {
+		op, ok := LookupOperation(string(name))
+		if !ok {
+			t.Fatalf("operation %s is not registered", name)
+		}
+		return op
+	}
+	ctx := &RequestContext{Config: Config{URL: "http://target"}}
+	state := func(id int64) *State { return &State{ProductID: id} }
+	tracker := newResourceTracker()
+
+	tests := []struct {
+		name   string
+		op     Operation
+		status int
+		id     int64
+		left   []string
+	}{
+		{name: "created", op: lookup(CreateProduct), status: http.StatusCreated, id: 1, left: []string{"http://target/api/products/1"}},
+		{name: "create failed", op: lookup(CreateProduct), status: http.StatusInternalServerError, id: 2, left: []string{"http://target/api/products/1"}},
+		{name: "created again", op: lookup(CreateProduct), status: http.StatusCreated, id: 3, left: []string{"http://target/api/products/1", "http://target/api/products/3"}},
+		{name: "read", op: lookup(GetProductByID), status: http.StatusOK, id: 3, left: []string{"http://target/api/products/1", "http://target/api/products/3"}},
+		{name: "delete failed", op: lookup(DeleteProduct), status: http.StatusInternalServerError, id: 1, left: []string{"http://target/api/products/1", "http://target/api/products/3"}},
+		{name: "deleted", op: lookup(DeleteProduct), status: http.StatusNoContent, id: 1, left: []string{"http://target/api/products/3"}},
+		{name: "already gone", op: lookup(DeleteProduct), status: http.StatusNotFound, id: 3, left: []string{}},
+		{name: "not a resource operation", op: getOperation{url: "http://target"}, status: http.StatusCreated, id: 4, left: []string{}},
+	}
+
+	for _, tt := range tests {
+		tracker.track(ctx, tt.op, &Response{StatusCode: tt.status}, state(tt.id))
+		left := make([]string, 0)
+		for url := range tracker.urls {
+			left = append(left, url)
+		}
+		slices.Sort(left)
+		if !slices.Equal(left, tt.left) {
+			t.Errorf("%s: left = %v, want %v", tt.name, left, tt.left)
+		}
+	}
+	if tracker.created != 2 {
+		t.Errorf("created = %d, want 2", tracker.created)
+	}
+}
+
+func TestResourceTrackerTeardown(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts = make(map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		mu.Lock()
+		attempts[r.URL.Path]++
+		attempt := attempts[r.URL.Path]
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/api/products/flaky":
+			if attempt == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/api/products/gone":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/api/products/locked":
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tracker := newResourceTracker()
+	for _, id := range []string{"1", "2", "flaky", "gone", "locked"} {
+		tracker.urls[server.URL+"/api/products/"+id] = struct{}{}
+	}
+	tracker.created = 7
+
+	if stats := tracker.teardown(server.Client(), 2, false); stats.Enabled || stats.Left != 5 || stats.Created != 7 || len(attempts) != 0 {
+		t.Errorf("disabled teardown = %+v, %d requests", stats, len(attempts))
+	}
+
+	stats := tracker.teardown(server.Client(), 0, true)
+	if !stats.Enabled || stats.Left != 5 || stats.Deleted != 4 || stats.Failed != 1 {
+		t.Errorf("stats = %+v, want 4 deleted, 1 failed", stats)
+	}
+	if len(stats.Errors) != 1 || !strings.HasSuffix(stats.Errors[0], "/api/products/locked: status 409") {
+		t.Errorf("errors = %q", stats.Errors)
+	}
+	// One retry of a failed delete
+	if attempts["/api/products/flaky"] != 2 || attempts["/api/products/locked"] != 2 || attempts["/api/products/1"] != 1 {
+		t.Errorf("attempts = %v", attempts)
+	}
+
+	if stats := newResourceTracker().teardown(server.Client(), 4, true); stats.Left != 0 || stats.Duration != 0 {
+		t.Errorf("empty teardown = %+v", stats)
+	}
+}
+
+func TestDeleteResourceNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL + "/api/products/1"
+	server.Close()
+
+	if err := deleteResource(http.DefaultClient, url); err == nil || !strings.HasPrefix(err.Error(), "DELETE "+url+": ") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+// recordAt records a response size at the second of the run
+func recordAt(r *bodySizeRecorder, second int, operation string, size int) {
+	r.start = time.Now().Add(-time.Duration(second)*time.Second - time.Second/2)
+	r.Record(operation, size)
+}
+
+func TestBodySizeRecorder(t *testing.T) {
+	r := newBodySizeRecorder()
+	// The list grows by 10 bytes every second
+	for second := 0; second < 30; second++ {
+		recordAt(r, second, "GET /api/products", 100+second*10)
+	}
+	recordAt(r, 0, "POST /api/products", 50)
+	recordAt(r, 2, "POST /api/products", 70)
+	recordAt(r, 2, "POST /api/products", 90)
+
+	results := r.Results()
+	if len(results) != 2 || results[0].Operation != "GET /api/products" || results[1].Operation != "POST /api/products" {
+		t.Fatalf("results = %+v", results)
+	}
+
+	list := results[0]
+	if len(list.Series) != 30 || list.Max != 390 {
+		t.Errorf("series = %d seconds, max %d", len(list.Series), list.Max)
+	}
+	if list.FirstAvg != 145 || list.LastAvg != 345 || math.Abs(list.Growth-200.0/145) > 1e-12 {
+		t.Errorf("first %v, last %v, growth %v", list.FirstAvg, list.LastAvg, list.Growth)
+	}
+
+	// Short runs compare their first and last thirds, seconds without responses count as no data
+	create := results[1]
+	if !slices.Equal(create.Series, []float64{50, 0, 80}) || create.FirstAvg != 50 || create.LastAvg != 80 || math.Abs(create.Growth-0.6) > 1e-12 {
+		t.Errorf("create = %+v", create)
+	}
+}
+
+func TestWindowAverage(t *testing.T) {
+	series := &bodySizeSeries{bytes: []int64{100, 0, 300, 600}, counts: []int64{1, 0, 1, 2}}
+	tests := []struct {
+		from, to int
+		want     float64
+	}{
+		{0, 1, 100},
+		{0, 3, 200},
+		{1, 2, 0},
+		{2, 4, 300},
+		{-5, 10, 250},
+	}
+	for _, tt := range tests {
+		if got := windowAverage(series, tt.from, tt.to); got != tt.want {
+			t.Errorf("windowAverage(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
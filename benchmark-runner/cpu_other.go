@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// processCPUTime is not supported on this platform, CPU usage is reported as 0
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"math/rand"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +20,22 @@ type UniqueError struct {
 	FirstSeen    time.Time // First occurrence
 	LastSeen     time.Time // Last occurrence
 	SampleBody   string    // Sample response body (for first error)
+
+	Samples  []ErrorSample // Random samples of occurrences (reservoir of errorSampleSize)
+	Timeline []int64       // Occurrences per second since the start of the run
+}
+
+// errorSampleSize is the number of occurrences sampled per unique error
+const errorSampleSize = 5
+
+// ErrorSample is a single occurrence of an error
+type ErrorSample struct {
+	Time       time.Time
+	Latency    time.Duration
+	StatusCode int
+	RequestID  string            // X-Request-Id of the response or request, or trace ID
+	Headers    map[string]string // Response headers
+	Body       string            // Truncated response body
 }
 
 // ErrorKey is a key for grouping unique errors
@@ -32,19 +51,30 @@ type ErrorStats struct {
 	mu           sync.Mutex
 	UniqueErrors map[ErrorKey]*UniqueError // Unique errors
 	TotalCount   int64                     // Total error count
+	Start        time.Time                 // Start of the run, origin of error timelines
 }
 
 func NewErrorStats() *ErrorStats {
 	return &ErrorStats{
 		UniqueErrors: make(map[ErrorKey]*UniqueError),
+		Start:        time.Now(),
 	}
 }
 
 // RecordError records an error, grouping by unique key
 func (es *ErrorStats) RecordError(operation, errType, errMsg string, statusCode int, responseBody string) {
+	es.RecordErrorSample(operation, errType, errMsg, ErrorSample{StatusCode: statusCode, Body: responseBody})
+}
+
+// RecordErrorSample records an error with details of the occurrence.
+// Samples are kept using reservoir sampling, so they represent the whole run.
+// The occurrence time is sample.Time if set (replayed event logs), now otherwise
+func (es *ErrorStats) RecordErrorSample(operation, errType, errMsg string, sample ErrorSample) {
 	es.mu.Lock()
 	defer es.mu.Unlock()
 
+	statusCode, responseBody := sample.StatusCode, sample.Body
+
 	// Normalize error message (remove dynamic parts)
 	normalizedMsg := normalizeErrorMessage(errMsg)
 
@@ -55,14 +85,18 @@ func (es *ErrorStats) RecordError(operation, errType, errMsg string, statusCode
 		StatusCode:   statusCode,
 	}
 
-	now := time.Now()
+	now := sample.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
 	es.TotalCount++
 
-	if existing, ok := es.UniqueErrors[key]; ok {
+	existing, ok := es.UniqueErrors[key]
+	if ok {
 		existing.Count++
 		existing.LastSeen = now
 	} else {
-		es.UniqueErrors[key] = &UniqueError{
+		existing = &UniqueError{
 			Operation:    operation,
 			ErrorType:    errType,
 			ErrorMessage: normalizedMsg,
@@ -72,6 +106,21 @@ func (es *ErrorStats) RecordError(operation, errType, errMsg string, statusCode
 			LastSeen:     now,
 			SampleBody:   truncateString(responseBody, 500),
 		}
+		es.UniqueErrors[key] = existing
+	}
+
+	second := max(int(now.Sub(es.Start)/time.Second), 0)
+	for len(existing.Timeline) <= second {
+		existing.Timeline = append(existing.Timeline, 0)
+	}
+	existing.Timeline[second]++
+
+	sample.Time = now
+	sample.Body = truncateString(responseBody, 500)
+	if len(existing.Samples) < errorSampleSize {
+		existing.Samples = append(existing.Samples, sample)
+	} else if i := rand.Int63n(existing.Count); i < errorSampleSize {
+		existing.Samples[i] = sample
 	}
 }
 
@@ -92,6 +141,23 @@ func (es *ErrorStats) GetSortedErrors() []*UniqueError {
 	return errors
 }
 
+// TopErrors returns copies of the n most frequent errors
+func (es *ErrorStats) TopErrors(n int) []UniqueError {
+	sorted := es.GetSortedErrors()
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	top := make([]UniqueError, 0, len(sorted))
+	for _, err := range sorted {
+		top = append(top, *err)
+	}
+	return top
+}
+
 // GetTotalCount returns total error count
 func (es *ErrorStats) GetTotalCount() int64 {
 	es.mu.Lock()
@@ -106,13 +172,36 @@ func (es *ErrorStats) GetUniqueCount() int {
 	return len(es.UniqueErrors)
 }
 
+// localPortPattern matches the local address of network errors, e.g. "tcp 127.0.0.1:50900->"
+var localPortPattern = regexp.MustCompile(`(tcp \S+):\d+->`)
+
 // normalizeErrorMessage normalizes error message by removing dynamic parts
 func normalizeErrorMessage(msg string) string {
+	// Ephemeral local ports make every connection error unique
+	msg = localPortPattern.ReplaceAllString(msg, "$1:{port}->")
+
 	// Can add more normalization rules here
-	// For example, remove IP addresses, ports, timestamps, etc.
+	// For example, remove IP addresses, timestamps, etc.
 	return msg
 }
 
+// normalizeOperationPath replaces dynamic path segments (numeric IDs, UUIDs)
+// with placeholders, so errors of the same endpoint are grouped together
+func normalizeOperationPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			segments[i] = "{id}"
+		} else if len(segment) == 36 && strings.Count(segment, "-") == 4 {
+			segments[i] = "{uuid}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
 // truncateString truncates string to specified length
 func truncateString(s string, maxLen int) string {
 	s = strings.TrimSpace(s)
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordErrorSampleTimeline(t *testing.T) {
+	es := NewErrorStats()
+	es.Start = time.Unix(1000, 0)
+	at := func(offset time.Duration) ErrorSample {
+		return ErrorSample{Time: es.Start.Add(offset), StatusCode: 500, Body: "  internal error  "}
+	}
+
+	es.RecordErrorSample("GET /api/products", "http_error", "status 500", at(100*time.Millisecond))
+	es.RecordErrorSample("GET /api/products", "http_error", "status 500", at(900*time.Millisecond))
+	es.RecordErrorSample("GET /api/products", "http_error", "status 500", at(3500*time.Millisecond))
+	// Occurrences recorded just before the start count in the first second
+	es.RecordErrorSample("GET /api/products", "http_error", "status 500", at(-time.Millisecond))
+
+	errors := es.GetSortedErrors()
+	if len(errors) != 1 {
+		t.Fatalf("unique errors = %d, want 1", len(errors))
+	}
+	err := errors[0]
+	if !slices.Equal(err.Timeline, []int64{3, 0, 0, 1}) {
+		t.Errorf("timeline = %v, want [3 0 0 1]", err.Timeline)
+	}
+	if err.Count != 4 || es.GetTotalCount() != 4 || es.GetUniqueCount() != 1 {
+		t.Errorf("count = %d, total %d, unique %d", err.Count, es.GetTotalCount(), es.GetUniqueCount())
+	}
+	if err.FirstSeen != es.Start.Add(100*time.Millisecond) || err.LastSeen != es.Start.Add(-time.Millisecond) {
+		t.Errorf("first seen %s, last seen %s", err.FirstSeen, err.LastSeen)
+	}
+	if err.SampleBody != "internal error" || err.Samples[0].Body != "internal error" || err.Samples[0].StatusCode != 500 {
+		t.Errorf("sample = %q, %+v", err.SampleBody, err.Samples[0])
+	}
+}
+
+func TestRecordErrorSampleReservoir(t *testing.T) {
+	es := NewErrorStats()
+	for i := 0; i < 1000; i++ {
+		es.RecordErrorSample("POST /api/products", "network_error", "connection refused", ErrorSample{RequestID: fmt.Sprint(i)})
+	}
+
+	err := es.GetSortedErrors()[0]
+	if len(err.Samples) != errorSampleSize {
+		t.Fatalf("samples = %d, want %d", len(err.Samples), errorSampleSize)
+	}
+	// The reservoir keeps samples from the whole run, not only the first occurrences
+	replaced := false
+	for _, sample := range err.Samples {
+		if sample.Time.IsZero() {
+			t.Errorf("sample without time: %+v", sample)
+		}
+		var id int
+		fmt.Sscan(sample.RequestID, &id)
+		replaced = replaced || id >= errorSampleSize
+	}
+	if !replaced {
+		t.Errorf("samples %+v are the first occurrences only", err.Samples)
+	}
+}
+
+func TestErrorGrouping(t *testing.T) {
+	es := NewErrorStats()
+	es.RecordError("GET /api/products/{id}", "network_error", "dial tcp 127.0.0.1:50900->127.0.0.1:8080: connection reset", 0, "")
+	es.RecordError("GET /api/products/{id}", "network_error", "dial tcp 127.0.0.1:50901->127.0.0.1:8080: connection reset", 0, "")
+	es.RecordError("GET /api/products/{id}", "http_error", "status 404", 404, "not found")
+	es.RecordError("GET /api/products/{id}", "http_error", "status 404", 404, "")
+	es.RecordError("GET /api/products/{id}", "http_error", "status 404", 404, "")
+	es.RecordError("DELETE /api/products/{id}", "http_error", "status 404", 404, "")
+
+	sorted := es.GetSortedErrors()
+	if len(sorted) != 3 || sorted[0].Count != 3 || sorted[1].Count != 2 || sorted[2].Count != 1 {
+		t.Fatalf("errors = %d, want 3 groups sorted by count", len(sorted))
+	}
+	if sorted[1].ErrorMessage != "dial tcp 127.0.0.1:{port}->127.0.0.1:8080: connection reset" {
+		t.Errorf("normalized message = %q", sorted[1].ErrorMessage)
+	}
+
+	top := es.TopErrors(2)
+	if len(top) != 2 || top[0].StatusCode != 404 || top[1].ErrorType != "network_error" {
+		t.Errorf("top errors = %+v", top)
+	}
+	if len(es.TopErrors(10)) != 3 {
+		t.Error("TopErrors returned more errors than recorded")
+	}
+}
+
+func TestNormalizeErrorMessage(t *testing.T) {
+	tests := map[string]string{
+		"read tcp 10.0.0.5:41234->10.0.0.9:8080: i/o timeout": "read tcp 10.0.0.5:{port}->10.0.0.9:8080: i/o timeout",
+		"write tcp [::1]:60000->[::1]:8080: broken pipe":      "write tcp [::1]:{port}->[::1]:8080: broken pipe",
+		"unexpected status: 503":                              "unexpected status: 503",
+	}
+	for msg, want := range tests {
+		if got := normalizeErrorMessage(msg); got != want {
+			t.Errorf("normalizeErrorMessage(%q) = %q, want %q", msg, got, want)
+		}
+	}
+}
+
+func TestNormalizeOperationPath(t *testing.T) {
+	tests := map[string]string{
+		"/api/products":     "/api/products",
+		"/api/products/42":  "/api/products/{id}",
+		"/api/products/-1":  "/api/products/{id}",
+		"/users/7/orders/9": "/users/{id}/orders/{id}",
+		"/items/0b5f7a52-63d4-4a3c-b7e6-39b6c5a0e2f1/details": "/items/{uuid}/details",
+		"/api/products/abc": "/api/products/abc",
+		"":                  "",
+	}
+	for path, want := range tests {
+		if got := normalizeOperationPath(path); got != want {
+			t.Errorf("normalizeOperationPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"  padded  ", 6, "padded"},
+		{"exactly10!", 10, "exactly10!"},
+		{"longer than limit", 6, "longer..."},
+	}
+	for _, tt := range tests {
+		if got := truncateString(tt.s, tt.max); got != tt.want {
+			t.Errorf("truncateString(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestErrorSamplesJSON(t *testing.T) {
+	start := time.Unix(1000, 0)
+	samples := errorSamplesJSON([]ErrorSample{{Time: start.Add(1500 * time.Millisecond), Latency: time.Millisecond, StatusCode: 502, RequestID: "abc"}}, start)
+	if len(samples) != 1 {
+		t.Fatalf("samples = %v", samples)
+	}
+	sample := samples[0]
+	if sample["offset"] != "1.5s" || sample["latency"] != "1ms" || sample["statusCode"] != 502 || sample["requestId"] != "abc" {
+		t.Errorf("sample = %v", sample)
+	}
+	if !strings.HasPrefix(sample["time"].(string), start.Add(1500*time.Millisecond).Format("2006-01-02T15:04:05")) {
+		t.Errorf("time = %v", sample["time"])
+	}
+}
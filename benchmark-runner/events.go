@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// eventLogVersion is the version of both event log formats
+	eventLogVersion = 1
+
+	// eventLogBuffer is the number of events queued between the workers and the file writer.
+	// When the writer falls behind, events are dropped and counted rather than slowing the run down
+	eventLogBuffer = 65536
+
+	// eventLogMagic starts binary event logs
+	eventLogMagic = "BRLOG\x00"
+
+	// eventLogMaxHeader and eventLogMaxString limit lengths read from binary event logs,
+	// so a corrupt length fails the read instead of allocating gigabytes
+	eventLogMaxHeader = 1 << 20
+	eventLogMaxString = 64 << 10
+
+	// eventLogMaxOverrun is how far past the configured duration of the run an event may start,
+	// so a corrupt offset fails the read instead of stretching the time-series over years
+	eventLogMaxOverrun = time.Hour
+)
+
+// Record kinds of binary event logs
+const (
+	eventRecordString byte = 'S' // Adds a string to the string table: uvarint length, bytes
+	eventRecordEvent  byte = 'E' // Request: uvarints offset_us, scenario, step, op, status, latency_us, bytes, error
+	eventRecordFooter byte = 'F' // End of the run: uvarints duration_us, dropped
+)
+
+// RequestEvent is a request of the run as written to the event log
+type RequestEvent struct {
+	Offset    time.Duration // Start of the request from the start of the run
+	Scenario  string        // Concurrent scenario, "" for a single one
+	Step      string        // Operation name, e.g. update-product
+	Operation string        // Method and path, e.g. PUT /api/products/{id}
+	Status    int           // 0 - no response
+	Latency   time.Duration
+	Bytes     int    // Response body size
+	Error     string // Error type as in error statistics, "" - success
+}
+
+// EventLogHeader describes the run of an event log
+type EventLogHeader struct {
+	Version   int           `json:"version"`
+	StartTime time.Time     `json:"start_time"`
+	URL       string        `json:"url"`
+	Type      BenchmarkType `json:"type"`
+	RPS       int           `json:"rps"`
+	Duration  float64       `json:"duration_seconds"` // Configured duration of the run
+}
+
+// EventLogStats is the outcome of writing the event log
+type EventLogStats struct {
+	Path    string
+	Format  string // ndjson or binary
+	Events  int64  // Events written
+	Dropped int64  // Events dropped because the writer fell behind
+	Bytes   int64  // Size of the file
+	Error   string // Write error, the log is incomplete
+}
+
+// maxOffset returns the latest offset an event of the run may start at
+func (h EventLogHeader) maxOffset() time.Duration {
+	return time.Duration(h.Duration*float64(time.Second)) + eventLogMaxOverrun
+}
+
+// eventLogFormat returns the format of the event log file: NDJSON for .ndjson and .jsonl, binary otherwise
+func eventLogFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	return "binary"
+}
+
+// eventLog streams events of every request to a file. Workers only queue events,
+// a single goroutine encodes and writes them, so memory is bounded by eventLogBuffer
+type eventLog struct {
+	path    string
+	format  string
+	config  Config
+	file    *os.File
+	writer  *bufio.Writer
+	encoder eventEncoder
+
+	start   time.Time
+	events  chan RequestEvent
+	done    chan struct{}
+	written int64
+	dropped int64 // Atomic
+	err     error // First write error, owned by the writer goroutine until done
+}
+
+// newEventLog creates the event log of the run, nil if path is empty. The file is created by Open
+func newEventLog(path string, config Config) *eventLog {
+	if path == "" {
+		return nil
+	}
+	return &eventLog{path: path, format: eventLogFormat(path), config: config}
+}
+
+// Open creates the file
+func (l *eventLog) Open() error {
+	if l == nil {
+		return nil
+	}
+	file, err := os.Create(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to create event log: %v", err)
+	}
+	l.file = file
+	l.writer = bufio.NewWriterSize(file, 256*1024)
+	if l.format == "ndjson" {
+		l.encoder = &ndjsonEncoder{w: l.writer}
+	} else {
+		l.encoder = &binaryEncoder{w: l.writer, strings: map[string]uint64{"": 0}}
+	}
+	return nil
+}
+
+// Start writes the header and starts the writer. Offsets of events are counted from start
+func (l *eventLog) Start(start time.Time) {
+	if l == nil || l.file == nil {
+		return
+	}
+	l.start = start
+	l.events = make(chan RequestEvent, eventLogBuffer)
+	l.done = make(chan struct{})
+
+	l.err = l.encoder.Header(EventLogHeader{
+		Version:   eventLogVersion,
+		StartTime: start,
+		URL:       l.config.URL,
+		Type:      l.config.BenchmarkType,
+		RPS:       l.config.RPS,
+		Duration:  l.config.Duration.Seconds(),
+	})
+	go func() {
+		defer close(l.done)
+		// Flushed every second, so a killed run leaves the events up to the last second
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case event, ok := <-l.events:
+				if !ok {
+					return
+				}
+				if l.err != nil {
+					continue
+				}
+				if l.err = l.encoder.Event(event); l.err == nil {
+					l.written++
+				}
+			case <-ticker.C:
+				if l.err == nil {
+					l.err = l.writer.Flush()
+				}
+			}
+		}
+	}()
+}
+
+// Record queues the event of the request started at start. It never blocks:
+// if the writer fell behind, the event is dropped
+func (l *eventLog) Record(start time.Time, event RequestEvent) {
+	if l == nil || l.events == nil {
+		return
+	}
+	event.Offset = start.Sub(l.start)
+	select {
+	case l.events <- event:
+	default:
+		atomic.AddInt64(&l.dropped, 1)
+	}
+}
+
+// Close writes the queued events and the footer and closes the file.
+// Must be called after all requests completed
+func (l *eventLog) Close(duration time.Duration) *EventLogStats {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	if l.events != nil {
+		close(l.events)
+		<-l.done
+	}
+	dropped := atomic.LoadInt64(&l.dropped)
+
+	err := l.err
+	if err == nil {
+		err = l.encoder.Footer(duration, dropped)
+	}
+	if flushErr := l.writer.Flush(); err == nil {
+		err = flushErr
+	}
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	stats := &EventLogStats{Path: l.path, Format: l.format, Events: l.written, Dropped: dropped}
+	if info, statErr := os.Stat(l.path); statErr == nil {
+		stats.Bytes = info.Size()
+	}
+	if err != nil {
+		stats.Error = err.Error()
+	}
+	return stats
+}
+
+// eventEncoder writes an event log format
+type eventEncoder interface {
+	Header(header EventLogHeader) error
+	Event(event RequestEvent) error
+	Footer(duration time.Duration, dropped int64) error
+}
+
+// ndjsonEvent is an event line of NDJSON event logs
+type ndjsonEvent struct {
+	Offset    int64  `json:"t_us"`
+	Scenario  string `json:"scenario,omitempty"`
+	Step      string `json:"step"`
+	Operation string `json:"op"`
+	Status    int    `json:"status"`
+	Latency   int64  `json:"latency_us"`
+	Bytes     int    `json:"bytes"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ndjsonFooter is the last line of NDJSON event logs
+type ndjsonFooter struct {
+	End      bool    `json:"end"`
+	Duration float64 `json:"duration_seconds"`
+	Dropped  int64   `json:"dropped"`
+}
+
+// ndjsonEncoder writes the header, one line per event and the footer as JSON lines
+type ndjsonEncoder struct {
+	w *bufio.Writer
+}
+
+func (e *ndjsonEncoder) line(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	e.w.Write(data)
+	return e.w.WriteByte('\n')
+}
+
+func (e *ndjsonEncoder) Header(header EventLogHeader) error {
+	return e.line(header)
+}
+
+func (e *ndjsonEncoder) Event(event RequestEvent) error {
+	return e.line(ndjsonEvent{
+		Offset:    event.Offset.Microseconds(),
+		Scenario:  event.Scenario,
+		Step:      event.Step,
+		Operation: event.Operation,
+		Status:    event.Status,
+		Latency:   event.Latency.Microseconds(),
+		Bytes:     event.Bytes,
+		Error:     event.Error,
+	})
+}
+
+func (e *ndjsonEncoder) Footer(duration time.Duration, dropped int64) error {
+	return e.line(ndjsonFooter{End: true, Duration: duration.Seconds(), Dropped: dropped})
+}
+
+// binaryEncoder writes the magic, the JSON header and records of varints. Strings (operations,
+// error types) are written once to a string table and referenced by index, so an event takes ~15 bytes
+type binaryEncoder struct {
+	w       *bufio.Writer
+	strings map[string]uint64
+	buf     []byte
+}
+
+func (e *binaryEncoder) Header(header EventLogHeader) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	e.w.WriteString(eventLogMagic)
+	e.w.Write(binary.AppendUvarint(nil, uint64(len(data))))
+	_, err = e.w.Write(data)
+	return err
+}
+
+// intern returns index of s in the string table, adding it if needed
+func (e *binaryEncoder) intern(s string) uint64 {
+	if index, ok := e.strings[s]; ok {
+		return index
+	}
+	index := uint64(len(e.strings))
+	e.strings[s] = index
+	e.buf = append(e.buf[:0], eventRecordString)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+	e.w.Write(e.buf)
+	return index
+}
+
+func (e *binaryEncoder) Event(event RequestEvent) error {
+	scenario, step, operation, errType := e.intern(event.Scenario), e.intern(event.Step), e.intern(event.Operation), e.intern(event.Error)
+	e.buf = append(e.buf[:0], eventRecordEvent)
+	for _, value := range []uint64{
+		uint64(max(event.Offset.Microseconds(), 0)), scenario, step, operation,
+		uint64(event.Status), uint64(event.Latency.Microseconds()), uint64(event.Bytes), errType,
+	} {
+		e.buf = binary.AppendUvarint(e.buf, value)
+	}
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+func (e *binaryEncoder) Footer(duration time.Duration, dropped int64) error {
+	e.buf = append(e.buf[:0], eventRecordFooter)
+	e.buf = binary.AppendUvarint(e.buf, uint64(duration.Microseconds()))
+	e.buf = binary.AppendUvarint(e.buf, uint64(dropped))
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// EventLogInfo is what an event log says about its run besides the events
+type EventLogInfo struct {
+	Header   EventLogHeader
+	Format   string
+	Complete bool          // The log has the footer: the run finished and the log was closed
+	Duration time.Duration // Duration of the run (complete logs only)
+	Dropped  int64         // Events dropped while writing (complete logs only)
+}
+
+// readEventLog reads the event log of either format. It calls onHeader with the header, then fn
+// for every event in the order they were written
+func readEventLog(path string, onHeader func(header EventLogHeader), fn func(event RequestEvent)) (*EventLogInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReaderSize(file, 256*1024)
+
+	magic, _ := reader.Peek(len(eventLogMagic))
+	if string(magic) == eventLogMagic {
+		stat, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		reader.Discard(len(eventLogMagic))
+		info, err := readBinaryEvents(&eventLogReader{Reader: reader, left: stat.Size() - int64(len(eventLogMagic))}, onHeader, fn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event log %s: %v", path, err)
+		}
+		return info, nil
+	}
+	info, err := readNDJSONEvents(reader, onHeader, fn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log %s: %v", path, err)
+	}
+	return info, nil
+}
+
+// readNDJSONEvents reads NDJSON event log: the header line, event lines and the footer line
+func readNDJSONEvents(reader *bufio.Reader, onHeader func(header EventLogHeader), fn func(event RequestEvent)) (*EventLogInfo, error) {
+	info := &EventLogInfo{Format: "ndjson"}
+	decoder := json.NewDecoder(reader)
+	if err := decoder.Decode(&info.Header); err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if info.Header.Version != eventLogVersion {
+		return nil, fmt.Errorf("unsupported version %d", info.Header.Version)
+	}
+	onHeader(info.Header)
+
+	for line := 2; ; line++ {
+		var record struct {
+			ndjsonEvent
+			ndjsonFooter
+		}
+		if err := decoder.Decode(&record); err == io.EOF {
+			return info, nil
+		} else if err != nil {
+			// A run that was killed leaves a truncated last line
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return info, nil
+			}
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if record.End {
+			info.Complete = true
+			info.Duration = time.Duration(record.Duration * float64(time.Second))
+			info.Dropped = record.Dropped
+			continue
+		}
+		if record.Offset < 0 || record.Offset > int64(info.Header.maxOffset()/time.Microsecond) {
+			return nil, fmt.Errorf("line %d: offset %dµs is outside the run", line, record.Offset)
+		}
+		fn(RequestEvent{
+			Offset:    time.Duration(record.Offset) * time.Microsecond,
+			Scenario:  record.Scenario,
+			Step:      record.Step,
+			Operation: record.Operation,
+			Status:    record.Status,
+			Latency:   time.Duration(record.ndjsonEvent.Latency) * time.Microsecond,
+			Bytes:     record.Bytes,
+			Error:     record.Error,
+		})
+	}
+}
+
+// eventLogReader reads a binary event log and counts the bytes left in the file
+type eventLogReader struct {
+	*bufio.Reader
+	left int64
+}
+
+func (r *eventLogReader) ReadByte() (byte, error) {
+	b, err := r.Reader.ReadByte()
+	if err == nil {
+		r.left--
+	}
+	return b, err
+}
+
+// field reads a field of length bytes. The length is checked against limit and the rest
+// of the file before allocating; io.ErrUnexpectedEOF means the field is cut off
+func (r *eventLogReader) field(length uint64, limit int) ([]byte, error) {
+	if length > uint64(limit) {
+		return nil, fmt.Errorf("length %d is over the limit of %d", length, limit)
+	}
+	if length > uint64(max(r.left, 0)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, length)
+	n, err := io.ReadFull(r.Reader, data)
+	r.left -= int64(n)
+	return data, err
+}
+
+// readBinaryEvents reads binary event log after the magic
+func readBinaryEvents(reader *eventLogReader, onHeader func(header EventLogHeader), fn func(event RequestEvent)) (*EventLogInfo, error) {
+	info := &EventLogInfo{Format: "binary"}
+	size, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	data, err := reader.field(size, eventLogMaxHeader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if err := json.Unmarshal(data, &info.Header); err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if info.Header.Version != eventLogVersion {
+		return nil, fmt.Errorf("unsupported version %d", info.Header.Version)
+	}
+	onHeader(info.Header)
+
+	table := []string{""}
+	str := func(index uint64) (string, error) {
+		if index >= uint64(len(table)) {
+			return "", fmt.Errorf("string %d is not defined", index)
+		}
+		return table[index], nil
+	}
+	var values [8]uint64
+	for {
+		kind, err := reader.ReadByte()
+		if err == io.EOF {
+			return info, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		// A run that was killed leaves a truncated last record, it is ignored
+		switch kind {
+		case eventRecordString:
+			length, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return info, nil
+			}
+			s, err := reader.field(length, eventLogMaxString)
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return info, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("string %d: %v", len(table), err)
+			}
+			table = append(table, string(s))
+
+		case eventRecordEvent:
+			for i := range values {
+				if values[i], err = binary.ReadUvarint(reader); err != nil {
+					return info, nil
+				}
+			}
+			if values[0] > uint64(info.Header.maxOffset()/time.Microsecond) {
+				return nil, fmt.Errorf("offset %dµs is outside the run", values[0])
+			}
+			event := RequestEvent{
+				Offset:  time.Duration(values[0]) * time.Microsecond,
+				Status:  int(values[4]),
+				Latency: time.Duration(values[5]) * time.Microsecond,
+				Bytes:   int(values[6]),
+			}
+			for i, field := range []*string{&event.Scenario, &event.Step, &event.Operation} {
+				if *field, err = str(values[i+1]); err != nil {
+					return nil, err
+				}
+			}
+			if event.Error, err = str(values[7]); err != nil {
+				return nil, err
+			}
+			fn(event)
+
+		case eventRecordFooter:
+			duration, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return info, nil
+			}
+			dropped, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return info, nil
+			}
+			info.Complete = true
+			info.Duration = time.Duration(duration) * time.Microsecond
+			info.Dropped = int64(dropped)
+
+		default:
+			return nil, fmt.Errorf("unknown record %q", kind)
+		}
+	}
+}
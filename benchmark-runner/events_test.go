@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testEvents are events of a run with a failed request and a request without response
+var testEvents = []RequestEvent{
+	{Offset: 0, Step: "create-product", Operation: "POST /api/products", Status: 201, Latency: 1500 * time.Microsecond, Bytes: 120},
+	{Offset: 250 * time.Millisecond, Scenario: "reads", Step: "get-products", Operation: "GET /api/products", Status: 200, Latency: 3 * time.Millisecond, Bytes: 4096},
+	{Offset: 1200 * time.Millisecond, Step: "create-product", Operation: "POST /api/products", Status: 500, Latency: 20 * time.Millisecond, Bytes: 30, Error: "request_error"},
+	{Offset: 2 * time.Second, Scenario: "reads", Step: "get-products", Operation: "GET /api/products", Latency: 5 * time.Second, Error: "request_error"},
+}
+
+// writeEventLog writes the events to an event log in dir, the format is chosen by name
+func writeEventLog(t *testing.T, dir, name string, events []RequestEvent) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	log := newEventLog(path, Config{URL: "http://api", BenchmarkType: GetProducts, RPS: 100, Duration: 3 * time.Second})
+	if err := log.Open(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	log.Start(start)
+	for _, event := range events {
+		log.Record(start.Add(event.Offset), event)
+	}
+	stats := log.Close(3 * time.Second)
+	if stats.Error != "" || stats.Events != int64(len(events)) || stats.Dropped != 0 {
+		t.Fatalf("write stats = %+v", stats)
+	}
+	return path
+}
+
+// readEvents reads all events of the event log
+func readEvents(path string) ([]RequestEvent, *EventLogInfo, error) {
+	var events []RequestEvent
+	info, err := readEventLog(path, func(EventLogHeader) {}, func(event RequestEvent) {
+		events = append(events, event)
+	})
+	return events, info, err
+}
+
+func TestEventLogFormat(t *testing.T) {
+	tests := map[string]string{
+		"run.ndjson": "ndjson",
+		"run.JSONL":  "ndjson",
+		"run.bin":    "binary",
+		"run":        "binary",
+		"run.json":   "binary",
+	}
+	for path, want := range tests {
+		if got := eventLogFormat(path); got != want {
+			t.Errorf("eventLogFormat(%q) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestEventLogRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"run.ndjson", "run.events"} {
+		path := writeEventLog(t, dir, name, testEvents)
+		var header EventLogHeader
+		var events []RequestEvent
+		info, err := readEventLog(path, func(h EventLogHeader) { header = h }, func(event RequestEvent) {
+			events = append(events, event)
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if info.Format != eventLogFormat(name) || !info.Complete || info.Duration != 3*time.Second || info.Dropped != 0 {
+			t.Errorf("%s: info = %+v", name, info)
+		}
+		if header.Version != eventLogVersion || header.URL != "http://api" || header.Type != GetProducts || header.RPS != 100 ||
+			header.Duration != 3 || !header.StartTime.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Errorf("%s: header = %+v", name, header)
+		}
+		if !reflect.DeepEqual(events, testEvents) {
+			t.Errorf("%s: events = %+v, want %+v", name, events, testEvents)
+		}
+	}
+}
+
+func TestReadTruncatedEventLog(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"run.ndjson", "run.events"} {
+		path := writeEventLog(t, dir, name, testEvents)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Cut the footer and the middle of the last event, as a killed run leaves them
+		full, _, _ := readEvents(path)
+		for cut := 1; cut < 40; cut++ {
+			if err := os.WriteFile(path, data[:len(data)-cut], 0o644); err != nil {
+				t.Fatal(err)
+			}
+			events, info, err := readEvents(path)
+			if err != nil {
+				t.Errorf("%s cut by %d bytes: %v", name, cut, err)
+				continue
+			}
+			if len(events) > len(full) || !reflect.DeepEqual(events, full[:len(events)]) {
+				t.Errorf("%s cut by %d bytes: events = %+v", name, cut, events)
+			}
+			if info.Complete && len(events) != len(full) {
+				t.Errorf("%s cut by %d bytes: complete without all events", name, cut)
+			}
+		}
+	}
+}
+
+func TestReadCorruptBinaryEventLog(t *testing.T) {
+	header := `{"version":1}`
+	log := func(records ...string) string {
+		data := eventLogMagic + string(binary.AppendUvarint(nil, uint64(len(header)))) + header
+		return data + strings.Join(records, "")
+	}
+	uvarints := func(values ...uint64) string {
+		var data []byte
+		for _, value := range values {
+			data = binary.AppendUvarint(data, value)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string // Empty - the log is read without events
+	}{
+		{name: "empty", data: log()},
+		{name: "huge header", data: eventLogMagic + uvarints(1<<62) + header, wantErr: "invalid header: length 4611686018427387904 is over the limit"},
+		{name: "header over the rest", data: eventLogMagic + uvarints(1000) + header, wantErr: "invalid header: unexpected EOF"},
+		{name: "header not json", data: eventLogMagic + uvarints(3) + "abc", wantErr: "invalid header"},
+		{name: "version", data: eventLogMagic + uvarints(13) + `{"version":7}`, wantErr: "unsupported version 7"},
+		{name: "huge string", data: log("S" + uvarints(1<<40) + "abc"), wantErr: "string 1: length 1099511627776 is over the limit"},
+		{name: "string over the rest", data: log("S" + uvarints(100) + "abc")},
+		{name: "undefined string", data: log("E" + uvarints(0, 1, 0, 0, 200, 10, 0, 0)), wantErr: "string 1 is not defined"},
+		{name: "unknown record", data: log("X"), wantErr: `unknown record 'X'`},
+		{name: "huge offset", data: log("E" + uvarints(9e15, 0, 0, 0, 200, 10, 0, 0)), wantErr: "offset 9000000000000000µs is outside the run"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, "corrupt.events")
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		events, info, err := readEvents(path)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || len(events) != 0 || info.Complete {
+			t.Errorf("%s: events = %v, info = %+v, error = %v, want an incomplete log without events", tt.name, events, info, err)
+		}
+	}
+}
+
+func TestReadCorruptNDJSONEventLog(t *testing.T) {
+	header := `{"version":1,"duration_seconds":10}` + "\n"
+	tests := map[string]string{
+		`{"t_us":9000000000000000,"step":"get","status":200}`: "line 2: offset 9000000000000000µs is outside the run",
+		`{"t_us":-1,"step":"get","status":200}`:               "line 2: offset -1µs is outside the run",
+	}
+	path := filepath.Join(t.TempDir(), "corrupt.ndjson")
+	for event, wantErr := range tests {
+		if err := os.WriteFile(path, []byte(header+event+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := readEvents(path); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("%s: error = %v, want %q", event, err, wantErr)
+		}
+	}
+
+	// Events may start late, within the overrun after the configured duration
+	late := fmt.Sprintf(`{"t_us":%d,"step":"get","status":200}`, (10*time.Second+eventLogMaxOverrun)/time.Microsecond)
+	if err := os.WriteFile(path, []byte(header+late+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if events, _, err := readEvents(path); err != nil || len(events) != 1 {
+		t.Errorf("late event: events = %v, error = %v", events, err)
+	}
+}
+
+func TestEventLogDisabled(t *testing.T) {
+	log := newEventLog("", Config{})
+	if log != nil {
+		t.Fatal("event log without a path is not nil")
+	}
+	if err := log.Open(); err != nil {
+		t.Errorf("Open = %v", err)
+	}
+	log.Start(time.Now())
+	log.Record(time.Now(), RequestEvent{})
+	if stats := log.Close(time.Second); stats != nil {
+		t.Errorf("Close = %+v, want nil", stats)
+	}
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// faultCheckInterval is how often the proxy checks for reset windows and blackhole end
+	faultCheckInterval = 50 * time.Millisecond
+
+	// proxyDialTimeout is the timeout of connecting to the upstream
+	proxyDialTimeout = 5 * time.Second
+)
+
+// Fault kinds of the fault-injection proxy
+const (
+	faultLatency   = "latency"   // Delay data from the upstream
+	faultBandwidth = "bandwidth" // Limit throughput in bytes per second in both directions
+	faultReset     = "reset"     // Reset open connections at the start and new connections during the window
+	faultBlackhole = "blackhole" // Stop forwarding data and hold new connections until the window ends
+)
+
+// Fault is a fault injected by the proxy during a window of the run
+type Fault struct {
+	Spec      string
+	Start     time.Duration // Offset from the start of the run
+	End       time.Duration
+	Kind      string
+	Latency   time.Duration // latency
+	Bandwidth int64         // bandwidth, bytes per second
+}
+
+// ProxyConfig configures the fault-injection TCP proxy
+type ProxyConfig struct {
+	Listen   string // Address the proxy listens on, e.g. :15432
+	Upstream string // Address the proxy forwards to, e.g. postgres:5432
+	Faults   []Fault
+}
+
+// Enabled reports whether the proxy is configured
+func (c ProxyConfig) Enabled() bool {
+	return c.Listen != "" && c.Upstream != ""
+}
+
+// ProxyStats describes what the proxy did during the run
+type ProxyStats struct {
+	Listen      string
+	Upstream    string
+	Start       time.Time // Time the fault schedule started
+	Connections int64     // Accepted connections
+	DialErrors  int64     // Failed connections to the upstream
+	Faults      []FaultStats
+}
+
+// FaultStats is a fault window of the run and connections it affected
+type FaultStats struct {
+	Fault
+	Resets int64 // Connections reset (reset)
+	Held   int64 // Connections held until the window ended (blackhole)
+}
+
+// parseProxy parses "LISTEN=UPSTREAM", e.g. ":15432=postgres:5432"
+func parseProxy(spec string) (ProxyConfig, error) {
+	listen, upstream, ok := strings.Cut(spec, "=")
+	if !ok || listen == "" || upstream == "" {
+		return ProxyConfig{}, fmt.Errorf("invalid proxy %q: expected LISTEN=UPSTREAM, e.g. :15432=postgres:5432", spec)
+	}
+	return ProxyConfig{Listen: listen, Upstream: upstream}, nil
+}
+
+// parseFault parses "START-END:KIND[=VALUE]", e.g. "30s-1m:latency=200ms", "1m-1m10s:reset",
+// "2m-2m30s:blackhole" or "3m-4m:bandwidth=64KB"
+func parseFault(spec string) (Fault, error) {
+	window, fault, ok := strings.Cut(spec, ":")
+	startStr, endStr, ok2 := strings.Cut(window, "-")
+	if !ok || !ok2 {
+		return Fault{}, fmt.Errorf("invalid fault %q: expected START-END:KIND[=VALUE], e.g. 30s-1m:latency=200ms", spec)
+	}
+
+	f := Fault{Spec: spec}
+	var err error
+	if f.Start, err = time.ParseDuration(startStr); err != nil {
+		return f, fmt.Errorf("invalid fault %q: %v", spec, err)
+	}
+	if f.End, err = time.ParseDuration(endStr); err != nil {
+		return f, fmt.Errorf("invalid fault %q: %v", spec, err)
+	}
+	if f.Start < 0 || f.End <= f.Start {
+		return f, fmt.Errorf("invalid fault %q: window end must be after its start", spec)
+	}
+
+	kind, value, _ := strings.Cut(fault, "=")
+	f.Kind = kind
+	switch kind {
+	case faultLatency:
+		if f.Latency, err = time.ParseDuration(value); err != nil || f.Latency <= 0 {
+			return f, fmt.Errorf("invalid fault %q: expected latency=DURATION", spec)
+		}
+	case faultBandwidth:
+		if f.Bandwidth, err = parseBytes(value); err != nil || f.Bandwidth <= 0 {
+			return f, fmt.Errorf("invalid fault %q: expected bandwidth=BYTES per second, e.g. 64KB", spec)
+		}
+	case faultReset, faultBlackhole:
+		if value != "" {
+			return f, fmt.Errorf("invalid fault %q: %s takes no value", spec, kind)
+		}
+	default:
+		return f, fmt.Errorf("invalid fault %q: unknown fault %q, use latency, bandwidth, reset or blackhole", spec, kind)
+	}
+	return f, nil
+}
+
+// parseBytes parses a size like 512, 64KB or 1MB (1KB = 1024 bytes)
+func parseBytes(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{{"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			value, multiplier = number, unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	return n * multiplier, err
+}
+
+// String returns the fault without its window, e.g. latency=200ms
+func (f Fault) String() string {
+	switch f.Kind {
+	case faultLatency:
+		return fmt.Sprintf("%s=%s", f.Kind, f.Latency)
+	case faultBandwidth:
+		// Limits that aren't whole kilobytes, like 512B, are printed in bytes
+		if f.Bandwidth%1024 != 0 {
+			return fmt.Sprintf("%s=%dB/s", f.Kind, f.Bandwidth)
+		}
+		return fmt.Sprintf("%s=%dKB/s", f.Kind, f.Bandwidth/1024)
+	default:
+		return f.Kind
+	}
+}
+
+// activeFaults is the combined effect of the faults active at a moment
+type activeFaults struct {
+	latency   time.Duration // Sum of active latencies
+	bandwidth int64         // The lowest active bandwidth limit, 0 if none
+	reset     bool
+	blackhole bool
+}
+
+// faultProxy is a TCP proxy injecting faults on a schedule
+type faultProxy struct {
+	config   ProxyConfig
+	listener net.Listener
+	start    time.Time
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	stats ProxyStats
+}
+
+// startFaultProxy starts listening and the fault schedule. Returns nil if the proxy is disabled
+func startFaultProxy(config ProxyConfig) (*faultProxy, error) {
+	if !config.Enabled() {
+		return nil, nil
+	}
+
+	listener, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start proxy: %v", err)
+	}
+
+	p := &faultProxy{
+		config:   config,
+		listener: listener,
+		start:    time.Now(),
+		done:     make(chan struct{}),
+		conns:    make(map[net.Conn]struct{}),
+		stats: ProxyStats{
+			Listen:   config.Listen,
+			Upstream: config.Upstream,
+		},
+	}
+	p.stats.Start = p.start
+	for _, f := range config.Faults {
+		p.stats.Faults = append(p.stats.Faults, FaultStats{Fault: f})
+	}
+
+	p.wg.Add(2)
+	go p.acceptLoop()
+	go p.scheduleLoop()
+	return p, nil
+}
+
+// Stop closes the listener and all connections and returns statistics. Safe to call on nil
+func (p *faultProxy) Stop() *ProxyStats {
+	if p == nil {
+		return nil
+	}
+
+	close(p.done)
+	p.listener.Close()
+	p.mu.Lock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Faults = append([]FaultStats(nil), p.stats.Faults...)
+	return &stats
+}
+
+// active returns faults active at the moment
+func (p *faultProxy) active() activeFaults {
+	elapsed := time.Since(p.start)
+	var a activeFaults
+	for _, f := range p.config.Faults {
+		if elapsed < f.Start || elapsed >= f.End {
+			continue
+		}
+		switch f.Kind {
+		case faultLatency:
+			a.latency += f.Latency
+		case faultBandwidth:
+			if a.bandwidth == 0 || f.Bandwidth < a.bandwidth {
+				a.bandwidth = f.Bandwidth
+			}
+		case faultReset:
+			a.reset = true
+		case faultBlackhole:
+			a.blackhole = true
+		}
+	}
+	return a
+}
+
+// count increments statistics of the active faults of the kind
+func (p *faultProxy) count(kind string, n int64) {
+	elapsed := time.Since(p.start)
+	for i := range p.stats.Faults {
+		f := &p.stats.Faults[i]
+		if f.Kind != kind || elapsed < f.Start || elapsed >= f.End {
+			continue
+		}
+		switch kind {
+		case faultReset:
+			f.Resets += n
+		case faultBlackhole:
+			f.Held += n
+		}
+	}
+}
+
+func (p *faultProxy) acceptLoop() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+			log.Printf("Proxy accept failed: %v", err)
+			time.Sleep(faultCheckInterval)
+			continue
+		}
+
+		p.mu.Lock()
+		p.stats.Connections++
+		p.mu.Unlock()
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.handle(conn)
+		}()
+	}
+}
+
+// scheduleLoop resets open connections when a reset window starts
+func (p *faultProxy) scheduleLoop() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(faultCheckInterval)
+	defer ticker.Stop()
+
+	started := make([]bool, len(p.config.Faults))
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		elapsed := time.Since(p.start)
+		for i, f := range p.config.Faults {
+			if f.Kind != faultReset || started[i] || elapsed < f.Start {
+				continue
+			}
+			started[i] = true
+
+			p.mu.Lock()
+			for conn := range p.conns {
+				resetConn(conn)
+			}
+			p.stats.Faults[i].Resets += int64(len(p.conns))
+			p.mu.Unlock()
+			log.Printf("Proxy: fault %s started", f.Spec)
+		}
+	}
+}
+
+// track adds or removes connection from the open connections
+func (p *faultProxy) track(conn net.Conn, open bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if open {
+		p.conns[conn] = struct{}{}
+	} else {
+		delete(p.conns, conn)
+	}
+}
+
+// handle proxies a client connection to the upstream
+func (p *faultProxy) handle(client net.Conn) {
+	p.track(client, true)
+	defer p.track(client, false)
+	defer client.Close()
+
+	if p.active().blackhole {
+		p.mu.Lock()
+		p.count(faultBlackhole, 1)
+		p.mu.Unlock()
+		if !p.waitBlackhole() {
+			return
+		}
+	}
+	if p.active().reset {
+		p.mu.Lock()
+		p.count(faultReset, 1)
+		p.mu.Unlock()
+		resetConn(client)
+		return
+	}
+
+	upstream, err := net.DialTimeout("tcp", p.config.Upstream, proxyDialTimeout)
+	if err != nil {
+		p.mu.Lock()
+		p.stats.DialErrors++
+		p.mu.Unlock()
+		return
+	}
+	p.track(upstream, true)
+	defer p.track(upstream, false)
+	defer upstream.Close()
+
+	done := make(chan struct{})
+	go func() {
+		p.pipe(upstream, client, false)
+		close(done)
+	}()
+	p.pipe(client, upstream, true)
+	<-done
+}
+
+// proxyChunk is data read from a connection and the time it is due to be forwarded
+type proxyChunk struct {
+	data []byte
+	due  time.Time
+}
+
+// pipe copies src to dst applying active faults. Latency is added to data from the upstream.
+// Both connections are closed when either side closes
+func (p *faultProxy) pipe(dst, src net.Conn, fromUpstream bool) {
+	defer dst.Close()
+	defer src.Close()
+
+	// Chunks are timestamped on arrival, so latency delays every chunk once instead of adding up
+	chunks := make(chan proxyChunk, 64)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(chunks)
+		for {
+			buf := make([]byte, 32*1024)
+			n, err := src.Read(buf)
+			if n > 0 {
+				chunk := proxyChunk{data: buf[:n], due: time.Now()}
+				if fromUpstream {
+					chunk.due = chunk.due.Add(p.active().latency)
+				}
+				select {
+				case chunks <- chunk:
+				case <-stop:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for chunk := range chunks {
+		time.Sleep(time.Until(chunk.due))
+		if !p.waitBlackhole() {
+			return
+		}
+		if err := p.write(dst, chunk.data, p.active().bandwidth); err != nil {
+			return
+		}
+	}
+}
+
+// write writes data, limited to bandwidth bytes per second if it is positive
+func (p *faultProxy) write(dst net.Conn, data []byte, bandwidth int64) error {
+	if bandwidth <= 0 {
+		_, err := dst.Write(data)
+		return err
+	}
+
+	// Send chunks of a tenth of a second worth of bytes
+	chunk := int(bandwidth / 10)
+	if chunk < 1 {
+		chunk = 1
+	}
+	for len(data) > 0 {
+		n := chunk
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := dst.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+		time.Sleep(time.Duration(float64(n) / float64(bandwidth) * float64(time.Second)))
+	}
+	return nil
+}
+
+// waitBlackhole blocks while a blackhole is active. Returns false if the proxy stopped
+func (p *faultProxy) waitBlackhole() bool {
+	for p.active().blackhole {
+		select {
+		case <-p.done:
+			return false
+		case <-time.After(faultCheckInterval):
+		}
+	}
+	return true
+}
+
+// resetConn closes connection with RST instead of FIN
+func resetConn(conn net.Conn) {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		tcp.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// annotateFaults marks seconds of the time-series that overlap fault windows
+func annotateFaults(series []TimeSeriesPoint, runStart time.Time, stats *ProxyStats) {
+	if stats == nil {
+		return
+	}
+	offset := stats.Start.Sub(runStart)
+	for i := range series {
+		from := time.Duration(series[i].Second) * time.Second
+		to := from + time.Second
+
+		var faults []string
+		for _, f := range stats.Faults {
+			if from < f.End+offset && to > f.Start+offset {
+				faults = append(faults, f.String())
+			}
+		}
+		series[i].Fault = strings.Join(faults, ",")
+	}
+}
+
+// FaultImpact is statistics of the seconds of a fault window, or of seconds without faults
+type FaultImpact struct {
+	Label    string
+	Fault    string
+	Seconds  int
+	RPS      float64
+	AvgP99   time.Duration // Average of per-second p99
+	Errors   int64
+	Requests int64
+}
+
+// faultImpact compares seconds without faults with every fault window
+func faultImpact(series []TimeSeriesPoint, runStart time.Time, stats *ProxyStats) []FaultImpact {
+	if stats == nil {
+		return nil
+	}
+	offset := stats.Start.Sub(runStart)
+
+	// Every second belongs to the windows containing its middle
+	impact := func(label, fault string, inWindow func(middle time.Duration) bool) FaultImpact {
+		result := FaultImpact{Label: label, Fault: fault}
+		var p99Sum time.Duration
+		for _, point := range series {
+			if !inWindow(time.Duration(point.Second)*time.Second + time.Second/2 - offset) {
+				continue
+			}
+			result.Seconds++
+			result.Requests += point.Requests
+			result.Errors += point.Errors
+			p99Sum += point.P99Latency
+		}
+		if result.Seconds > 0 {
+			result.RPS = float64(result.Requests) / float64(result.Seconds)
+			result.AvgP99 = p99Sum / time.Duration(result.Seconds)
+		}
+		return result
+	}
+
+	results := []FaultImpact{impact("no faults", "-", func(middle time.Duration) bool {
+		for _, f := range stats.Faults {
+			if middle >= f.Start && middle < f.End {
+				return false
+			}
+		}
+		return true
+	})}
+	for _, f := range stats.Faults {
+		f := f
+		label := fmt.Sprintf("%s-%s", f.Start, f.End)
+		results = append(results, impact(label, f.String(), func(middle time.Duration) bool {
+			return middle >= f.Start && middle < f.End
+		}))
+	}
+	return results
+}
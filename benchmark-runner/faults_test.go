@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProxy(t *testing.T) {
+	config, err := parseProxy(":15432=postgres:5432")
+	if err != nil || config.Listen != ":15432" || config.Upstream != "postgres:5432" || !config.Enabled() {
+		t.Errorf("parseProxy = %+v, %v", config, err)
+	}
+	for _, spec := range []string{"", ":15432", "=postgres:5432", ":15432="} {
+		if _, err := parseProxy(spec); err == nil || !strings.Contains(err.Error(), "expected LISTEN=UPSTREAM") {
+			t.Errorf("parseProxy(%q) error = %v", spec, err)
+		}
+	}
+	if (ProxyConfig{Listen: ":1"}).Enabled() {
+		t.Error("proxy without upstream is enabled")
+	}
+}
+
+func TestParseFault(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Fault
+		err  string
+	}{
+		{spec: "30s-1m:latency=200ms", want: Fault{Start: 30 * time.Second, End: time.Minute, Kind: faultLatency, Latency: 200 * time.Millisecond}},
+		{spec: "0s-10s:bandwidth=64KB", want: Fault{End: 10 * time.Second, Kind: faultBandwidth, Bandwidth: 64 << 10}},
+		{spec: "1m-1m10s:reset", want: Fault{Start: time.Minute, End: 70 * time.Second, Kind: faultReset}},
+		{spec: "2m-2m30s:blackhole", want: Fault{Start: 2 * time.Minute, End: 150 * time.Second, Kind: faultBlackhole}},
+		{spec: "30s:latency=1s", err: "expected START-END:KIND[=VALUE]"},
+		{spec: "30s-1m", err: "expected START-END:KIND[=VALUE]"},
+		{spec: "x-1m:reset", err: `invalid fault "x-1m:reset"`},
+		{spec: "1m-y:reset", err: `invalid fault "1m-y:reset"`},
+		{spec: "1m-30s:reset", err: "window end must be after its start"},
+		{spec: "1m-1m:reset", err: "window end must be after its start"},
+		{spec: "0s-1m:latency", err: "expected latency=DURATION"},
+		{spec: "0s-1m:latency=-1s", err: "expected latency=DURATION"},
+		{spec: "0s-1m:bandwidth=fast", err: "expected bandwidth=BYTES"},
+		{spec: "0s-1m:bandwidth=0", err: "expected bandwidth=BYTES"},
+		{spec: "0s-1m:reset=5", err: "reset takes no value"},
+		{spec: "0s-1m:drop", err: `unknown fault "drop"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseFault(tt.spec)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			tt.want.Spec = tt.spec
+			if err != nil || got != tt.want {
+				t.Errorf("parseFault = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		err   bool
+	}{
+		{value: "512", want: 512},
+		{value: "512B", want: 512},
+		{value: "64KB", want: 64 << 10},
+		{value: " 64kb ", want: 64 << 10},
+		{value: "2MB", want: 2 << 20},
+		{value: "", err: true},
+		{value: "1.5KB", err: true},
+		{value: "KB", err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseBytes(tt.value)
+		if (err != nil) != tt.err || (!tt.err && got != tt.want) {
+			t.Errorf("parseBytes(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestFaultString(t *testing.T) {
+	tests := map[string]string{
+		"0s-1s:latency=200ms":  "latency=200ms",
+		"0s-1s:bandwidth=64KB": "bandwidth=64KB/s",
+		"0s-1s:bandwidth=512B": "bandwidth=512B/s",
+		"0s-1s:bandwidth=1500": "bandwidth=1500B/s",
+		"0s-1s:bandwidth=1MB":  "bandwidth=1024KB/s",
+		"0s-1s:reset":          "reset",
+		"0s-1s:blackhole":      "blackhole",
+	}
+	for spec, want := range tests {
+		f, err := parseFault(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.String(); got != want {
+			t.Errorf("%s: String = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestFaultProxyActive(t *testing.T) {
+	faults := func(specs ...string) []Fault {
+		var result []Fault
+		for _, spec := range specs {
+			f, err := parseFault(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result = append(result, f)
+		}
+		return result
+	}
+	proxy := &faultProxy{
+		config: ProxyConfig{Faults: faults(
+			"0s-1h:latency=100ms", "0s-1h:latency=50ms", "0s-1h:bandwidth=64KB", "0s-1h:bandwidth=8KB",
+			"0s-1h:reset", "1h-2h:blackhole",
+		)},
+		start: time.Now(),
+	}
+
+	// Latencies add up, the lowest bandwidth limit wins, windows that didn't start are inactive
+	want := activeFaults{latency: 150 * time.Millisecond, bandwidth: 8 << 10, reset: true}
+	if got := proxy.active(); got != want {
+		t.Errorf("active = %+v, want %+v", got, want)
+	}
+}
+
+func TestAnnotateFaults(t *testing.T) {
+	runStart := time.Unix(1000, 0)
+	latency, _ := parseFault("1s-2500ms:latency=100ms")
+	reset, _ := parseFault("2s-3s:reset")
+	// The schedule started half a second after the run
+	stats := &ProxyStats{Start: runStart.Add(500 * time.Millisecond), Faults: []FaultStats{{Fault: latency}, {Fault: reset}}}
+
+	series := make([]TimeSeriesPoint, 5)
+	for i := range series {
+		series[i].Second = i
+	}
+	annotateFaults(series, runStart, stats)
+
+	var got []string
+	for _, point := range series {
+		got = append(got, point.Fault)
+	}
+	want := []string{"", "latency=100ms", "latency=100ms,reset", "reset", ""}
+	if !slices.Equal(got, want) {
+		t.Errorf("faults = %q, want %q", got, want)
+	}
+
+	annotateFaults(series, runStart, nil)
+}
+
+func TestFaultImpact(t *testing.T) {
+	runStart := time.Unix(1000, 0)
+	blackhole, _ := parseFault("2s-4s:blackhole")
+	stats := &ProxyStats{Start: runStart, Faults: []FaultStats{{Fault: blackhole}}}
+
+	series := []TimeSeriesPoint{
+		{Second: 0, Requests: 100, P99Latency: 10 * time.Millisecond},
+		{Second: 1, Requests: 100, P99Latency: 20 * time.Millisecond},
+		{Second: 2, Requests: 10, Errors: 5, P99Latency: time.Second},
+		{Second: 3, Requests: 0, Errors: 10, P99Latency: 0},
+		{Second: 4, Requests: 90, P99Latency: 30 * time.Millisecond},
+	}
+	impact := faultImpact(series, runStart, stats)
+	if len(impact) != 2 {
+		t.Fatalf("impact = %+v", impact)
+	}
+
+	normal, fault := impact[0], impact[1]
+	if normal.Label != "no faults" || normal.Seconds != 3 || normal.RPS != 290.0/3 || normal.AvgP99 != 20*time.Millisecond || normal.Errors != 0 {
+		t.Errorf("no faults = %+v", normal)
+	}
+	if fault.Label != "2s-4s" || fault.Fault != "blackhole" || fault.Seconds != 2 || fault.RPS != 5 || fault.Errors != 15 || fault.AvgP99 != 500*time.Millisecond {
+		t.Errorf("fault window = %+v", fault)
+	}
+
+	if faultImpact(series, runStart, nil) != nil {
+		t.Error("impact without proxy")
+	}
+}
+
+func TestFaultProxyForwards(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				conn.Write([]byte("echo " + line))
+			}()
+		}
+	}()
+
+	latency, _ := parseFault("0s-1h:latency=50ms")
+	proxy, err := startFaultProxy(ProxyConfig{Listen: "127.0.0.1:0", Upstream: upstream.Addr().String(), Faults: []Fault{latency}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", proxy.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	conn.Write([]byte("ping\n"))
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	elapsed := time.Since(start)
+	conn.Close()
+	if err != nil || reply != "echo ping\n" {
+		t.Errorf("reply = %q, %v", reply, err)
+	}
+	if elapsed < 50*time.Millisecond {
+		t.Errorf("reply after %s, want the injected latency", elapsed)
+	}
+
+	stats := proxy.Stop()
+	if stats.Connections != 1 || stats.DialErrors != 0 || len(stats.Faults) != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	if proxy, err := startFaultProxy(ProxyConfig{}); proxy != nil || err != nil || proxy.Stop() != nil {
+		t.Errorf("disabled proxy = %v, %v", proxy, err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// histogramBuckets is the number of logarithmic buckets of the latency histogram
+const histogramBuckets = 24
+
+// LatencyBucket is a bucket of the latency histogram
+type LatencyBucket struct {
+	From  time.Duration
+	To    time.Duration
+	Count int
+}
+
+// latencyHistogram splits sorted latencies into logarithmic buckets between min and max.
+// Logarithmic scale keeps both fast responses and slow tail (e.g. GC pauses) visible
+func latencyHistogram(sorted []time.Duration, buckets int) []LatencyBucket {
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	min, max := sorted[0], sorted[len(sorted)-1]
+	if min < time.Microsecond {
+		min = time.Microsecond
+	}
+	if max <= min {
+		return []LatencyBucket{{From: sorted[0], To: max, Count: len(sorted)}}
+	}
+
+	ratio := math.Pow(float64(max)/float64(min), 1/float64(buckets))
+	result := make([]LatencyBucket, buckets)
+	bound := float64(min)
+	for i := range result {
+		result[i].From = time.Duration(bound)
+		bound *= ratio
+		result[i].To = time.Duration(bound)
+	}
+	result[0].From = sorted[0]
+	result[buckets-1].To = max
+
+	i := 0
+	for _, latency := range sorted {
+		for i < buckets-1 && latency > result[i].To {
+			i++
+		}
+		result[i].Count++
+	}
+	return result
+}
+
+// spectrumPercentiles are percentiles of the percentile spectrum chart
+var spectrumPercentiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99, 0.999, 0.9999, 1}
+
+// SlowRequest is one of the slowest requests of the run
+type SlowRequest struct {
+	Operation  string
+	StatusCode int // 0 for network errors
+	Start      time.Time
+	Latency    time.Duration
+	ProductID  int64 // 0 if the operation doesn't address a product
+}
+
+// slowestRecorder keeps the N slowest requests. Safe for concurrent use
+type slowestRecorder struct {
+	threshold int64 // Latency of the fastest kept request once full, read without the lock
+
+	mu       sync.Mutex
+	requests *topN[SlowRequest]
+}
+
+func newSlowestRecorder(size int) *slowestRecorder {
+	return &slowestRecorder{requests: newTopN(size, func(r SlowRequest) time.Duration { return r.Latency })}
+}
+
+// Record keeps request if it is one of the slowest
+func (s *slowestRecorder) Record(request SlowRequest) {
+	if s == nil || s.requests.size <= 0 || int64(request.Latency) <= atomic.LoadInt64(&s.threshold) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests.Add(request)
+	atomic.StoreInt64(&s.threshold, int64(s.requests.Threshold()))
+}
+
+// Slowest returns kept requests, slowest first
+func (s *slowestRecorder) Slowest() []SlowRequest {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests.Sorted()
+}
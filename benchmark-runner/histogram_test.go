@@ -0,0 +1,197 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		latencies := make([]time.Duration, len(values))
+		for i, v := range values {
+			latencies[i] = time.Duration(v) * time.Millisecond
+		}
+		return latencies
+	}
+
+	tests := []struct {
+		name    string
+		sorted  []time.Duration
+		buckets int
+		counts  []int
+	}{
+		{name: "empty", sorted: nil, buckets: 4, counts: nil},
+		{name: "constant", sorted: ms(5, 5, 5), buckets: 4, counts: []int{3}},
+		// 1ms..16ms in 4 buckets: bounds 2, 4, 8, 16ms
+		{name: "logarithmic", sorted: ms(1, 2, 3, 4, 5, 8, 9, 16), buckets: 4, counts: []int{2, 2, 2, 2}},
+		{name: "slow tail", sorted: ms(1, 1, 1, 1, 1, 1, 1000), buckets: 3, counts: []int{6, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			histogram := latencyHistogram(tt.sorted, tt.buckets)
+			counts := make([]int, 0, len(histogram))
+			for _, bucket := range histogram {
+				counts = append(counts, bucket.Count)
+			}
+			if len(histogram) == 0 {
+				counts = nil
+			}
+			if !slices.Equal(counts, tt.counts) {
+				t.Fatalf("counts = %v, want %v", counts, tt.counts)
+			}
+			if len(histogram) == 0 {
+				return
+			}
+			if histogram[0].From != tt.sorted[0] || histogram[len(histogram)-1].To != tt.sorted[len(tt.sorted)-1] {
+				t.Errorf("histogram covers %s..%s, want min..max", histogram[0].From, histogram[len(histogram)-1].To)
+			}
+			for i := 1; i < len(histogram); i++ {
+				if histogram[i].From != histogram[i-1].To {
+					t.Errorf("bucket %d starts at %s, previous ends at %s", i, histogram[i].From, histogram[i-1].To)
+				}
+			}
+		})
+	}
+}
+
+func TestLatencyHistogramSubMicrosecondMin(t *testing.T) {
+	histogram := latencyHistogram([]time.Duration{0, 500, time.Millisecond}, 3)
+	if len(histogram) != 3 || histogram[0].From != 0 || histogram[0].Count != 2 || histogram[2].Count != 1 {
+		t.Errorf("histogram = %+v", histogram)
+	}
+}
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		share float64
+		want  string
+	}{
+		{share: 0, want: ""},
+		{share: 0.001, want: "▏"},
+		{share: 0.5, want: "██"},
+		{share: 0.625, want: "██▌"},
+		{share: 1, want: "████"},
+	}
+	for _, tt := range tests {
+		if got := bar(tt.share, 4); got != tt.want {
+			t.Errorf("bar(%v) = %q, want %q", tt.share, got, tt.want)
+		}
+	}
+}
+
+func TestTopN(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		add       []time.Duration
+		want      []time.Duration
+		threshold time.Duration
+	}{
+		{name: "not full", size: 3, add: []time.Duration{2, 1}, want: []time.Duration{2, 1}, threshold: 0},
+		{name: "keeps longest", size: 3, add: []time.Duration{5, 1, 9, 3, 7, 2}, want: []time.Duration{9, 7, 5}, threshold: 5},
+		{name: "equal to threshold", size: 2, add: []time.Duration{4, 4, 4}, want: []time.Duration{4, 4}, threshold: 4},
+		{name: "disabled", size: 0, add: []time.Duration{1, 2}, want: []time.Duration{}, threshold: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top := newTopN(tt.size, func(d time.Duration) time.Duration { return d })
+			for _, d := range tt.add {
+				top.Add(d)
+			}
+			if got := top.Sorted(); !slices.Equal(got, tt.want) {
+				t.Errorf("sorted = %v, want %v", got, tt.want)
+			}
+			if got := top.Threshold(); got != tt.threshold {
+				t.Errorf("threshold = %v, want %v", got, tt.threshold)
+			}
+			// Sorted doesn't consume the kept items
+			if got := top.Sorted(); !slices.Equal(got, tt.want) {
+				t.Errorf("second sorted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlowestRecorderConcurrent(t *testing.T) {
+	recorder := newSlowestRecorder(5)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				recorder.Record(SlowRequest{Operation: "GET /", Latency: time.Duration(i*8 + w)})
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var latencies []time.Duration
+	for _, request := range recorder.Slowest() {
+		latencies = append(latencies, request.Latency)
+	}
+	if want := []time.Duration{7999, 7998, 7997, 7996, 7995}; !slices.Equal(latencies, want) {
+		t.Errorf("slowest = %v, want %v", latencies, want)
+	}
+
+	var disabled *slowestRecorder
+	disabled.Record(SlowRequest{Latency: time.Second})
+	if disabled.Slowest() != nil || len(newSlowestRecorder(0).Slowest()) != 0 {
+		t.Error("disabled recorder kept requests")
+	}
+}
+
+func TestSlowRequestProductID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer server.Close()
+
+	byHeader, err := newTemplateOperation("by-header", TemplateRequest{URL: server.URL + "/items", Headers: map[string]string{"X-Product": "{{.ProductID}}"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := newTemplateOperation("plain", TemplateRequest{URL: server.URL + "/items"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := func(name BenchmarkType) Operation {
+		op, ok := LookupOperation(string(name))
+		if !ok {
+			t.Fatalf("operation %s is not registered", name)
+		}
+		return op
+	}
+
+	tests := []struct {
+		op   Operation
+		want int64
+	}{
+		{op: lookup(GetProducts), want: 0},
+		{op: lookup(GetProductByID), want: 42},
+		{op: lookup(UpdateProduct), want: 42},
+		{op: byHeader, want: 42},
+		{op: plain, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.op.Name(), func(t *testing.T) {
+			ctx := newRequestContext(Config{URL: server.URL, Slowest: 1}, NewErrorStats())
+			state := newState()
+			state.ProductID = 42
+			executeRequest(ctx, tt.op, state)
+
+			slowest := ctx.Slowest.Slowest()
+			if len(slowest) != 1 || slowest[0].ProductID != tt.want || slowest[0].StatusCode != http.StatusOK {
+				t.Errorf("slowest = %+v, want product ID %d", slowest, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Journey is a user-defined multi-step scenario loaded from a JSON file.
+// Each iteration executes all steps in order. Its steps see the values extracted in the iteration first,
+// then the values captured by other iterations and scenarios (shared between workers)
+type Journey struct {
+	Name  string        `json:"name"`
+	Steps []JourneyStep `json:"steps"`
+}
+
+// JourneyStep is a single templated request of a journey
+type JourneyStep struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"` // Template. Paths starting with "/" are relative to -url
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`    // Template: JSON string or JSON object/array
+	Extract map[string]string `json:"extract,omitempty"` // Variable name -> "$.json.path" or "header:Name"
+	Assert  *StepAssertions   `json:"assert,omitempty"`
+}
+
+// StepAssertions are checks applied to the step response.
+// A failed assertion marks the request (and the journey iteration) as failed
+type StepAssertions struct {
+	Status       []int             `json:"status,omitempty"`       // Allowed status codes
+	JSON         map[string]string `json:"json,omitempty"`         // JSON path -> expected value
+	Headers      map[string]string `json:"headers,omitempty"`      // Header -> expected value ("" - must be present)
+	BodyContains string            `json:"bodyContains,omitempty"` // Substring the body must contain
+}
+
+// AssertionError is returned when response doesn't satisfy step assertions
+type AssertionError struct {
+	Message string
+}
+
+func (e *AssertionError) Error() string {
+	return "assertion failed: " + e.Message
+}
+
+// Check verifies response against assertions other than status
+func (a *StepAssertions) Check(resp *Response) error {
+	if a == nil {
+		return nil
+	}
+
+	// Sorted, so the first failed assertion reported is the same in every iteration
+	for _, path := range sortedKeys(a.JSON) {
+		expected := a.JSON[path]
+		actual, err := extractJSONPath(resp.Body, path)
+		if err != nil {
+			return &AssertionError{Message: err.Error()}
+		}
+		if actual != expected {
+			return &AssertionError{Message: fmt.Sprintf("%s = %q, expected %q", path, actual, expected)}
+		}
+	}
+
+	for _, header := range sortedKeys(a.Headers) {
+		expected := a.Headers[header]
+		actual := resp.Header.Get(header)
+		if actual == "" {
+			return &AssertionError{Message: fmt.Sprintf("header %s is missing", header)}
+		}
+		if expected != "" && actual != expected {
+			return &AssertionError{Message: fmt.Sprintf("header %s = %q, expected %q", header, actual, expected)}
+		}
+	}
+
+	if a.BodyContains != "" && !bytes.Contains(resp.Body, []byte(a.BodyContains)) {
+		return &AssertionError{Message: fmt.Sprintf("body doesn't contain %q", a.BodyContains)}
+	}
+
+	return nil
+}
+
+// loadJourney reads and validates journey definition
+func loadJourney(path string) (*Journey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseJourney(data, path)
+}
+
+// parseJourney parses and validates journey definition. source names it in errors
+func parseJourney(data []byte, source string) (*Journey, error) {
+	var journey Journey
+	if err := json.Unmarshal(data, &journey); err != nil {
+		return nil, fmt.Errorf("failed to parse journey %s: %v", source, err)
+	}
+	if len(journey.Steps) == 0 {
+		return nil, fmt.Errorf("journey %s has no steps", source)
+	}
+
+	seen := make(map[string]bool, len(journey.Steps))
+	for i := range journey.Steps {
+		step := &journey.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step-%d", i+1)
+		}
+		if seen[step.Name] {
+			return nil, fmt.Errorf("journey %s: duplicate step name %q", source, step.Name)
+		}
+		seen[step.Name] = true
+		if step.URL == "" {
+			return nil, fmt.Errorf("journey %s: step %q has no url", source, step.Name)
+		}
+	}
+
+	if journey.Name == "" {
+		journey.Name = "journey"
+	}
+	return &journey, nil
+}
+
+// Operations builds templated operations for journey steps
+func (j *Journey) Operations(baseURL string) ([]Operation, error) {
+	ops := make([]Operation, 0, len(j.Steps))
+	for _, step := range j.Steps {
+		op, err := newTemplateOperation(step.Name, step.request(baseURL))
+		if err != nil {
+			return nil, fmt.Errorf("step %q: %v", step.Name, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// request converts journey step into templated request definition
+func (s JourneyStep) request(baseURL string) TemplateRequest {
+	def := TemplateRequest{
+		Method:     strings.ToUpper(s.Method),
+		URL:        s.URL,
+		Headers:    s.Headers,
+		Assertions: s.Assert,
+	}
+	if strings.HasPrefix(s.URL, "/") {
+		def.URL = strings.TrimSuffix(baseURL, "/") + s.URL
+	}
+
+	// Body is either a JSON string with the template, or a JSON document used as is
+	if len(s.Body) > 0 {
+		var text string
+		if err := json.Unmarshal(s.Body, &text); err == nil {
+			def.Body = text
+		} else {
+			def.Body = string(s.Body)
+		}
+	}
+
+	// Sorted, so the first failed capture reported is the same in every iteration
+	for _, name := range sortedKeys(s.Extract) {
+		def.Captures = append(def.Captures, CaptureRule{Name: name, Source: s.Extract[name]})
+	}
+	if s.Assert != nil {
+		def.ExpectStatus = s.Assert.Status
+	}
+
+	return def
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseJourney(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		steps []string
+		err   string
+	}{
+		{
+			name:  "named steps",
+			data:  `{"name": "checkout", "steps": [{"name": "list", "url": "/api/products"}, {"name": "view", "url": "/api/products/1"}]}`,
+			steps: []string{"list", "view"},
+		},
+		{
+			name:  "default names",
+			data:  `{"steps": [{"url": "/a"}, {"url": "/b"}]}`,
+			steps: []string{"step-1", "step-2"},
+		},
+		{name: "invalid json", data: `{"steps": [`, err: "failed to parse journey test.json"},
+		{name: "no steps", data: `{"name": "empty", "steps": []}`, err: "journey test.json has no steps"},
+		{name: "duplicate step", data: `{"steps": [{"name": "a", "url": "/a"}, {"name": "a", "url": "/b"}]}`, err: `duplicate step name "a"`},
+		{name: "default name clash", data: `{"steps": [{"name": "step-2", "url": "/a"}, {"url": "/b"}]}`, err: `duplicate step name "step-2"`},
+		{name: "no url", data: `{"steps": [{"name": "a"}]}`, err: `step "a" has no url`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			journey, err := parseJourney([]byte(tt.data), "test.json")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(journey.Steps) != len(tt.steps) {
+				t.Fatalf("steps = %d, want %d", len(journey.Steps), len(tt.steps))
+			}
+			for i, name := range tt.steps {
+				if journey.Steps[i].Name != name {
+					t.Errorf("step %d name = %q, want %q", i, journey.Steps[i].Name, name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJourneyDefaultName(t *testing.T) {
+	journey, err := parseJourney([]byte(`{"steps": [{"url": "/"}]}`), "test.json")
+	if err != nil || journey.Name != "journey" {
+		t.Errorf("journey = %+v, %v, want name journey", journey, err)
+	}
+}
+
+func TestLoadJourneyExample(t *testing.T) {
+	journey, err := loadJourney("journeys/crud.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := journey.Operations("http://localhost:8080"); err != nil {
+		t.Errorf("example journey operations: %v", err)
+	}
+}
+
+func TestJourneyStepRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		step JourneyStep
+		url  string
+		body string
+	}{
+		{
+			name: "relative url",
+			step: JourneyStep{Method: "get", URL: "/api/products"},
+			url:  "http://localhost:8080/api/products",
+		},
+		{
+			name: "absolute url",
+			step: JourneyStep{URL: "http://other:9000/health"},
+			url:  "http://other:9000/health",
+		},
+		{
+			name: "string body template",
+			step: JourneyStep{URL: "/", Body: []byte(`"{\"id\": {{.Var \"id\"}}}"`)},
+			url:  "http://localhost:8080/",
+			body: `{"id": {{.Var "id"}}}`,
+		},
+		{
+			name: "json body",
+			step: JourneyStep{URL: "/", Body: []byte(`{"name": "x"}`)},
+			url:  "http://localhost:8080/",
+			body: `{"name": "x"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def := tt.step.request("http://localhost:8080/")
+			if def.URL != tt.url || def.Body != tt.body {
+				t.Errorf("url = %q, body = %q, want %q, %q", def.URL, def.Body, tt.url, tt.body)
+			}
+		})
+	}
+
+	def := JourneyStep{Method: "post", URL: "/", Extract: map[string]string{"id": "$.id"}, Assert: &StepAssertions{Status: []int{201}}}.request("")
+	if def.Method != http.MethodPost || len(def.Captures) != 1 || def.Captures[0] != (CaptureRule{Name: "id", Source: "$.id"}) || len(def.ExpectStatus) != 1 || def.ExpectStatus[0] != 201 {
+		t.Errorf("request = %+v", def)
+	}
+
+	// Captures are in name order, so failures are reported the same way in every iteration
+	extract := map[string]string{"z": "$.z", "a": "$.a", "m": "header:M"}
+	want := []CaptureRule{{Name: "a", Source: "$.a"}, {Name: "m", Source: "header:M"}, {Name: "z", Source: "$.z"}}
+	for i := 0; i < 10; i++ {
+		if def := (JourneyStep{URL: "/", Extract: extract}).request(""); !slices.Equal(def.Captures, want) {
+			t.Fatalf("captures = %+v, want %+v", def.Captures, want)
+		}
+	}
+}
+
+func TestStepAssertionsCheck(t *testing.T) {
+	resp := &Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       []byte(`{"id": 5, "status": "active"}`),
+	}
+
+	tests := []struct {
+		name   string
+		assert *StepAssertions
+		err    string
+	}{
+		{name: "nil", assert: nil},
+		{name: "json matches", assert: &StepAssertions{JSON: map[string]string{"$.id": "5", "$.status": "active"}}},
+		{name: "json differs", assert: &StepAssertions{JSON: map[string]string{"$.status": "deleted"}}, err: `$.status = "active", expected "deleted"`},
+		{name: "json missing", assert: &StepAssertions{JSON: map[string]string{"$.name": "x"}}, err: `field "name" not found`},
+		{name: "header present", assert: &StepAssertions{Headers: map[string]string{"Content-Type": ""}}},
+		{name: "header matches", assert: &StepAssertions{Headers: map[string]string{"content-type": "application/json"}}},
+		{name: "header missing", assert: &StepAssertions{Headers: map[string]string{"ETag": ""}}, err: "header ETag is missing"},
+		{name: "header differs", assert: &StepAssertions{Headers: map[string]string{"Content-Type": "text/plain"}}, err: `header Content-Type = "application/json", expected "text/plain"`},
+		{name: "body contains", assert: &StepAssertions{BodyContains: `"active"`}},
+		{name: "body lacks", assert: &StepAssertions{BodyContains: "deleted"}, err: `body doesn't contain "deleted"`},
+		// Several failures report the first in name order, the same in every iteration
+		{name: "json failures", assert: &StepAssertions{JSON: map[string]string{"$.status": "x", "$.id": "1", "$.a": "y"}}, err: `field "a" not found`},
+		{name: "header failures", assert: &StepAssertions{Headers: map[string]string{"X-B": "", "X-A": "", "Content-Type": "text/plain"}}, err: `header Content-Type = "application/json"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.assert.Check(resp)
+			for i := 0; i < 10 && err != nil; i++ {
+				if again := tt.assert.Check(resp); again.Error() != err.Error() {
+					t.Fatalf("error = %v, then %v", err, again)
+				}
+			}
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var assertErr *AssertionError
+			if !errors.As(err, &assertErr) || !strings.Contains(err.Error(), tt.err) || !strings.HasPrefix(err.Error(), "assertion failed: ") {
+				t.Errorf("error = %v, want assertion failed containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestJourneyOperationsAssertStatus(t *testing.T) {
+	journey, err := parseJourney([]byte(`{"steps": [{"name": "create", "method": "POST", "url": "/api/products", "body": {"name": "x"},
+		"extract": {"id": "$.id"}, "assert": {"status": [201], "json": {"$.name": "x"}}}]}`), "test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := journey.Operations("http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := ops[0]
+
+	req, err := op.BuildRequest(newRequestContext(Config{}, NewErrorStats()), newState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.Method != http.MethodPost || req.URL.String() != "http://localhost:8080/api/products" || string(body) != `{"name": "x"}` {
+		t.Errorf("request = %s %s %s", req.Method, req.URL, body)
+	}
+
+	if err := op.ClassifyResponse(&Response{StatusCode: 200, Body: []byte(`{"name": "x"}`)}); err == nil {
+		t.Error("status 200 passed assertion of status 201")
+	}
+	if err := op.ClassifyResponse(&Response{StatusCode: 201, Body: []byte(`{"name": "y"}`)}); err == nil {
+		t.Error("wrong name passed json assertion")
+	}
+	if err := op.ClassifyResponse(&Response{StatusCode: 201, Body: []byte(`{"name": "x"}`)}); err != nil {
+		t.Errorf("valid response failed: %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// extractJSONPath returns value at the JSONPath-like path from JSON body.
+// Supported syntax: "$.id", "$.items[0].id", "items.0.id", "$[2].name".
+// Strings are returned as is, other values are returned as JSON
+func extractJSONPath(body []byte, path string) (string, error) {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return "", fmt.Errorf("response is not JSON: %v", err)
+	}
+
+	value, err := lookupJSONPath(document, path)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(encoded), nil
+	}
+}
+
+// lookupJSONPath walks decoded JSON document along the path
+func lookupJSONPath(document interface{}, path string) (interface{}, error) {
+	current := document
+	for _, segment := range splitJSONPath(path) {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				// Like encoding/json, fall back to a case-insensitive match, e.g. "ID" of gorm models for $.id
+				for key, v := range node {
+					if strings.EqualFold(key, segment) {
+						value, ok = v, true
+						break
+					}
+				}
+			}
+			if !ok {
+				return nil, fmt.Errorf("path %s: field %q not found", path, segment)
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil {
+				return nil, fmt.Errorf("path %s: %q is not an array index", path, segment)
+			}
+			if index < 0 {
+				index += len(node)
+			}
+			if index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("path %s: index %d out of range (len %d)", path, index, len(node))
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %s: can't descend into %q", path, segment)
+		}
+	}
+	return current, nil
+}
+
+// splitJSONPath splits "$.items[0].id" into ["items", "0", "id"]
+func splitJSONPath(path string) []string {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+
+	segments := make([]string, 0, 4)
+	for _, segment := range strings.Split(path, ".") {
+		segment = strings.Trim(segment, `"'`)
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractJSONPath(t *testing.T) {
+	body := []byte(`{
+		"id": 42,
+		"name": "widget",
+		"price": 9.5,
+		"active": true,
+		"tags": ["a", "b", "c"],
+		"items": [{"id": 1, "sku": "x1"}, {"id": 2, "sku": "x2"}],
+		"owner": {"name": "ops", "address": {"city": "Berlin"}},
+		"ID": "ignored by exact match",
+		"Code": "C-7",
+		"empty": null
+	}`)
+
+	tests := []struct {
+		path string
+		want string
+		err  string
+	}{
+		{path: "$.id", want: "42"},
+		{path: "id", want: "42"},
+		{path: "$.name", want: "widget"},
+		{path: "$.price", want: "9.5"},
+		{path: "$.active", want: "true"},
+		{path: "$.empty", want: "null"},
+		{path: "$.tags", want: `["a","b","c"]`},
+		{path: "$.tags[1]", want: "b"},
+		{path: "$.tags[-1]", want: "c"},
+		{path: "tags.0", want: "a"},
+		{path: "$.items[1].sku", want: "x2"},
+		{path: "$.items[0]", want: `{"id":1,"sku":"x1"}`},
+		{path: "$.owner.address.city", want: "Berlin"},
+		{path: `$["owner"]["name"]`, want: "ops"},
+		{path: "$.code", want: "C-7"},
+		{path: "$.missing", err: `field "missing" not found`},
+		{path: "$.tags[3]", err: "index 3 out of range (len 3)"},
+		{path: "$.tags[-4]", err: "out of range"},
+		{path: "$.tags.first", err: `"first" is not an array index`},
+		{path: "$.name.first", err: `can't descend into "first"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := extractJSONPath(body, tt.path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractJSONPathTopLevelArray(t *testing.T) {
+	got, err := extractJSONPath([]byte(`[{"name":"a"},{"name":"b"},{"name":"c"}]`), "$[2].name")
+	if err != nil || got != "c" {
+		t.Errorf("got %q, %v, want c", got, err)
+	}
+	if _, err := extractJSONPath([]byte("not json"), "$.id"); err == nil || !strings.Contains(err.Error(), "response is not JSON") {
+		t.Errorf("error = %v, want response is not JSON", err)
+	}
+}
+
+func TestSplitJSONPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"$.items[0].id", []string{"items", "0", "id"}},
+		{"items.0.id", []string{"items", "0", "id"}},
+		{"$[2].name", []string{"2", "name"}},
+		{`$['a']["b"]`, []string{"a", "b"}},
+		{" $.id ", []string{"id"}},
+		{"$", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := splitJSONPath(tt.path); !slices.Equal(got, tt.want) {
+			t.Errorf("splitJSONPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
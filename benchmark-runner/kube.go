@@ -0,0 +1,668 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// kubePollInterval is how often Job and pod status is checked
+	kubePollInterval = 2 * time.Second
+
+	// kubeLogDrainTimeout is how long to wait for log streams after the Job finished
+	kubeLogDrainTimeout = 30 * time.Second
+)
+
+// kubeApps are shortcuts for -app: target URLs of the benchmarked services
+var kubeApps = map[string]string{
+	"quarkus":        "http://benchmark-quarkus:8080",
+	"quarkus-native": "http://benchmark-quarkus-native:8080",
+	"golang":         "http://benchmark-golang:8080",
+}
+
+// KubeConfig configures the benchmark Job created by the kube command
+type KubeConfig struct {
+	Namespace     string
+	Image         string
+	Name          string // Job name
+	App           string // Target app label (and URL shortcut)
+	Parallelism   int    // Number of runner pods
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+	Timeout       time.Duration // Maximum time to wait for the Job
+	Keep          bool          // Don't delete the Job after completion
+	Args          []string      // Runner arguments of every pod
+}
+
+// PodResult is the result of one runner pod
+type PodResult struct {
+	Pod      string
+	ExitCode int32
+	Logs     string
+	Result   *runnerJSON // nil if the pod didn't print results
+	Err      error       // Why results are missing
+}
+
+// runnerJSON is the part of the runner's JSON results merged across pods
+type runnerJSON struct {
+	TotalRequests   int64             `json:"total_requests"`
+	SuccessRequests int64             `json:"success_requests"`
+	FailedRequests  int64             `json:"failed_requests"`
+	Cycles          int64             `json:"crud_cycles"`
+	SuccessCycles   int64             `json:"success_cycles"`
+	FailedCycles    int64             `json:"failed_cycles"`
+	HTTPRequests    int64             `json:"total_http_requests"`
+	FailedHTTP      int64             `json:"failed_http_requests"`
+	RPS             float64           `json:"rps"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	Latency         map[string]string `json:"latency"`
+	Errors          struct {
+		Total int64 `json:"total"`
+		List  []struct {
+			Count      int64  `json:"count"`
+			Operation  string `json:"operation"`
+			Type       string `json:"type"`
+			Message    string `json:"message"`
+			StatusCode int    `json:"statusCode"`
+		} `json:"list"`
+	} `json:"errors"`
+}
+
+// kubeRunner runs benchmark Jobs using the Kubernetes API
+type kubeRunner struct {
+	client kubernetes.Interface
+	config KubeConfig
+	out    io.Writer // Streamed pod logs
+
+	outMu sync.Mutex
+}
+
+// runKubeCommand implements "benchmark-runner kube [flags] -- [runner flags]"
+func runKubeCommand(args []string) int {
+	var (
+		config     KubeConfig
+		kubeconfig string
+	)
+
+	fs := flag.NewFlagSet("kube", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s kube [flags] -- [runner flags]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "Runs the benchmark as a Kubernetes Job, streams logs and merges results of all pods.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&kubeconfig, "kubeconfig", os.Getenv("KUBECONFIG"), "Path to kubeconfig (default: $KUBECONFIG, ~/.kube/config or in-cluster config)")
+	fs.StringVar(&config.Namespace, "namespace", "benchmark", "Namespace of the Job")
+	fs.StringVar(&config.Image, "image", "axidex/benchmark-runner:latest", "Runner image")
+	fs.StringVar(&config.Name, "name", "", "Job name (default: benchmark-<app>-<timestamp>)")
+	fs.StringVar(&config.App, "app", "", "Target app: quarkus, quarkus-native, golang. Sets -url of the runner unless given")
+	fs.IntVar(&config.Parallelism, "parallelism", 1, "Number of runner pods; every pod runs the same flags, so the total load is multiplied")
+	fs.StringVar(&config.CPURequest, "cpu-request", "1000m", "CPU request of a runner pod")
+	fs.StringVar(&config.CPULimit, "cpu-limit", "2000m", "CPU limit of a runner pod")
+	fs.StringVar(&config.MemoryRequest, "memory-request", "512Mi", "Memory request of a runner pod")
+	fs.StringVar(&config.MemoryLimit, "memory-limit", "1Gi", "Memory limit of a runner pod")
+	fs.DurationVar(&config.Timeout, "timeout", 30*time.Minute, "Maximum time to wait for the Job to complete")
+	fs.BoolVar(&config.Keep, "keep", false, "Keep the Job and its pods after completion")
+	fs.Parse(args)
+	config.Args = fs.Args()
+
+	if config.Parallelism < 1 {
+		log.Printf("Invalid -parallelism %d: must be at least 1", config.Parallelism)
+		return 2
+	}
+
+	quantities := []struct{ flag, value string }{
+		{"cpu-request", config.CPURequest},
+		{"cpu-limit", config.CPULimit},
+		{"memory-request", config.MemoryRequest},
+		{"memory-limit", config.MemoryLimit},
+	}
+	for _, quantity := range quantities {
+		if _, err := resource.ParseQuantity(quantity.value); err != nil {
+			log.Printf("Invalid -%s %q: %v", quantity.flag, quantity.value, err)
+			return 2
+		}
+	}
+
+	if config.App != "" {
+		url, ok := kubeApps[config.App]
+		if !ok {
+			log.Printf("Unknown app %q. Use: quarkus, quarkus-native, golang", config.App)
+			return 1
+		}
+		if !hasFlag(config.Args, "url") {
+			config.Args = append([]string{"-url=" + url}, config.Args...)
+		}
+	}
+	if !hasFlag(config.Args, "url") {
+		log.Printf("Runner flags must include -url (or use -app)")
+		return 1
+	}
+	if config.Name == "" {
+		app := config.App
+		if app == "" {
+			app = "custom"
+		}
+		config.Name = fmt.Sprintf("benchmark-%s-%s", app, time.Now().Format("20060102-150405"))
+	}
+
+	client, err := newKubeClient(kubeconfig)
+	if err != nil {
+		log.Printf("Failed to create Kubernetes client: %v", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner := &kubeRunner{client: client, config: config, out: os.Stdout}
+	results, err := runner.Run(ctx)
+	if err != nil {
+		log.Printf("Benchmark Job failed: %v", err)
+		return 1
+	}
+
+	printKubeResults(results)
+
+	exitCode := 0
+	for _, result := range results {
+		code := int(result.ExitCode)
+		if code < 0 {
+			code = 1 // Didn't terminate normally
+		}
+		if code > exitCode {
+			exitCode = code
+		}
+	}
+	return exitCode
+}
+
+// hasFlag reports whether args contain flag -name or --name
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		arg = strings.TrimLeft(arg, "-")
+		if arg == name || strings.HasPrefix(arg, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// newKubeClient creates client from kubeconfig, the default kubeconfig location or in-cluster config
+func newKubeClient(kubeconfig string) (kubernetes.Interface, error) {
+	var (
+		restConfig *rest.Config
+		err        error
+	)
+	if kubeconfig == "" {
+		if restConfig, err = rest.InClusterConfig(); err != nil {
+			kubeconfig = clientcmd.RecommendedHomeFile
+		}
+	}
+	if restConfig == nil {
+		if restConfig, err = clientcmd.BuildConfigFromFlags("", kubeconfig); err != nil {
+			return nil, err
+		}
+	}
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// Run creates the Job, streams logs of its pods, waits for completion and collects results.
+// The Job is deleted afterwards (also on cancellation) unless Keep is set
+func (k *kubeRunner) Run(ctx context.Context) ([]*PodResult, error) {
+	jobs := k.client.BatchV1().Jobs(k.config.Namespace)
+
+	job, err := jobs.Create(ctx, k.buildJob(), metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Job: %v", err)
+	}
+	log.Printf("Created Job %s/%s with %d pod(s)", k.config.Namespace, job.Name, k.config.Parallelism)
+
+	if !k.config.Keep {
+		defer k.cleanup(job.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, k.config.Timeout)
+	defer cancel()
+
+	var (
+		logsMu  sync.Mutex
+		logs    = make(map[string]*strings.Builder)
+		streams sync.WaitGroup
+	)
+	startStream := func(pod string) {
+		logsMu.Lock()
+		if _, started := logs[pod]; started {
+			logsMu.Unlock()
+			return
+		}
+		buf := &strings.Builder{}
+		logs[pod] = buf
+		logsMu.Unlock()
+
+		streams.Add(1)
+		go func() {
+			defer streams.Done()
+			if err := k.streamLogs(ctx, pod, buf, &logsMu); err != nil {
+				log.Printf("Failed to stream logs of %s: %v", pod, err)
+			}
+		}()
+	}
+
+	// Stream logs of pods as they start and wait for the Job to finish
+	var finished *batchv1.Job
+	ticker := time.NewTicker(kubePollInterval)
+	defer ticker.Stop()
+	for finished == nil {
+		pods, err := k.listPods(ctx, job.Name)
+		if err == nil {
+			for _, pod := range pods {
+				if pod.Status.Phase != corev1.PodPending {
+					startStream(pod.Name)
+				}
+			}
+		}
+
+		current, err := jobs.Get(ctx, job.Name, metav1.GetOptions{})
+		if err == nil && jobFinished(current) {
+			finished = current
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("job %s didn't finish: %v", job.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	// Pods that finished between polls still need their logs
+	pods, err := k.listPods(context.Background(), job.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	for _, pod := range pods {
+		startStream(pod.Name)
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		streams.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(kubeLogDrainTimeout):
+		log.Printf("Timed out waiting for pod logs")
+	}
+
+	logsMu.Lock()
+	defer logsMu.Unlock()
+
+	results := make([]*PodResult, 0, len(pods))
+	for _, pod := range pods {
+		result := &PodResult{Pod: pod.Name, ExitCode: podExitCode(pod)}
+		if buf, ok := logs[pod.Name]; ok {
+			result.Logs = buf.String()
+		}
+		result.Result, result.Err = parseRunnerJSON(result.Logs)
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Pod < results[j].Pod })
+
+	log.Printf("Job %s finished: %d succeeded, %d failed", job.Name, finished.Status.Succeeded, finished.Status.Failed)
+	return results, nil
+}
+
+// buildJob creates Job manifest: Indexed Job with Parallelism pods running the runner
+func (k *kubeRunner) buildJob() *batchv1.Job {
+	parallelism := int32(k.config.Parallelism)
+	backoffLimit := int32(0)
+	ttl := int32(3600)
+	completionMode := batchv1.IndexedCompletion
+
+	labels := map[string]string{"app": "benchmark-runner"}
+	if k.config.App != "" {
+		labels["target"] = k.config.App
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k.config.Name,
+			Namespace: k.config.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			Parallelism:             &parallelism,
+			Completions:             &parallelism,
+			CompletionMode:          &completionMode,
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:            "benchmark-runner",
+						Image:           k.config.Image,
+						ImagePullPolicy: corev1.PullAlways,
+						Args:            k.config.Args,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse(k.config.CPURequest),
+								corev1.ResourceMemory: resource.MustParse(k.config.MemoryRequest),
+							},
+							Limits: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse(k.config.CPULimit),
+								corev1.ResourceMemory: resource.MustParse(k.config.MemoryLimit),
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// listPods returns pods of the Job
+func (k *kubeRunner) listPods(ctx context.Context, job string) ([]corev1.Pod, error) {
+	pods, err := k.client.CoreV1().Pods(k.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + job,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// streamLogs follows logs of the pod, writing them prefixed with the pod name.
+// Streaming stops when ctx is cancelled
+func (k *kubeRunner) streamLogs(ctx context.Context, pod string, buf *strings.Builder, bufMu *sync.Mutex) error {
+	stream, err := k.client.CoreV1().Pods(k.config.Namespace).
+		GetLogs(pod, &corev1.PodLogOptions{Follow: true}).
+		Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	prefix := pod
+	if k.config.Parallelism == 1 {
+		prefix = ""
+	}
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		bufMu.Lock()
+		buf.WriteString(line)
+		buf.WriteString("\n")
+		bufMu.Unlock()
+
+		k.outMu.Lock()
+		if prefix != "" {
+			fmt.Fprintf(k.out, "[%s] %s\n", prefix, line)
+		} else {
+			fmt.Fprintln(k.out, line)
+		}
+		k.outMu.Unlock()
+	}
+	return scanner.Err()
+}
+
+// cleanup deletes the Job together with its pods
+func (k *kubeRunner) cleanup(job string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	propagation := metav1.DeletePropagationBackground
+	err := k.client.BatchV1().Jobs(k.config.Namespace).Delete(ctx, job, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		log.Printf("Failed to delete Job %s: %v", job, err)
+		return
+	}
+	log.Printf("Deleted Job %s", job)
+}
+
+// jobFinished reports whether all pods of the Job completed or the Job failed
+func jobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) &&
+			condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+	return job.Status.Succeeded+job.Status.Failed >= completions
+}
+
+// podExitCode returns exit code of the runner container (-1 if it didn't terminate)
+func podExitCode(pod corev1.Pod) int32 {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			return status.State.Terminated.ExitCode
+		}
+	}
+	if pod.Status.Phase == corev1.PodSucceeded {
+		return 0
+	}
+	return -1
+}
+
+// parseRunnerJSON extracts JSON results printed by the runner after "JSON Results:"
+func parseRunnerJSON(logs string) (*runnerJSON, error) {
+	i := strings.LastIndex(logs, "JSON Results:")
+	if i < 0 {
+		return nil, fmt.Errorf("no JSON results in logs")
+	}
+
+	var result runnerJSON
+	decoder := json.NewDecoder(strings.NewReader(logs[i+len("JSON Results:"):]))
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid JSON results: %v", err)
+	}
+
+	// Multi-step scenarios report cycles separately, requests stay HTTP requests like the rate
+	if result.Cycles > 0 {
+		result.TotalRequests, result.FailedRequests = result.HTTPRequests, result.FailedHTTP
+		result.SuccessRequests = result.HTTPRequests - result.FailedHTTP
+	}
+	return &result, nil
+}
+
+// latencyWeight returns the number of samples behind the pod's latencies
+// (multi-step scenarios measure whole cycles)
+func (r *runnerJSON) latencyWeight() int64 {
+	if r.Cycles > 0 {
+		return r.Cycles
+	}
+	return r.TotalRequests
+}
+
+// latency returns latency of the pod result by name (avg, p99, ...)
+func (r *runnerJSON) latency(name string) time.Duration {
+	d, _ := time.ParseDuration(r.Latency[name])
+	return d
+}
+
+// kubeSummary is the merged result of all runner pods
+type kubeSummary struct {
+	TotalRequests   int64 // HTTP requests
+	FailedRequests  int64
+	Cycles          int64 // Multi-step scenario cycles, 0 for single-step runs
+	FailedCycles    int64
+	RPS             float64 // Sum of the pods' rates
+	DurationSeconds float64 // Longest pod run
+	AvgLatency      time.Duration
+	P95Latency      time.Duration // Worst pod
+	P99Latency      time.Duration // Worst pod
+	Errors          []kubeError   // Most frequent first
+}
+
+// kubeError is an error group summed across pods
+type kubeError struct {
+	Count     int64
+	Operation string
+	Type      string
+	Message   string
+}
+
+// mergePodResults merges results of the pods that printed them.
+// Percentiles can't be merged from summaries, so P95 and P99 are of the worst pod
+func mergePodResults(results []*PodResult) kubeSummary {
+	var (
+		summary    kubeSummary
+		latencySum float64 // Avg latency weighted by its samples, in seconds
+		samples    int64
+	)
+	type errorKey struct{ operation, errType, message string }
+	errorCounts := make(map[errorKey]int64)
+
+	for _, pod := range results {
+		r := pod.Result
+		if r == nil {
+			continue
+		}
+		summary.TotalRequests += r.TotalRequests
+		summary.FailedRequests += r.FailedRequests
+		summary.Cycles += r.Cycles
+		summary.FailedCycles += r.FailedCycles
+		summary.RPS += r.RPS
+		summary.DurationSeconds = math.Max(summary.DurationSeconds, r.DurationSeconds)
+		latencySum += r.latency("avg").Seconds() * float64(r.latencyWeight())
+		samples += r.latencyWeight()
+		summary.P95Latency = max(summary.P95Latency, r.latency("p95"))
+		summary.P99Latency = max(summary.P99Latency, r.latency("p99"))
+		for _, e := range r.Errors.List {
+			errorCounts[errorKey{e.Operation, e.Type, e.Message}] += e.Count
+		}
+	}
+
+	if samples > 0 {
+		summary.AvgLatency = time.Duration(latencySum / float64(samples) * float64(time.Second))
+	}
+	for key, count := range errorCounts {
+		summary.Errors = append(summary.Errors, kubeError{Count: count, Operation: key.operation, Type: key.errType, Message: key.message})
+	}
+	sort.Slice(summary.Errors, func(i, j int) bool {
+		a, b := summary.Errors[i], summary.Errors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Operation+a.Type+a.Message < b.Operation+b.Type+b.Message
+	})
+	return summary
+}
+
+// printKubeResults prints results of every runner pod and the merged result
+func printKubeResults(results []*PodResult) {
+	fmt.Println("")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("                      KUBERNETES JOB RESULTS")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("┌──────────────────────────────────┬──────┬──────────┬────────┬──────────┬────────────┬────────────┬────────────┐")
+	fmt.Println("│ Pod                              │ Exit │ Requests │ Failed │ RPS      │ Avg        │ P95        │ P99        │")
+	fmt.Println("├──────────────────────────────────┼──────┼──────────┼────────┼──────────┼────────────┼────────────┼────────────┤")
+
+	var podList []map[string]interface{}
+	for _, pod := range results {
+		podJSON := map[string]interface{}{"pod": pod.Pod, "exit_code": pod.ExitCode}
+		podList = append(podList, podJSON)
+
+		r := pod.Result
+		if r == nil {
+			fmt.Printf("│ %-32s │ %4d │ %-67s │\n", truncateString(pod.Pod, 29), pod.ExitCode, truncateString(pod.Err.Error(), 64))
+			podJSON["error"] = pod.Err.Error()
+			continue
+		}
+
+		fmt.Printf("│ %-32s │ %4d │ %8d │ %6d │ %8.1f │ %10s │ %10s │ %10s │\n",
+			truncateString(pod.Pod, 29), pod.ExitCode, r.TotalRequests, r.FailedRequests, r.RPS,
+			r.latency("avg").Round(time.Microsecond), r.latency("p95").Round(time.Microsecond), r.latency("p99").Round(time.Microsecond))
+		podJSON["total_requests"] = r.TotalRequests
+		podJSON["failed_requests"] = r.FailedRequests
+		podJSON["rps"] = r.RPS
+		if r.Cycles > 0 {
+			podJSON["crud_cycles"] = r.Cycles
+			podJSON["failed_cycles"] = r.FailedCycles
+		}
+		podJSON["latency"] = r.Latency
+	}
+
+	summary := mergePodResults(results)
+	fmt.Println("├──────────────────────────────────┼──────┼──────────┼────────┼──────────┼────────────┼────────────┼────────────┤")
+	fmt.Printf("│ %-32s │ %4s │ %8d │ %6d │ %8.1f │ %10s │ %10s │ %10s │\n",
+		"Combined (P95/P99 of worst pod)", "", summary.TotalRequests, summary.FailedRequests, summary.RPS,
+		summary.AvgLatency.Round(time.Microsecond), summary.P95Latency.Round(time.Microsecond), summary.P99Latency.Round(time.Microsecond))
+	fmt.Println("└──────────────────────────────────┴──────┴──────────┴────────┴──────────┴────────────┴────────────┴────────────┘")
+	if summary.Cycles > 0 {
+		fmt.Printf("Requests and RPS count HTTP requests, latencies are per cycle (%d cycles, %d failed)\n", summary.Cycles, summary.FailedCycles)
+	}
+
+	var errorList []map[string]interface{}
+	if len(summary.Errors) > 0 {
+		fmt.Println("")
+		fmt.Println("Errors (all pods):")
+		for _, e := range summary.Errors {
+			fmt.Printf("  %7d  %-30s %-16s %s\n", e.Count, truncateString(e.Operation, 30), e.Type, e.Message)
+			errorList = append(errorList, map[string]interface{}{
+				"count":     e.Count,
+				"operation": e.Operation,
+				"type":      e.Type,
+				"message":   e.Message,
+			})
+		}
+	}
+
+	jsonData := map[string]interface{}{
+		"pods":             podList,
+		"total_requests":   summary.TotalRequests,
+		"failed_requests":  summary.FailedRequests,
+		"rps":              summary.RPS,
+		"duration_seconds": summary.DurationSeconds,
+		"latency": map[string]string{
+			"avg":           summary.AvgLatency.String(),
+			"p95_worst_pod": summary.P95Latency.String(),
+			"p99_worst_pod": summary.P99Latency.String(),
+		},
+		"errors": errorList,
+	}
+	if summary.Cycles > 0 {
+		jsonData["crud_cycles"] = summary.Cycles
+		jsonData["failed_cycles"] = summary.FailedCycles
+	}
+	jsonResult, _ := json.MarshalIndent(jsonData, "", "  ")
+	fmt.Println("")
+	fmt.Println("JSON Results:")
+	fmt.Println(string(jsonResult))
+}
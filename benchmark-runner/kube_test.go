@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// finishedPod is a terminated runner pod of the Job
+func finishedPod(job, name string, exitCode int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "benchmark", Labels: map[string]string{"job-name": job}},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}},
+			}},
+		},
+	}
+}
+
+func TestKubeRunnerRun(t *testing.T) {
+	client := fake.NewClientset(
+		finishedPod("bench", "bench-1", 3),
+		finishedPod("bench", "bench-0", 0),
+		finishedPod("other", "other-0", 1),
+	)
+	// The Job completes as soon as it is created
+	client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		job.Status.Succeeded = 1
+		job.Status.Failed = 1
+		return false, nil, nil
+	})
+
+	var out bytes.Buffer
+	runner := &kubeRunner{
+		client: client,
+		out:    &out,
+		config: KubeConfig{
+			Namespace: "benchmark", Image: "runner:test", Name: "bench", App: "golang", Parallelism: 2,
+			CPURequest: "500m", CPULimit: "1", MemoryRequest: "256Mi", MemoryLimit: "512Mi",
+			Timeout: time.Minute, Args: []string{"-url=http://benchmark-golang:8080"},
+		},
+	}
+	results, err := runner.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 || results[0].Pod != "bench-0" || results[1].Pod != "bench-1" {
+		t.Fatalf("results = %+v, want pods of the Job sorted by name", results)
+	}
+	if results[0].ExitCode != 0 || results[1].ExitCode != 3 {
+		t.Errorf("exit codes = %d, %d, want 0, 3", results[0].ExitCode, results[1].ExitCode)
+	}
+	// The fake clientset streams "fake logs" without results
+	if results[0].Logs != "fake logs\n" || results[0].Result != nil || results[0].Err == nil {
+		t.Errorf("result = %+v", results[0])
+	}
+	if !strings.Contains(out.String(), "[bench-0] fake logs") {
+		t.Errorf("streamed logs = %q, want lines prefixed with the pod", out.String())
+	}
+
+	// The Job is deleted after completion
+	if _, err := client.BatchV1().Jobs("benchmark").Get(context.Background(), "bench", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("Job after the run: %v, want deleted", err)
+	}
+}
+
+func TestKubeRunnerRunKeepsJob(t *testing.T) {
+	client := fake.NewClientset()
+	client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+		return false, nil, nil
+	})
+
+	runner := &kubeRunner{client: client, out: &bytes.Buffer{}, config: KubeConfig{
+		Namespace: "benchmark", Name: "kept", Parallelism: 1, Keep: true, Timeout: time.Minute,
+		CPURequest: "1", CPULimit: "1", MemoryRequest: "1Gi", MemoryLimit: "1Gi",
+	}}
+	results, err := runner.Run(context.Background())
+	if err != nil || len(results) != 0 {
+		t.Fatalf("results = %v, %v, want no pods", results, err)
+	}
+	if _, err := client.BatchV1().Jobs("benchmark").Get(context.Background(), "kept", metav1.GetOptions{}); err != nil {
+		t.Errorf("Job with -keep was deleted: %v", err)
+	}
+}
+
+func TestKubeRunnerRunTimeout(t *testing.T) {
+	runner := &kubeRunner{client: fake.NewClientset(), out: &bytes.Buffer{}, config: KubeConfig{
+		Namespace: "benchmark", Name: "stuck", Parallelism: 1, Timeout: 10 * time.Millisecond,
+		CPURequest: "1", CPULimit: "1", MemoryRequest: "1Gi", MemoryLimit: "1Gi",
+	}}
+	if _, err := runner.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "job stuck didn't finish") {
+		t.Errorf("error = %v, want the Job to time out", err)
+	}
+}
+
+func TestBuildJob(t *testing.T) {
+	runner := &kubeRunner{config: KubeConfig{
+		Namespace: "benchmark", Image: "runner:test", Name: "bench", App: "quarkus", Parallelism: 3,
+		CPURequest: "500m", CPULimit: "2", MemoryRequest: "256Mi", MemoryLimit: "1Gi",
+		Args: []string{"-url=http://benchmark-quarkus:8080", "-rps=100"},
+	}}
+	job := runner.buildJob()
+
+	if job.Name != "bench" || job.Namespace != "benchmark" || job.Labels["target"] != "quarkus" {
+		t.Errorf("metadata = %+v", job.ObjectMeta)
+	}
+	if *job.Spec.Parallelism != 3 || *job.Spec.Completions != 3 || *job.Spec.BackoffLimit != 0 ||
+		*job.Spec.CompletionMode != batchv1.IndexedCompletion {
+		t.Errorf("spec = %+v", job.Spec)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if container.Image != "runner:test" || !slices.Equal(container.Args, runner.config.Args) {
+		t.Errorf("container = %+v", container)
+	}
+	resources := container.Resources
+	if !resources.Requests.Cpu().Equal(resource.MustParse("500m")) || !resources.Limits.Memory().Equal(resource.MustParse("1Gi")) {
+		t.Errorf("resources = %+v", resources)
+	}
+}
+
+func TestJobFinished(t *testing.T) {
+	two := int32(2)
+	condition := func(kind batchv1.JobConditionType, status corev1.ConditionStatus) []batchv1.JobCondition {
+		return []batchv1.JobCondition{{Type: kind, Status: status}}
+	}
+
+	tests := []struct {
+		name string
+		job  batchv1.Job
+		want bool
+	}{
+		{name: "running", job: batchv1.Job{Spec: batchv1.JobSpec{Completions: &two}}, want: false},
+		{name: "complete", job: batchv1.Job{Status: batchv1.JobStatus{Conditions: condition(batchv1.JobComplete, corev1.ConditionTrue)}}, want: true},
+		{name: "failed", job: batchv1.Job{Status: batchv1.JobStatus{Conditions: condition(batchv1.JobFailed, corev1.ConditionTrue)}}, want: true},
+		{name: "condition not true", job: batchv1.Job{Spec: batchv1.JobSpec{Completions: &two}, Status: batchv1.JobStatus{Conditions: condition(batchv1.JobComplete, corev1.ConditionFalse)}}, want: false},
+		{name: "suspended", job: batchv1.Job{Spec: batchv1.JobSpec{Completions: &two}, Status: batchv1.JobStatus{Conditions: condition(batchv1.JobSuspended, corev1.ConditionTrue)}}, want: false},
+		{name: "some pods done", job: batchv1.Job{Spec: batchv1.JobSpec{Completions: &two}, Status: batchv1.JobStatus{Succeeded: 1}}, want: false},
+		{name: "all pods done", job: batchv1.Job{Spec: batchv1.JobSpec{Completions: &two}, Status: batchv1.JobStatus{Succeeded: 1, Failed: 1}}, want: true},
+		{name: "default completions", job: batchv1.Job{Status: batchv1.JobStatus{Failed: 1}}, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := jobFinished(&tt.job); got != tt.want {
+			t.Errorf("%s: jobFinished = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPodExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  corev1.Pod
+		want int32
+	}{
+		{name: "terminated", pod: *finishedPod("job", "pod", 3), want: 3},
+		{name: "succeeded without status", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}}, want: 0},
+		{name: "running", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}, want: -1},
+	}
+	for _, tt := range tests {
+		if got := podExitCode(tt.pod); got != tt.want {
+			t.Errorf("%s: podExitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseRunnerJSON(t *testing.T) {
+	// 100 cycles of a two-step scenario, 3 failed cycles with one failed step each
+	cycles, err := json.Marshal(resultJSON(&Result{
+		TotalRequests:   100,
+		SuccessRequests: 97,
+		FailedRequests:  3,
+		TotalDuration:   10 * time.Second,
+		StepsPerCycle:   2,
+		Steps:           []*StepStats{{Name: "create", Requests: 100, Failed: 3}, {Name: "delete", Requests: 100}},
+		Errors:          NewErrorStats(),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		logs     string
+		requests int64
+		failed   int64
+		err      string
+	}{
+		{
+			name:     "single step",
+			logs:     "Starting benchmark\n\nJSON Results:\n{\"total_requests\": 100, \"failed_requests\": 2, \"rps\": 10, \"latency\": {\"avg\": \"1.5ms\"}}\n",
+			requests: 100, failed: 2,
+		},
+		{
+			name:     "cycles",
+			logs:     "JSON Results:\n" + string(cycles),
+			requests: 200, failed: 3,
+		},
+		{
+			name:     "last results",
+			logs:     "JSON Results:\n{\"total_requests\": 1}\nJSON Results:\n{\"total_requests\": 2}\nshutting down\n",
+			requests: 2,
+		},
+		{name: "no results", logs: "connection refused\n", err: "no JSON results in logs"},
+		{name: "truncated", logs: "JSON Results:\n{\"total_requests\": ", err: "invalid JSON results"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseRunnerJSON(tt.logs)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.TotalRequests != tt.requests || result.FailedRequests != tt.failed {
+				t.Errorf("requests = %d, failed %d, want %d, %d", result.TotalRequests, result.FailedRequests, tt.requests, tt.failed)
+			}
+		})
+	}
+}
+
+func TestMergePodResults(t *testing.T) {
+	parse := func(logs string) *runnerJSON {
+		result, err := parseRunnerJSON("JSON Results:\n" + logs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	results := []*PodResult{
+		{Pod: "bench-0", Result: parse(`{"total_requests": 300, "failed_requests": 3, "rps": 30, "duration_seconds": 10,
+			"latency": {"avg": "1ms", "p95": "5ms", "p99": "20ms"},
+			"errors": {"total": 3, "list": [{"count": 3, "operation": "GET /api/products", "type": "http_error", "message": "status 500"}]}}`)},
+		{Pod: "bench-1", Result: parse(`{"total_requests": 100, "failed_requests": 5, "rps": 10.5, "duration_seconds": 12,
+			"latency": {"avg": "5ms", "p95": "8ms", "p99": "10ms"},
+			"errors": {"total": 5, "list": [
+				{"count": 1, "operation": "GET /api/products", "type": "http_error", "message": "status 500"},
+				{"count": 4, "operation": "GET /api/products", "type": "network_error", "message": "connection reset"}]}}`)},
+		{Pod: "bench-2", ExitCode: 1, Err: errors.New("crashed")},
+	}
+
+	summary := mergePodResults(results)
+	if summary.TotalRequests != 400 || summary.FailedRequests != 8 || summary.RPS != 40.5 || summary.DurationSeconds != 12 {
+		t.Errorf("summary = %+v", summary)
+	}
+	// Average weighted by requests: (300*1ms + 100*5ms) / 400
+	if summary.AvgLatency != 2*time.Millisecond || summary.P95Latency != 8*time.Millisecond || summary.P99Latency != 20*time.Millisecond {
+		t.Errorf("latency = avg %s, p95 %s, p99 %s, want 2ms, 8ms, 20ms", summary.AvgLatency, summary.P95Latency, summary.P99Latency)
+	}
+	want := []kubeError{
+		{Count: 4, Operation: "GET /api/products", Type: "http_error", Message: "status 500"},
+		{Count: 4, Operation: "GET /api/products", Type: "network_error", Message: "connection reset"},
+	}
+	if !slices.Equal(summary.Errors, want) {
+		t.Errorf("errors = %+v, want %+v", summary.Errors, want)
+	}
+
+	if empty := mergePodResults(results[2:]); empty.TotalRequests != 0 || empty.AvgLatency != 0 || empty.Errors != nil {
+		t.Errorf("summary without results = %+v", empty)
+	}
+}
+
+func TestMergePodResultsCycles(t *testing.T) {
+	pod := func(cycles int64, avg time.Duration) *PodResult {
+		logs, err := json.Marshal(resultJSON(&Result{
+			TotalRequests:   cycles,
+			SuccessRequests: cycles - 1,
+			FailedRequests:  1,
+			TotalDuration:   10 * time.Second,
+			AvgLatency:      avg,
+			StepsPerCycle:   4,
+			Steps:           []*StepStats{{Requests: cycles, Failed: 1}, {Requests: cycles}, {Requests: cycles}, {Requests: cycles}},
+			Errors:          NewErrorStats(),
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := parseRunnerJSON("JSON Results:\n" + string(logs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &PodResult{Result: result}
+	}
+
+	summary := mergePodResults([]*PodResult{pod(300, 4*time.Millisecond), pod(100, 8*time.Millisecond)})
+	// Requests and rate count HTTP requests, cycles are kept apart
+	if summary.TotalRequests != 1600 || summary.FailedRequests != 2 || summary.RPS != 160 {
+		t.Errorf("requests = %d, failed %d, rps %v, want 1600, 2, 160", summary.TotalRequests, summary.FailedRequests, summary.RPS)
+	}
+	if summary.Cycles != 400 || summary.FailedCycles != 2 {
+		t.Errorf("cycles = %d, failed %d, want 400, 2", summary.Cycles, summary.FailedCycles)
+	}
+	// Cycle latency weighted by cycles: (300*4ms + 100*8ms) / 400
+	if summary.AvgLatency != 5*time.Millisecond {
+		t.Errorf("avg latency = %s, want 5ms", summary.AvgLatency)
+	}
+}
+
+func TestHasFlag(t *testing.T) {
+	args := []string{"-url=http://a", "--rps", "100", "-verbose"}
+	for name, want := range map[string]bool{"url": true, "rps": true, "verbose": true, "ur": false, "duration": false} {
+		if got := hasFlag(args, name); got != want {
+			t.Errorf("hasFlag(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRunKubeCommandRejectsInvalidQuantity(t *testing.T) {
+	for _, flag := range []string{"-cpu-request=1 core", "-cpu-limit=two", "-memory-request=512MB!", "-memory-limit="} {
+		if code := runKubeCommand([]string{flag, "--", "-url=http://a"}); code != 2 {
+			t.Errorf("%s: exit code %d, want 2", flag, code)
+		}
+	}
+}
+
+func TestRunKubeCommandRejectsInvalidParallelism(t *testing.T) {
+	for _, flag := range []string{"-parallelism=0", "-parallelism=-2"} {
+		if code := runKubeCommand([]string{flag, "--", "-url=http://a"}); code != 2 {
+			t.Errorf("%s: exit code %d, want 2", flag, code)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// liveWindow is the sliding window of live latency percentiles, in seconds
+	liveWindow = 10
+
+	// sparklineWidth is the number of seconds of RPS history in the dashboard
+	sparklineWidth = 60
+
+	// liveLogInterval is how often progress is logged when stdout is not a terminal
+	liveLogInterval = 10 * time.Second
+
+	// liveTopErrors is the number of most frequent errors shown in the dashboard
+	liveTopErrors = 3
+)
+
+// TimeSeriesPoint is statistics of one second of the run
+type TimeSeriesPoint struct {
+	Second     int   // Offset from the start of the run
+	Requests   int64 // HTTP requests completed during the second
+	Errors     int64
+	InFlight   int64 // HTTP requests in flight at the end of the second
+	AvgLatency time.Duration
+	P50Latency time.Duration
+	P95Latency time.Duration
+	P99Latency time.Duration
+	MaxLatency time.Duration
+	Fault      string // Faults injected by the proxy during the second, e.g. latency=200ms
+}
+
+// LiveStats aggregates HTTP requests per second while the benchmark runs.
+// It builds the time-series of the run and feeds the live dashboard
+type LiveStats struct {
+	config   Config
+	errors   *ErrorStats
+	inFlight int64
+
+	mu            sync.Mutex
+	start         time.Time
+	current       []time.Duration   // Latencies of the current second
+	currentErrors int64             // Errors of the current second
+	window        [][]time.Duration // Latencies of the last liveWindow seconds
+	series        []TimeSeriesPoint
+	totalRequests int64
+	totalErrors   int64
+
+	display *liveDisplay // nil unless -live
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newLiveStats creates live statistics of a run. The dashboard is shown only with -live
+func newLiveStats(config Config, errors *ErrorStats) *LiveStats {
+	ls := &LiveStats{
+		config: config,
+		errors: errors,
+		done:   make(chan struct{}),
+	}
+	if config.Live {
+		ls.display = newLiveDisplay()
+	}
+	return ls
+}
+
+// Start starts per-second aggregation
+func (ls *LiveStats) Start() {
+	ls.mu.Lock()
+	ls.start = time.Now()
+	ls.mu.Unlock()
+
+	ls.wg.Add(1)
+	go func() {
+		defer ls.wg.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ls.done:
+				return
+			case <-ticker.C:
+				snapshot := ls.roll()
+				if ls.display != nil {
+					ls.display.render(snapshot)
+				}
+				if ls.config.Progress != nil {
+					ls.config.Progress(ProgressUpdate{
+						Elapsed:       snapshot.elapsed,
+						Last:          snapshot.last,
+						TotalRequests: snapshot.totalRequests,
+						TotalErrors:   snapshot.totalErrors,
+					})
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops aggregation and returns the time-series of complete seconds
+func (ls *LiveStats) Stop() []TimeSeriesPoint {
+	close(ls.done)
+	ls.wg.Wait()
+
+	if ls.display != nil {
+		ls.display.finish()
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.series
+}
+
+// Begin marks start of an HTTP request
+func (ls *LiveStats) Begin() {
+	atomic.AddInt64(&ls.inFlight, 1)
+}
+
+// End records a completed HTTP request
+func (ls *LiveStats) End(latency time.Duration, failed bool) {
+	atomic.AddInt64(&ls.inFlight, -1)
+
+	ls.mu.Lock()
+	ls.current = append(ls.current, latency)
+	if failed {
+		ls.currentErrors++
+	}
+	ls.mu.Unlock()
+}
+
+// ProgressUpdate is the state of the run passed to Config.Progress every second
+type ProgressUpdate struct {
+	Elapsed       time.Duration
+	Last          TimeSeriesPoint // The last complete second
+	TotalRequests int64
+	TotalErrors   int64
+}
+
+// liveSnapshot is the state of the run passed to the dashboard every second
+type liveSnapshot struct {
+	config        Config
+	elapsed       time.Duration
+	last          TimeSeriesPoint
+	history       []TimeSeriesPoint
+	totalRequests int64
+	totalErrors   int64
+	p50, p95, p99 time.Duration // Sliding window
+	max           time.Duration
+	topErrors     []UniqueError
+}
+
+// roll closes the current second: appends time-series point and updates the sliding window
+func (ls *LiveStats) roll() liveSnapshot {
+	ls.mu.Lock()
+	latencies, errors := ls.current, ls.currentErrors
+	ls.current, ls.currentErrors = nil, 0
+	elapsed := time.Since(ls.start)
+	ls.mu.Unlock()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	point := TimeSeriesPoint{
+		Requests: int64(len(latencies)),
+		Errors:   errors,
+		InFlight: atomic.LoadInt64(&ls.inFlight),
+	}
+	if len(latencies) > 0 {
+		var sum time.Duration
+		for _, l := range latencies {
+			sum += l
+		}
+		point.AvgLatency = sum / time.Duration(len(latencies))
+		point.P50Latency = percentile(latencies, 0.50)
+		point.P95Latency = percentile(latencies, 0.95)
+		point.P99Latency = percentile(latencies, 0.99)
+		point.MaxLatency = latencies[len(latencies)-1]
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	point.Second = len(ls.series)
+	ls.series = append(ls.series, point)
+	ls.totalRequests += point.Requests
+	ls.totalErrors += point.Errors
+
+	snapshot := liveSnapshot{
+		config:        ls.config,
+		elapsed:       elapsed,
+		last:          point,
+		totalRequests: ls.totalRequests,
+		totalErrors:   ls.totalErrors,
+	}
+	if ls.display == nil {
+		return snapshot
+	}
+
+	// Sliding window percentiles and the rest are only needed by the dashboard
+	ls.window = append(ls.window, latencies)
+	if len(ls.window) > liveWindow {
+		ls.window = ls.window[len(ls.window)-liveWindow:]
+	}
+	var window []time.Duration
+	for _, second := range ls.window {
+		window = append(window, second...)
+	}
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+	if len(window) > 0 {
+		snapshot.p50 = percentile(window, 0.50)
+		snapshot.p95 = percentile(window, 0.95)
+		snapshot.p99 = percentile(window, 0.99)
+		snapshot.max = window[len(window)-1]
+	}
+
+	history := ls.series
+	if len(history) > sparklineWidth {
+		history = history[len(history)-sparklineWidth:]
+	}
+	snapshot.history = append([]TimeSeriesPoint(nil), history...)
+	snapshot.topErrors = ls.errors.TopErrors(liveTopErrors)
+
+	return snapshot
+}
+
+// liveDisplay renders the dashboard in a terminal or logs progress lines otherwise
+type liveDisplay struct {
+	tty     bool
+	lines   int // Lines of the previous frame to overwrite
+	lastLog time.Time
+}
+
+func newLiveDisplay() *liveDisplay {
+	return &liveDisplay{tty: isTerminal(os.Stdout), lastLog: time.Now()}
+}
+
+// isTerminal reports whether file is a character device (terminal)
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// render draws the dashboard frame, or logs a progress line every liveLogInterval
+func (d *liveDisplay) render(s liveSnapshot) {
+	if !d.tty {
+		if time.Since(d.lastLog) < liveLogInterval {
+			return
+		}
+		d.lastLog = time.Now()
+		log.Printf("[%s/%s] %.0f req/s, in-flight %d, p50 %s, p95 %s, p99 %s (last %ds), errors %d (%.2f%%)",
+			s.elapsed.Round(time.Second), s.config.Duration, float64(s.last.Requests), s.last.InFlight,
+			s.p50.Round(time.Microsecond), s.p95.Round(time.Microsecond), s.p99.Round(time.Microsecond), liveWindow,
+			s.totalErrors, errorPercent(s.totalErrors, s.totalRequests))
+		return
+	}
+
+	var frame []string
+	progress := float64(s.elapsed) / float64(s.config.Duration)
+	frame = append(frame,
+		fmt.Sprintf(" %s -> %s", s.config.BenchmarkType, s.config.URL),
+		fmt.Sprintf(" %s %s / %s", progressBar(progress, 40), s.elapsed.Round(time.Second), s.config.Duration),
+		"",
+	)
+
+	target := fmt.Sprintf("target %d", s.config.RPS)
+	if s.config.BenchmarkType == UserSession {
+		target = fmt.Sprintf("%d virtual users", s.config.VirtualUsers)
+	}
+	frame = append(frame,
+		fmt.Sprintf(" RPS:        %8.0f req/s (%s)", float64(s.last.Requests), target),
+		fmt.Sprintf(" In-flight:  %8d", s.last.InFlight),
+		fmt.Sprintf(" Requests:   %8d, errors %d (%.2f%%)", s.totalRequests, s.totalErrors, errorPercent(s.totalErrors, s.totalRequests)),
+		fmt.Sprintf(" Latency:    p50 %s, p95 %s, p99 %s, max %s (last %ds)",
+			s.p50.Round(time.Microsecond), s.p95.Round(time.Microsecond), s.p99.Round(time.Microsecond), s.max.Round(time.Microsecond), liveWindow),
+		fmt.Sprintf(" RPS [%ds]:  %s", sparklineWidth, sparkline(s.history)),
+		"",
+	)
+
+	if len(s.topErrors) == 0 {
+		frame = append(frame, " Top errors: none")
+	} else {
+		frame = append(frame, " Top errors:")
+		for _, err := range s.topErrors {
+			frame = append(frame, fmt.Sprintf("   %6d  %-28s %s",
+				err.Count, truncateString(err.Operation, 28), truncateString(err.ErrorMessage, 60)))
+		}
+	}
+
+	var b strings.Builder
+	if d.lines > 0 {
+		fmt.Fprintf(&b, "\033[%dA", d.lines) // Move cursor to the start of the previous frame
+	}
+	for _, line := range frame {
+		b.WriteString("\033[2K") // Clear line
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\033[J") // Clear leftovers of a longer previous frame
+	fmt.Print(b.String())
+	d.lines = len(frame)
+}
+
+// finish leaves the last frame on the screen
+func (d *liveDisplay) finish() {
+	if d.tty && d.lines > 0 {
+		fmt.Println("")
+	}
+}
+
+// errorPercent returns errors as a percentage of requests
+func errorPercent(errors, requests int64) float64 {
+	if requests == 0 {
+		return 0
+	}
+	return float64(errors) / float64(requests) * 100
+}
+
+// progressBar renders progress (0..1) as a bar of the given width
+func progressBar(progress float64, width int) string {
+	if progress > 1 {
+		progress = 1
+	}
+	filled := int(progress * float64(width))
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", width-filled) + "]"
+}
+
+// sparkline renders requests per second of the points as a line of block characters
+func sparkline(points []TimeSeriesPoint) string {
+	values := make([]int64, len(points))
+	for i, p := range points {
+		values[i] = p.Requests
+	}
+	return sparklineValues(values)
+}
+
+// sparklineValues renders values as a line of block characters scaled to the maximum
+func sparklineValues(values []int64) string {
+	levels := []rune("▁▂▃▄▅▆▇█")
+
+	var max int64
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if max > 0 {
+			level = int(float64(v) / float64(max) * float64(len(levels)-1))
+		}
+		if v > 0 && level == 0 && max > int64(len(levels)) {
+			level = 1 // Keep rare non-zero values visible
+		}
+		b.WriteRune(levels[level])
+	}
+	return b.String()
+}
+
+// downsample sums values into at most width buckets. Returns buckets and values per bucket
+func downsample(values []int64, width int) ([]int64, int) {
+	per := (len(values) + width - 1) / width
+	if per < 1 {
+		per = 1
+	}
+	buckets := make([]int64, (len(values)+per-1)/per)
+	for i, v := range values {
+		buckets[i/per] += v
+	}
+	return buckets, per
+}
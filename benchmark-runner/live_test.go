@@ -0,0 +1,164 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSparklineValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int64
+		want   string
+	}{
+		{name: "empty", values: nil, want: ""},
+		{name: "zeros", values: []int64{0, 0, 0}, want: "▁▁▁"},
+		{name: "scale", values: []int64{0, 1, 2, 3, 4, 5, 6, 7}, want: "▁▂▃▄▅▆▇█"},
+		{name: "flat", values: []int64{5, 5}, want: "██"},
+		{name: "rare non-zero visible", values: []int64{1, 0, 100}, want: "▂▁█"},
+		{name: "small max", values: []int64{1, 0, 8}, want: "▁▁█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sparklineValues(tt.values); got != tt.want {
+				t.Errorf("sparklineValues(%v) = %s, want %s", tt.values, got, tt.want)
+			}
+		})
+	}
+
+	points := []TimeSeriesPoint{{Requests: 0}, {Requests: 7}}
+	if got := sparkline(points); got != "▁█" {
+		t.Errorf("sparkline = %s, want ▁█", got)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	tests := []struct {
+		values  []int64
+		width   int
+		buckets []int64
+		per     int
+	}{
+		{values: []int64{1, 2, 3}, width: 5, buckets: []int64{1, 2, 3}, per: 1},
+		{values: []int64{1, 2, 3, 4}, width: 2, buckets: []int64{3, 7}, per: 2},
+		{values: []int64{1, 2, 3, 4, 5}, width: 2, buckets: []int64{6, 9}, per: 3},
+		{values: []int64{1, 1, 1, 1, 1, 1, 1}, width: 3, buckets: []int64{3, 3, 1}, per: 3},
+		{values: nil, width: 3, buckets: []int64{}, per: 1},
+	}
+
+	for _, tt := range tests {
+		buckets, per := downsample(tt.values, tt.width)
+		if !slices.Equal(buckets, tt.buckets) || per != tt.per {
+			t.Errorf("downsample(%v, %d) = %v, %d, want %v, %d", tt.values, tt.width, buckets, per, tt.buckets, tt.per)
+		}
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	tests := map[float64]string{
+		0:   "[░░░░]",
+		0.5: "[██░░]",
+		1:   "[████]",
+		1.7: "[████]",
+	}
+	for progress, want := range tests {
+		if got := progressBar(progress, 4); got != want {
+			t.Errorf("progressBar(%v) = %s, want %s", progress, got, want)
+		}
+	}
+}
+
+func TestErrorPercent(t *testing.T) {
+	if got := errorPercent(5, 200); got != 2.5 {
+		t.Errorf("errorPercent = %v, want 2.5", got)
+	}
+	if got := errorPercent(5, 0); got != 0 {
+		t.Errorf("errorPercent without requests = %v, want 0", got)
+	}
+}
+
+func TestLiveStatsRoll(t *testing.T) {
+	ls := newLiveStats(Config{}, NewErrorStats())
+	ls.start = time.Now()
+
+	ls.Begin()
+	ls.Begin()
+	ls.Begin()
+	for i := 1; i <= 100; i++ {
+		ls.Begin()
+		ls.End(time.Duration(i)*time.Millisecond, i%10 == 0)
+	}
+	first := ls.roll()
+
+	want := TimeSeriesPoint{
+		Second:     0,
+		Requests:   100,
+		Errors:     10,
+		InFlight:   3,
+		AvgLatency: 50500 * time.Microsecond,
+		P50Latency: percentile(sortedMillis(100), 0.50),
+		P95Latency: percentile(sortedMillis(100), 0.95),
+		P99Latency: percentile(sortedMillis(100), 0.99),
+		MaxLatency: 100 * time.Millisecond,
+	}
+	if first.last != want {
+		t.Errorf("point = %+v, want %+v", first.last, want)
+	}
+
+	// An idle second still has a point
+	second := ls.roll()
+	if second.last.Second != 1 || second.last.Requests != 0 || second.last.AvgLatency != 0 {
+		t.Errorf("idle point = %+v", second.last)
+	}
+	if second.totalRequests != 100 || second.totalErrors != 10 {
+		t.Errorf("totals = %d, %d, want 100, 10", second.totalRequests, second.totalErrors)
+	}
+	// Without the dashboard the sliding window is not computed
+	if second.p99 != 0 || second.history != nil {
+		t.Errorf("dashboard fields computed without -live: %+v", second)
+	}
+
+	series := ls.Stop()
+	if len(series) != 2 {
+		t.Errorf("series = %d points, want 2", len(series))
+	}
+}
+
+func TestLiveStatsDashboardWindow(t *testing.T) {
+	ls := newLiveStats(Config{}, NewErrorStats())
+	ls.display = &liveDisplay{}
+	ls.start = time.Now()
+
+	// The slow second falls out of the sliding window after liveWindow seconds
+	ls.End(time.Second, false)
+	ls.roll()
+	var snapshot liveSnapshot
+	for i := 0; i < liveWindow; i++ {
+		ls.End(time.Millisecond, false)
+		snapshot = ls.roll()
+	}
+	if snapshot.max != time.Millisecond || snapshot.p99 != time.Millisecond {
+		t.Errorf("window max = %s, p99 = %s, want 1ms", snapshot.max, snapshot.p99)
+	}
+	if len(snapshot.history) != liveWindow+1 {
+		t.Errorf("history = %d points, want %d", len(snapshot.history), liveWindow+1)
+	}
+
+	for i := 0; i < sparklineWidth; i++ {
+		snapshot = ls.roll()
+	}
+	if len(snapshot.history) != sparklineWidth || snapshot.history[sparklineWidth-1].Second != liveWindow+sparklineWidth {
+		t.Errorf("history = %d points ending at %d, want the last %d", len(snapshot.history), snapshot.history[len(snapshot.history)-1].Second, sparklineWidth)
+	}
+}
+
+// sortedMillis returns latencies of 1..n milliseconds
+func sortedMillis(n int) []time.Duration {
+	latencies := make([]time.Duration, n)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+	return latencies
+}
@@ -1,22 +1,114 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
+// stringList is a flag that can be repeated
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// commands are subcommands, e.g. "benchmark-runner kube ...". They return the exit code
+var commands = map[string]func(args []string) int{
+	"analyze":   runAnalyzeCommand,
+	"calibrate": runCalibrateCommand,
+	"kube":      runKubeCommand,
+	"serve":     runServeCommand,
+	"suite":     runSuiteCommand,
+}
+
 func main() {
-	var config Config
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	var (
+		config   Config
+		repeat   RepeatConfig
+		headers  stringList
+		captures stringList
+		asserts  stringList
+		faults   stringList
+	)
 
-	flag.StringVar(&config.URL, "url", "", "Target URL (required)")
+	flag.StringVar(&config.URL, "url", "", "Target URL (required). For -type=http a URL template, e.g. http://host/users/{{randInt 1 100}}")
 	flag.IntVar(&config.RPS, "rps", 100, "Requests per second")
 	durationStr := flag.String("duration", "30s", "Benchmark duration (e.g., 30s, 1m, 5m)")
-	benchType := flag.String("type", string(GetProducts), "Benchmark type: get-products, create-product, get-product-by-id, update-product, delete-product, mixed-operations")
-	flag.IntVar(&config.Concurrency, "concurrency", 10, "Number of concurrent workers")
+	benchType := flag.String("type", string(GetProducts), "Benchmark type: "+strings.Join(append(ScenarioNames(), string(UserSession), string(HTTPTemplate), string(JourneyType), string(ScriptType)), ", "))
+	flag.IntVar(&config.Concurrency, "concurrency", 10, "Initial number of concurrent workers")
+	flag.IntVar(&config.MaxConcurrency, "max-concurrency", 1000, "Maximum number of workers the pool may grow to when the queue backs up")
 	flag.BoolVar(&config.Verbose, "verbose", false, "Enable verbose error logging (show response bodies)")
+	flag.BoolVar(&config.Cleanup, "cleanup", true, "Delete resources created during the run (e.g. by create-product) after it, or on interrupt")
+	flag.IntVar(&config.Slowest, "slowest", 10, "Number of the slowest requests listed in the report")
+	warmUpFlags(flag.CommandLine, &config.WarmUp)
+	flag.StringVar(&config.EventLog, "events", "", "Write every request to an event log file for the analyze command: NDJSON for .ndjson/.jsonl, compact binary otherwise")
+	flag.BoolVar(&config.Live, "live", false, "Show live dashboard refreshed every second (progress log lines if stdout is not a terminal)")
+	flag.IntVar(&config.VirtualUsers, "vus", 10, "Number of virtual users (user-session only)")
+	thinkTimeStr := flag.String("think", "1s", "Think time between session steps: 1s, uniform:500ms-2s, exp:1s, 0 (user-session only)")
+	flag.IntVar(&config.SessionViews, "session-views", 3, "Number of product views per session (user-session only)")
+	flag.StringVar(&config.Request.Method, "method", "GET", "HTTP method (http only)")
+	flag.Var(&headers, "header", "Request header template 'Name: value', can be repeated (http only)")
+	flag.StringVar(&config.Request.Body, "body", "", "Request body template (http only)")
+	flag.Var(&captures, "capture", "Capture response value into variable: name=$.json.path or name=header:Name, can be repeated (http only)")
+	flag.BoolVar(&config.Tracing.Enabled, "trace", false, "Inject W3C traceparent header and record client spans")
+	flag.StringVar(&config.Tracing.OTLPEndpoint, "otlp-endpoint", "", "OTLP/HTTP collector URL to export spans to, e.g. http://otel-collector:4318 (implies -trace)")
+	flag.Float64Var(&config.Tracing.SampleRatio, "trace-sample", 1.0, "Share of traces marked as sampled and exported (0..1)")
+	flag.StringVar(&config.MetricsURL, "metrics-url", "", "Target Prometheus metrics URL to scrape during the run, or 'auto' to detect /metrics or /q/metrics on the target host")
+	flag.DurationVar(&config.ScrapeInterval, "scrape-interval", 5*time.Second, "Interval of target metrics scraping")
+	flag.StringVar(&config.Prometheus.URL, "prometheus-url", "", "Prometheus server URL to query target containers' CPU and memory over the run, e.g. http://prometheus:9090")
+	flag.StringVar(&config.Prometheus.Namespace, "prometheus-namespace", "", "Namespace of the target pods for Prometheus queries")
+	flag.StringVar(&config.Prometheus.Pods, "prometheus-pods", "", "Regular expression of the target pod names, e.g. 'benchmark-golang-.*'")
+	flag.DurationVar(&config.Prometheus.Lag, "prometheus-lag", 15*time.Second, "Wait after the run before querying Prometheus, so the last samples are scraped")
+	proxySpec := flag.String("proxy", "", "Start a fault-injection TCP proxy for the run: LISTEN=UPSTREAM, e.g. :15432=postgres:5432")
+	flag.Var(&faults, "fault", "Fault injected by -proxy: START-END:KIND[=VALUE], e.g. 30s-1m:latency=200ms, 1m-1m10s:reset, 2m-2m30s:blackhole, 3m-4m:bandwidth=64KB; can be repeated")
+	flag.IntVar(&repeat.Runs, "repeat", 1, "Number of runs per target; with more than one run prints mean, median, stddev and confidence intervals")
+	flag.StringVar(&repeat.CompareURL, "compare-url", "", "Second target URL to run the same configuration against and compare with -url")
+	flag.StringVar(&repeat.ComparePods, "compare-prometheus-pods", "", "Regular expression of the second target's pod names for Prometheus queries")
+	flag.BoolVar(&repeat.Interleave, "interleave", false, "Alternate targets run by run (A B A B) instead of running them one after another")
+	flag.DurationVar(&repeat.Cooldown, "cooldown", 10*time.Second, "Pause between repeated runs")
+	flag.StringVar(&repeat.Baseline, "baseline", "", "Baseline file saved with -save-baseline to compare the runs against")
+	flag.StringVar(&repeat.SaveBaseline, "save-baseline", "", "Save metrics of the runs to a baseline file")
+	flag.Var(&asserts, "assert", "SLO assertion, e.g. 'p99<50ms', 'error_rate<0.1%', 'rps>=0.98*target'; can be repeated, exit code 3 if any fails")
+	journeyPath := flag.String("journey", "", "Journey definition JSON file, implies -type=journey")
+	scriptPath := flag.String("script", "", "Starlark script building requests and checking responses, implies -type=script")
+	scenariosPath := flag.String("scenarios", "", "Scenarios JSON file: concurrent scenarios with their own rate, profile and worker pool")
+	openAPIPath := flag.String("openapi", "", "OpenAPI 3 JSON document (file or URL): run a weighted mix of its operations as concurrent scenarios")
+	openAPIDump := flag.String("openapi-dump", "", "Write the workload derived from -openapi as a scenarios file ('-' for stdout) and exit, to edit and run with -scenarios")
+	profile := flag.String("profile", "constant", "Request rate profile: constant, ramp:30s (from zero to -rps), burst:10s/1m (-rps for 10s every minute)")
+	expectStatus := flag.String("expect-status", "", "Comma separated successful status codes, default: anything but 5xx (http only)")
 	flag.Parse()
 
+	if *openAPIDump != "" {
+		if *openAPIPath == "" {
+			log.Fatal("-openapi-dump requires -openapi")
+		}
+		defs, err := loadWorkload(*openAPIPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := dumpWorkload(*openAPIDump, defs); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	if config.URL == "" {
 		log.Fatal("URL is required. Use -url flag")
 	}
@@ -26,16 +118,263 @@ func main() {
 		log.Fatalf("Invalid duration: %v", err)
 	}
 	config.Duration = duration
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{"rps", config.RPS},
+		{"concurrency", config.Concurrency},
+		{"max-concurrency", config.MaxConcurrency},
+	}
+	for _, f := range positive {
+		if f.value <= 0 {
+			log.Fatalf("-%s must be positive, got %d", f.name, f.value)
+		}
+	}
+	if config.SessionViews < 0 {
+		log.Fatalf("-session-views must not be negative, got %d", config.SessionViews)
+	}
+	if err := config.WarmUp.validate(); err != nil {
+		log.Fatal(err)
+	}
 	config.BenchmarkType = BenchmarkType(*benchType)
+	if config.Tracing.OTLPEndpoint != "" {
+		config.Tracing.Enabled = true
+	}
+	if *journeyPath != "" {
+		journey, err := loadJourney(*journeyPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Journey = journey
+		config.BenchmarkType = JourneyType
+	}
+	if *scriptPath != "" {
+		script, err := loadScript(*scriptPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Script = script
+		config.BenchmarkType = ScriptType
+	}
+
+	config.Request.Headers = make(map[string]string, len(headers))
+	for _, header := range headers {
+		name, value, err := parseHeader(header)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Request.Headers[name] = value
+	}
+	for _, capture := range captures {
+		rule, err := parseCaptureRule(capture)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Request.Captures = append(config.Request.Captures, rule)
+	}
+	if config.Request.ExpectStatus, err = parseStatusList(*expectStatus); err != nil {
+		log.Fatal(err)
+	}
+
+	if *proxySpec != "" {
+		if config.Proxy, err = parseProxy(*proxySpec); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, spec := range faults {
+		fault, err := parseFault(spec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Proxy.Faults = append(config.Proxy.Faults, fault)
+	}
+	if len(config.Proxy.Faults) > 0 && !config.Proxy.Enabled() {
+		log.Fatal("-fault requires -proxy")
+	}
+
+	if config.Profile, err = parseRateProfile(*profile); err != nil {
+		log.Fatal(err)
+	}
+
+	if *scenariosPath != "" && *openAPIPath != "" {
+		log.Fatal("-scenarios and -openapi can't be used together")
+	}
+	if *scenariosPath != "" {
+		if config.Scenarios, err = loadScenarios(*scenariosPath, config); err != nil {
+			log.Fatal(err)
+		}
+		config.BenchmarkType = ScenariosType
+	} else if *openAPIPath != "" {
+		defs, err := loadWorkload(*openAPIPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if config.Scenarios, err = buildScenarios(defs, *openAPIPath, ".", config); err != nil {
+			log.Fatal(err)
+		}
+		config.BenchmarkType = ScenariosType
+	} else if config.BenchmarkType != UserSession {
+		if _, err := resolveOperations(config); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	thinkTime, err := parseThinkTime(*thinkTimeStr)
+	if err != nil {
+		log.Fatalf("Invalid think time: %v", err)
+	}
+	config.ThinkTime = thinkTime
+
+	targetRPS := scheduledRPS(config)
+	var assertions []Assertion
+	for _, spec := range asserts {
+		assertion, err := parseAssertion(spec, targetRPS)
+		if err != nil {
+			log.Fatal(err)
+		}
+		assertions = append(assertions, assertion)
+	}
+
+	if config.BenchmarkType == UserSession && config.VirtualUsers <= 0 {
+		log.Fatal("Number of virtual users must be positive. Use -vus flag")
+	}
 
 	log.Printf("Starting benchmark:")
 	log.Printf("  URL: %s", config.URL)
 	log.Printf("  Type: %s", config.BenchmarkType)
-	log.Printf("  RPS: %d", config.RPS)
+	if config.BenchmarkType == HTTPTemplate {
+		log.Printf("  Method: %s", config.Request.Method)
+		if config.Request.Body != "" {
+			log.Printf("  Body: %s", config.Request.Body)
+		}
+	}
+	if config.Journey != nil {
+		log.Printf("  Journey: %s (%d steps)", config.Journey.Name, len(config.Journey.Steps))
+	}
+	if config.Script != nil {
+		log.Printf("  Script: %s (%d steps)", config.Script.Name, len(config.Script.steps))
+	}
+	if config.BenchmarkType == UserSession {
+		log.Printf("  Virtual Users: %d", config.VirtualUsers)
+		log.Printf("  Think Time: %s", config.ThinkTime)
+		log.Printf("  Views/Session: %d", config.SessionViews)
+	} else if len(config.Scenarios) > 0 {
+		for _, scenario := range config.Scenarios {
+			log.Printf("  Scenario %s: %s, RPS %d (%s), concurrency %d (max %d)", scenario.Name, scenario.Config.BenchmarkType,
+				scenario.Config.RPS, scenario.Config.Profile, scenario.Config.Concurrency, scenario.Config.MaxConcurrency)
+		}
+	} else {
+		log.Printf("  RPS: %d (%s)", config.RPS, config.Profile)
+		log.Printf("  Concurrency: %d (max %d)", config.Concurrency, config.MaxConcurrency)
+	}
 	log.Printf("  Duration: %s", config.Duration)
-	log.Printf("  Concurrency: %d", config.Concurrency)
+	if config.Tracing.Enabled {
+		log.Printf("  Tracing: sample ratio %.2f, OTLP endpoint %q", config.Tracing.SampleRatio, config.Tracing.OTLPEndpoint)
+	}
+	if config.MetricsURL != "" {
+		log.Printf("  Target Metrics: %s (every %s)", config.MetricsURL, config.ScrapeInterval)
+	}
+	if config.Prometheus.URL != "" {
+		log.Printf("  Prometheus: %s, pods %s", config.Prometheus.URL, containerSelector(config.Prometheus))
+	}
+	if config.EventLog != "" {
+		log.Printf("  Event Log: %s (%s)", config.EventLog, eventLogFormat(config.EventLog))
+	}
+	if config.Proxy.Enabled() {
+		log.Printf("  Fault Proxy: %s -> %s", config.Proxy.Listen, config.Proxy.Upstream)
+		for _, fault := range config.Proxy.Faults {
+			log.Printf("    %s-%s: %s", fault.Start, fault.End, fault)
+		}
+	}
+	if repeat.Enabled() {
+		log.Printf("  Runs: %d per target, cooldown %s", repeat.Runs, repeat.Cooldown)
+		if repeat.CompareURL != "" {
+			log.Printf("  Compare With: %s (interleave: %v)", repeat.CompareURL, repeat.Interleave)
+		}
+		if repeat.Baseline != "" {
+			log.Printf("  Baseline: %s", repeat.Baseline)
+		}
+	}
 	log.Printf("")
 
-	result := runBenchmark(config)
+	// The first interrupt stops the run gracefully: the report is printed and created resources are deleted.
+	// The second one exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Printf("Interrupted: stopping the run, press Ctrl+C again to exit immediately")
+	}()
+
+	if repeat.Enabled() {
+		if repeat.CompareURL != "" && repeat.Baseline != "" {
+			log.Fatal("-compare-url and -baseline can't be used together")
+		}
+		if len(assertions) > 0 {
+			log.Fatal("-assert can't be used with repeated runs")
+		}
+		run := func(config Config) (*Result, error) {
+			return runOnce(ctx, config)
+		}
+		if err := runRepeated(ctx, config, repeat, run); err != nil {
+			log.Fatalf("Benchmark failed: %v", err)
+		}
+		return
+	}
+
+	result, err := runOnce(ctx, config)
+	if err != nil {
+		log.Fatalf("Benchmark failed: %v", err)
+	}
+
+	var passed bool
+	result.Assertions, passed = evaluateAssertions(assertions, result)
 	printResults(result, config.Verbose)
+
+	if !passed {
+		os.Exit(exitAssertionFailed)
+	}
+}
+
+// scheduledRPS returns the average request rate asked for by the rate profiles of the run,
+// 0 for virtual users: a closed model has no target rate
+func scheduledRPS(config Config) int {
+	if config.BenchmarkType == UserSession {
+		return 0
+	}
+	scenarios := config.Scenarios
+	if len(scenarios) == 0 {
+		scenarios = []LoadScenario{{Config: config}}
+	}
+	var rps float64
+	for _, scenario := range scenarios {
+		rps += float64(scenario.Config.RPS) * scenario.Config.Profile.Average(config.Duration)
+	}
+	return int(math.Round(rps))
+}
+
+// runOnce runs the benchmark once and collects target's resource usage
+func runOnce(ctx context.Context, config Config) (*Result, error) {
+	proxy, err := startFaultProxy(config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *Result
+	if config.BenchmarkType == UserSession {
+		result, err = runVirtualUsers(ctx, config)
+	} else {
+		result, err = runBenchmark(ctx, config)
+	}
+	if err != nil {
+		proxy.Stop()
+		return nil, err
+	}
+	result.Proxy = proxy.Stop()
+	annotateFaults(result.TimeSeries, result.StartTime, result.Proxy)
+	result.WarmUp = analyzeWarmUp(result.TimeSeries, result.TotalDuration, config.WarmUp)
+	result.Resources = queryResourceStats(ctx, config.Prometheus, result)
+	return result, nil
 }
\ No newline at end of file
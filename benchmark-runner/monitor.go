@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	// monitorInterval is how often the runner samples its own resource usage
+	monitorInterval = time.Second
+
+	// rpsShortfallThreshold - achieved rate below this fraction of the target is reported
+	rpsShortfallThreshold = 0.95
+
+	// cpuSaturationThreshold - runner CPU usage above this fraction of GOMAXPROCS is considered saturated
+	cpuSaturationThreshold = 0.90
+
+	// gcPauseThreshold - share of wall time spent in GC pauses considered harmful
+	gcPauseThreshold = 0.05
+)
+
+// RunnerSample is a single measurement of the runner's own resource usage
+type RunnerSample struct {
+	Offset      time.Duration // Time since benchmark start
+	CPUPercent  float64       // CPU usage in percent of one core
+	Goroutines  int           // Number of goroutines
+	HeapMB      float64       // Heap in use, MB
+	GCCount     uint32        // GC cycles during the interval
+	GCPauseMax  time.Duration // Longest GC pause during the interval
+	QueueDepth  int           // Tasks waiting in the request queue
+	BusyWorkers int64         // Workers executing requests
+}
+
+// RunnerStats summarizes the runner's own resource usage during the benchmark
+type RunnerStats struct {
+	Samples       []RunnerSample
+	CPUCores      int     // GOMAXPROCS during the run
+	AvgCPUPercent float64 // Average CPU usage in percent of one core
+	MaxCPUPercent float64 // Peak CPU usage in percent of one core
+	MaxGoroutines int
+	MaxHeapMB     float64
+	GCCount       uint32
+	GCPauseTotal  time.Duration
+	GCPauseMax    time.Duration
+	AvgQueueDepth float64
+	MaxQueueDepth int
+	Warnings      []string // Client-side bottleneck warnings
+}
+
+// runnerMonitor periodically samples the runner process
+type runnerMonitor struct {
+	queueDepth  func() int   // Current request queue depth (optional)
+	busyWorkers func() int64 // Current number of busy workers (optional)
+
+	start    time.Time
+	done     chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	samples  []RunnerSample
+	gcBefore debug.GCStats
+}
+
+// newRunnerMonitor creates monitor. queueDepth and busyWorkers may be nil
+func newRunnerMonitor(queueDepth func() int, busyWorkers func() int64) *runnerMonitor {
+	return &runnerMonitor{
+		queueDepth:  queueDepth,
+		busyWorkers: busyWorkers,
+		done:        make(chan struct{}),
+	}
+}
+
+// Start begins sampling in background
+func (m *runnerMonitor) Start() {
+	m.start = time.Now()
+	debug.ReadGCStats(&m.gcBefore)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(monitorInterval)
+		defer ticker.Stop()
+
+		lastTime := m.start
+		lastCPU := processCPUTime()
+		lastGC := m.gcBefore.NumGC
+
+		for {
+			select {
+			case <-m.done:
+				return
+			case now := <-ticker.C:
+				cpu := processCPUTime()
+				var gc debug.GCStats
+				debug.ReadGCStats(&gc)
+				var mem runtime.MemStats
+				runtime.ReadMemStats(&mem)
+
+				sample := RunnerSample{
+					Offset:     now.Sub(m.start),
+					CPUPercent: float64(cpu-lastCPU) / float64(now.Sub(lastTime)) * 100,
+					Goroutines: runtime.NumGoroutine(),
+					HeapMB:     float64(mem.HeapInuse) / 1024 / 1024,
+					GCCount:    uint32(gc.NumGC - lastGC),
+				}
+				// gc.Pause is ordered from the most recent
+				for i := 0; i < int(sample.GCCount) && i < len(gc.Pause); i++ {
+					if gc.Pause[i] > sample.GCPauseMax {
+						sample.GCPauseMax = gc.Pause[i]
+					}
+				}
+				if m.queueDepth != nil {
+					sample.QueueDepth = m.queueDepth()
+				}
+				if m.busyWorkers != nil {
+					sample.BusyWorkers = m.busyWorkers()
+				}
+
+				m.mu.Lock()
+				m.samples = append(m.samples, sample)
+				m.mu.Unlock()
+
+				lastTime, lastCPU, lastGC = now, cpu, gc.NumGC
+			}
+		}
+	}()
+}
+
+// Stop stops sampling and returns summarized statistics
+func (m *runnerMonitor) Stop() *RunnerStats {
+	close(m.done)
+	m.wg.Wait()
+
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := &RunnerStats{
+		Samples:      m.samples,
+		CPUCores:     runtime.GOMAXPROCS(0),
+		GCCount:      uint32(gc.NumGC - m.gcBefore.NumGC),
+		GCPauseTotal: gc.PauseTotal - m.gcBefore.PauseTotal,
+	}
+
+	var cpuSum, queueSum float64
+	for _, s := range m.samples {
+		cpuSum += s.CPUPercent
+		queueSum += float64(s.QueueDepth)
+		if s.CPUPercent > stats.MaxCPUPercent {
+			stats.MaxCPUPercent = s.CPUPercent
+		}
+		if s.Goroutines > stats.MaxGoroutines {
+			stats.MaxGoroutines = s.Goroutines
+		}
+		if s.HeapMB > stats.MaxHeapMB {
+			stats.MaxHeapMB = s.HeapMB
+		}
+		if s.GCPauseMax > stats.GCPauseMax {
+			stats.GCPauseMax = s.GCPauseMax
+		}
+		if s.QueueDepth > stats.MaxQueueDepth {
+			stats.MaxQueueDepth = s.QueueDepth
+		}
+	}
+	if len(m.samples) > 0 {
+		stats.AvgCPUPercent = cpuSum / float64(len(m.samples))
+		stats.AvgQueueDepth = queueSum / float64(len(m.samples))
+	}
+
+	return stats
+}
+
+// detectClientBottleneck checks whether achieved rate fell short of the target
+// because of the runner itself and records warnings in result.Runner
+func detectClientBottleneck(result *Result, targetRate float64) {
+	stats := result.Runner
+	if stats == nil || targetRate <= 0 || result.TotalDuration <= 0 {
+		return
+	}
+
+	achieved := float64(result.TotalRequests) / result.TotalDuration.Seconds()
+	if achieved >= targetRate*rpsShortfallThreshold {
+		return
+	}
+
+	shortfall := fmt.Sprintf("achieved %.2f/s of target %.2f/s (%.1f%%)", achieved, targetRate, achieved/targetRate*100)
+	if result.Scenario != "" {
+		shortfall = "scenario " + result.Scenario + ": " + shortfall
+	}
+
+	var causes []string
+	if stats.AvgCPUPercent >= float64(stats.CPUCores)*100*cpuSaturationThreshold {
+		causes = append(causes, fmt.Sprintf("runner CPU saturated (avg %.0f%% of %d cores)", stats.AvgCPUPercent/float64(stats.CPUCores), stats.CPUCores))
+	}
+	if result.SaturatedTicks > 0 {
+		causes = append(causes, fmt.Sprintf("worker pool at -max-concurrency=%d, queue backed up %d times", result.MaxConcurrency, result.SaturatedTicks))
+	}
+	if scheduled := targetRate * result.TotalDuration.Seconds(); result.Dispatched > 0 && float64(result.Dispatched) < scheduled*rpsShortfallThreshold {
+		causes = append(causes, fmt.Sprintf("request generator fell behind, dispatched %d of %.0f scheduled requests", result.Dispatched, scheduled))
+	}
+	if pauseShare := stats.GCPauseTotal.Seconds() / result.TotalDuration.Seconds(); pauseShare >= gcPauseThreshold {
+		causes = append(causes, fmt.Sprintf("runner spent %.1f%% of time in GC pauses", pauseShare*100))
+	}
+
+	if len(causes) == 0 {
+		stats.Warnings = append(stats.Warnings, shortfall+": runner was not saturated, the limit is on the server side")
+		return
+	}
+	for _, cause := range causes {
+		stats.Warnings = append(stats.Warnings, "CLIENT BOTTLENECK: "+shortfall+": "+cause)
+	}
+}
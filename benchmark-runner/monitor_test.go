@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetectClientBottleneck(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   Result
+		runner   RunnerStats
+		target   float64
+		warnings []string // Substrings expected in the warnings, in order
+	}{
+		{
+			name:   "target reached",
+			result: Result{TotalRequests: 1000, TotalDuration: 10 * time.Second},
+			runner: RunnerStats{CPUCores: 4},
+			target: 100,
+		},
+		{
+			name:   "within threshold",
+			result: Result{TotalRequests: 960, TotalDuration: 10 * time.Second},
+			runner: RunnerStats{CPUCores: 4},
+			target: 100,
+		},
+		{
+			name:     "server side limit",
+			result:   Result{TotalRequests: 500, TotalDuration: 10 * time.Second},
+			runner:   RunnerStats{CPUCores: 4},
+			target:   100,
+			warnings: []string{"achieved 50.00/s of target 100.00/s (50.0%): runner was not saturated"},
+		},
+		{
+			name:     "cpu saturated",
+			result:   Result{TotalRequests: 500, TotalDuration: 10 * time.Second},
+			runner:   RunnerStats{CPUCores: 2, AvgCPUPercent: 190},
+			target:   100,
+			warnings: []string{"CLIENT BOTTLENECK: achieved 50.00/s of target 100.00/s (50.0%): runner CPU saturated (avg 95% of 2 cores)"},
+		},
+		{
+			name:     "worker pool saturated",
+			result:   Result{TotalRequests: 500, TotalDuration: 10 * time.Second, MaxConcurrency: 8, SaturatedTicks: 3},
+			runner:   RunnerStats{CPUCores: 4},
+			target:   100,
+			warnings: []string{"worker pool at -max-concurrency=8, queue backed up 3 times"},
+		},
+		{
+			name:     "generator behind",
+			result:   Result{TotalRequests: 500, TotalDuration: 10 * time.Second, Dispatched: 600},
+			runner:   RunnerStats{CPUCores: 4},
+			target:   100,
+			warnings: []string{"dispatched 600 of 1000 scheduled requests"},
+		},
+		{
+			name:     "gc pauses",
+			result:   Result{TotalRequests: 500, TotalDuration: 10 * time.Second},
+			runner:   RunnerStats{CPUCores: 4, GCPauseTotal: time.Second},
+			target:   100,
+			warnings: []string{"runner spent 10.0% of time in GC pauses"},
+		},
+		{
+			name:     "several causes",
+			result:   Result{TotalRequests: 500, TotalDuration: 10 * time.Second, MaxConcurrency: 8, SaturatedTicks: 1, Scenario: "read"},
+			runner:   RunnerStats{CPUCores: 1, AvgCPUPercent: 100},
+			target:   100,
+			warnings: []string{"scenario read: achieved", "worker pool at -max-concurrency=8"},
+		},
+		{
+			name:   "no target",
+			result: Result{TotalRequests: 500, TotalDuration: 10 * time.Second},
+			runner: RunnerStats{CPUCores: 4},
+		},
+		{
+			name:   "zero duration",
+			result: Result{TotalRequests: 500},
+			runner: RunnerStats{CPUCores: 4},
+			target: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.result
+			runner := tt.runner
+			result.Runner = &runner
+			detectClientBottleneck(&result, tt.target)
+
+			got := runner.Warnings
+			if len(got) != len(tt.warnings) {
+				t.Fatalf("warnings = %q, want %d", got, len(tt.warnings))
+			}
+			for i, want := range tt.warnings {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("warning %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectClientBottleneckWithoutMonitor(t *testing.T) {
+	result := &Result{TotalRequests: 10, TotalDuration: time.Second}
+	detectClientBottleneck(result, 100)
+	if result.Runner != nil {
+		t.Errorf("Runner = %+v, want nil", result.Runner)
+	}
+}
+
+func TestRunnerMonitorSummarizesSamples(t *testing.T) {
+	monitor := newRunnerMonitor(func() int { return 4 }, func() int64 { return 2 })
+	monitor.Start()
+	monitor.mu.Lock()
+	monitor.samples = append(monitor.samples,
+		RunnerSample{CPUPercent: 50, Goroutines: 10, HeapMB: 5, QueueDepth: 2, GCPauseMax: time.Millisecond},
+		RunnerSample{CPUPercent: 150, Goroutines: 30, HeapMB: 3, QueueDepth: 6, GCPauseMax: 3 * time.Millisecond},
+	)
+	monitor.mu.Unlock()
+	stats := monitor.Stop()
+
+	if len(stats.Samples) < 2 {
+		t.Fatalf("samples = %d, want at least 2", len(stats.Samples))
+	}
+	if stats.MaxCPUPercent < 150 || stats.MaxGoroutines < 30 || stats.MaxHeapMB < 5 || stats.MaxQueueDepth < 6 {
+		t.Errorf("maximums = %+v, want at least the recorded samples", stats)
+	}
+	if stats.GCPauseMax < 3*time.Millisecond {
+		t.Errorf("GCPauseMax = %s, want at least 3ms", stats.GCPauseMax)
+	}
+	if stats.CPUCores < 1 {
+		t.Errorf("CPUCores = %d, want GOMAXPROCS", stats.CPUCores)
+	}
+}
@@ -0,0 +1,715 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	openAPIMaxDepth      = 10 // Nesting of schema references in generated request bodies
+	openAPIDefaultWeight = 1
+	openAPIReadWeight    = 3 // GET operations run more often than writes by default
+)
+
+// openAPIMethods are the operations of a path item in the order they are listed
+var openAPIMethods = []string{"get", "post", "put", "patch", "delete"}
+
+// openAPIPathParam matches path templating, e.g. {id} in /api/products/{id}
+var openAPIPathParam = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// openAPIDoc is the subset of an OpenAPI 3 document used to derive a workload
+type openAPIDoc struct {
+	OpenAPI string `json:"openapi"`
+	Swagger string `json:"swagger"`
+	Info    struct {
+		Title string `json:"title"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas       map[string]*openAPISchema      `json:"schemas"`
+		Parameters    map[string]*openAPIParameter   `json:"parameters"`
+		RequestBodies map[string]*openAPIRequestBody `json:"requestBodies"`
+		Responses     map[string]*openAPIResponse    `json:"responses"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Parameters  []*openAPIParameter         `json:"parameters"`
+	RequestBody *openAPIRequestBody         `json:"requestBody"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Weight      *int                        `json:"x-benchmark-weight"` // 0 excludes the operation
+}
+
+type openAPIParameter struct {
+	Ref      string                    `json:"$ref"`
+	Name     string                    `json:"name"`
+	In       string                    `json:"in"`
+	Required bool                      `json:"required"`
+	Schema   *openAPISchema            `json:"schema"`
+	Example  json.RawMessage           `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+}
+
+type openAPIRequestBody struct {
+	Ref     string                  `json:"$ref"`
+	Content map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIResponse struct {
+	Ref     string                  `json:"$ref"`
+	Content map[string]openAPIMedia `json:"content"`
+}
+
+type openAPIMedia struct {
+	Schema   *openAPISchema            `json:"schema"`
+	Example  json.RawMessage           `json:"example"`
+	Examples map[string]openAPIExample `json:"examples"`
+}
+
+type openAPIExample struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type openAPISchema struct {
+	Ref        string                    `json:"$ref"`
+	Type       openAPIType               `json:"type"`
+	Format     string                    `json:"format"`
+	Enum       []json.RawMessage         `json:"enum"`
+	Example    json.RawMessage           `json:"example"`
+	Default    json.RawMessage           `json:"default"`
+	Minimum    *float64                  `json:"minimum"`
+	Maximum    *float64                  `json:"maximum"`
+	MinLength  *int                      `json:"minLength"`
+	MaxLength  *int                      `json:"maxLength"`
+	Properties map[string]*openAPISchema `json:"properties"`
+	Items      *openAPISchema            `json:"items"`
+	ReadOnly   bool                      `json:"readOnly"`
+	AllOf      []*openAPISchema          `json:"allOf"`
+	OneOf      []*openAPISchema          `json:"oneOf"`
+	AnyOf      []*openAPISchema          `json:"anyOf"`
+}
+
+// openAPIType is the schema type: a string in OpenAPI 3.0, also a list in 3.1 (["string", "null"])
+type openAPIType string
+
+func (t *openAPIType) UnmarshalJSON(data []byte) error {
+	var types []string
+	if err := json.Unmarshal(data, &types); err != nil {
+		var single string
+		if err := json.Unmarshal(data, &single); err != nil {
+			return err
+		}
+		types = []string{single}
+	}
+	for _, value := range types {
+		if value != "null" {
+			*t = openAPIType(value)
+			return nil
+		}
+	}
+	return nil
+}
+
+// loadOpenAPI reads an OpenAPI 3 JSON document from a file or an http(s) URL
+func loadOpenAPI(source string) (*openAPIDoc, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch OpenAPI document: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch OpenAPI document %s: status %d", source, resp.StatusCode)
+		}
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("failed to fetch OpenAPI document: %v", err)
+		}
+	} else if data, err = os.ReadFile(source); err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, fmt.Errorf("OpenAPI document %s is not JSON: only JSON documents are supported, convert YAML first (e.g. yq -o json)", source)
+	}
+	var doc openAPIDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document %s: %v", source, err)
+	}
+	if doc.Swagger != "" {
+		return nil, fmt.Errorf("OpenAPI document %s: Swagger %s is not supported, convert it to OpenAPI 3", source, doc.Swagger)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("OpenAPI document %s: unsupported version %q, expected 3.x", source, doc.OpenAPI)
+	}
+	if len(doc.Paths) == 0 {
+		return nil, fmt.Errorf("OpenAPI document %s: no paths", source)
+	}
+	return &doc, nil
+}
+
+// loadWorkload derives the workload of the OpenAPI document, logging operations that were skipped
+func loadWorkload(source string) ([]scenarioDef, error) {
+	doc, err := loadOpenAPI(source)
+	if err != nil {
+		return nil, err
+	}
+	defs, notes, err := doc.deriveWorkload()
+	for _, note := range notes {
+		log.Printf("OpenAPI: %s", note)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OpenAPI document %s: %v", source, err)
+	}
+	return defs, nil
+}
+
+// openAPIEndpoint is an operation of the document with its resolved parameters
+type openAPIEndpoint struct {
+	Name      string
+	Method    string
+	Path      string
+	Operation *openAPIOperation
+	Params    []*openAPIParameter
+}
+
+// deriveWorkload derives concurrent http scenarios from the operations of the document: one scenario per operation,
+// weighted by x-benchmark-weight or by method. Path parameters are taken from examples, from ids captured from
+// responses of create (POST) operations of the parent path or generated from schemas, request bodies from examples
+// or schemas. Returns the scenarios and notes about operations that were skipped or only partially derived
+func (d *openAPIDoc) deriveWorkload() ([]scenarioDef, []string, error) {
+	var notes []string
+	prefix := ""
+	if len(d.Servers) > 0 {
+		if server, err := url.Parse(d.Servers[0].URL); err == nil {
+			prefix = strings.TrimSuffix(server.Path, "/")
+		}
+	}
+
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var endpoints []openAPIEndpoint
+	for _, path := range paths {
+		item := d.Paths[path]
+		var shared []*openAPIParameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, nil, fmt.Errorf("path %s: invalid parameters: %v", path, err)
+			}
+		}
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op openAPIOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, nil, fmt.Errorf("%s %s: %v", strings.ToUpper(method), path, err)
+			}
+			endpoint := openAPIEndpoint{Name: op.OperationID, Method: strings.ToUpper(method), Path: prefix + path, Operation: &op}
+			if endpoint.Name == "" {
+				endpoint.Name = operationSlug(method, path)
+			}
+			if op.Weight != nil && *op.Weight <= 0 {
+				notes = append(notes, fmt.Sprintf("%s: excluded by x-benchmark-weight", endpoint.Name))
+				continue
+			}
+			endpoint.Params = d.mergeParameters(shared, op.Parameters)
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, notes, fmt.Errorf("no operations to run")
+	}
+
+	// Path parameters without examples are filled with values captured by create operations of the parent path
+	captures := make(map[string][]string) // Endpoint name -> capture rules
+	vars := make(map[string]string)       // "path param" -> variable name
+	for _, endpoint := range endpoints {
+		for _, param := range endpoint.Params {
+			if param.In != "path" || exampleValue(param) != nil {
+				continue
+			}
+			variable, parent := pathVariable(endpoint.Path, param.Name)
+			for _, creator := range endpoints {
+				if creator.Method != http.MethodPost || creator.Path != parent {
+					continue
+				}
+				property := d.responseProperty(creator.Operation, param.Name)
+				if property == "" {
+					continue
+				}
+				rule := variable + "=$." + property
+				if !slices.Contains(captures[creator.Name], rule) {
+					captures[creator.Name] = append(captures[creator.Name], rule)
+				}
+				vars[endpoint.Path+" "+param.Name] = variable
+				break
+			}
+		}
+	}
+
+	defs := make([]scenarioDef, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		def := scenarioDef{
+			Name:     endpoint.Name,
+			Type:     string(HTTPTemplate),
+			Weight:   openAPIDefaultWeight,
+			Method:   endpoint.Method,
+			Captures: captures[endpoint.Name],
+		}
+		if endpoint.Method == http.MethodGet {
+			def.Weight = openAPIReadWeight
+		}
+		if endpoint.Operation.Weight != nil {
+			def.Weight = *endpoint.Operation.Weight
+		}
+
+		var query []string
+		path := endpoint.Path
+		declared := make(map[string]bool)
+		for _, param := range endpoint.Params {
+			if param.In == "path" {
+				declared[param.Name] = true
+			}
+		}
+		if undeclared := undeclaredPathParams(path, declared); len(undeclared) > 0 {
+			notes = append(notes, fmt.Sprintf("%s: path parameters %s are not declared, skipped", endpoint.Name, strings.Join(undeclared, ", ")))
+			continue
+		}
+		for _, param := range endpoint.Params {
+			switch param.In {
+			case "path":
+				value := d.parameterTemplate(param)
+				if variable, ok := vars[endpoint.Path+" "+param.Name]; ok {
+					value = fmt.Sprintf(`{{or (.Var %q) (%s)}}`, variable, d.schemaAction(d.resolveSchema(param.Schema)))
+				}
+				path = strings.ReplaceAll(path, "{"+param.Name+"}", value)
+			case "query":
+				if param.Required {
+					query = append(query, url.QueryEscape(param.Name)+"="+d.parameterTemplate(param))
+				}
+			case "header":
+				if param.Required {
+					if def.Headers == nil {
+						def.Headers = make(map[string]string)
+					}
+					def.Headers[param.Name] = d.parameterTemplate(param)
+				}
+			}
+		}
+		def.URL = path
+		if len(query) > 0 {
+			def.URL += "?" + strings.Join(query, "&")
+		}
+
+		if body := d.resolveRequestBody(endpoint.Operation.RequestBody); body != nil {
+			media, ok := jsonMedia(body.Content)
+			if !ok {
+				notes = append(notes, fmt.Sprintf("%s: request body has no JSON content, sent without a body", endpoint.Name))
+			} else if example := mediaExample(media); example != nil {
+				def.Body = templateLiteral(compactJSON(example))
+			} else {
+				def.Body = d.jsonTemplate(media.Schema, nil)
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs, notes, nil
+}
+
+// mergeParameters resolves parameters of the path item and the operation, the operation's override by name and location
+func (d *openAPIDoc) mergeParameters(shared, own []*openAPIParameter) []*openAPIParameter {
+	var params []*openAPIParameter
+	index := make(map[string]int)
+	for _, param := range append(append([]*openAPIParameter{}, shared...), own...) {
+		param = d.resolveParameter(param)
+		if param == nil {
+			continue
+		}
+		key := param.In + " " + param.Name
+		if i, ok := index[key]; ok {
+			params[i] = param
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, param)
+	}
+	return params
+}
+
+// parameterTemplate returns the template of a parameter value: its example or a value generated from its schema
+func (d *openAPIDoc) parameterTemplate(param *openAPIParameter) string {
+	if example := exampleValue(param); example != nil {
+		var text string
+		if err := json.Unmarshal(example, &text); err != nil {
+			text = compactJSON(example)
+		}
+		switch param.In {
+		case "header":
+			return templateLiteral(text)
+		case "query":
+			return templateLiteral(url.QueryEscape(text))
+		}
+		return templateLiteral(url.PathEscape(text))
+	}
+	return "{{" + d.schemaAction(d.resolveSchema(param.Schema)) + "}}"
+}
+
+// schemaAction returns a template pipeline generating a scalar value of the schema, e.g. randInt 1 100
+func (d *openAPIDoc) schemaAction(schema *openAPISchema) string {
+	if schema == nil {
+		return "randString 8"
+	}
+	if value := schemaExample(schema); value != nil {
+		return fmt.Sprintf("%q", rawText(value))
+	}
+	switch schema.Type {
+	case "integer":
+		low, high := schemaRange(schema, 1, 100)
+		return fmt.Sprintf("randInt %d %d", int64(low), int64(high))
+	case "number":
+		low, high := schemaRange(schema, 1, 100)
+		return fmt.Sprintf(`printf "%%.2f" (randFloat %s %s)`, formatFloat(low), formatFloat(high))
+	case "boolean":
+		return `"true"`
+	}
+	switch schema.Format {
+	case "uuid":
+		return "uuid"
+	case "date-time":
+		return `"2024-01-01T00:00:00Z"`
+	case "date":
+		return `"2024-01-01"`
+	case "email":
+		return `printf "user%d@example.com" seq`
+	}
+	length := 8
+	if schema.MaxLength != nil && *schema.MaxLength < length {
+		length = *schema.MaxLength
+	}
+	if schema.MinLength != nil && *schema.MinLength > length {
+		length = *schema.MinLength
+	}
+	return fmt.Sprintf("randString %d", length)
+}
+
+// jsonTemplate returns a template rendering a JSON value of the schema. Read-only properties and properties
+// referencing a schema being generated (recursive schemas) are omitted. refs are the schemas being generated
+func (d *openAPIDoc) jsonTemplate(schema *openAPISchema, refs []string) string {
+	if schema != nil && schema.Ref != "" {
+		refs = append(refs, schema.Ref)
+	}
+	schema = d.resolveSchema(schema)
+	if schema == nil || len(refs) > openAPIMaxDepth {
+		return "null"
+	}
+	if value := schemaExample(schema); value != nil {
+		return templateLiteral(compactJSON(value))
+	}
+	switch {
+	case schema.Type == "object" || (schema.Type == "" && schema.Properties != nil):
+		names := make([]string, 0, len(schema.Properties))
+		for name, property := range schema.Properties {
+			if property != nil && slices.Contains(refs, property.Ref) {
+				continue
+			}
+			if resolved := d.resolveSchema(property); resolved == nil || !resolved.ReadOnly {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		fields := make([]string, 0, len(names))
+		for _, name := range names {
+			key, _ := json.Marshal(name)
+			fields = append(fields, templateLiteral(string(key))+":"+d.jsonTemplate(schema.Properties[name], refs))
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	case schema.Type == "array" || (schema.Type == "" && schema.Items != nil):
+		if schema.Items != nil && slices.Contains(refs, schema.Items.Ref) {
+			return "[]"
+		}
+		return "[" + d.jsonTemplate(schema.Items, refs) + "]"
+	case schema.Type == "integer" || schema.Type == "number" || schema.Type == "boolean":
+		return "{{" + d.schemaAction(schema) + "}}"
+	default:
+		return `"{{` + d.schemaAction(schema) + `}}"`
+	}
+}
+
+// responseProperty returns the property of the operation's success response object that holds the value
+// of the path parameter: the property of the same name, or "id" for parameters like id or productId
+func (d *openAPIDoc) responseProperty(op *openAPIOperation, param string) string {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		response := d.resolveResponse(op.Responses[code])
+		if response == nil {
+			continue
+		}
+		media, ok := jsonMedia(response.Content)
+		if !ok {
+			continue
+		}
+		schema := d.resolveSchema(media.Schema)
+		if schema == nil {
+			continue
+		}
+		if _, ok := schema.Properties[param]; ok {
+			return param
+		}
+		if _, ok := schema.Properties["id"]; ok && strings.HasSuffix(strings.ToLower(param), "id") {
+			return "id"
+		}
+	}
+	return ""
+}
+
+// resolveSchema follows $ref and merges allOf; of oneOf and anyOf the first alternative is used
+func (d *openAPIDoc) resolveSchema(schema *openAPISchema) *openAPISchema {
+	for i := 0; schema != nil && schema.Ref != ""; i++ {
+		name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+		if !ok || i > openAPIMaxDepth {
+			return nil
+		}
+		schema = d.Components.Schemas[name]
+	}
+	if schema == nil {
+		return nil
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		alternative := append(schema.OneOf, schema.AnyOf...)[0]
+		if resolved := d.resolveSchema(alternative); resolved != nil {
+			return resolved
+		}
+	}
+	if len(schema.AllOf) == 0 {
+		return schema
+	}
+	merged := *schema
+	merged.AllOf = nil
+	merged.Properties = make(map[string]*openAPISchema)
+	for _, part := range schema.AllOf {
+		resolved := d.resolveSchema(part)
+		if resolved == nil {
+			continue
+		}
+		for name, property := range resolved.Properties {
+			merged.Properties[name] = property
+		}
+		if merged.Type == "" {
+			merged.Type = resolved.Type
+		}
+	}
+	for name, property := range schema.Properties {
+		merged.Properties[name] = property
+	}
+	return &merged
+}
+
+func (d *openAPIDoc) resolveParameter(param *openAPIParameter) *openAPIParameter {
+	if param == nil || param.Ref == "" {
+		return param
+	}
+	name, _ := strings.CutPrefix(param.Ref, "#/components/parameters/")
+	return d.Components.Parameters[name]
+}
+
+func (d *openAPIDoc) resolveRequestBody(body *openAPIRequestBody) *openAPIRequestBody {
+	if body == nil || body.Ref == "" {
+		return body
+	}
+	name, _ := strings.CutPrefix(body.Ref, "#/components/requestBodies/")
+	return d.Components.RequestBodies[name]
+}
+
+func (d *openAPIDoc) resolveResponse(response *openAPIResponse) *openAPIResponse {
+	if response == nil || response.Ref == "" {
+		return response
+	}
+	name, _ := strings.CutPrefix(response.Ref, "#/components/responses/")
+	return d.Components.Responses[name]
+}
+
+// jsonMedia returns the JSON media type of the content: application/json or another +json type
+func jsonMedia(content map[string]openAPIMedia) (openAPIMedia, bool) {
+	if media, ok := content["application/json"]; ok {
+		return media, true
+	}
+	types := make([]string, 0, len(content))
+	for contentType := range content {
+		types = append(types, contentType)
+	}
+	sort.Strings(types)
+	for _, contentType := range types {
+		if strings.HasSuffix(strings.Split(contentType, ";")[0], "+json") {
+			return content[contentType], true
+		}
+	}
+	return openAPIMedia{}, false
+}
+
+// mediaExample returns the example of the media type: example or the first of examples (by name)
+func mediaExample(media openAPIMedia) json.RawMessage {
+	if media.Example != nil {
+		return media.Example
+	}
+	return firstExample(media.Examples)
+}
+
+// exampleValue returns the example of the parameter or of its schema
+func exampleValue(param *openAPIParameter) json.RawMessage {
+	if param.Example != nil {
+		return param.Example
+	}
+	if example := firstExample(param.Examples); example != nil {
+		return example
+	}
+	if param.Schema != nil {
+		return schemaExample(param.Schema)
+	}
+	return nil
+}
+
+// schemaExample returns the example, the default or the first enum value of the schema
+func schemaExample(schema *openAPISchema) json.RawMessage {
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	}
+	return nil
+}
+
+func firstExample(examples map[string]openAPIExample) json.RawMessage {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if examples[name].Value != nil {
+			return examples[name].Value
+		}
+	}
+	return nil
+}
+
+// schemaRange returns the range of generated numbers: minimum and maximum of the schema or the defaults
+func schemaRange(schema *openAPISchema, low, high float64) (float64, float64) {
+	if schema.Minimum != nil {
+		low = *schema.Minimum
+		if schema.Maximum == nil && high <= low {
+			high = low + 100
+		}
+	}
+	if schema.Maximum != nil {
+		high = *schema.Maximum
+		if schema.Minimum == nil && low >= high {
+			low = high - 100
+		}
+	}
+	return low, high
+}
+
+// pathVariable returns the name of the variable holding values of the path parameter and the parent path of the
+// resource, e.g. products_id and /api/products for {id} of /api/products/{id}
+func pathVariable(path, param string) (string, string) {
+	before, _, _ := strings.Cut(path, "{"+param+"}")
+	parent := strings.TrimSuffix(before, "/")
+	if !strings.EqualFold(param, "id") {
+		return param, parent
+	}
+	segment := parent[strings.LastIndex(parent, "/")+1:]
+	if segment == "" || strings.Contains(segment, "{") {
+		return param, parent
+	}
+	return segment + "_" + param, parent
+}
+
+// undeclaredPathParams returns path templating parameters without a parameter definition
+func undeclaredPathParams(path string, declared map[string]bool) []string {
+	var names []string
+	for _, match := range openAPIPathParam.FindAllStringSubmatch(path, -1) {
+		if !declared[match[1]] {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// operationSlug names an operation without operationId, e.g. get-api-products-id
+func operationSlug(method, path string) string {
+	slug := strings.NewReplacer("{", "", "}", "", "/", "-").Replace(strings.Trim(path, "/"))
+	return strings.ToLower(method) + "-" + slug
+}
+
+// templateLiteral escapes template delimiters of literal text
+func templateLiteral(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
+
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// rawText returns a JSON string value unquoted, other values as JSON
+func rawText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	return compactJSON(raw)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// dumpWorkload writes the derived scenarios as a scenarios file, to stdout for "-"
+func dumpWorkload(path string, defs []scenarioDef) error {
+	data, err := json.MarshalIndent(scenarioFile{Scenarios: defs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testOpenAPI covers the derivation rules: server prefix, weights, parameters, captures and bodies
+const testOpenAPI = `{
+  "openapi": "3.1.0",
+  "servers": [{"url": "https://example.com/v1/"}],
+  "paths": {
+    "/orders": {
+      "get": {
+        "parameters": [
+          {"name": "limit", "in": "query", "required": true, "example": 10},
+          {"name": "cursor", "in": "query", "schema": {"type": "string"}},
+          {"name": "X-Tenant", "in": "header", "required": true, "schema": {"type": "string", "enum": ["acme", "other"]}}
+        ]
+      },
+      "post": {
+        "operationId": "create-order",
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/NewOrder"}}}},
+        "responses": {"201": {"content": {"application/vnd.api+json": {"schema": {"type": "object", "properties": {"orderId": {"type": "string"}}}}}}}
+      }
+    },
+    "/orders/{orderId}": {
+      "get": {
+        "operationId": "get-order",
+        "parameters": [{"name": "orderId", "in": "path", "required": true, "schema": {"type": ["string", "null"], "format": "uuid"}}]
+      },
+      "delete": {
+        "operationId": "delete-order",
+        "x-benchmark-weight": 0,
+        "parameters": [{"name": "orderId", "in": "path", "required": true}]
+      }
+    },
+    "/orders/{orderId}/items/{itemId}": {
+      "put": {
+        "operationId": "update-item",
+        "parameters": [{"name": "orderId", "in": "path", "required": true, "example": "a b"}],
+        "requestBody": {"content": {"text/plain": {"schema": {"type": "string"}}}}
+      }
+    },
+    "/uploads": {
+      "post": {
+        "operationId": "upload",
+        "x-benchmark-weight": 5,
+        "requestBody": {"content": {"text/plain": {"schema": {"type": "string"}}}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Base": {"type": "object", "properties": {"id": {"type": "integer", "readOnly": true}, "note": {"type": "string", "example": "{{x}}"}}},
+      "NewOrder": {
+        "allOf": [{"$ref": "#/components/schemas/Base"}],
+        "properties": {
+          "count": {"type": "integer", "minimum": 1, "maximum": 5},
+          "paid": {"type": "boolean"},
+          "email": {"type": "string", "format": "email"},
+          "tags": {"type": "array", "items": {"type": "string", "maxLength": 4}},
+          "parent": {"$ref": "#/components/schemas/NewOrder"}
+        }
+      }
+    }
+  }
+}`
+
+func TestDeriveWorkload(t *testing.T) {
+	var doc openAPIDoc
+	if err := json.Unmarshal([]byte(testOpenAPI), &doc); err != nil {
+		t.Fatal(err)
+	}
+	defs, notes, err := doc.deriveWorkload()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []scenarioDef{
+		{Name: "get-orders", Type: "http", Weight: 3, Method: "GET", URL: "/v1/orders?limit=10", Headers: map[string]string{"X-Tenant": "acme"}},
+		{Name: "create-order", Type: "http", Weight: 1, Method: "POST", URL: "/v1/orders", Captures: []string{"orderId=$.orderId"},
+			Body: `{"count":{{randInt 1 5}},"email":"{{printf "user%d@example.com" seq}}","note":"{{"{{"}}x}}","paid":{{"true"}},"tags":["{{randString 4}}"]}`},
+		{Name: "get-order", Type: "http", Weight: 3, Method: "GET", URL: `/v1/orders/{{or (.Var "orderId") (uuid)}}`},
+		{Name: "upload", Type: "http", Weight: 5, Method: "POST", URL: "/v1/uploads"},
+	}
+	if !reflect.DeepEqual(defs, want) {
+		got, _ := json.MarshalIndent(defs, "", "  ")
+		t.Errorf("workload = %s", got)
+	}
+
+	wantNotes := []string{
+		"delete-order: excluded by x-benchmark-weight",
+		"update-item: path parameters itemId are not declared, skipped",
+		"upload: request body has no JSON content, sent without a body",
+	}
+	if !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("notes = %q, want %q", notes, wantNotes)
+	}
+}
+
+func TestDeriveWorkloadWithoutOperations(t *testing.T) {
+	doc := openAPIDoc{Paths: map[string]map[string]json.RawMessage{
+		"/health": {"get": json.RawMessage(`{"x-benchmark-weight": 0}`)},
+	}}
+	if _, notes, err := doc.deriveWorkload(); err == nil || len(notes) != 1 {
+		t.Errorf("error = %v, notes = %q, want no operations to run", err, notes)
+	}
+}
+
+// TestProductsWorkload runs the workload of openapi/products.json through the scenario configuration
+// and renders the request of every operation
+func TestProductsWorkload(t *testing.T) {
+	defs, err := loadWorkload("openapi/products.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenarios, err := buildScenarios(defs, "openapi/products.json", ".", Config{URL: "http://api", RPS: 1000, BenchmarkType: GetProducts})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		rps    int
+		method string
+		url    string
+		body   bool
+	}{
+		"list-products":  {rps: 100, method: "GET", url: "http://api/api/products"},
+		"create-product": {rps: 100, method: "POST", url: "http://api/api/products", body: true},
+		"get-product":    {rps: 600, method: "GET", url: "http://api/api/products/42"},
+		"update-product": {rps: 100, method: "PUT", url: "http://api/api/products/42", body: true},
+		"delete-product": {rps: 100, method: "DELETE", url: "http://api/api/products/42"},
+	}
+	if len(scenarios) != len(want) {
+		t.Fatalf("got %d scenarios, want %d", len(scenarios), len(want))
+	}
+
+	vars := NewVarStore()
+	vars.Set("products_id", "42")
+	for _, scenario := range scenarios {
+		w, ok := want[scenario.Name]
+		if !ok {
+			t.Errorf("unexpected scenario %s", scenario.Name)
+			continue
+		}
+		if scenario.Config.RPS != w.rps {
+			t.Errorf("%s: rps = %d, want %d", scenario.Name, scenario.Config.RPS, w.rps)
+		}
+		ops, err := resolveOperations(scenario.Config)
+		if err != nil {
+			t.Fatalf("%s: %v", scenario.Name, err)
+		}
+		req, err := ops[0].BuildRequest(&RequestContext{Vars: vars}, &State{})
+		if err != nil {
+			t.Fatalf("%s: %v", scenario.Name, err)
+		}
+		if req.Method != w.method || req.URL.String() != w.url {
+			t.Errorf("%s: request = %s %s, want %s %s", scenario.Name, req.Method, req.URL, w.method, w.url)
+		}
+		if (req.Body != nil) != w.body {
+			t.Errorf("%s: has body = %v, want %v", scenario.Name, req.Body != nil, w.body)
+			continue
+		}
+		if req.Body == nil {
+			continue
+		}
+		var product map[string]interface{}
+		data, _ := io.ReadAll(req.Body)
+		if err := json.Unmarshal(data, &product); err != nil {
+			t.Errorf("%s: body %s is not JSON: %v", scenario.Name, data, err)
+			continue
+		}
+		if _, ok := product["id"]; ok || product["name"] == nil || product["price"] == nil || product["quantity"] == nil {
+			t.Errorf("%s: body = %s, want a product without read-only properties", scenario.Name, data)
+		}
+	}
+}
+
+func TestLoadOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "yaml", data: "openapi: 3.0.0\n", err: "is not JSON"},
+		{name: "invalid json", data: `{"openapi": `, err: "failed to parse OpenAPI document"},
+		{name: "swagger", data: `{"swagger": "2.0", "paths": {"/": {}}}`, err: "Swagger 2.0 is not supported"},
+		{name: "version", data: `{"openapi": "2.1", "paths": {"/": {}}}`, err: `unsupported version "2.1"`},
+		{name: "no paths", data: `{"openapi": "3.0.3"}`, err: "no paths"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, "openapi.json")
+		if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadOpenAPI(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestLoadOpenAPIFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/q/openapi" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testOpenAPI))
+	}))
+	defer server.Close()
+
+	doc, err := loadOpenAPI(server.URL + "/q/openapi")
+	if err != nil || len(doc.Paths) != 4 {
+		t.Errorf("document = %+v, %v", doc, err)
+	}
+	if _, err := loadOpenAPI(server.URL + "/missing"); err == nil || !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("missing document: error = %v", err)
+	}
+}
+
+func TestParameterTemplateEscapes(t *testing.T) {
+	tests := []struct {
+		in      string
+		example string
+		want    string
+	}{
+		{in: "query", example: `"a&b=c"`, want: "a%26b%3Dc"},
+		{in: "query", example: `"1+1 2"`, want: "1%2B1+2"},
+		{in: "path", example: `"a b/c"`, want: "a%20b%2Fc"},
+		{in: "header", example: `"a&b=c"`, want: "a&b=c"},
+	}
+
+	var doc openAPIDoc
+	for _, tt := range tests {
+		param := &openAPIParameter{Name: "p", In: tt.in, Example: json.RawMessage(tt.example)}
+		if got := doc.parameterTemplate(param); got != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.in, tt.example, got, tt.want)
+		}
+	}
+}
+
+func TestSchemaAction(t *testing.T) {
+	number := func(v float64) *float64 { return &v }
+	length := func(v int) *int { return &v }
+	tests := []struct {
+		schema *openAPISchema
+		want   string
+	}{
+		{schema: nil, want: "randString 8"},
+		{schema: &openAPISchema{Type: "integer"}, want: "randInt 1 100"},
+		{schema: &openAPISchema{Type: "integer", Minimum: number(5), Maximum: number(10)}, want: "randInt 5 10"},
+		{schema: &openAPISchema{Type: "integer", Minimum: number(500)}, want: "randInt 500 600"},
+		{schema: &openAPISchema{Type: "integer", Maximum: number(-1)}, want: "randInt -101 -1"},
+		{schema: &openAPISchema{Type: "number", Minimum: number(0.5)}, want: `printf "%.2f" (randFloat 0.5 100)`},
+		{schema: &openAPISchema{Type: "boolean"}, want: `"true"`},
+		{schema: &openAPISchema{Type: "string", Format: "uuid"}, want: "uuid"},
+		{schema: &openAPISchema{Type: "string", Format: "date"}, want: `"2024-01-01"`},
+		{schema: &openAPISchema{Type: "string", MaxLength: length(3)}, want: "randString 3"},
+		{schema: &openAPISchema{Type: "string", MinLength: length(20)}, want: "randString 20"},
+		{schema: &openAPISchema{Type: "string", Enum: []json.RawMessage{json.RawMessage(`"red"`)}}, want: `"red"`},
+		{schema: &openAPISchema{Type: "integer", Default: json.RawMessage(`7`)}, want: `"7"`},
+	}
+	var doc openAPIDoc
+	for _, tt := range tests {
+		if got := doc.schemaAction(tt.schema); got != tt.want {
+			t.Errorf("schemaAction(%+v) = %s, want %s", tt.schema, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAPIType(t *testing.T) {
+	tests := map[string]openAPIType{
+		`{"type": "integer"}`:          "integer",
+		`{"type": ["string", "null"]}`: "string",
+		`{"type": ["null", "number"]}`: "number",
+		`{"type": ["null"]}`:           "",
+		`{"properties": {"a": {}}}`:    "",
+	}
+	for data, want := range tests {
+		var schema openAPISchema
+		if err := json.Unmarshal([]byte(data), &schema); err != nil || schema.Type != want {
+			t.Errorf("%s: type = %q, %v, want %q", data, schema.Type, err, want)
+		}
+	}
+	var schema openAPISchema
+	if err := json.Unmarshal([]byte(`{"type": 1}`), &schema); err == nil {
+		t.Error("numeric type: no error")
+	}
+}
+
+func TestPathVariable(t *testing.T) {
+	tests := []struct {
+		path, param      string
+		variable, parent string
+	}{
+		{"/api/products/{id}", "id", "products_id", "/api/products"},
+		{"/api/products/{ID}/reviews", "ID", "products_ID", "/api/products"},
+		{"/api/products/{productId}", "productId", "productId", "/api/products"},
+		{"/{id}", "id", "id", ""},
+		{"/orgs/{org}/{id}", "id", "id", "/orgs/{org}"},
+	}
+	for _, tt := range tests {
+		variable, parent := pathVariable(tt.path, tt.param)
+		if variable != tt.variable || parent != tt.parent {
+			t.Errorf("pathVariable(%q, %q) = %q, %q, want %q, %q", tt.path, tt.param, variable, parent, tt.variable, tt.parent)
+		}
+	}
+}
+
+func TestOperationSlug(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{"get", "/api/products/{id}", "get-api-products-id"},
+		{"POST", "/orders/", "post-orders"},
+		{"delete", "/", "delete-"},
+	}
+	for _, tt := range tests {
+		if got := operationSlug(tt.method, tt.path); got != tt.want {
+			t.Errorf("operationSlug(%q, %q) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestResolveSchema(t *testing.T) {
+	var doc openAPIDoc
+	if err := json.Unmarshal([]byte(testOpenAPI), &doc); err != nil {
+		t.Fatal(err)
+	}
+	schema := doc.resolveSchema(&openAPISchema{Ref: "#/components/schemas/NewOrder"})
+	if schema == nil || schema.Type != "object" {
+		t.Fatalf("NewOrder = %+v, want an object", schema)
+	}
+	var names []string
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	if len(names) != 7 || schema.Properties["id"] == nil || schema.Properties["count"] == nil {
+		t.Errorf("NewOrder properties = %v, want its own and Base ones", names)
+	}
+
+	if schema := doc.resolveSchema(&openAPISchema{Ref: "#/components/schemas/Missing"}); schema != nil {
+		t.Errorf("missing schema = %+v, want nil", schema)
+	}
+	if schema := doc.resolveSchema(&openAPISchema{Ref: "other.json#/Product"}); schema != nil {
+		t.Errorf("external schema = %+v, want nil", schema)
+	}
+	oneOf := &openAPISchema{OneOf: []*openAPISchema{{Ref: "#/components/schemas/Base"}, {Type: "string"}}}
+	if schema := doc.resolveSchema(oneOf); schema != doc.Components.Schemas["Base"] {
+		t.Errorf("oneOf = %+v, want the first alternative", schema)
+	}
+}
+
+func TestJSONMedia(t *testing.T) {
+	tests := []struct {
+		types []string
+		want  string
+		ok    bool
+	}{
+		{types: []string{"text/plain", "application/json"}, want: "application/json", ok: true},
+		{types: []string{"application/problem+json; charset=utf-8"}, want: "application/problem+json; charset=utf-8", ok: true},
+		{types: []string{"text/plain", "application/xml"}, ok: false},
+	}
+	for _, tt := range tests {
+		content := make(map[string]openAPIMedia)
+		for _, contentType := range tt.types {
+			content[contentType] = openAPIMedia{Example: json.RawMessage(`"` + contentType + `"`)}
+		}
+		media, ok := jsonMedia(content)
+		if ok != tt.ok || (ok && rawText(media.Example) != tt.want) {
+			t.Errorf("jsonMedia(%v) = %s, %v, want %s, %v", tt.types, media.Example, ok, tt.want, tt.ok)
+		}
+	}
+}
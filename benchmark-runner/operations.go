@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,128 +17,290 @@ const (
 	FixedProductID = 1
 )
 
-// executeRequest performs a single HTTP request and returns latency and error
-func executeRequest(ctx *RequestContext, task RequestTask) (time.Duration, error) {
-	start := time.Now()
+func init() {
+	RegisterOperation(productOperation{
+		name:   string(GetProducts),
+		method: "GET",
+		path:   func(state *State) string { return "/api/products" },
+	})
+	RegisterOperation(productOperation{
+		name:   string(CreateProduct),
+		method: "POST",
+		path:   func(state *State) string { return "/api/products" },
+		body: func(state *State) interface{} {
+			return Product{
+				Name:        "Benchmark Product",
+				Description: "Created by benchmark tool",
+				Price:       99.99,
+				Quantity:    100,
+			}
+		},
+		extract: extractCreatedProductID,
+		creates: true,
+	})
+	RegisterOperation(productOperation{
+		name:   string(GetProductByID),
+		byID:   true,
+		method: "GET",
+		path:   productPath,
+	})
+	RegisterOperation(productOperation{
+		name:   string(UpdateProduct),
+		byID:   true,
+		method: "PUT",
+		path:   productPath,
+		body: func(state *State) interface{} {
+			return Product{
+				ID:          state.ProductID,
+				Name:        "Updated Product",
+				Description: "Updated by benchmark tool",
+				Price:       149.99,
+				Quantity:    200,
+			}
+		},
+	})
+	RegisterOperation(productOperation{
+		name:    string(DeleteProduct),
+		byID:    true,
+		method:  "DELETE",
+		path:    productPath,
+		deletes: true,
+	})
+
+	RegisterScenario(Scenario{
+		Name:        string(MixedOperations),
+		Description: "Full CRUD cycle: CREATE -> GET -> UPDATE -> DELETE",
+		Steps: []string{
+			string(CreateProduct),
+			string(GetProductByID),
+			string(UpdateProduct),
+			string(DeleteProduct),
+		},
+	})
+}
+
+// productOperation is an operation of the /api/products contract
+type productOperation struct {
+	name    string
+	byID    bool // Addresses the product in state
+	method  string
+	path    func(state *State) string
+	body    func(state *State) interface{}           // JSON request body (optional)
+	extract func(resp *Response, state *State) error // State extraction (optional)
+	creates bool                                     // Creates the product in state, tracked for teardown
+	deletes bool                                     // Deletes the product in state
+}
+
+func (op productOperation) Name() string {
+	return op.name
+}
 
-	var err error
-	var statusCode int
-	var responseBody string
-
-	// Use ProductID from context, fallback to FixedProductID if not set
-	productID := int(ctx.ProductID)
-	if productID == 0 {
-		productID = FixedProductID
-	}
-
-	switch task.Type {
-	case GetProducts:
-		statusCode, responseBody, err = getProducts(ctx)
-	case CreateProduct:
-		statusCode, responseBody, err = createProduct(ctx)
-	case GetProductByID:
-		statusCode, responseBody, err = getProductByID(ctx, productID)
-	case UpdateProduct:
-		statusCode, responseBody, err = updateProduct(ctx, productID)
-	case DeleteProduct:
-		statusCode, responseBody, err = deleteProduct(ctx, productID)
-	default:
-		err = fmt.Errorf("unknown benchmark type: %s", task.Type)
-		statusCode = 0
-		responseBody = ""
+func (op productOperation) UsesProductID() bool {
+	return op.byID
+}
+
+func (op productOperation) BuildRequest(ctx *RequestContext, state *State) (*http.Request, error) {
+	var body []byte
+	if op.body != nil {
+		var err error
+		body, err = json.Marshal(op.body(state))
+		if err != nil {
+			return nil, err
+		}
 	}
+	return newRequest(op.method, ctx.Config.URL+op.path(state), body)
+}
 
-	latency := time.Since(start)
+func (op productOperation) ClassifyResponse(resp *Response) error {
+	return classifyServerErrors(resp)
+}
 
-	// Record error if any
-	if err != nil {
-		operation := fmt.Sprintf("%s %s", getHTTPMethod(task.Type), getEndpoint(task.Type, productID))
-		ctx.ErrorStats.RecordError(operation, "request_error", err.Error(), statusCode, responseBody)
+func (op productOperation) ExtractState(resp *Response, state *State) error {
+	if op.extract == nil {
+		return nil
 	}
+	return op.extract(resp, state)
+}
 
-	return latency, err
+func (op productOperation) ExtractsState() bool {
+	return op.extract != nil
 }
 
-// getProducts performs GET /api/products
-func getProducts(ctx *RequestContext) (int, string, error) {
-	url := ctx.Config.URL + "/api/products"
-	return doRequest(ctx.Client, "GET", url, nil)
+func (op productOperation) CreatedResource(ctx *RequestContext, resp *Response, state *State) string {
+	if !op.creates || resp.StatusCode != http.StatusCreated {
+		return ""
+	}
+	return ctx.Config.URL + productPath(state)
 }
 
-// createProduct performs POST /api/products
-func createProduct(ctx *RequestContext) (int, string, error) {
-	url := ctx.Config.URL + "/api/products"
-	product := Product{
-		Name:        "Benchmark Product",
-		Description: "Created by benchmark tool",
-		Price:       99.99,
-		Quantity:    100,
+func (op productOperation) DeletedResource(ctx *RequestContext, resp *Response, state *State) string {
+	if !op.deletes || (resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound) {
+		return ""
 	}
-	body, err := json.Marshal(product)
-	if err != nil {
-		return 0, "", err
+	return ctx.Config.URL + productPath(state)
+}
+
+// productPath returns /api/products/{id} for the product in state
+func productPath(state *State) string {
+	return fmt.Sprintf("/api/products/%d", state.ProductID)
+}
+
+// extractCreatedProductID stores ID of the product created by POST /api/products
+func extractCreatedProductID(resp *Response, state *State) error {
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to create product: status=%d", resp.StatusCode)
+	}
+
+	var createdProduct Product
+	if err := json.Unmarshal(resp.Body, &createdProduct); err != nil {
+		return fmt.Errorf("failed to parse created product: %v", err)
+	}
+
+	state.ProductID = createdProduct.ID
+	return nil
+}
+
+// classifyServerErrors considers 2xx and 4xx as "successful" request (we reached the API)
+// We only care about network/server errors
+func classifyServerErrors(resp *Response) error {
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("server error: %d", resp.StatusCode)
 	}
-	return doRequest(ctx.Client, "POST", url, body)
+	return nil
 }
 
-// createProductAndGetID performs POST /api/products and extracts the ID from response
-func createProductAndGetID(ctx *RequestContext) (int64, error) {
-	url := ctx.Config.URL + "/api/products"
-	product := Product{
-		Name:        "Benchmark Product",
-		Description: "Created by benchmark tool",
-		Price:       99.99,
-		Quantity:    100,
+// executeRequest performs a single operation and returns response, latency and error.
+// Errors are recorded in ErrorStats
+func executeRequest(ctx *RequestContext, op Operation, state *State) (*Response, time.Duration, error) {
+	req, err := op.BuildRequest(ctx, state)
+	if errors.Is(err, errSkipStep) {
+		return nil, 0, err
 	}
-	body, err := json.Marshal(product)
 	if err != nil {
-		return 0, err
+		ctx.ErrorStats.RecordError(op.Name(), errorType(err, "build_error"), err.Error(), 0, "")
+		return nil, 0, err
 	}
 
-	statusCode, responseBody, err := doRequest(ctx.Client, "POST", url, body)
-	if err != nil || statusCode != 201 {
-		return 0, fmt.Errorf("failed to create product: status=%d, error=%v", statusCode, err)
+	operation := req.Method + " " + normalizeOperationPath(req.URL.Path)
+
+	span := ctx.Tracer.StartSpan(operation, spanKindClient, state.span)
+	span.Inject(req)
+	span.SetAttr("http.request.method", req.Method)
+	span.SetAttr("url.full", req.URL.String())
+
+	ctx.Live.Begin()
+	start := time.Now()
+	resp, err := doRequest(ctx.Client, req)
+	latency := time.Since(start)
+
+	if err == nil {
+		err = op.ClassifyResponse(resp)
+	}
+	ctx.Live.End(latency, err != nil)
+	event := RequestEvent{Scenario: ctx.Scenario, Step: op.Name(), Operation: operation, Latency: latency}
+	if resp != nil {
+		ctx.BodySizes.Record(operation, len(resp.Body))
+		event.Status, event.Bytes = resp.StatusCode, len(resp.Body)
+	}
+	if err != nil {
+		event.Error = errorType(err, "request_error")
 	}
+	ctx.Events.Record(start, event)
 
-	var createdProduct Product
-	if err := json.Unmarshal([]byte(responseBody), &createdProduct); err != nil {
-		return 0, fmt.Errorf("failed to parse created product: %v", err)
+	slow := SlowRequest{Operation: operation, Start: start, Latency: latency}
+	if productOp, ok := op.(ProductOperation); ok && productOp.UsesProductID() {
+		slow.ProductID = state.ProductID
+	}
+	if resp != nil {
+		slow.StatusCode = resp.StatusCode
+	}
+	ctx.Slowest.Record(slow)
+
+	if resp != nil {
+		span.SetAttr("http.response.status_code", resp.StatusCode)
 	}
+	span.Finish(err)
 
-	return createdProduct.ID, nil
+	// Record error if any
+	if err != nil {
+		sample := ErrorSample{
+			Latency:   latency,
+			RequestID: req.Header.Get("X-Request-Id"),
+		}
+		if sample.RequestID == "" && span != nil {
+			sample.RequestID = hex.EncodeToString(span.TraceID[:])
+		}
+		if resp != nil {
+			sample.StatusCode, sample.Body = resp.StatusCode, string(resp.Body)
+			sample.Headers = make(map[string]string, len(resp.Header))
+			for name := range resp.Header {
+				sample.Headers[name] = resp.Header.Get(name)
+			}
+			if id := resp.Header.Get("X-Request-Id"); id != "" {
+				sample.RequestID = id
+			}
+		}
+		ctx.ErrorStats.RecordErrorSample(operation, errorType(err, "request_error"), err.Error(), sample)
+	}
+
+	return resp, latency, err
 }
 
-// getProductByID performs GET /api/products/{id}
-func getProductByID(ctx *RequestContext, id int) (int, string, error) {
-	url := fmt.Sprintf("%s/api/products/%d", ctx.Config.URL, id)
-	return doRequest(ctx.Client, "GET", url, nil)
+// errorType returns ErrorStats type of err: assertion and script errors have their own types
+func errorType(err error, def string) string {
+	var assertionErr *AssertionError
+	if errors.As(err, &assertionErr) {
+		return "assertion_error"
+	}
+	var scriptErr *ScriptError
+	if errors.As(err, &scriptErr) {
+		return "script_error"
+	}
+	return def
 }
 
-// updateProduct performs PUT /api/products/{id}
-func updateProduct(ctx *RequestContext, id int) (int, string, error) {
-	url := fmt.Sprintf("%s/api/products/%d", ctx.Config.URL, id)
-	product := Product{
-		ID:          int64(id),
-		Name:        "Updated Product",
-		Description: "Updated by benchmark tool",
-		Price:       149.99,
-		Quantity:    200,
+// executeStep performs a scenario step: executes operation and extracts state for the next steps.
+// Returns false if the rest of the iteration can't be executed, errSkipStep if the step was skipped
+func executeStep(ctx *RequestContext, op Operation, state *State, last bool) (time.Duration, bool, error) {
+	resp, latency, err := executeRequest(ctx, op, state)
+	if errors.Is(err, errSkipStep) {
+		return 0, true, err
+	}
+	if resp == nil {
+		return latency, false, err
 	}
-	body, err := json.Marshal(product)
+	// The failure is already recorded, a failed response has nothing to extract
 	if err != nil {
-		return 0, "", err
+		stateful, ok := op.(StatefulOperation)
+		return latency, last || (ok && !stateful.ExtractsState()), err
+	}
+
+	if extractErr := op.ExtractState(resp, state); extractErr != nil {
+		// Nothing depends on the state of the last step unless it stores some: captures are
+		// shared with other workers, so their errors are reported like script errors
+		var scriptErr *ScriptError
+		if stateful, ok := op.(StatefulOperation); last && ok && !stateful.ExtractsState() && !errors.As(extractErr, &scriptErr) {
+			return latency, true, nil
+		}
+		ctx.ErrorStats.RecordError(op.Name(), errorType(extractErr, "extract_error"), extractErr.Error(), resp.StatusCode, string(resp.Body))
+		return latency, false, extractErr
 	}
-	return doRequest(ctx.Client, "PUT", url, body)
-}
 
-// deleteProduct performs DELETE /api/products/{id}
-func deleteProduct(ctx *RequestContext, id int) (int, string, error) {
-	url := fmt.Sprintf("%s/api/products/%d", ctx.Config.URL, id)
-	return doRequest(ctx.Client, "DELETE", url, nil)
+	ctx.Created.track(ctx, op, resp, state)
+
+	// Share captured variables with other workers
+	if ctx.Vars != nil {
+		for _, name := range state.captured {
+			ctx.Vars.Set(name, state.Vars[name])
+		}
+	}
+	state.captured = state.captured[:0]
+
+	return latency, true, nil
 }
 
-// doRequest is a helper function to perform HTTP request
-func doRequest(client *http.Client, method, url string, body []byte) (int, string, error) {
+// newRequest creates HTTP request with optional JSON body
+func newRequest(method, url string, body []byte) (*http.Request, error) {
 	var reqBody io.Reader
 	if body != nil {
 		reqBody = bytes.NewReader(body)
@@ -144,63 +308,32 @@ func doRequest(client *http.Client, method, url string, body []byte) (int, strin
 
 	req, err := http.NewRequest(method, url, reqBody)
 	if err != nil {
-		return 0, "", err
+		return nil, err
 	}
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
+	return req, nil
+}
+
+// doRequest is a helper function to perform HTTP request and read the whole response
+func doRequest(client *http.Client, req *http.Request) (*Response, error) {
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return resp.StatusCode, "", err
+		return &Response{StatusCode: resp.StatusCode, Header: resp.Header}, err
 	}
 
-	// Consider 2xx and 4xx as "successful" request (we reached the API)
-	// We only care about network/server errors
-	if resp.StatusCode >= 500 {
-		return resp.StatusCode, string(responseBody), fmt.Errorf("server error: %d", resp.StatusCode)
-	}
-
-	return resp.StatusCode, string(responseBody), nil
-}
-
-// getHTTPMethod returns HTTP method for benchmark type
-func getHTTPMethod(benchType BenchmarkType) string {
-	switch benchType {
-	case GetProducts, GetProductByID:
-		return "GET"
-	case CreateProduct:
-		return "POST"
-	case UpdateProduct:
-		return "PUT"
-	case DeleteProduct:
-		return "DELETE"
-	default:
-		return "UNKNOWN"
-	}
-}
-
-// getEndpoint returns endpoint for benchmark type
-func getEndpoint(benchType BenchmarkType, productID int) string {
-	switch benchType {
-	case GetProducts:
-		return "/api/products"
-	case CreateProduct:
-		return "/api/products"
-	case GetProductByID:
-		return fmt.Sprintf("/api/products/%d", productID)
-	case UpdateProduct:
-		return fmt.Sprintf("/api/products/%d", productID)
-	case DeleteProduct:
-		return fmt.Sprintf("/api/products/%d", productID)
-	default:
-		return "/unknown"
-	}
-}
\ No newline at end of file
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       responseBody,
+	}, nil
+}
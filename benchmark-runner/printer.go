@@ -3,21 +3,26 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // printResults prints the benchmark results in a formatted table and JSON
 func printResults(r *Result, verbose bool) {
-	// For mixed operations, calculate total HTTP requests
-	// Success cycles = 4 HTTP requests each
-	// Failed cycles counted as cycles (partial completion)
+	// For multi-step scenarios, calculate total HTTP requests
+	// from the per-step statistics (failed cycles may be partial)
 	totalHTTPRequests := r.TotalRequests
 	actualRPS := float64(r.TotalRequests) / r.TotalDuration.Seconds()
 
-	if r.BenchmarkType == MixedOperations {
-		// Successful cycles: 4 HTTP requests each
-		// Failed cycles: counted as partial, not multiplied
-		totalHTTPRequests = r.SuccessRequests*4 + r.FailedRequests
+	isCycle := r.StepsPerCycle > 1
+	if isCycle {
+		totalHTTPRequests = 0
+		for _, step := range r.Steps {
+			totalHTTPRequests += step.Requests
+		}
 		actualRPS = float64(totalHTTPRequests) / r.TotalDuration.Seconds()
 	}
 
@@ -26,14 +31,27 @@ func printResults(r *Result, verbose bool) {
 	fmt.Println("                      BENCHMARK RESULTS")
 	fmt.Println("════════════════════════════════════════════════════════════════")
 
-	if r.BenchmarkType == MixedOperations {
-		fmt.Printf("CRUD Cycles:      %d\n", r.TotalRequests)
+	if isCycle {
+		fmt.Printf("Cycles:           %d\n", r.TotalRequests)
 		fmt.Printf("  Success:        %d cycles (%.2f%%)\n", r.SuccessRequests, float64(r.SuccessRequests)/float64(r.TotalRequests)*100)
 		fmt.Printf("  Failed:         %d cycles (%.2f%%)\n", r.FailedRequests, float64(r.FailedRequests)/float64(r.TotalRequests)*100)
-		fmt.Printf("Total HTTP Reqs:  ~%d\n", totalHTTPRequests)
+		fmt.Printf("Total HTTP Reqs:  %d\n", totalHTTPRequests)
 		fmt.Printf("Duration:         %s\n", r.TotalDuration)
 		fmt.Printf("Actual RPS:       %.2f req/s\n", actualRPS)
 		fmt.Printf("Cycles/sec:       %.2f cycles/s\n", float64(r.TotalRequests)/r.TotalDuration.Seconds())
+	} else if r.BenchmarkType == UserSession {
+		fmt.Printf("Virtual Users:    %d\n", r.VirtualUsers)
+		fmt.Printf("Sessions:         %d\n", r.Sessions)
+		if r.Sessions > 0 {
+			fmt.Printf("  Failed:         %d sessions (%.2f%%)\n", r.FailedSessions, float64(r.FailedSessions)/float64(r.Sessions)*100)
+		}
+		fmt.Printf("Total Requests:   %d\n", r.TotalRequests)
+		fmt.Printf("Success:          %d (%.2f%%)\n", r.SuccessRequests, float64(r.SuccessRequests)/float64(r.TotalRequests)*100)
+		fmt.Printf("Failed:           %d (%.2f%%)\n", r.FailedRequests, float64(r.FailedRequests)/float64(r.TotalRequests)*100)
+		fmt.Printf("Duration:         %s\n", r.TotalDuration)
+		fmt.Printf("Actual RPS:       %.2f req/s\n", actualRPS)
+		fmt.Printf("Sessions/sec:     %.2f sessions/s\n", float64(r.Sessions)/r.TotalDuration.Seconds())
+		fmt.Printf("Avg Session:      %s (including think time)\n", r.AvgSessionDuration)
 	} else {
 		fmt.Printf("Total Requests:   %d\n", r.TotalRequests)
 		fmt.Printf("Success:          %d (%.2f%%)\n", r.SuccessRequests, float64(r.SuccessRequests)/float64(r.TotalRequests)*100)
@@ -42,10 +60,30 @@ func printResults(r *Result, verbose bool) {
 		fmt.Printf("Actual RPS:       %.2f req/s\n", actualRPS)
 	}
 
+	if len(r.Scenarios) > 0 {
+		printScenarios(r.Scenarios)
+	}
+
+	if r.WorkerPoolSize > 0 {
+		fmt.Println("")
+		fmt.Println("Workers:")
+		fmt.Printf("  Initial:        %d\n", r.Concurrency)
+		fmt.Printf("  Final Pool:     %d (max %d)\n", r.WorkerPoolSize, r.MaxConcurrency)
+		fmt.Printf("  Peak Busy:      %d\n", r.PeakConcurrency)
+		if r.SaturatedTicks > 0 {
+			fmt.Printf("  WARNING: worker pool reached -max-concurrency=%d and the queue backed up %d times,\n", r.MaxConcurrency, r.SaturatedTicks)
+			fmt.Println("           target RPS was capped by the runner. Increase -max-concurrency.")
+		}
+	}
+
 	fmt.Println("")
 	fmt.Println("Latency:")
-	if r.BenchmarkType == MixedOperations {
-		fmt.Println("  (Full CRUD cycle: CREATE->GET->UPDATE->DELETE)")
+	if isCycle {
+		names := make([]string, 0, len(r.Steps))
+		for _, step := range r.Steps {
+			names = append(names, step.Name)
+		}
+		fmt.Printf("  (Full cycle: %s)\n", strings.Join(names, " -> "))
 	}
 	fmt.Printf("  Min:            %s\n", r.MinLatency)
 	fmt.Printf("  Avg:            %s\n", r.AvgLatency)
@@ -54,6 +92,87 @@ func printResults(r *Result, verbose bool) {
 	fmt.Printf("  P95:            %s\n", r.P95Latency)
 	fmt.Printf("  P99:            %s\n", r.P99Latency)
 
+	// Print per-step statistics for sessions and multi-step scenarios
+	if len(r.Steps) > 0 {
+		fmt.Println("")
+		fmt.Println("Per-Step Latency:")
+		fmt.Println("┌────────────────────┬──────────┬────────┬──────────┬────────────┬────────────┬────────────┬────────────┐")
+		fmt.Println("│        STEP        │ REQUESTS │ FAILED │  REQ/S   │    AVG     │    P50     │    P95     │    P99     │")
+		fmt.Println("├────────────────────┼──────────┼────────┼──────────┼────────────┼────────────┼────────────┼────────────┤")
+		for _, step := range r.Steps {
+			fmt.Printf("│ %-18s │ %8d │ %6d │ %8.2f │ %10s │ %10s │ %10s │ %10s │\n",
+				truncateString(step.Name, 18), step.Requests, step.Failed,
+				float64(step.Requests)/r.TotalDuration.Seconds(),
+				step.AvgLatency.Round(time.Microsecond), step.P50Latency.Round(time.Microsecond),
+				step.P95Latency.Round(time.Microsecond), step.P99Latency.Round(time.Microsecond))
+		}
+		fmt.Println("└────────────────────┴──────────┴────────┴──────────┴────────────┴────────────┴────────────┴────────────┘")
+	}
+
+	// Print latency distribution and the slowest requests
+	if len(r.Latencies) > 0 {
+		printLatencyDistribution(r.Latencies)
+	}
+	if len(r.SlowestRequests) > 0 {
+		printSlowestRequests(r.SlowestRequests, r.StartTime)
+	}
+
+	// Print time to steady state and the warm-up penalty
+	if r.WarmUp != nil {
+		printWarmUp(r.WarmUp)
+	}
+
+	// Print runner self-monitoring
+	if r.Runner != nil {
+		printRunnerStats(r.Runner)
+	}
+
+	// Print time spent in scripts
+	for _, script := range r.Scripts {
+		fmt.Println("")
+		fmt.Printf("Script %s:\n", script.Name)
+		fmt.Printf("  Calls:          %d, %d errors\n", script.Calls, script.Errors)
+		fmt.Printf("  Time:           avg %s per call\n", script.AvgTime.Round(100*time.Nanosecond))
+	}
+
+	// Print target's own metrics
+	if r.Server != nil {
+		printServerStats(r.Server, r)
+	}
+
+	// Print target containers' resource usage
+	if r.Resources != nil {
+		printResourceStats(r.Resources)
+	}
+
+	// Print fault windows of the fault-injection proxy
+	if r.Proxy != nil {
+		printFaultImpact(r)
+	}
+
+	// Print response sizes and the teardown of created resources
+	if len(r.BodySizes) > 0 {
+		printBodySizes(r.BodySizes)
+	}
+	if r.Cleanup != nil && r.Cleanup.Created > 0 {
+		printCleanup(r.Cleanup)
+	}
+
+	// Print where the per-request events were written
+	if r.Events != nil {
+		printEventLog(r.Events)
+	}
+
+	// Print slowest traced requests
+	if len(r.SlowestTraces) > 0 {
+		fmt.Println("")
+		fmt.Println("Slowest Traced Requests:")
+		for _, trace := range r.SlowestTraces {
+			fmt.Printf("  %12s  %3d  %-32s  trace_id=%s\n",
+				trace.Duration.Round(time.Microsecond), trace.StatusCode, truncateString(trace.Name, 32), trace.TraceID)
+		}
+	}
+
 	// Print error statistics
 	if r.Errors != nil && r.Errors.GetTotalCount() > 0 {
 		fmt.Println("")
@@ -80,6 +199,8 @@ func printResults(r *Result, verbose bool) {
 		}
 		fmt.Println("└─────────┴────────────────────────────────┴──────────────┴────────────────────────────────────────┘")
 
+		printErrorTimelines(sortedErrors, r.TotalDuration)
+
 		// Detailed output with response bodies (if verbose)
 		if verbose {
 			fmt.Println("")
@@ -100,14 +221,54 @@ func printResults(r *Result, verbose bool) {
 				if err.SampleBody != "" {
 					fmt.Printf("    Body:    %s\n", err.SampleBody)
 				}
+				samples := append([]ErrorSample(nil), err.Samples...)
+				sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+				for j, sample := range samples {
+					fmt.Printf("    Sample %d: +%s, latency %s, status %d",
+						j+1, sample.Time.Sub(r.Errors.Start).Round(time.Millisecond), sample.Latency.Round(time.Microsecond), sample.StatusCode)
+					if sample.RequestID != "" {
+						fmt.Printf(", request %s", sample.RequestID)
+					}
+					fmt.Println("")
+					for _, name := range sortedKeys(sample.Headers) {
+						fmt.Printf("      %s: %s\n", name, sample.Headers[name])
+					}
+					if sample.Body != "" {
+						fmt.Printf("      Body: %s\n", sample.Body)
+					}
+				}
 			}
 		}
 	}
 
+	// Print SLO assertions
+	if len(r.Assertions) > 0 {
+		fmt.Println("")
+		fmt.Println("SLO Assertions:")
+		for _, a := range r.Assertions {
+			status := "PASS"
+			if !a.Passed {
+				status = "FAIL"
+			}
+			fmt.Printf("  %s  %-28s actual %s, threshold %s\n", status, a.Spec, a.Actual, a.Threshold)
+		}
+	}
+
 	fmt.Println("")
 	fmt.Println("════════════════════════════════════════════════════════════════")
 
-	// JSON results
+	jsonResult, _ := json.MarshalIndent(resultJSON(r), "", "  ")
+	fmt.Println("")
+	fmt.Println("JSON Results:")
+	fmt.Println(string(jsonResult))
+}
+
+// resultJSON builds JSON results of the run
+func resultJSON(r *Result) map[string]interface{} {
+	totalHTTPRequests := httpRequests(r)
+	actualRPS := float64(totalHTTPRequests) / r.TotalDuration.Seconds()
+	isCycle := r.StepsPerCycle > 1
+
 	errorList := make([]map[string]interface{}, 0)
 	if r.Errors != nil {
 		for _, err := range r.Errors.GetSortedErrors() {
@@ -119,6 +280,8 @@ func printResults(r *Result, verbose bool) {
 				"statusCode": err.StatusCode,
 				"firstSeen":  err.FirstSeen.Format(time.RFC3339),
 				"lastSeen":   err.LastSeen.Format(time.RFC3339),
+				"timeline":   err.Timeline,
+				"samples":    errorSamplesJSON(err.Samples, r.Errors.Start),
 			})
 		}
 	}
@@ -140,13 +303,24 @@ func printResults(r *Result, verbose bool) {
 		},
 	}
 
-	if r.BenchmarkType == MixedOperations {
+	if isCycle {
 		jsonData["crud_cycles"] = r.TotalRequests
 		jsonData["success_cycles"] = r.SuccessRequests
 		jsonData["failed_cycles"] = r.FailedRequests
 		jsonData["total_http_requests"] = totalHTTPRequests
+		jsonData["failed_http_requests"] = httpFailed(r)
 		jsonData["rps"] = actualRPS
 		jsonData["cycles_per_second"] = float64(r.TotalRequests) / r.TotalDuration.Seconds()
+	} else if r.BenchmarkType == UserSession {
+		jsonData["virtual_users"] = r.VirtualUsers
+		jsonData["sessions"] = r.Sessions
+		jsonData["failed_sessions"] = r.FailedSessions
+		jsonData["sessions_per_second"] = float64(r.Sessions) / r.TotalDuration.Seconds()
+		jsonData["avg_session_duration"] = r.AvgSessionDuration.String()
+		jsonData["total_requests"] = r.TotalRequests
+		jsonData["success_requests"] = r.SuccessRequests
+		jsonData["failed_requests"] = r.FailedRequests
+		jsonData["rps"] = actualRPS
 	} else {
 		jsonData["total_requests"] = r.TotalRequests
 		jsonData["success_requests"] = r.SuccessRequests
@@ -154,8 +328,677 @@ func printResults(r *Result, verbose bool) {
 		jsonData["rps"] = actualRPS
 	}
 
-	jsonResult, _ := json.MarshalIndent(jsonData, "", "  ")
+	if r.WorkerPoolSize > 0 {
+		jsonData["workers"] = map[string]interface{}{
+			"initial":         r.Concurrency,
+			"max":             r.MaxConcurrency,
+			"final":           r.WorkerPoolSize,
+			"peak_busy":       r.PeakConcurrency,
+			"saturated_ticks": r.SaturatedTicks,
+		}
+	}
+
+	if r.Runner != nil {
+		jsonData["runner"] = map[string]interface{}{
+			"cpu_cores":       r.Runner.CPUCores,
+			"avg_cpu_percent": r.Runner.AvgCPUPercent,
+			"max_cpu_percent": r.Runner.MaxCPUPercent,
+			"max_goroutines":  r.Runner.MaxGoroutines,
+			"max_heap_mb":     r.Runner.MaxHeapMB,
+			"gc_count":        r.Runner.GCCount,
+			"gc_pause_total":  r.Runner.GCPauseTotal.String(),
+			"gc_pause_max":    r.Runner.GCPauseMax.String(),
+			"avg_queue_depth": r.Runner.AvgQueueDepth,
+			"max_queue_depth": r.Runner.MaxQueueDepth,
+			"warnings":        r.Runner.Warnings,
+		}
+	}
+
+	if len(r.TimeSeries) > 0 {
+		series := make([]map[string]interface{}, 0, len(r.TimeSeries))
+		for _, point := range r.TimeSeries {
+			series = append(series, map[string]interface{}{
+				"second":    point.Second,
+				"requests":  point.Requests,
+				"errors":    point.Errors,
+				"in_flight": point.InFlight,
+				"avg_ms":    durationMs(point.AvgLatency),
+				"p50_ms":    durationMs(point.P50Latency),
+				"p95_ms":    durationMs(point.P95Latency),
+				"p99_ms":    durationMs(point.P99Latency),
+				"max_ms":    durationMs(point.MaxLatency),
+			})
+			if point.Fault != "" {
+				series[len(series)-1]["fault"] = point.Fault
+			}
+		}
+		jsonData["time_series"] = series
+	}
+
+	if len(r.Latencies) > 0 {
+		buckets := latencyHistogram(r.Latencies, histogramBuckets)
+		histogram := make([]map[string]interface{}, 0, len(buckets))
+		for _, bucket := range buckets {
+			histogram = append(histogram, map[string]interface{}{
+				"from":  bucket.From.String(),
+				"to":    bucket.To.String(),
+				"count": bucket.Count,
+			})
+		}
+		jsonData["histogram"] = histogram
+	}
+
+	if r.WarmUp != nil {
+		w := r.WarmUp
+		jsonData["warm_up"] = map[string]interface{}{
+			"window_seconds":            w.Window.Seconds(),
+			"tolerance":                 w.Tolerance,
+			"steady_p95_ms":             durationMs(w.SteadyP95),
+			"steady_rps":                w.SteadyRPS,
+			"steady_state_seconds":      w.SteadyState.Seconds(),
+			"latency_steady_seconds":    w.LatencySteady.Seconds(),
+			"throughput_steady_seconds": w.ThroughputSteady.Seconds(),
+			"reached":                   w.Reached,
+			"penalty_window_seconds":    w.PenaltyWindow.Seconds(),
+			"warmup_requests":           w.WarmUpRequests,
+			"warmup_avg_ms":             durationMs(w.WarmUpAvg),
+			"steady_avg_ms":             durationMs(w.SteadyAvg),
+			"warmup_avg_p99_ms":         durationMs(w.WarmUpP99),
+			"steady_avg_p99_ms":         durationMs(w.SteadyP99),
+			"penalty_ms":                durationMs(w.Penalty),
+			"penalty_percent":           w.PenaltyPercent(),
+			"extra_latency_seconds":     w.ExtraLatency.Seconds(),
+		}
+	}
+
+	if len(r.SlowestRequests) > 0 {
+		slowest := make([]map[string]interface{}, 0, len(r.SlowestRequests))
+		for _, request := range r.SlowestRequests {
+			slow := map[string]interface{}{
+				"operation":   request.Operation,
+				"status_code": request.StatusCode,
+				"latency":     request.Latency.String(),
+				"offset":      request.Start.Sub(r.StartTime).String(),
+			}
+			if request.ProductID != 0 {
+				slow["product_id"] = request.ProductID
+			}
+			slowest = append(slowest, slow)
+		}
+		jsonData["slowest_requests"] = slowest
+	}
+
+	if len(r.Assertions) > 0 {
+		assertionList := make([]map[string]interface{}, 0, len(r.Assertions))
+		for _, a := range r.Assertions {
+			assertionList = append(assertionList, map[string]interface{}{
+				"assertion": a.Spec,
+				"passed":    a.Passed,
+				"actual":    a.Actual,
+				"threshold": a.Threshold,
+			})
+		}
+		jsonData["assertions"] = assertionList
+	}
+
+	if r.Server != nil {
+		jsonData["server"] = map[string]interface{}{
+			"metrics_url":    r.Server.MetricsURL,
+			"scrapes":        r.Server.Scrapes,
+			"scrape_errors":  r.Server.ScrapeErrors,
+			"requests":       r.Server.Requests,
+			"error_requests": r.Server.ErrorRequests,
+			"latency": map[string]string{
+				"avg": r.Server.AvgLatency.String(),
+				"p50": r.Server.P50Latency.String(),
+				"p95": r.Server.P95Latency.String(),
+				"p99": r.Server.P99Latency.String(),
+			},
+			"cpu_seconds":      r.Server.CPUSeconds,
+			"avg_cpu_cores":    r.Server.AvgCPUCores,
+			"memory_start_mb":  r.Server.MemoryStartMB,
+			"memory_end_mb":    r.Server.MemoryEndMB,
+			"memory_max_mb":    r.Server.MemoryMaxMB,
+			"db_pool_max":      r.Server.DBPoolMax,
+			"db_pool_max_used": r.Server.DBPoolMaxUsed,
+			"db_pool_in_use":   r.Server.DBPoolInUseMax,
+			"db_pool_open":     r.Server.DBPoolOpenMax,
+			"db_pool_waits":    r.Server.DBPoolWaits,
+		}
+	}
+
+	if r.Resources != nil {
+		jsonData["resources"] = map[string]interface{}{
+			"prometheus_url":    r.Resources.PrometheusURL,
+			"selector":          r.Resources.Selector,
+			"pods":              r.Resources.Pods,
+			"avg_cpu_cores":     r.Resources.AvgCPUCores,
+			"max_cpu_cores":     r.Resources.MaxCPUCores,
+			"avg_memory_mb":     r.Resources.AvgMemoryMB,
+			"max_memory_mb":     r.Resources.MaxMemoryMB,
+			"requests_per_core": r.Resources.RequestsPerCore,
+			"mb_per_1k_rps":     r.Resources.MBPer1kRPS,
+			"errors":            r.Resources.Errors,
+		}
+	}
+
+	if r.Proxy != nil {
+		faultList := make([]map[string]interface{}, 0, len(r.Proxy.Faults))
+		for _, f := range r.Proxy.Faults {
+			faultList = append(faultList, map[string]interface{}{
+				"spec":   f.Spec,
+				"fault":  f.String(),
+				"start":  f.Start.String(),
+				"end":    f.End.String(),
+				"resets": f.Resets,
+				"held":   f.Held,
+			})
+		}
+		impactList := make([]map[string]interface{}, 0)
+		for _, impact := range faultImpact(r.TimeSeries, r.StartTime, r.Proxy) {
+			impactList = append(impactList, map[string]interface{}{
+				"window":     impact.Label,
+				"fault":      impact.Fault,
+				"seconds":    impact.Seconds,
+				"rps":        impact.RPS,
+				"avg_p99_ms": durationMs(impact.AvgP99),
+				"requests":   impact.Requests,
+				"errors":     impact.Errors,
+			})
+		}
+		jsonData["proxy"] = map[string]interface{}{
+			"listen":      r.Proxy.Listen,
+			"upstream":    r.Proxy.Upstream,
+			"connections": r.Proxy.Connections,
+			"dial_errors": r.Proxy.DialErrors,
+			"faults":      faultList,
+			"impact":      impactList,
+		}
+	}
+
+	if len(r.Scripts) > 0 {
+		scriptList := make([]map[string]interface{}, 0, len(r.Scripts))
+		for _, script := range r.Scripts {
+			scriptList = append(scriptList, map[string]interface{}{
+				"name":        script.Name,
+				"calls":       script.Calls,
+				"errors":      script.Errors,
+				"avg_call_us": float64(script.AvgTime) / float64(time.Microsecond),
+			})
+		}
+		jsonData["scripts"] = scriptList
+	}
+
+	if len(r.BodySizes) > 0 {
+		sizeList := make([]map[string]interface{}, 0, len(r.BodySizes))
+		for _, size := range r.BodySizes {
+			sizeList = append(sizeList, map[string]interface{}{
+				"operation":       size.Operation,
+				"start_avg_bytes": size.FirstAvg,
+				"end_avg_bytes":   size.LastAvg,
+				"max_bytes":       size.Max,
+				"growth":          size.Growth,
+				"avg_bytes":       size.Series,
+			})
+		}
+		jsonData["response_sizes"] = sizeList
+	}
+
+	if r.Cleanup != nil {
+		jsonData["cleanup"] = map[string]interface{}{
+			"enabled":     r.Cleanup.Enabled,
+			"created":     r.Cleanup.Created,
+			"left":        r.Cleanup.Left,
+			"deleted":     r.Cleanup.Deleted,
+			"failed":      r.Cleanup.Failed,
+			"duration_ms": durationMs(r.Cleanup.Duration),
+			"errors":      r.Cleanup.Errors,
+		}
+	}
+
+	if r.Events != nil {
+		eventLog := map[string]interface{}{
+			"path":    r.Events.Path,
+			"format":  r.Events.Format,
+			"events":  r.Events.Events,
+			"dropped": r.Events.Dropped,
+			"bytes":   r.Events.Bytes,
+		}
+		if r.Events.Error != "" {
+			eventLog["error"] = r.Events.Error
+		}
+		jsonData["event_log"] = eventLog
+	}
+
+	if len(r.SlowestTraces) > 0 {
+		traceList := make([]map[string]interface{}, 0, len(r.SlowestTraces))
+		for _, trace := range r.SlowestTraces {
+			traceList = append(traceList, map[string]interface{}{
+				"trace_id":    trace.TraceID,
+				"operation":   trace.Name,
+				"duration":    trace.Duration.String(),
+				"status_code": trace.StatusCode,
+				"start":       trace.Start.Format(time.RFC3339Nano),
+			})
+		}
+		jsonData["slowest_traces"] = traceList
+	}
+
+	if len(r.Scenarios) > 0 {
+		scenarioList := make([]map[string]interface{}, 0, len(r.Scenarios))
+		for _, scenario := range r.Scenarios {
+			scenarioList = append(scenarioList, scenarioJSON(scenario))
+		}
+		jsonData["scenarios"] = scenarioList
+	}
+
+	if len(r.Steps) > 0 {
+		jsonData["steps"] = stepsJSON(r)
+	}
+
+	return jsonData
+}
+
+// stepsJSON builds JSON of per-step statistics
+func stepsJSON(r *Result) []map[string]interface{} {
+	stepList := make([]map[string]interface{}, 0, len(r.Steps))
+	for _, step := range r.Steps {
+		stepList = append(stepList, map[string]interface{}{
+			"name":     step.Name,
+			"requests": step.Requests,
+			"failed":   step.Failed,
+			"rps":      float64(step.Requests) / r.TotalDuration.Seconds(),
+			"latency": map[string]string{
+				"min": step.MinLatency.String(),
+				"avg": step.AvgLatency.String(),
+				"max": step.MaxLatency.String(),
+				"p50": step.P50Latency.String(),
+				"p95": step.P95Latency.String(),
+				"p99": step.P99Latency.String(),
+			},
+		})
+	}
+	return stepList
+}
+
+// scenarioJSON builds JSON results of one of the concurrent scenarios
+func scenarioJSON(r *Result) map[string]interface{} {
+	jsonData := map[string]interface{}{
+		"name":            r.Scenario,
+		"type":            r.BenchmarkType,
+		"profile":         r.Profile.String(),
+		"target_rps":      r.TargetRPS,
+		"total_requests":  httpRequests(r),
+		"failed_requests": httpFailed(r),
+		"rps":             float64(httpRequests(r)) / r.TotalDuration.Seconds(),
+		"latency": map[string]string{
+			"min": r.MinLatency.String(),
+			"avg": r.AvgLatency.String(),
+			"max": r.MaxLatency.String(),
+			"p50": r.P50Latency.String(),
+			"p95": r.P95Latency.String(),
+			"p99": r.P99Latency.String(),
+		},
+		"workers": map[string]interface{}{
+			"initial":         r.Concurrency,
+			"max":             r.MaxConcurrency,
+			"final":           r.WorkerPoolSize,
+			"peak_busy":       r.PeakConcurrency,
+			"saturated_ticks": r.SaturatedTicks,
+		},
+	}
+	if r.StepsPerCycle > 1 {
+		jsonData["crud_cycles"] = r.TotalRequests
+		jsonData["failed_cycles"] = r.FailedRequests
+		jsonData["steps"] = stepsJSON(r)
+	}
+	return jsonData
+}
+
+// printScenarios prints results of concurrent scenarios
+func printScenarios(scenarios []*Result) {
 	fmt.Println("")
-	fmt.Println("JSON Results:")
-	fmt.Println(string(jsonResult))
-}
\ No newline at end of file
+	fmt.Println("Scenarios:")
+	fmt.Println("┌────────────────────┬────────────────────┬────────────────────────────┬──────────┬────────┬──────────┬────────────┬────────────┬────────────┐")
+	fmt.Println("│      SCENARIO      │        TYPE        │          PROFILE           │ REQUESTS │ FAILED │  REQ/S   │    AVG     │    P95     │    P99     │")
+	fmt.Println("├────────────────────┼────────────────────┼────────────────────────────┼──────────┼────────┼──────────┼────────────┼────────────┼────────────┤")
+	cycles := false
+	for _, r := range scenarios {
+		profile := fmt.Sprintf("%d/s %s", r.TargetRPS, r.Profile)
+		fmt.Printf("│ %-18s │ %-18s │ %-26s │ %8d │ %6d │ %8.2f │ %10s │ %10s │ %10s │\n",
+			truncateString(r.Scenario, 15), truncateString(string(r.BenchmarkType), 15), truncateString(profile, 23),
+			httpRequests(r), httpFailed(r), float64(httpRequests(r))/r.TotalDuration.Seconds(),
+			r.AvgLatency.Round(time.Microsecond), r.P95Latency.Round(time.Microsecond), r.P99Latency.Round(time.Microsecond))
+		cycles = cycles || r.StepsPerCycle > 1
+	}
+	fmt.Println("└────────────────────┴────────────────────┴────────────────────────────┴──────────┴────────┴──────────┴────────────┴────────────┴────────────┘")
+	if cycles {
+		fmt.Println("  Requests are HTTP requests; latency of multi-step scenarios is per cycle.")
+		fmt.Println("  Combined results above count HTTP requests of all scenarios.")
+	}
+}
+
+// printFaultImpact prints fault windows of the proxy and how the run behaved during them
+func printFaultImpact(r *Result) {
+	fmt.Println("")
+	fmt.Printf("Fault Injection (proxy %s -> %s, %d connections", r.Proxy.Listen, r.Proxy.Upstream, r.Proxy.Connections)
+	if r.Proxy.DialErrors > 0 {
+		fmt.Printf(", %d upstream dial errors", r.Proxy.DialErrors)
+	}
+	fmt.Println("):")
+	fmt.Println("┌──────────────────┬──────────────────────┬─────────┬──────────┬────────────┬──────────┬─────────────────┐")
+	fmt.Println("│      WINDOW      │        FAULT         │ SECONDS │  REQ/S   │  AVG P99   │  ERRORS  │    AFFECTED     │")
+	fmt.Println("├──────────────────┼──────────────────────┼─────────┼──────────┼────────────┼──────────┼─────────────────┤")
+	for i, impact := range faultImpact(r.TimeSeries, r.StartTime, r.Proxy) {
+		affected := ""
+		if i > 0 {
+			f := r.Proxy.Faults[i-1]
+			switch f.Kind {
+			case faultReset:
+				affected = fmt.Sprintf("%d conns reset", f.Resets)
+			case faultBlackhole:
+				affected = fmt.Sprintf("%d conns held", f.Held)
+			}
+		}
+		fmt.Printf("│ %-16s │ %-20s │ %7d │ %8.2f │ %10s │ %8d │ %-15s │\n",
+			truncateString(impact.Label, 13), truncateString(impact.Fault, 17), impact.Seconds, impact.RPS,
+			impact.AvgP99.Round(time.Microsecond), impact.Errors, affected)
+	}
+	fmt.Println("└──────────────────┴──────────────────────┴─────────┴──────────┴────────────┴──────────┴─────────────────┘")
+	fmt.Println("  Seconds are assigned to the window containing their middle; AVG P99 is the average of per-second p99.")
+}
+
+// printWarmUp prints when latency and throughput reached the steady state and the extra latency of the warm-up
+func printWarmUp(w *WarmUpStats) {
+	fmt.Println("")
+	fmt.Printf("Warm-up (steady state: median of the last third, %s rolling window, %.0f%% tolerance):\n", w.Window, w.Tolerance*100)
+	if w.Reached {
+		fmt.Printf("  Steady State:   after %s (latency %s, throughput %s)\n", w.SteadyState, w.LatencySteady, w.ThroughputSteady)
+	} else {
+		fmt.Printf("  Steady State:   not reached before the last third (latency %s, throughput %s)\n", w.LatencySteady, w.ThroughputSteady)
+	}
+	fmt.Printf("  Steady Level:   p95 %s, %.1f req/s\n", w.SteadyP95.Round(time.Microsecond), w.SteadyRPS)
+	if w.PenaltyWindow > 0 {
+		fmt.Printf("  First %-9s avg %s vs %s steady (%s, %+.1f%%), avg p99 %s vs %s\n", w.PenaltyWindow.String()+":",
+			w.WarmUpAvg.Round(time.Microsecond), w.SteadyAvg.Round(time.Microsecond), formatSignedDuration(w.Penalty),
+			w.PenaltyPercent(), w.WarmUpP99.Round(time.Microsecond), w.SteadyP99.Round(time.Microsecond))
+		fmt.Printf("  Extra Latency:  %s over %d requests\n", formatSignedDuration(w.ExtraLatency.Round(time.Millisecond)), w.WarmUpRequests)
+	}
+	if !w.Reached {
+		fmt.Println("  WARNING: latency or throughput was still changing in the last third of the run, run longer")
+	}
+}
+
+// printRunnerStats prints the runner's own resource usage and client-side bottleneck warnings
+func printRunnerStats(rs *RunnerStats) {
+	fmt.Println("")
+	fmt.Println("Runner Health:")
+	fmt.Printf("  CPU:            avg %.0f%%, max %.0f%% (of %d cores = %d%%)\n", rs.AvgCPUPercent, rs.MaxCPUPercent, rs.CPUCores, rs.CPUCores*100)
+	fmt.Printf("  Goroutines:     max %d\n", rs.MaxGoroutines)
+	fmt.Printf("  Heap:           max %.1f MB\n", rs.MaxHeapMB)
+	fmt.Printf("  GC:             %d cycles, total pause %s, max pause %s\n", rs.GCCount, rs.GCPauseTotal, rs.GCPauseMax)
+	fmt.Printf("  Queue Depth:    avg %.1f, max %d\n", rs.AvgQueueDepth, rs.MaxQueueDepth)
+
+	for _, warning := range rs.Warnings {
+		fmt.Printf("  WARNING: %s\n", warning)
+	}
+}
+
+// printServerStats prints statistics computed from the target's metrics next to the client-side view
+func printServerStats(ss *ServerStats, r *Result) {
+	fmt.Println("")
+	fmt.Printf("Server Metrics (%s, %d scrapes):\n", ss.MetricsURL, ss.Scrapes)
+	if ss.Scrapes < 2 {
+		fmt.Printf("  Not enough successful scrapes (%d errors)\n", ss.ScrapeErrors)
+		return
+	}
+
+	fmt.Printf("  Requests:       %.0f server-side vs %d client-side (%.0f 5xx)\n", ss.Requests, httpRequests(r), ss.ErrorRequests)
+	if ss.AvgLatency > 0 {
+		clientAvg := "client avg " + r.AvgLatency.Round(time.Microsecond).String()
+		if r.StepsPerCycle > 1 {
+			clientAvg += " per cycle"
+		}
+		fmt.Printf("  Latency:        avg %s (%s)\n", ss.AvgLatency.Round(time.Microsecond), clientAvg)
+	}
+	if ss.P99Latency > 0 {
+		fmt.Printf("  Percentiles:    p50 %s, p95 %s, p99 %s (estimated from buckets)\n",
+			ss.P50Latency.Round(time.Microsecond), ss.P95Latency.Round(time.Microsecond), ss.P99Latency.Round(time.Microsecond))
+	}
+	if ss.CPUSeconds > 0 {
+		fmt.Printf("  CPU:            %.1fs, avg %.2f cores\n", ss.CPUSeconds, ss.AvgCPUCores)
+	}
+	if ss.MemoryMaxMB > 0 {
+		fmt.Printf("  Memory:         %.1f MB -> %.1f MB, max %.1f MB\n", ss.MemoryStartMB, ss.MemoryEndMB, ss.MemoryMaxMB)
+	}
+	switch {
+	case ss.DBPoolMax > 0:
+		fmt.Printf("  DB Pool:        max %.0f in use, %.0f open (limit %.0f), %.0f waits\n",
+			ss.DBPoolInUseMax, ss.DBPoolOpenMax, ss.DBPoolMax, ss.DBPoolWaits)
+	case ss.DBPoolMaxUsed > 0 || ss.DBPoolInUseMax > 0:
+		// Agroal exposes no pool size limit, only the high-water mark since the pool started
+		fmt.Printf("  DB Pool:        max %.0f in use (%.0f max used since start), %.0f waits\n",
+			ss.DBPoolInUseMax, ss.DBPoolMaxUsed, ss.DBPoolWaits)
+	}
+	if ss.ScrapeErrors > 0 {
+		fmt.Printf("  WARNING: %d scrapes failed\n", ss.ScrapeErrors)
+	}
+}
+
+// printResourceStats prints container resource usage and efficiency ratios
+func printResourceStats(rs *ResourceStats) {
+	fmt.Println("")
+	fmt.Printf("Resource Efficiency (%s):\n", rs.PrometheusURL)
+	fmt.Printf("  Containers:     %s, %d pods\n", rs.Selector, rs.Pods)
+	fmt.Printf("  CPU:            avg %.2f cores, max %.2f cores\n", rs.AvgCPUCores, rs.MaxCPUCores)
+	fmt.Printf("  Memory:         avg %.1f MB, max %.1f MB\n", rs.AvgMemoryMB, rs.MaxMemoryMB)
+	if rs.RequestsPerCore > 0 {
+		fmt.Printf("  Requests/Core:  %.1f RPS per CPU core\n", rs.RequestsPerCore)
+	}
+	if rs.MBPer1kRPS > 0 {
+		fmt.Printf("  Memory/1k RPS:  %.1f MB\n", rs.MBPer1kRPS)
+	}
+	for _, err := range rs.Errors {
+		fmt.Printf("  WARNING: query failed: %s\n", err)
+	}
+}
+
+// printBodySizes prints response sizes per operation at the start and at the end of the run,
+// so lists growing with rows created by the benchmark are visible
+func printBodySizes(sizes []BodySizeStats) {
+	fmt.Println("")
+	fmt.Println("Response Size:")
+	fmt.Println("┌────────────────────────────────┬────────────┬────────────┬────────────┬──────────┐")
+	fmt.Println("│           OPERATION            │   START    │    END     │    MAX     │  GROWTH  │")
+	fmt.Println("├────────────────────────────────┼────────────┼────────────┼────────────┼──────────┤")
+	for _, size := range sizes {
+		fmt.Printf("│ %-30s │ %10s │ %10s │ %10s │ %+7.1f%% │\n",
+			truncateString(size.Operation, 30), formatBytes(size.FirstAvg), formatBytes(size.LastAvg),
+			formatBytes(float64(size.Max)), size.Growth*100)
+	}
+	fmt.Println("└────────────────────────────────┴────────────┴────────────┴────────────┴──────────┘")
+	for _, size := range sizes {
+		if size.Growth >= bodyGrowthThreshold {
+			fmt.Printf("  WARNING: %s responses grew %.1f%% during the run, results drift as the data set grows\n",
+				size.Operation, size.Growth*100)
+		}
+	}
+}
+
+// printCleanup prints resources created during the run and the outcome of the teardown
+func printCleanup(cs *CleanupStats) {
+	fmt.Println("")
+	fmt.Println("Created Resources:")
+	fmt.Printf("  Created:        %d, %d deleted by the run itself\n", cs.Created, cs.Created-int64(cs.Left))
+	if !cs.Enabled {
+		if cs.Left > 0 {
+			fmt.Printf("  WARNING: %d resources left on the target (-cleanup=false)\n", cs.Left)
+		}
+		return
+	}
+	if cs.Left > 0 {
+		fmt.Printf("  Teardown:       %d deleted, %d failed in %s\n", cs.Deleted, cs.Failed, cs.Duration.Round(time.Millisecond))
+	}
+	for _, err := range cs.Errors {
+		fmt.Printf("  WARNING: %s\n", err)
+	}
+}
+
+// printEventLog prints where the per-request events were written
+func printEventLog(es *EventLogStats) {
+	fmt.Println("")
+	fmt.Println("Event Log:")
+	fmt.Printf("  File:           %s (%s, %s)\n", es.Path, es.Format, formatBytes(float64(es.Bytes)))
+	fmt.Printf("  Events:         %d\n", es.Events)
+	if es.Dropped > 0 {
+		fmt.Printf("  WARNING: %d events dropped, the disk could not keep up with the request rate\n", es.Dropped)
+	}
+	if es.Error != "" {
+		fmt.Printf("  WARNING: the log is incomplete: %s\n", es.Error)
+	}
+}
+
+// formatBytes formats size in bytes as B, KB or MB
+func formatBytes(size float64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", size/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", size/(1<<10))
+	default:
+		return fmt.Sprintf("%.0f B", size)
+	}
+}
+
+// errorTimelineWidth is the maximum width of error timelines in characters
+const errorTimelineWidth = 60
+
+// printErrorTimelines prints occurrences over time of the most frequent errors,
+// showing whether an error was a burst or spread across the run
+func printErrorTimelines(errors []*UniqueError, duration time.Duration) {
+	seconds := int(duration.Seconds() + 0.999)
+	if seconds < 1 {
+		return
+	}
+
+	fmt.Println("")
+	fmt.Println("Error Timeline:")
+	for i, err := range errors {
+		if i >= 10 {
+			break
+		}
+
+		timeline := make([]int64, seconds)
+		copy(timeline, err.Timeline)
+		active, peak, peakSecond := 0, int64(0), 0
+		for second, count := range timeline {
+			if count > 0 {
+				active++
+			}
+			if count > peak {
+				peak, peakSecond = count, second
+			}
+		}
+
+		buckets, per := downsample(timeline, errorTimelineWidth)
+		fmt.Printf("  %-30s %3d  %s  in %d of %ds, peak %d/s at %ds",
+			truncateString(err.Operation, 30), err.StatusCode, sparklineValues(buckets), active, seconds, peak, peakSecond)
+		if per > 1 {
+			fmt.Printf(" (%ds per char)", per)
+		}
+		fmt.Println("")
+	}
+}
+
+// errorSamplesJSON converts error samples for JSON output
+func errorSamplesJSON(samples []ErrorSample, start time.Time) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(samples))
+	for _, sample := range samples {
+		result = append(result, map[string]interface{}{
+			"time":       sample.Time.Format(time.RFC3339Nano),
+			"offset":     sample.Time.Sub(start).String(),
+			"latency":    sample.Latency.String(),
+			"statusCode": sample.StatusCode,
+			"requestId":  sample.RequestID,
+			"headers":    sample.Headers,
+			"body":       sample.Body,
+		})
+	}
+	return result
+}
+
+// sortedKeys returns sorted keys of the map
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// chartWidth is the width of bars in latency charts
+const chartWidth = 40
+
+// printLatencyDistribution prints histogram with logarithmic buckets and percentile spectrum
+// of sorted latencies. Two humps in the histogram mean bimodal latency (e.g. GC pauses)
+func printLatencyDistribution(sorted []time.Duration) {
+	buckets := latencyHistogram(sorted, histogramBuckets)
+	maxCount := 0
+	for _, bucket := range buckets {
+		if bucket.Count > maxCount {
+			maxCount = bucket.Count
+		}
+	}
+
+	fmt.Println("")
+	fmt.Println("Latency Distribution:")
+	for _, bucket := range buckets {
+		b := bar(float64(bucket.Count)/float64(maxCount), chartWidth)
+		fmt.Printf("  %10s - %-10s │%s%s %8d %6.2f%%\n",
+			bucket.From.Round(time.Microsecond), bucket.To.Round(time.Microsecond),
+			b, strings.Repeat(" ", chartWidth-utf8.RuneCountInString(b)),
+			bucket.Count, float64(bucket.Count)/float64(len(sorted))*100)
+	}
+
+	max := sorted[len(sorted)-1]
+	fmt.Println("")
+	fmt.Println("Percentile Spectrum:")
+	for _, p := range spectrumPercentiles {
+		label := "max"
+		if p < 1 {
+			label = "p" + strconv.FormatFloat(p*100, 'f', -1, 64)
+		}
+		latency := percentile(sorted, p)
+		fmt.Printf("  %-7s %10s │%s\n", label, latency.Round(time.Microsecond), bar(float64(latency)/float64(max), chartWidth))
+	}
+}
+
+// bar renders share (0..1) as a horizontal bar with 1/8 character resolution
+func bar(share float64, width int) string {
+	partial := []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+	eighths := int(share * float64(width*8))
+	if share > 0 && eighths == 0 {
+		eighths = 1 // Keep non-empty buckets visible
+	}
+	return strings.Repeat("█", eighths/8) + partial[eighths%8]
+}
+
+// printSlowestRequests prints the slowest requests with their offset from the start of the run
+func printSlowestRequests(requests []SlowRequest, start time.Time) {
+	fmt.Println("")
+	fmt.Println("Slowest Requests:")
+	fmt.Printf("  %12s  %6s  %10s  %10s  %s\n", "LATENCY", "STATUS", "OFFSET", "PRODUCT", "OPERATION")
+	for _, request := range requests {
+		status, product := "-", "-"
+		if request.StatusCode > 0 {
+			status = strconv.Itoa(request.StatusCode)
+		}
+		if request.ProductID != 0 {
+			product = strconv.FormatInt(request.ProductID, 10)
+		}
+		fmt.Printf("  %12s  %6s  %10s  %10s  %s\n",
+			request.Latency.Round(time.Microsecond), status,
+			request.Start.Sub(start).Round(time.Millisecond), product, request.Operation)
+	}
+}
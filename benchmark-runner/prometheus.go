@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrometheusConfig configures resource usage queries of the target pods
+type PrometheusConfig struct {
+	URL       string        // Prometheus server base URL, e.g. http://prometheus:9090
+	Namespace string        // Namespace of the target pods (optional)
+	Pods      string        // Regular expression of target pod names, e.g. benchmark-golang-.*
+	Lag       time.Duration // Wait before querying so the last samples of the run are scraped
+}
+
+// ResourceStats are container resource usage of the target pods over the run window
+// and efficiency ratios derived from them
+type ResourceStats struct {
+	PrometheusURL string
+	Selector      string
+	Pods          int
+	AvgCPUCores   float64
+	MaxCPUCores   float64
+	AvgMemoryMB   float64 // Working set
+	MaxMemoryMB   float64
+
+	RequestsPerCore float64 // Achieved RPS per average CPU core
+	MBPer1kRPS      float64 // Average memory per 1000 achieved RPS
+
+	Errors []string // Failed queries
+}
+
+// prometheusResponse is the response of /api/v1/query
+type prometheusResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// queryResourceStats queries container CPU and memory of the target pods over
+// the run window and computes efficiency ratios from the achieved RPS.
+// Cancelling ctx cuts the wait for the last scrapes short
+func queryResourceStats(ctx context.Context, config PrometheusConfig, r *Result) *ResourceStats {
+	if config.URL == "" || r.StartTime.IsZero() {
+		return nil
+	}
+
+	end := r.StartTime.Add(r.TotalDuration)
+	if wait := time.Until(end.Add(config.Lag)); wait > 0 {
+		log.Printf("Waiting %s for Prometheus to scrape the end of the run...", wait.Round(time.Second))
+		if !sleepContext(ctx, wait) {
+			log.Printf("Interrupted, querying Prometheus without the last scrapes of the run")
+		}
+	}
+
+	selector := containerSelector(config)
+	window := fmt.Sprintf("%ds", int(r.TotalDuration.Seconds()+0.5))
+	stats := &ResourceStats{
+		PrometheusURL: config.URL,
+		Selector:      selector,
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	query := func(name, promQL string) float64 {
+		value, err := queryPrometheus(client, config.URL, promQL, end)
+		if err != nil {
+			stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %v", name, err))
+		}
+		return value
+	}
+
+	cpu := "container_cpu_usage_seconds_total" + selector
+	memory := "container_memory_working_set_bytes" + selector
+
+	stats.Pods = int(query("pods", fmt.Sprintf("count(count by (pod) (last_over_time(%s[%s])))", memory, window)))
+	stats.AvgCPUCores = query("avg cpu", fmt.Sprintf("sum(rate(%s[%s]))", cpu, window))
+	stats.MaxCPUCores = query("max cpu", fmt.Sprintf("max_over_time(sum(rate(%s[1m]))[%s:15s])", cpu, window))
+	stats.AvgMemoryMB = query("avg memory", fmt.Sprintf("sum(avg_over_time(%s[%s]))", memory, window)) / 1024 / 1024
+	stats.MaxMemoryMB = query("max memory", fmt.Sprintf("sum(max_over_time(%s[%s]))", memory, window)) / 1024 / 1024
+
+	// Efficiency is per HTTP request, multi-step scenarios count cycles in TotalRequests
+	var rps float64
+	if r.TotalDuration > 0 {
+		rps = float64(httpRequests(r)) / r.TotalDuration.Seconds()
+	}
+	if stats.AvgCPUCores > 0 {
+		stats.RequestsPerCore = rps / stats.AvgCPUCores
+	}
+	if rps > 0 {
+		stats.MBPer1kRPS = stats.AvgMemoryMB / (rps / 1000)
+	}
+
+	for _, err := range stats.Errors {
+		log.Printf("Prometheus query failed: %s", err)
+	}
+	return stats
+}
+
+// containerSelector returns label selector of the target containers
+// (excluding pause containers and pod-level cgroups, same as the Grafana dashboards)
+func containerSelector(config PrometheusConfig) string {
+	matchers := []string{`container!="POD"`, `image!=""`}
+	if config.Namespace != "" {
+		matchers = append(matchers, fmt.Sprintf("namespace=%q", config.Namespace))
+	}
+	if config.Pods != "" {
+		matchers = append(matchers, fmt.Sprintf("pod=~%q", config.Pods))
+	}
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// queryPrometheus evaluates instant query at the given time and returns the sum of the result vector
+func queryPrometheus(client *http.Client, baseURL, query string, at time.Time) (float64, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatFloat(float64(at.UnixMilli())/1000, 'f', 3, 64))
+
+	resp, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/api/v1/query?" + params.Encode())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("invalid response (status %d): %v", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return 0, fmt.Errorf("%s: %s", result.ErrorType, result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return 0, fmt.Errorf("unexpected result type %q", result.Data.ResultType)
+	}
+	if len(result.Data.Result) == 0 {
+		return 0, fmt.Errorf("no data")
+	}
+
+	var total float64
+	for _, sample := range result.Data.Result {
+		if len(sample.Value) != 2 {
+			return 0, fmt.Errorf("invalid sample value")
+		}
+		text, ok := sample.Value[1].(string)
+		if !ok {
+			return 0, fmt.Errorf("invalid sample value")
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, err
+		}
+		total += value
+	}
+	return total, nil
+}
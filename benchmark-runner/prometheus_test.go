@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prometheusServer answers instant queries with a vector of the values of the first matching query prefix
+func prometheusServer(t *testing.T, values map[string][]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" || r.URL.Query().Get("time") == "" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		query := r.URL.Query().Get("query")
+		for prefix, samples := range values {
+			if !strings.HasPrefix(query, prefix) {
+				continue
+			}
+			result := make([]string, 0, len(samples))
+			for i, value := range samples {
+				result = append(result, fmt.Sprintf(`{"metric":{"pod":"p%d"},"value":[1700000000,%q]}`, i, value))
+			}
+			fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, strings.Join(result, ","))
+			return
+		}
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestQueryPrometheus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "sum":
+			if got := r.URL.Query().Get("time"); got != "1700000000.500" {
+				t.Errorf("time = %s, want 1700000000.500", got)
+			}
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"pod":"a"},"value":[1700000000.5,"1.5"]},{"metric":{"pod":"b"},"value":[1700000000.5,"2"]}]}}`)
+		case "empty":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+		case "bad query":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+		case "matrix":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[]}}`)
+		case "number":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,2]}]}}`)
+		case "nan text":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"abc"]}]}}`)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, "<html>bad gateway</html>")
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		query string
+		want  float64
+		err   string
+	}{
+		{query: "sum", want: 3.5},
+		{query: "empty", err: "no data"},
+		{query: "bad query", err: "bad_data: parse error"},
+		{query: "matrix", err: `unexpected result type "matrix"`},
+		{query: "number", err: "invalid sample value"},
+		{query: "nan text", err: "invalid syntax"},
+		{query: "html", err: "invalid response (status 502)"},
+	}
+
+	at := time.UnixMilli(1700000000500)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := queryPrometheus(server.Client(), server.URL+"/", tt.query, at)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryResourceStats(t *testing.T) {
+	server := prometheusServer(t, map[string][]string{
+		"count(":             {"2"},
+		"sum(rate(":          {"0.5", "1.5"},
+		"max_over_time(":     {"3"},
+		"sum(avg_over_time(": {"104857600", "104857600"},
+		"sum(max_over_time(": {"314572800"},
+	})
+
+	// Two-step scenario: 500 cycles are 1000 HTTP requests
+	result := &Result{
+		StartTime:     time.Now().Add(-10 * time.Second),
+		TotalDuration: 10 * time.Second,
+		TotalRequests: 500,
+		StepsPerCycle: 2,
+		Steps:         []*StepStats{{Name: "create", Requests: 500}, {Name: "delete", Requests: 500}},
+	}
+	stats := queryResourceStats(context.Background(), PrometheusConfig{URL: server.URL, Namespace: "bench", Pods: "app-.*"}, result)
+
+	if len(stats.Errors) != 0 {
+		t.Fatalf("errors = %v", stats.Errors)
+	}
+	if stats.Selector != `{container!="POD",image!="",namespace="bench",pod=~"app-.*"}` {
+		t.Errorf("selector = %s", stats.Selector)
+	}
+	if stats.Pods != 2 || stats.AvgCPUCores != 2 || stats.MaxCPUCores != 3 || stats.AvgMemoryMB != 200 || stats.MaxMemoryMB != 300 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.RequestsPerCore != 50 {
+		t.Errorf("requests per core = %v, want 50 (100 HTTP requests/s on 2 cores)", stats.RequestsPerCore)
+	}
+	if stats.MBPer1kRPS != 2000 {
+		t.Errorf("MB per 1k RPS = %v, want 2000", stats.MBPer1kRPS)
+	}
+}
+
+func TestQueryResourceStatsRecordsFailedQueries(t *testing.T) {
+	server := prometheusServer(t, map[string][]string{"sum(rate(": {"1"}})
+	result := &Result{StartTime: time.Now().Add(-time.Second), TotalDuration: time.Second, TotalRequests: 10}
+
+	stats := queryResourceStats(context.Background(), PrometheusConfig{URL: server.URL}, result)
+	if len(stats.Errors) != 4 || !strings.HasPrefix(stats.Errors[0], "pods: no data") {
+		t.Errorf("errors = %q, want 4 failed queries", stats.Errors)
+	}
+	if stats.RequestsPerCore != 10 || stats.MBPer1kRPS != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	if queryResourceStats(context.Background(), PrometheusConfig{}, result) != nil {
+		t.Error("stats queried without Prometheus URL")
+	}
+}
+
+func TestQueryResourceStatsInterrupted(t *testing.T) {
+	server := prometheusServer(t, map[string][]string{"": {"1"}})
+	result := &Result{StartTime: time.Now(), TotalDuration: time.Second, TotalRequests: 10}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	stats := queryResourceStats(ctx, PrometheusConfig{URL: server.URL, Lag: time.Hour}, result)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("interrupted query took %s", elapsed)
+	}
+	if len(stats.Errors) != 0 || stats.AvgCPUCores != 1 {
+		t.Errorf("stats = %+v, want queried after the interrupt", stats)
+	}
+}
+
+func TestContainerSelector(t *testing.T) {
+	if got, want := containerSelector(PrometheusConfig{}), `{container!="POD",image!=""}`; got != want {
+		t.Errorf("selector = %s, want %s", got, want)
+	}
+}
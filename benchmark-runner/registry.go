@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"go.starlark.net/starlark"
+)
+
+// Operation is a single benchmarkable HTTP request.
+//
+// To add a new operation, implement this interface in a new file of this package
+// and register it from init():
+//
+//	func init() {
+//		RegisterOperation(myOperation{})
+//	}
+//
+// The operation becomes available as -type=<name> and can be used as a scenario step.
+type Operation interface {
+	// Name returns unique operation name, e.g. "get-products"
+	Name() string
+
+	// BuildRequest creates HTTP request. State holds values extracted by previous steps
+	BuildRequest(ctx *RequestContext, state *State) (*http.Request, error)
+
+	// ClassifyResponse returns an error if response must be counted as failed
+	ClassifyResponse(resp *Response) error
+
+	// ExtractState stores values from response needed by the following scenario steps.
+	// An error aborts the rest of the scenario iteration
+	ExtractState(resp *Response, state *State) error
+}
+
+// ProductOperation is implemented by operations addressing a single product by State.ProductID.
+// The slowest requests report the product ID only for them
+type ProductOperation interface {
+	// UsesProductID reports whether the request is built from State.ProductID
+	UsesProductID() bool
+}
+
+// StatefulOperation is implemented by operations that can tell whether ExtractState stores anything.
+// A failed request is not extracted from, so it aborts the rest of the scenario iteration
+// unless the operation reports it extracts nothing. Operations without it are assumed to extract
+type StatefulOperation interface {
+	// ExtractsState reports whether ExtractState stores values for the following steps
+	ExtractsState() bool
+}
+
+// Response is a fully read HTTP response passed to operations
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// State carries values between operations of one scenario iteration
+type State struct {
+	ProductID int64             // Product ID used by operations on a single product
+	Vars      map[string]string // Arbitrary values extracted from responses
+
+	captured []string       // Variables set by the current step
+	span     *Span          // Span of the iteration, parent of request spans (tracing only)
+	script   *starlark.Dict // Values shared by script steps of the iteration (script only)
+}
+
+// SetVar stores variable extracted from response.
+// Captured values are also shared with other workers after the step
+func (s *State) SetVar(name, value string) {
+	s.Vars[name] = value
+	s.captured = append(s.captured, name)
+}
+
+// newState creates state for a new scenario iteration
+func newState() *State {
+	return &State{
+		ProductID: FixedProductID,
+		Vars:      make(map[string]string),
+	}
+}
+
+// Scenario is an ordered list of operations executed as one iteration (cycle).
+// A single operation is a scenario with one step
+type Scenario struct {
+	Name        string
+	Description string
+	Steps       []string // Operation names
+}
+
+var (
+	registryMu sync.RWMutex
+	operations = make(map[string]Operation)
+	scenarios  = make(map[string]Scenario)
+)
+
+// RegisterOperation registers operation under its name. Panics on duplicates
+func RegisterOperation(op Operation) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := op.Name()
+	if _, exists := operations[name]; exists {
+		panic(fmt.Sprintf("operation %q is already registered", name))
+	}
+	operations[name] = op
+}
+
+// RegisterScenario registers multi-step scenario. Panics on duplicates
+func RegisterScenario(scenario Scenario) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := scenarios[scenario.Name]; exists {
+		panic(fmt.Sprintf("scenario %q is already registered", scenario.Name))
+	}
+	scenarios[scenario.Name] = scenario
+}
+
+// LookupOperation returns registered operation by name
+func LookupOperation(name string) (Operation, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	op, ok := operations[name]
+	return op, ok
+}
+
+// LookupScenario returns registered scenario by name.
+// Every operation is also available as a single-step scenario
+func LookupScenario(name string) (Scenario, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if scenario, ok := scenarios[name]; ok {
+		for _, step := range scenario.Steps {
+			if _, ok := operations[step]; !ok {
+				return Scenario{}, fmt.Errorf("scenario %q uses unknown operation %q", name, step)
+			}
+		}
+		return scenario, nil
+	}
+	if _, ok := operations[name]; ok {
+		return Scenario{Name: name, Steps: []string{name}}, nil
+	}
+	return Scenario{}, fmt.Errorf("unknown benchmark type: %s", name)
+}
+
+// ScenarioNames returns sorted names of all operations and scenarios
+func ScenarioNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(operations)+len(scenarios))
+	for name := range operations {
+		names = append(names, name)
+	}
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// registerForTest registers scenario and removes it when the test ends
+func registerForTest(t *testing.T, scenario Scenario) {
+	t.Helper()
+	RegisterScenario(scenario)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(scenarios, scenario.Name)
+		registryMu.Unlock()
+	})
+}
+
+func TestLookupScenario(t *testing.T) {
+	registerForTest(t, Scenario{Name: "test-broken", Steps: []string{string(GetProducts), "no-such-operation"}})
+
+	tests := []struct {
+		name  string
+		steps []string
+		err   string
+	}{
+		{name: string(GetProducts), steps: []string{string(GetProducts)}},
+		{name: string(DeleteProduct), steps: []string{string(DeleteProduct)}},
+		{name: string(MixedOperations), steps: []string{string(CreateProduct), string(GetProductByID), string(UpdateProduct), string(DeleteProduct)}},
+		{name: "test-broken", err: `scenario "test-broken" uses unknown operation "no-such-operation"`},
+		{name: "no-such-type", err: "unknown benchmark type: no-such-type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario, err := LookupScenario(tt.name)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if scenario.Name != tt.name || !slices.Equal(scenario.Steps, tt.steps) {
+				t.Errorf("scenario = %+v, want %s with steps %v", scenario, tt.name, tt.steps)
+			}
+		})
+	}
+}
+
+func TestLookupOperation(t *testing.T) {
+	op, ok := LookupOperation(string(GetProductByID))
+	if !ok || op.Name() != string(GetProductByID) {
+		t.Errorf("LookupOperation(%s) = %v, %v", GetProductByID, op, ok)
+	}
+	if _, ok := LookupOperation(string(MixedOperations)); ok {
+		t.Errorf("LookupOperation(%s) found a scenario", MixedOperations)
+	}
+}
+
+func TestRegisterDuplicatePanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func()
+	}{
+		{"operation", func() { op, _ := LookupOperation(string(GetProducts)); RegisterOperation(op) }},
+		{"scenario", func() { RegisterScenario(Scenario{Name: string(MixedOperations)}) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil || !strings.Contains(r.(string), "already registered") {
+					t.Errorf("recover() = %v, want an already registered panic", r)
+				}
+			}()
+			tt.register()
+		})
+	}
+}
+
+func TestScenarioNames(t *testing.T) {
+	names := ScenarioNames()
+	if !slices.IsSorted(names) {
+		t.Errorf("names are not sorted: %v", names)
+	}
+	for _, name := range []string{string(GetProducts), string(CreateProduct), string(MixedOperations)} {
+		if !slices.Contains(names, name) {
+			t.Errorf("names %v do not contain %s", names, name)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// RepeatConfig configures repeated runs of the same benchmark configuration
+type RepeatConfig struct {
+	Runs         int           // Runs per target
+	CompareURL   string        // Second target compared against -url (optional)
+	ComparePods  string        // Prometheus pod regex of the second target (optional)
+	Interleave   bool          // Alternate targets run by run instead of running them one after another
+	Cooldown     time.Duration // Pause between runs
+	Baseline     string        // Baseline file to compare against (optional)
+	SaveBaseline string        // File to save runs of the primary target to (optional)
+}
+
+// Enabled reports whether repeated runs or comparison were requested
+func (rc RepeatConfig) Enabled() bool {
+	return rc.Runs > 1 || rc.CompareURL != "" || rc.Baseline != "" || rc.SaveBaseline != ""
+}
+
+// RunMetrics are metric values of a single run, keyed by metric name
+type RunMetrics map[string]float64
+
+// runMetricNames are metrics compared between runs, in report order
+var runMetricNames = []string{
+	"rps", "avg_ms", "p50_ms", "p95_ms", "p99_ms", "error_rate",
+	"steady_state_s", "warmup_penalty_ms",
+	"server_cpu_cores", "requests_per_core", "mb_per_1k_rps",
+}
+
+// significanceLevel is the p-value below which a difference between targets is significant
+const significanceLevel = 0.05
+
+// higherIsBetter lists metrics where a larger value is an improvement
+var higherIsBetter = map[string]bool{"rps": true, "requests_per_core": true}
+
+// TargetRuns are all runs of one target
+type TargetRuns struct {
+	Name string       `json:"name"`
+	URL  string       `json:"url"`
+	Runs []RunMetrics `json:"runs"`
+}
+
+// MetricSummary is a descriptive summary of a metric over runs
+type MetricSummary struct {
+	Name   string
+	N      int
+	Mean   float64
+	Median float64
+	StdDev float64
+	CILow  float64 // 95% confidence interval of the mean
+	CIHigh float64
+	Min    float64
+	Max    float64
+}
+
+// MetricComparison is the result of the significance test of one metric between two targets
+type MetricComparison struct {
+	Name        string
+	MedianA     float64
+	MedianB     float64
+	DiffPercent float64 // (B - A) / A
+	U           float64 // Mann-Whitney U statistic
+	PValue      float64 // Two-sided
+	Significant bool    // PValue < significanceLevel
+	Better      string  // Name of the better target if the difference is significant
+}
+
+// runMetrics extracts compared metrics from the result of a single run
+func runMetrics(r *Result) RunMetrics {
+	m := RunMetrics{
+		"avg_ms": durationMs(r.AvgLatency),
+		"p50_ms": durationMs(r.P50Latency),
+		"p95_ms": durationMs(r.P95Latency),
+		"p99_ms": durationMs(r.P99Latency),
+	}
+	// Rates are per HTTP request, multi-step scenarios count cycles in TotalRequests
+	requests := httpRequests(r)
+	if r.TotalDuration > 0 {
+		m["rps"] = float64(requests) / r.TotalDuration.Seconds()
+	}
+	if requests > 0 {
+		m["error_rate"] = float64(httpFailed(r)) / float64(requests) * 100
+	}
+	if r.WarmUp != nil {
+		m["steady_state_s"] = r.WarmUp.SteadyState.Seconds()
+		if r.WarmUp.PenaltyWindow > 0 {
+			m["warmup_penalty_ms"] = durationMs(r.WarmUp.Penalty)
+		}
+	}
+	if r.Server != nil && r.Server.CPUSeconds > 0 {
+		m["server_cpu_cores"] = r.Server.AvgCPUCores
+	}
+	if r.Resources != nil && r.Resources.RequestsPerCore > 0 {
+		m["requests_per_core"] = r.Resources.RequestsPerCore
+		m["mb_per_1k_rps"] = r.Resources.MBPer1kRPS
+	}
+	return m
+}
+
+// durationMs converts duration into float milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// runRepeated runs the configuration several times per target and prints statistics
+// of the runs and the comparison between targets (or against the baseline).
+// Cancelling ctx stops the series, the runs completed before are still reported
+func runRepeated(ctx context.Context, config Config, repeat RepeatConfig, run func(Config) (*Result, error)) error {
+	if repeat.Runs < 1 {
+		repeat.Runs = 1
+	}
+
+	targets := []*TargetRuns{{Name: "A", URL: config.URL}}
+	configs := []Config{config}
+	if repeat.CompareURL != "" {
+		compare := config
+		compare.URL = repeat.CompareURL
+		if repeat.ComparePods != "" {
+			compare.Prometheus.Pods = repeat.ComparePods
+		}
+		targets = append(targets, &TargetRuns{Name: "B", URL: repeat.CompareURL})
+		configs = append(configs, compare)
+	}
+
+	// Order of runs: A A A B B B, or A B A B A B with interleaving
+	type plannedRun struct{ target, run int }
+	var plan []plannedRun
+	if repeat.Interleave {
+		for i := 0; i < repeat.Runs; i++ {
+			for t := range targets {
+				plan = append(plan, plannedRun{t, i})
+			}
+		}
+	} else {
+		for t := range targets {
+			for i := 0; i < repeat.Runs; i++ {
+				plan = append(plan, plannedRun{t, i})
+			}
+		}
+	}
+
+	completed := 0
+	for i, p := range plan {
+		if i > 0 && !sleepContext(ctx, repeat.Cooldown) {
+			break
+		}
+
+		target := targets[p.target]
+		log.Printf("Run %d/%d: target %s (%s), repetition %d/%d", i+1, len(plan), target.Name, target.URL, p.run+1, repeat.Runs)
+		result, err := run(configs[p.target])
+		if ctx.Err() != nil {
+			// The interrupted run was cut short and is not comparable with the others
+			break
+		}
+		if err != nil {
+			return err
+		}
+		completed++
+		metrics := runMetrics(result)
+		target.Runs = append(target.Runs, metrics)
+		log.Printf("  %.1f RPS, avg %.2fms, p99 %.2fms, errors %.2f%%",
+			metrics["rps"], metrics["avg_ms"], metrics["p99_ms"], metrics["error_rate"])
+	}
+
+	interrupted := completed < len(plan)
+	if interrupted {
+		if completed == 0 {
+			return errors.New("interrupted before the first run completed")
+		}
+		log.Printf("Interrupted: reporting %d of %d planned runs", completed, len(plan))
+	}
+
+	// A target not started before the interrupt has nothing to report
+	reported := targets[:0]
+	for _, target := range targets {
+		if len(target.Runs) > 0 {
+			reported = append(reported, target)
+		}
+	}
+	targets = reported
+
+	if repeat.SaveBaseline != "" && interrupted {
+		log.Printf("Baseline not saved to %s: the series was interrupted", repeat.SaveBaseline)
+	} else if repeat.SaveBaseline != "" {
+		if err := saveBaseline(repeat.SaveBaseline, targets[0]); err != nil {
+			return err
+		}
+		log.Printf("Baseline saved to %s", repeat.SaveBaseline)
+	}
+	if repeat.Baseline != "" {
+		baseline, err := loadBaseline(repeat.Baseline)
+		if err != nil {
+			return err
+		}
+		// Baseline is the reference (A), the current runs are compared against it (B)
+		targets = []*TargetRuns{baseline, targets[0]}
+		targets[1].Name = "current"
+	}
+
+	printRepeatResults(targets)
+	return nil
+}
+
+// saveBaseline writes runs of the target to a JSON file
+func saveBaseline(path string, target *TargetRuns) error {
+	data, err := json.MarshalIndent(target, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// loadBaseline reads runs saved by saveBaseline
+func loadBaseline(path string) (*TargetRuns, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline TargetRuns
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %v", path, err)
+	}
+	if len(baseline.Runs) == 0 {
+		return nil, fmt.Errorf("baseline %s has no runs", path)
+	}
+	baseline.Name = "baseline"
+	return &baseline, nil
+}
+
+// values returns values of the metric over runs that have it
+func (t *TargetRuns) values(metric string) []float64 {
+	var values []float64
+	for _, run := range t.Runs {
+		if value, ok := run[metric]; ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// summarize computes descriptive statistics and the 95% confidence interval of the mean
+func summarize(name string, values []float64) MetricSummary {
+	s := MetricSummary{Name: name, N: len(values)}
+	if s.N == 0 {
+		return s
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	s.Min, s.Max = sorted[0], sorted[s.N-1]
+	if s.N%2 == 1 {
+		s.Median = sorted[s.N/2]
+	} else {
+		s.Median = (sorted[s.N/2-1] + sorted[s.N/2]) / 2
+	}
+
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= float64(s.N)
+
+	s.CILow, s.CIHigh = s.Mean, s.Mean
+	if s.N > 1 {
+		var squares float64
+		for _, v := range values {
+			squares += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(squares / float64(s.N-1))
+
+		margin := tCritical95(s.N-1) * s.StdDev / math.Sqrt(float64(s.N))
+		s.CILow, s.CIHigh = s.Mean-margin, s.Mean+margin
+	}
+	return s
+}
+
+// tCritical95 returns two-sided 95% critical value of Student's t-distribution
+func tCritical95(df int) float64 {
+	table := []float64{
+		12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+		2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+		2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+	}
+	if df < 1 {
+		return math.Inf(1)
+	}
+	if df <= len(table) {
+		return table[df-1]
+	}
+	return 1.96
+}
+
+// compareMetric runs two-sided Mann-Whitney U test between runs of two targets
+func compareMetric(name string, a, b *TargetRuns) (MetricComparison, bool) {
+	x, y := a.values(name), b.values(name)
+	if len(x) == 0 || len(y) == 0 {
+		return MetricComparison{}, false
+	}
+
+	c := MetricComparison{
+		Name:    name,
+		MedianA: summarize(name, x).Median,
+		MedianB: summarize(name, y).Median,
+	}
+	if c.MedianA != 0 {
+		c.DiffPercent = (c.MedianB - c.MedianA) / c.MedianA * 100
+	}
+	c.U, c.PValue = mannWhitney(x, y)
+	c.Significant = c.PValue < significanceLevel
+	if c.Significant && c.MedianA != c.MedianB {
+		if (c.MedianB > c.MedianA) == higherIsBetter[name] {
+			c.Better = b.Name
+		} else {
+			c.Better = a.Name
+		}
+	}
+	return c, true
+}
+
+// mannWhitney returns U statistic of the first sample and two-sided p-value.
+// Small samples without ties use the exact distribution, others the normal
+// approximation with tie and continuity corrections
+func mannWhitney(x, y []float64) (float64, float64) {
+	n1, n2 := len(x), len(y)
+	type ranked struct {
+		value float64
+		first bool
+	}
+	all := make([]ranked, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, ranked{v, true})
+	}
+	for _, v := range y {
+		all = append(all, ranked{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Average ranks of ties
+	var rankSum, tieTerm float64
+	ties := false
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].first {
+				rankSum += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+
+	u := rankSum - float64(n1*(n1+1))/2
+	if !ties && n1 <= 20 && n2 <= 20 {
+		return u, mannWhitneyExact(u, n1, n2)
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		return u, 1
+	}
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	return u, math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// mannWhitneyMinPValue returns the smallest two-sided p-value the exact test can reach for the sample sizes,
+// when all values of one sample are below all values of the other: 2 / C(n1+n2, n1)
+func mannWhitneyMinPValue(n1, n2 int) float64 {
+	orderings := 1.0
+	for i := 1; i <= n1; i++ {
+		orderings = orderings * float64(n2+i) / float64(i)
+	}
+	return math.Min(1, 2/orderings)
+}
+
+// mannWhitneyExact returns exact two-sided p-value of U for samples without ties
+func mannWhitneyExact(u float64, n1, n2 int) float64 {
+	// counts[i][j][k] - number of orderings of i and j values with U = k,
+	// computed iteratively over i with a rolling table over j
+	maxU := n1 * n2
+	prev := make([][]float64, n2+1)
+	for j := range prev {
+		prev[j] = make([]float64, maxU+1)
+		prev[j][0] = 1
+	}
+	for i := 1; i <= n1; i++ {
+		cur := make([][]float64, n2+1)
+		for j := 0; j <= n2; j++ {
+			cur[j] = make([]float64, maxU+1)
+			for k := 0; k <= i*j; k++ {
+				// The largest value belongs either to the first sample (adds j to U) or to the second
+				if k >= j {
+					cur[j][k] += prev[j][k-j]
+				}
+				if j > 0 {
+					cur[j][k] += cur[j-1][k]
+				}
+			}
+		}
+		prev = cur
+	}
+
+	counts := prev[n2]
+	var total, tail float64
+	uLow := math.Min(u, float64(maxU)-u)
+	for k, count := range counts {
+		total += count
+		if float64(k) <= uLow {
+			tail += count
+		}
+	}
+	return math.Min(1, 2*tail/total)
+}
+
+// printRepeatResults prints statistics of repeated runs per target and the comparison between targets
+func printRepeatResults(targets []*TargetRuns) {
+	fmt.Println("")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("                      REPEATED RUNS")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+
+	jsonTargets := make([]map[string]interface{}, 0, len(targets))
+	for _, target := range targets {
+		fmt.Println("")
+		fmt.Printf("Target %s: %s (%d runs)\n", target.Name, target.URL, len(target.Runs))
+		fmt.Println("┌───────────────────┬────────────┬────────────┬────────────┬─────────────────────────┬────────────┬────────────┐")
+		fmt.Println("│ Metric            │ Mean       │ Median     │ StdDev     │ 95% CI                  │ Min        │ Max        │")
+		fmt.Println("├───────────────────┼────────────┼────────────┼────────────┼─────────────────────────┼────────────┼────────────┤")
+
+		summaries := make(map[string]interface{})
+		for _, name := range runMetricNames {
+			values := target.values(name)
+			if len(values) == 0 {
+				continue
+			}
+			s := summarize(name, values)
+			ci := fmt.Sprintf("%.2f .. %.2f", s.CILow, s.CIHigh)
+			if s.N < 2 {
+				ci = "-"
+			}
+			fmt.Printf("│ %-17s │ %10.2f │ %10.2f │ %10.2f │ %-23s │ %10.2f │ %10.2f │\n",
+				name, s.Mean, s.Median, s.StdDev, ci, s.Min, s.Max)
+			summaries[name] = map[string]interface{}{
+				"n":       s.N,
+				"mean":    s.Mean,
+				"median":  s.Median,
+				"stddev":  s.StdDev,
+				"ci_low":  s.CILow,
+				"ci_high": s.CIHigh,
+				"min":     s.Min,
+				"max":     s.Max,
+			}
+		}
+		fmt.Println("└───────────────────┴────────────┴────────────┴────────────┴─────────────────────────┴────────────┴────────────┘")
+
+		jsonTargets = append(jsonTargets, map[string]interface{}{
+			"name":    target.Name,
+			"url":     target.URL,
+			"runs":    target.Runs,
+			"summary": summaries,
+		})
+	}
+
+	jsonData := map[string]interface{}{"targets": jsonTargets}
+
+	if len(targets) == 2 {
+		a, b := targets[0], targets[1]
+		fmt.Println("")
+		fmt.Printf("Comparison (Mann-Whitney U, two-sided, significant at p < %.2f):\n", significanceLevel)
+		fmt.Printf("  A: %s (%s)\n", a.Name, a.URL)
+		fmt.Printf("  B: %s (%s)\n", b.Name, b.URL)
+		fmt.Println("┌───────────────────┬────────────┬────────────┬──────────┬──────────┬──────────────────┐")
+		fmt.Println("│ Metric            │ Median A   │ Median B   │ B vs A   │ p-value  │ Significant      │")
+		fmt.Println("├───────────────────┼────────────┼────────────┼──────────┼──────────┼──────────────────┤")
+
+		var comparisons []map[string]interface{}
+		for _, name := range runMetricNames {
+			c, ok := compareMetric(name, a, b)
+			if !ok {
+				continue
+			}
+			verdict := "no"
+			if c.Significant {
+				verdict = "yes"
+				if c.Better == a.Name {
+					verdict = "yes, A better"
+				} else if c.Better == b.Name {
+					verdict = "yes, B better"
+				}
+			}
+			fmt.Printf("│ %-17s │ %10.2f │ %10.2f │ %+7.1f%% │ %8.4f │ %-16s │\n",
+				name, c.MedianA, c.MedianB, c.DiffPercent, c.PValue, verdict)
+			comparisons = append(comparisons, map[string]interface{}{
+				"metric":       c.Name,
+				"median_a":     c.MedianA,
+				"median_b":     c.MedianB,
+				"diff_percent": c.DiffPercent,
+				"u":            c.U,
+				"p_value":      c.PValue,
+				"significant":  c.Significant,
+				"better":       c.Better,
+			})
+		}
+		fmt.Println("└───────────────────┴────────────┴────────────┴──────────┴──────────┴──────────────────┘")
+
+		if minP := mannWhitneyMinPValue(len(a.Runs), len(b.Runs)); minP >= significanceLevel {
+			fmt.Printf("WARNING: %d vs %d runs can't show a significant difference: the smallest possible p-value is %.4f, add runs\n",
+				len(a.Runs), len(b.Runs), minP)
+		}
+		jsonData["comparison"] = comparisons
+	}
+
+	jsonResult, _ := json.MarshalIndent(jsonData, "", "  ")
+	fmt.Println("")
+	fmt.Println("JSON Results:")
+	fmt.Println(string(jsonResult))
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMannWhitney(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		u    float64
+		p    float64
+	}{
+		{name: "3 vs 3 separated", x: []float64{1, 2, 3}, y: []float64{4, 5, 6}, u: 0, p: 0.1},
+		{name: "4 vs 4 separated", x: []float64{1, 2, 3, 4}, y: []float64{5, 6, 7, 8}, u: 0, p: 2.0 / 70},
+		{name: "4 vs 4 reversed", x: []float64{5, 6, 7, 8}, y: []float64{1, 2, 3, 4}, u: 16, p: 2.0 / 70},
+		{name: "interleaved", x: []float64{1, 3, 5, 7}, y: []float64{2, 4, 6, 8}, u: 6, p: 0.6857142857142857},
+		{name: "identical", x: []float64{1, 2, 3}, y: []float64{1, 2, 3}, u: 4.5, p: 1},
+		{name: "constant", x: []float64{5, 5, 5}, y: []float64{5, 5, 5}, u: 4.5, p: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, p := mannWhitney(tt.x, tt.y)
+			if u != tt.u || math.Abs(p-tt.p) > 1e-9 {
+				t.Errorf("mannWhitney = U %v, p %v, want U %v, p %v", u, p, tt.u, tt.p)
+			}
+		})
+	}
+}
+
+func TestMannWhitneyApproximation(t *testing.T) {
+	// Ties and large samples use the normal approximation
+	x := []float64{1, 2, 2, 3, 3, 3, 4, 4}
+	y := []float64{3, 4, 4, 5, 5, 6, 6, 7}
+	u, p := mannWhitney(x, y)
+	uSwapped, pSwapped := mannWhitney(y, x)
+	if u+uSwapped != float64(len(x)*len(y)) || math.Abs(p-pSwapped) > 1e-12 {
+		t.Errorf("swapped samples: U %v + %v, p %v vs %v", u, uSwapped, p, pSwapped)
+	}
+	if p <= 0 || p >= significanceLevel {
+		t.Errorf("p = %v, want a significant difference", p)
+	}
+
+	large := make([]float64, 25)
+	shifted := make([]float64, 25)
+	for i := range large {
+		large[i] = float64(i)
+		shifted[i] = float64(i) + 0.5
+	}
+	if _, p := mannWhitney(large, shifted); p < 0.5 {
+		t.Errorf("p = %v for nearly identical large samples", p)
+	}
+}
+
+func TestMannWhitneyExactSumsToOne(t *testing.T) {
+	// The two-sided p-value of the median U is 1
+	for _, n := range [][2]int{{3, 3}, {4, 6}, {5, 5}, {1, 7}} {
+		if p := mannWhitneyExact(float64(n[0]*n[1])/2, n[0], n[1]); p != 1 {
+			t.Errorf("p of median U for %v = %v, want 1", n, p)
+		}
+	}
+}
+
+func TestMannWhitneyMinPValue(t *testing.T) {
+	tests := []struct {
+		n1, n2 int
+		want   float64
+	}{
+		{1, 1, 1},
+		{2, 2, 1.0 / 3},
+		{3, 3, 0.1},
+		{3, 4, 2.0 / 35},
+		{4, 3, 2.0 / 35},
+		{4, 4, 2.0 / 70},
+		{2, 20, 2.0 / 231},
+	}
+
+	for _, tt := range tests {
+		got := mannWhitneyMinPValue(tt.n1, tt.n2)
+		if math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("mannWhitneyMinPValue(%d, %d) = %v, want %v", tt.n1, tt.n2, got, tt.want)
+		}
+		// The bound is the p-value of completely separated samples
+		x, y := make([]float64, tt.n1), make([]float64, tt.n2)
+		for i := range x {
+			x[i] = float64(i)
+		}
+		for i := range y {
+			y[i] = float64(tt.n1 + i)
+		}
+		if _, p := mannWhitney(x, y); math.Abs(p-got) > 1e-12 {
+			t.Errorf("separated %d vs %d: p = %v, want %v", tt.n1, tt.n2, p, got)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := summarize("avg_ms", []float64{9, 2, 4, 4, 4, 5, 5, 7})
+	if s.N != 8 || s.Mean != 5 || s.Median != 4.5 || s.Min != 2 || s.Max != 9 {
+		t.Errorf("summary = %+v", s)
+	}
+	stddev := math.Sqrt(32.0 / 7)
+	if math.Abs(s.StdDev-stddev) > 1e-12 {
+		t.Errorf("stddev = %v, want %v", s.StdDev, stddev)
+	}
+	margin := 2.365 * stddev / math.Sqrt(8)
+	if math.Abs(s.CILow-(5-margin)) > 1e-12 || math.Abs(s.CIHigh-(5+margin)) > 1e-12 {
+		t.Errorf("CI = %v .. %v, want 5 ± %v", s.CILow, s.CIHigh, margin)
+	}
+
+	single := summarize("rps", []float64{100})
+	if single.Median != 100 || single.StdDev != 0 || single.CILow != 100 || single.CIHigh != 100 {
+		t.Errorf("single value summary = %+v", single)
+	}
+	if empty := summarize("rps", nil); empty.N != 0 || empty.Mean != 0 {
+		t.Errorf("empty summary = %+v", empty)
+	}
+}
+
+func TestTCritical95(t *testing.T) {
+	tests := map[int]float64{1: 12.706, 2: 4.303, 9: 2.262, 30: 2.042, 31: 1.96, 1000: 1.96}
+	for df, want := range tests {
+		if got := tCritical95(df); got != want {
+			t.Errorf("tCritical95(%d) = %v, want %v", df, got, want)
+		}
+	}
+	if !math.IsInf(tCritical95(0), 1) {
+		t.Error("tCritical95(0) is finite")
+	}
+}
+
+func TestCompareMetric(t *testing.T) {
+	a := &TargetRuns{Name: "A", Runs: []RunMetrics{{"rps": 100, "p99_ms": 10}, {"rps": 101, "p99_ms": 11}, {"rps": 102, "p99_ms": 12}, {"rps": 103, "p99_ms": 13}}}
+	b := &TargetRuns{Name: "B", Runs: []RunMetrics{{"rps": 110, "p99_ms": 20}, {"rps": 111, "p99_ms": 21}, {"rps": 112, "p99_ms": 22}, {"rps": 113, "p99_ms": 23}}}
+
+	rps, ok := compareMetric("rps", a, b)
+	if !ok || !rps.Significant || rps.Better != "B" || math.Abs(rps.DiffPercent-(111.5-101.5)/101.5*100) > 1e-9 {
+		t.Errorf("rps comparison = %+v, want B better", rps)
+	}
+	p99, ok := compareMetric("p99_ms", a, b)
+	if !ok || !p99.Significant || p99.Better != "A" {
+		t.Errorf("p99 comparison = %+v, want A better", p99)
+	}
+	if _, ok := compareMetric("server_cpu_cores", a, b); ok {
+		t.Error("compared a metric missing in the runs")
+	}
+}
+
+func TestRunMetricsCountsHTTPRequests(t *testing.T) {
+	result := &Result{
+		TotalRequests:  100,
+		FailedRequests: 10,
+		TotalDuration:  10 * time.Second,
+		AvgLatency:     1500 * time.Microsecond,
+		StepsPerCycle:  4,
+		Steps: []*StepStats{
+			{Requests: 100}, {Requests: 100, Failed: 10}, {Requests: 100}, {Requests: 100, Failed: 10},
+		},
+		WarmUp: &WarmUpStats{SteadyState: 3 * time.Second},
+	}
+
+	m := runMetrics(result)
+	if m["rps"] != 40 || m["error_rate"] != 5 || m["avg_ms"] != 1.5 || m["steady_state_s"] != 3 {
+		t.Errorf("metrics = %v", m)
+	}
+	if _, ok := m["warmup_penalty_ms"]; ok {
+		t.Error("warm-up penalty reported without a penalty window")
+	}
+	if m := runMetrics(&Result{}); len(m) != 4 {
+		t.Errorf("metrics of an empty run = %v, want only latencies", m)
+	}
+}
+
+// fakeRuns returns a run function producing results with growing RPS and the number of calls.
+// cancel is called during the run number cancelAt (never if 0)
+func fakeRuns(cancelAt int, cancel context.CancelFunc) (func(Config) (*Result, error), *int) {
+	calls := 0
+	return func(Config) (*Result, error) {
+		calls++
+		if calls == cancelAt {
+			cancel()
+		}
+		return &Result{TotalRequests: int64(100 * calls), TotalDuration: time.Second}, nil
+	}, &calls
+}
+
+func TestRunRepeatedSavesBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	run, calls := fakeRuns(0, nil)
+
+	if err := runRepeated(context.Background(), Config{URL: "http://a"}, RepeatConfig{Runs: 3, SaveBaseline: path}, run); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 {
+		t.Errorf("runs = %d, want 3", *calls)
+	}
+	baseline, err := loadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if baseline.URL != "http://a" || len(baseline.Runs) != 3 || baseline.Runs[2]["rps"] != 300 {
+		t.Errorf("baseline = %+v", baseline)
+	}
+}
+
+func TestRunRepeatedInterrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run, calls := fakeRuns(3, cancel)
+
+	repeat := RepeatConfig{Runs: 3, CompareURL: "http://b", SaveBaseline: path}
+	if err := runRepeated(ctx, Config{URL: "http://a"}, repeat, run); err != nil {
+		t.Fatalf("interrupted series failed: %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("runs = %d, want the series to stop at the interrupted run", *calls)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("baseline of an interrupted series was saved: %v", err)
+	}
+}
+
+func TestRunRepeatedInterruptedFirstRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	run, _ := fakeRuns(1, cancel)
+
+	err := runRepeated(ctx, Config{}, RepeatConfig{Runs: 3}, run)
+	if err == nil || err.Error() != "interrupted before the first run completed" {
+		t.Errorf("error = %v, want interrupted before the first run completed", err)
+	}
+}
+
+func TestLoadBaselineErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.json")
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(empty, []byte(`{"name": "A", "runs": []}`), 0o644)
+	os.WriteFile(invalid, []byte(`{`), 0o644)
+
+	for _, path := range []string{empty, invalid, filepath.Join(dir, "missing.json")} {
+		if _, err := loadBaseline(path); err == nil {
+			t.Errorf("loadBaseline(%s) succeeded", filepath.Base(path))
+		}
+	}
+}
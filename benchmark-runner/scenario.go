@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RateProfile describes how the request rate of an open-model scenario changes over the run.
+// The zero value is the constant rate
+type RateProfile struct {
+	Kind   string        // constant, ramp, burst
+	Ramp   time.Duration // Time to reach the full rate from zero (ramp)
+	On     time.Duration // Time at the full rate at the start of every period (burst)
+	Period time.Duration // Burst period (burst)
+}
+
+// parseRateProfile parses rate profile specification.
+// Supported formats: "constant", "ramp:30s", "burst:10s/1m"
+func parseRateProfile(spec string) (RateProfile, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "constant" {
+		return RateProfile{}, nil
+	}
+
+	kind, value, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "ramp":
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return RateProfile{}, fmt.Errorf("invalid rate profile %q: expected ramp:DURATION", spec)
+		}
+		return RateProfile{Kind: "ramp", Ramp: d}, nil
+	case "burst":
+		onStr, periodStr, ok := strings.Cut(value, "/")
+		if !ok {
+			return RateProfile{}, fmt.Errorf("invalid rate profile %q: expected burst:ON/PERIOD", spec)
+		}
+		on, err := time.ParseDuration(onStr)
+		if err != nil {
+			return RateProfile{}, fmt.Errorf("invalid rate profile %q: %v", spec, err)
+		}
+		period, err := time.ParseDuration(periodStr)
+		if err != nil {
+			return RateProfile{}, fmt.Errorf("invalid rate profile %q: %v", spec, err)
+		}
+		if on <= 0 || period < on {
+			return RateProfile{}, fmt.Errorf("invalid rate profile %q: burst must be positive and not longer than the period", spec)
+		}
+		return RateProfile{Kind: "burst", On: on, Period: period}, nil
+	default:
+		return RateProfile{}, fmt.Errorf("unknown rate profile: %s", kind)
+	}
+}
+
+// Factor returns the share of the full rate (0..1) at the elapsed time of the run
+func (p RateProfile) Factor(elapsed time.Duration) float64 {
+	switch p.Kind {
+	case "ramp":
+		if elapsed >= p.Ramp {
+			return 1
+		}
+		return float64(elapsed) / float64(p.Ramp)
+	case "burst":
+		if elapsed%p.Period < p.On {
+			return 1
+		}
+		return 0
+	default:
+		return 1
+	}
+}
+
+// Average returns the average share of the full rate over the duration
+func (p RateProfile) Average(duration time.Duration) float64 {
+	if p.Kind == "" || duration <= 0 {
+		return 1
+	}
+	const samples = 1000
+	var sum float64
+	for i := 0; i < samples; i++ {
+		sum += p.Factor(duration * time.Duration(2*i+1) / (2 * samples))
+	}
+	return sum / samples
+}
+
+// String returns human readable representation of the profile
+func (p RateProfile) String() string {
+	switch p.Kind {
+	case "ramp":
+		return fmt.Sprintf("ramp %s", p.Ramp)
+	case "burst":
+		return fmt.Sprintf("burst %s every %s", p.On, p.Period)
+	default:
+		return "constant"
+	}
+}
+
+// LoadScenario is one of the concurrent scenarios of a run, with its own rate and worker pool
+type LoadScenario struct {
+	Name   string
+	Config Config
+}
+
+// scenarioFile is the format of -scenarios files
+type scenarioFile struct {
+	Scenarios []scenarioDef `json:"scenarios"`
+}
+
+// scenarioDef is a scenario definition. Empty fields take values of the command line flags
+type scenarioDef struct {
+	Name           string            `json:"name"`
+	Type           string            `json:"type,omitempty"`
+	RPS            int               `json:"rps,omitempty"`
+	Weight         int               `json:"weight,omitempty"` // Share of -rps among weighted scenarios, instead of rps
+	Profile        string            `json:"profile,omitempty"`
+	Concurrency    int               `json:"concurrency,omitempty"`
+	MaxConcurrency int               `json:"max_concurrency,omitempty"`
+	URL            string            `json:"url,omitempty"` // URL template (http), relative to the target URL if it starts with "/"
+	Method         string            `json:"method,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	Captures       []string          `json:"captures,omitempty"` // name=$.json.path or name=header:Name, shared with other scenarios (http)
+	ExpectStatus   string            `json:"expect_status,omitempty"`
+	Journey        string            `json:"journey,omitempty"` // Journey file, relative to the scenarios file
+	Script         string            `json:"script,omitempty"`  // Starlark script, relative to the scenarios file
+}
+
+// loadScenarios reads scenario definitions. base is the configuration of the run
+func loadScenarios(path string, base Config) ([]LoadScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseScenarios(data, path, filepath.Dir(path), base)
+}
+
+// parseScenarios parses and validates scenario definitions. source names them in errors,
+// journey files are resolved relative to dir
+func parseScenarios(data []byte, source, dir string, base Config) ([]LoadScenario, error) {
+	var file scenarioFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse scenarios %s: %v", source, err)
+	}
+	return buildScenarios(file.Scenarios, source, dir, base)
+}
+
+// buildScenarios validates scenario definitions and derives their configurations.
+// Weighted scenarios share the rate of the run in proportion to their weights
+func buildScenarios(defs []scenarioDef, source, dir string, base Config) ([]LoadScenario, error) {
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("scenarios %s: no scenarios", source)
+	}
+
+	var totalWeight int
+	for _, def := range defs {
+		if def.Weight < 0 {
+			return nil, fmt.Errorf("scenarios %s: scenario %q: negative weight", source, def.Name)
+		}
+		if def.Weight > 0 && def.RPS > 0 {
+			return nil, fmt.Errorf("scenarios %s: scenario %q: rps and weight can't be used together", source, def.Name)
+		}
+		totalWeight += def.Weight
+	}
+
+	scenarios := make([]LoadScenario, 0, len(defs))
+	seen := make(map[string]bool, len(defs))
+	for i, def := range defs {
+		if def.Name == "" {
+			def.Name = fmt.Sprintf("scenario-%d", i+1)
+		}
+		if seen[def.Name] {
+			return nil, fmt.Errorf("scenarios %s: duplicate scenario name %q", source, def.Name)
+		}
+		seen[def.Name] = true
+		if def.Weight > 0 {
+			def.RPS = max(1, int(math.Round(float64(base.RPS*def.Weight)/float64(totalWeight))))
+		}
+
+		config, err := def.config(base, dir)
+		if err != nil {
+			return nil, fmt.Errorf("scenarios %s: scenario %q: %v", source, def.Name, err)
+		}
+		scenarios = append(scenarios, LoadScenario{Name: def.Name, Config: config})
+	}
+	return scenarios, nil
+}
+
+// config derives configuration of the scenario from the run configuration
+func (def scenarioDef) config(base Config, dir string) (Config, error) {
+	config := base
+	config.Scenarios = nil
+
+	if def.Type != "" {
+		config.BenchmarkType = BenchmarkType(def.Type)
+	}
+	if def.RPS > 0 {
+		config.RPS = def.RPS
+	}
+	if def.Concurrency > 0 {
+		config.Concurrency = def.Concurrency
+	}
+	if def.MaxConcurrency > 0 {
+		config.MaxConcurrency = def.MaxConcurrency
+	}
+
+	var err error
+	if def.Profile != "" {
+		if config.Profile, err = parseRateProfile(def.Profile); err != nil {
+			return config, err
+		}
+	}
+
+	if def.URL != "" {
+		config.URL = def.URL
+		if strings.HasPrefix(def.URL, "/") {
+			config.URL = strings.TrimSuffix(base.URL, "/") + def.URL
+		}
+	}
+	if def.Method != "" {
+		config.Request.Method = def.Method
+	}
+	if def.Headers != nil {
+		config.Request.Headers = def.Headers
+	}
+	if def.Body != "" {
+		config.Request.Body = def.Body
+	}
+	if def.Captures != nil {
+		config.Request.Captures = nil
+		for _, spec := range def.Captures {
+			rule, err := parseCaptureRule(spec)
+			if err != nil {
+				return config, err
+			}
+			config.Request.Captures = append(config.Request.Captures, rule)
+		}
+	}
+	if def.ExpectStatus != "" {
+		if config.Request.ExpectStatus, err = parseStatusList(def.ExpectStatus); err != nil {
+			return config, err
+		}
+	}
+
+	if def.Journey != "" {
+		path := def.Journey
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if config.Journey, err = loadJourney(path); err != nil {
+			return config, err
+		}
+		config.BenchmarkType = JourneyType
+	}
+	if def.Script != "" {
+		path := def.Script
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if config.Script, err = loadScript(path); err != nil {
+			return config, err
+		}
+		config.BenchmarkType = ScriptType
+	}
+
+	if config.BenchmarkType == UserSession || config.BenchmarkType == ScenariosType {
+		return config, fmt.Errorf("type %s can't be used in scenarios", config.BenchmarkType)
+	}
+	if _, err := resolveOperations(config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRateProfile(t *testing.T) {
+	tests := []struct {
+		spec string
+		want RateProfile
+		err  string
+	}{
+		{spec: "", want: RateProfile{}},
+		{spec: " constant ", want: RateProfile{}},
+		{spec: "ramp:30s", want: RateProfile{Kind: "ramp", Ramp: 30 * time.Second}},
+		{spec: "burst:10s/1m", want: RateProfile{Kind: "burst", On: 10 * time.Second, Period: time.Minute}},
+		{spec: "burst:1m/1m", want: RateProfile{Kind: "burst", On: time.Minute, Period: time.Minute}},
+		{spec: "ramp", err: "expected ramp:DURATION"},
+		{spec: "ramp:0s", err: "expected ramp:DURATION"},
+		{spec: "ramp:-5s", err: "expected ramp:DURATION"},
+		{spec: "burst:10s", err: "expected burst:ON/PERIOD"},
+		{spec: "burst:ten/1m", err: `invalid rate profile "burst:ten/1m"`},
+		{spec: "burst:10s/x", err: `invalid rate profile "burst:10s/x"`},
+		{spec: "burst:2m/1m", err: "not longer than the period"},
+		{spec: "burst:0s/1m", err: "burst must be positive"},
+		{spec: "sine:1m", err: "unknown rate profile: sine"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseRateProfile(tt.spec)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseRateProfile = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateProfileFactor(t *testing.T) {
+	ramp := RateProfile{Kind: "ramp", Ramp: 10 * time.Second}
+	burst := RateProfile{Kind: "burst", On: 10 * time.Second, Period: time.Minute}
+
+	tests := []struct {
+		profile RateProfile
+		elapsed time.Duration
+		want    float64
+	}{
+		{profile: RateProfile{}, elapsed: 0, want: 1},
+		{profile: ramp, elapsed: 0, want: 0},
+		{profile: ramp, elapsed: 2500 * time.Millisecond, want: 0.25},
+		{profile: ramp, elapsed: 10 * time.Second, want: 1},
+		{profile: ramp, elapsed: time.Hour, want: 1},
+		{profile: burst, elapsed: 0, want: 1},
+		{profile: burst, elapsed: 9 * time.Second, want: 1},
+		{profile: burst, elapsed: 10 * time.Second, want: 0},
+		{profile: burst, elapsed: 59 * time.Second, want: 0},
+		{profile: burst, elapsed: 65 * time.Second, want: 1},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.Factor(tt.elapsed); got != tt.want {
+			t.Errorf("%s at %s = %v, want %v", tt.profile, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestRateProfileAverage(t *testing.T) {
+	tests := []struct {
+		profile  RateProfile
+		duration time.Duration
+		want     float64
+	}{
+		{profile: RateProfile{}, duration: time.Minute, want: 1},
+		{profile: RateProfile{Kind: "ramp", Ramp: time.Minute}, duration: time.Minute, want: 0.5},
+		{profile: RateProfile{Kind: "ramp", Ramp: 30 * time.Second}, duration: time.Minute, want: 0.75},
+		{profile: RateProfile{Kind: "burst", On: 10 * time.Second, Period: time.Minute}, duration: 2 * time.Minute, want: 1.0 / 6},
+		{profile: RateProfile{Kind: "ramp", Ramp: time.Minute}, duration: 0, want: 1},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.Average(tt.duration); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("%s over %s = %v, want %v", tt.profile, tt.duration, got, tt.want)
+		}
+	}
+}
+
+func TestRateProfileString(t *testing.T) {
+	tests := map[string]string{
+		"constant":     "constant",
+		"ramp:30s":     "ramp 30s",
+		"burst:10s/1m": "burst 10s every 1m0s",
+	}
+	for spec, want := range tests {
+		profile, err := parseRateProfile(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := profile.String(); got != want {
+			t.Errorf("%s: String = %q, want %q", spec, got, want)
+		}
+	}
+}
+
+func TestParseScenarios(t *testing.T) {
+	base := Config{URL: "http://target:8080/", RPS: 100, Concurrency: 10, MaxConcurrency: 1000, Request: TemplateRequest{Method: "GET"}}
+	data := []byte(`{"scenarios": [
+		{"name": "browse", "type": "get-products", "rps": 80, "profile": "ramp:10s", "concurrency": 4},
+		{"type": "http", "url": "/health", "method": "HEAD", "max_concurrency": 5},
+		{"name": "absolute", "type": "http", "url": "http://other/ping", "captures": ["token=$.token"], "expect_status": "200,204"}
+	]}`)
+
+	scenarios, err := parseScenarios(data, "test.json", ".", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range scenarios {
+		names = append(names, s.Name)
+	}
+	if !slices.Equal(names, []string{"browse", "scenario-2", "absolute"}) {
+		t.Fatalf("names = %v", names)
+	}
+
+	browse := scenarios[0].Config
+	if browse.RPS != 80 || browse.Concurrency != 4 || browse.MaxConcurrency != 1000 || browse.Profile.Kind != "ramp" || browse.Scenarios != nil {
+		t.Errorf("browse = rps %d, concurrency %d/%d, profile %s", browse.RPS, browse.Concurrency, browse.MaxConcurrency, browse.Profile)
+	}
+	health := scenarios[1].Config
+	if health.URL != "http://target:8080/health" || health.Request.Method != "HEAD" || health.RPS != 100 || health.MaxConcurrency != 5 {
+		t.Errorf("health = %s %s, rps %d, max concurrency %d", health.Request.Method, health.URL, health.RPS, health.MaxConcurrency)
+	}
+	absolute := scenarios[2].Config
+	if absolute.URL != "http://other/ping" || len(absolute.Request.Captures) != 1 || !slices.Equal(absolute.Request.ExpectStatus, []int{200, 204}) {
+		t.Errorf("absolute = %s, captures %v, expect %v", absolute.URL, absolute.Request.Captures, absolute.Request.ExpectStatus)
+	}
+	// The base configuration is not modified
+	if base.URL != "http://target:8080/" || base.Request.Method != "GET" {
+		t.Errorf("base changed: %+v", base)
+	}
+}
+
+func TestParseScenariosWeights(t *testing.T) {
+	tests := []struct {
+		name string
+		defs string
+		rps  []int
+	}{
+		{name: "proportional", defs: `[{"weight": 3}, {"weight": 1}]`, rps: []int{75, 25}},
+		{name: "rounded", defs: `[{"weight": 1}, {"weight": 1}, {"weight": 1}]`, rps: []int{33, 33, 33}},
+		{name: "at least one", defs: `[{"weight": 1000}, {"weight": 1}]`, rps: []int{100, 1}},
+		{name: "with fixed rate", defs: `[{"rps": 10}, {"weight": 1}, {}]`, rps: []int{10, 100, 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenarios, err := parseScenarios([]byte(`{"scenarios": `+tt.defs+`}`), "test.json", ".", Config{URL: "http://a", RPS: 100, BenchmarkType: GetProducts})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rps []int
+			for _, s := range scenarios {
+				rps = append(rps, s.Config.RPS)
+			}
+			if !slices.Equal(rps, tt.rps) {
+				t.Errorf("rps = %v, want %v", rps, tt.rps)
+			}
+		})
+	}
+}
+
+func TestParseScenariosErrors(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "journey.json"), []byte(`{"steps": [{"name": "list", "url": "/api/products"}]}`), 0o644)
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "invalid json", data: `{"scenarios": [`, err: "failed to parse scenarios test.json"},
+		{name: "empty", data: `{"scenarios": []}`, err: "scenarios test.json: no scenarios"},
+		{name: "negative weight", data: `{"scenarios": [{"name": "a", "weight": -1}]}`, err: `scenario "a": negative weight`},
+		{name: "rps and weight", data: `{"scenarios": [{"name": "a", "weight": 1, "rps": 5}]}`, err: "rps and weight can't be used together"},
+		{name: "duplicate", data: `{"scenarios": [{"name": "a"}, {"name": "a"}]}`, err: `duplicate scenario name "a"`},
+		{name: "profile", data: `{"scenarios": [{"name": "a", "profile": "ramp"}]}`, err: `scenario "a": invalid rate profile`},
+		{name: "capture", data: `{"scenarios": [{"name": "a", "type": "http", "captures": ["x"]}]}`, err: `invalid capture "x"`},
+		{name: "expect status", data: `{"scenarios": [{"name": "a", "expect_status": "2xx"}]}`, err: "invalid status code"},
+		{name: "missing journey", data: `{"scenarios": [{"name": "a", "journey": "missing.json"}]}`, err: "missing.json"},
+		{name: "user session", data: `{"scenarios": [{"name": "a", "type": "user-session"}]}`, err: "type user-session can't be used in scenarios"},
+		{name: "unknown type", data: `{"scenarios": [{"name": "a", "type": "get-all"}]}`, err: "unknown benchmark type: get-all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseScenarios([]byte(tt.data), "test.json", dir, Config{URL: "http://a", RPS: 100, BenchmarkType: GetProducts})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+
+	// Journey files are resolved relative to the scenarios file
+	scenarios, err := loadScenarios(writeScenarios(t, dir, `{"scenarios": [{"name": "j", "journey": "journey.json"}]}`), Config{URL: "http://a", RPS: 1})
+	if err != nil || scenarios[0].Config.BenchmarkType != JourneyType {
+		t.Errorf("journey scenario = %+v, %v", scenarios, err)
+	}
+}
+
+// writeScenarios writes a scenarios file into dir and returns its path
+func writeScenarios(t *testing.T, dir, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, "scenarios.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCombineResults(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		latencies := make([]time.Duration, len(values))
+		for i, v := range values {
+			latencies[i] = time.Duration(v) * time.Millisecond
+		}
+		return latencies
+	}
+	results := []*Result{
+		{
+			Scenario: "browse", TotalRequests: 4, FailedRequests: 1, TotalDuration: 10 * time.Second, Latencies: ms(1, 2, 3, 4),
+			TargetRPS: 80, Concurrency: 4, MaxConcurrency: 100, WorkerPoolSize: 6, PeakConcurrency: 5, SaturatedTicks: 2, Dispatched: 4,
+		},
+		{
+			// Two cycles of a two-step scenario are four HTTP requests
+			Scenario: "crud", TotalRequests: 2, FailedRequests: 1, TotalDuration: 10 * time.Second, Latencies: ms(100, 100),
+			TargetRPS: 20, Concurrency: 2, MaxConcurrency: 50, WorkerPoolSize: 2, PeakConcurrency: 2, Dispatched: 2,
+			StepsPerCycle: 2,
+			Steps: []*StepStats{
+				{Name: "create", Requests: 2, Failed: 1, Latencies: ms(60, 70)},
+				{Name: "delete", Requests: 2, Latencies: ms(30, 40)},
+			},
+		},
+	}
+
+	combined := combineResults(results)
+	if combined.BenchmarkType != ScenariosType || combined.TotalDuration != 10*time.Second {
+		t.Errorf("type %s, duration %s", combined.BenchmarkType, combined.TotalDuration)
+	}
+	if combined.TotalRequests != 8 || combined.FailedRequests != 2 || combined.SuccessRequests != 6 {
+		t.Errorf("requests = %d total, %d failed, %d ok, want 8, 2, 6", combined.TotalRequests, combined.FailedRequests, combined.SuccessRequests)
+	}
+	if combined.TargetRPS != 100 || combined.Concurrency != 6 || combined.MaxConcurrency != 150 || combined.WorkerPoolSize != 8 ||
+		combined.PeakConcurrency != 7 || combined.SaturatedTicks != 2 || combined.Dispatched != 6 {
+		t.Errorf("pool = %+v", combined)
+	}
+	// Latencies of steps, not cycles
+	if combined.MaxLatency != 70*time.Millisecond || combined.MinLatency != time.Millisecond || len(combined.Latencies) != 8 {
+		t.Errorf("latency min %s, max %s over %d requests", combined.MinLatency, combined.MaxLatency, len(combined.Latencies))
+	}
+	if combined.AvgLatency != 26250*time.Microsecond {
+		t.Errorf("avg = %s, want 26.25ms", combined.AvgLatency)
+	}
+}
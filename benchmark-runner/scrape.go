@@ -0,0 +1,493 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricSample is a single sample of Prometheus text exposition format
+type MetricSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// MetricsSnapshot is the result of one scrape of the target's metrics endpoint
+type MetricsSnapshot struct {
+	Time    time.Time
+	Samples []MetricSample
+}
+
+// ServerStats are server-side statistics computed from the target's own metrics
+type ServerStats struct {
+	MetricsURL   string
+	Scrapes      int
+	ScrapeErrors int
+
+	Requests      float64       // Requests counted by the server during the run
+	ErrorRequests float64       // 5xx responses counted by the server
+	AvgLatency    time.Duration // Server-side request duration
+	P50Latency    time.Duration // Estimated from histogram buckets (0 if not exposed)
+	P95Latency    time.Duration
+	P99Latency    time.Duration
+
+	CPUSeconds     float64 // Process CPU time consumed during the run
+	AvgCPUCores    float64 // CPUSeconds / wall time
+	MemoryStartMB  float64 // Process memory (RSS, or JVM used memory) before the run
+	MemoryEndMB    float64
+	MemoryMaxMB    float64
+	DBPoolMax      float64 // Connection pool size limit (GORM only)
+	DBPoolMaxUsed  float64 // High-water mark of connections in use since the pool started (Agroal only)
+	DBPoolInUseMax float64 // Maximum connections in use seen during the run
+	DBPoolOpenMax  float64 // Maximum open connections seen during the run
+	DBPoolWaits    float64 // Requests that waited for a pool connection during the run
+}
+
+// serverMetricNames maps server-side statistics to metric names of the
+// Gin application (client_golang, GORM plugin) and Quarkus (Micrometer, Agroal)
+var serverMetricNames = struct {
+	requests, durationSum, durationCount, durationBucket []string
+	cpuSeconds, cpuNanos, memory, jvmMemory              []string
+	poolMax, poolMaxUsed, poolInUse, poolOpen, poolWaits []string
+}{
+	requests:       []string{"http_requests_total", "http_server_requests_seconds_count"},
+	durationSum:    []string{"http_request_duration_seconds_sum", "http_server_requests_seconds_sum"},
+	durationCount:  []string{"http_request_duration_seconds_count", "http_server_requests_seconds_count"},
+	durationBucket: []string{"http_request_duration_seconds_bucket", "http_server_requests_seconds_bucket"},
+	cpuSeconds:     []string{"process_cpu_seconds_total"},
+	cpuNanos:       []string{"process_cpu_time_ns_total"},
+	memory:         []string{"process_resident_memory_bytes"},
+	jvmMemory:      []string{"jvm_memory_used_bytes"},
+	poolMax:        []string{"gorm_dbstats_max_open_connections"},
+	poolMaxUsed:    []string{"agroal_max_used_count"},
+	poolInUse:      []string{"gorm_dbstats_in_use", "agroal_active_count"},
+	poolOpen:       []string{"gorm_dbstats_open_connections"},
+	poolWaits:      []string{"gorm_dbstats_wait_count", "agroal_awaiting_count"},
+}
+
+// metricsScraper periodically scrapes the target's metrics endpoint during the run
+type metricsScraper struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	snapshots []*MetricsSnapshot
+	errors    int
+}
+
+// newMetricsScraper creates scraper for metrics URL. "auto" detects /metrics or /q/metrics
+// on the target host. Returns nil if scraping is disabled or the endpoint can't be found
+func newMetricsScraper(metricsURL, targetURL string, interval time.Duration) *metricsScraper {
+	if metricsURL == "" {
+		return nil
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	s := &metricsScraper{
+		url:      metricsURL,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		done:     make(chan struct{}),
+	}
+
+	if metricsURL == "auto" {
+		detected, err := s.detect(targetURL)
+		if err != nil {
+			log.Printf("Metrics scraping disabled: %v", err)
+			return nil
+		}
+		s.url = detected
+	}
+	return s
+}
+
+// detect finds metrics endpoint of the Gin (/metrics) or Quarkus (/q/metrics) application
+func (s *metricsScraper) detect(targetURL string) (string, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil || target.Host == "" {
+		return "", fmt.Errorf("can't derive metrics URL from %q", targetURL)
+	}
+
+	for _, path := range []string{"/metrics", "/q/metrics"} {
+		candidate := target.Scheme + "://" + target.Host + path
+		if snapshot, err := s.scrapeURL(candidate); err == nil && len(snapshot.Samples) > 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no metrics endpoint found at %s (tried /metrics, /q/metrics)", target.Host)
+}
+
+// Start takes the "before" snapshot and starts periodic scraping
+func (s *metricsScraper) Start() {
+	if s == nil {
+		return
+	}
+	s.scrape()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				s.scrape()
+			}
+		}
+	}()
+}
+
+// Stop takes the "after" snapshot and computes server-side statistics
+func (s *metricsScraper) Stop() *ServerStats {
+	if s == nil {
+		return nil
+	}
+	close(s.done)
+	s.wg.Wait()
+	s.scrape()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := &ServerStats{
+		MetricsURL:   s.url,
+		Scrapes:      len(s.snapshots),
+		ScrapeErrors: s.errors,
+	}
+	if len(s.snapshots) < 2 {
+		return stats
+	}
+	computeServerStats(stats, s.snapshots)
+	return stats
+}
+
+// scrape takes a snapshot and stores it
+func (s *metricsScraper) scrape() {
+	snapshot, err := s.scrapeURL(s.url)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		if s.errors == 0 {
+			log.Printf("Failed to scrape %s: %v", s.url, err)
+		}
+		s.errors++
+		return
+	}
+	s.snapshots = append(s.snapshots, snapshot)
+}
+
+// scrapeURL fetches and parses metrics
+func (s *metricsScraper) scrapeURL(metricsURL string) (*MetricsSnapshot, error) {
+	resp, err := s.client.Get(metricsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	samples, err := parsePrometheusText(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsSnapshot{Time: time.Now(), Samples: samples}, nil
+}
+
+// computeServerStats calculates deltas between the first and the last snapshot
+// and maxima of gauges over all snapshots
+func computeServerStats(stats *ServerStats, snapshots []*MetricsSnapshot) {
+	names := serverMetricNames
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+	wall := last.Time.Sub(first.Time).Seconds()
+
+	stats.Requests = last.sum(names.requests, isAPIRequest) - first.sum(names.requests, isAPIRequest)
+	stats.ErrorRequests = last.sum(names.requests, isServerError) - first.sum(names.requests, isServerError)
+
+	durationCount := last.sum(names.durationCount, isAPIRequest) - first.sum(names.durationCount, isAPIRequest)
+	if durationCount > 0 {
+		durationSum := last.sum(names.durationSum, isAPIRequest) - first.sum(names.durationSum, isAPIRequest)
+		stats.AvgLatency = secondsToDuration(durationSum / durationCount)
+	}
+
+	buckets := histogramDelta(first.buckets(names.durationBucket), last.buckets(names.durationBucket))
+	stats.P50Latency = secondsToDuration(histogramQuantile(0.50, buckets))
+	stats.P95Latency = secondsToDuration(histogramQuantile(0.95, buckets))
+	stats.P99Latency = secondsToDuration(histogramQuantile(0.99, buckets))
+
+	if last.has(names.cpuSeconds) {
+		stats.CPUSeconds = last.sum(names.cpuSeconds, nil) - first.sum(names.cpuSeconds, nil)
+	} else if last.has(names.cpuNanos) {
+		stats.CPUSeconds = (last.sum(names.cpuNanos, nil) - first.sum(names.cpuNanos, nil)) / 1e9
+	}
+	if wall > 0 {
+		stats.AvgCPUCores = stats.CPUSeconds / wall
+	}
+
+	memory := func(snapshot *MetricsSnapshot) float64 {
+		if snapshot.has(names.memory) {
+			return snapshot.sum(names.memory, nil) / 1024 / 1024
+		}
+		return snapshot.sum(names.jvmMemory, nil) / 1024 / 1024
+	}
+	stats.MemoryStartMB = memory(first)
+	stats.MemoryEndMB = memory(last)
+
+	for _, snapshot := range snapshots {
+		stats.MemoryMaxMB = math.Max(stats.MemoryMaxMB, memory(snapshot))
+		stats.DBPoolMax = math.Max(stats.DBPoolMax, snapshot.sum(names.poolMax, nil))
+		stats.DBPoolMaxUsed = math.Max(stats.DBPoolMaxUsed, snapshot.sum(names.poolMaxUsed, nil))
+		stats.DBPoolInUseMax = math.Max(stats.DBPoolInUseMax, snapshot.sum(names.poolInUse, nil))
+		stats.DBPoolOpenMax = math.Max(stats.DBPoolOpenMax, snapshot.sum(names.poolOpen, nil))
+	}
+
+	// gorm_dbstats_wait_count is a counter, agroal_awaiting_count is a gauge
+	if last.has([]string{"gorm_dbstats_wait_count"}) {
+		stats.DBPoolWaits = last.sum(names.poolWaits, nil) - first.sum(names.poolWaits, nil)
+	} else {
+		for _, snapshot := range snapshots {
+			stats.DBPoolWaits = math.Max(stats.DBPoolWaits, snapshot.sum(names.poolWaits, nil))
+		}
+	}
+}
+
+// isAPIRequest excludes requests of Quarkus management endpoints (/q/metrics, /q/health)
+func isAPIRequest(labels map[string]string) bool {
+	return !strings.HasPrefix(labels["uri"], "/q/")
+}
+
+// isServerError selects API requests with 5xx status
+func isServerError(labels map[string]string) bool {
+	return isAPIRequest(labels) && strings.HasPrefix(labels["status"], "5")
+}
+
+// has reports whether snapshot contains any of the metrics
+func (s *MetricsSnapshot) has(names []string) bool {
+	for _, sample := range s.Samples {
+		for _, name := range names {
+			if sample.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sum returns sum of samples of the first metric name present in the snapshot.
+// Samples are filtered by labels if filter is not nil
+func (s *MetricsSnapshot) sum(names []string, filter func(map[string]string) bool) float64 {
+	for _, name := range names {
+		found := false
+		var total float64
+		for _, sample := range s.Samples {
+			if sample.Name != name {
+				continue
+			}
+			found = true
+			if filter == nil || filter(sample.Labels) {
+				total += sample.Value
+			}
+		}
+		if found {
+			return total
+		}
+	}
+	return 0
+}
+
+// buckets returns cumulative histogram buckets summed over all label sets, keyed by upper bound
+func (s *MetricsSnapshot) buckets(names []string) map[float64]float64 {
+	for _, name := range names {
+		result := make(map[float64]float64)
+		for _, sample := range s.Samples {
+			if sample.Name != name || !isAPIRequest(sample.Labels) {
+				continue
+			}
+			le, err := strconv.ParseFloat(sample.Labels["le"], 64)
+			if err != nil {
+				continue
+			}
+			result[le] += sample.Value
+		}
+		if len(result) > 0 {
+			return result
+		}
+	}
+	return nil
+}
+
+// histogramBucket is a cumulative histogram bucket
+type histogramBucket struct {
+	UpperBound float64
+	Count      float64
+}
+
+// histogramDelta returns sorted buckets of observations made between two snapshots
+func histogramDelta(before, after map[float64]float64) []histogramBucket {
+	buckets := make([]histogramBucket, 0, len(after))
+	for le, count := range after {
+		buckets = append(buckets, histogramBucket{UpperBound: le, Count: count - before[le]})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].UpperBound < buckets[j].UpperBound
+	})
+	return buckets
+}
+
+// histogramQuantile estimates quantile from cumulative buckets using linear
+// interpolation, the same way as PromQL histogram_quantile
+func histogramQuantile(q float64, buckets []histogramBucket) float64 {
+	if len(buckets) == 0 {
+		return 0
+	}
+	total := buckets[len(buckets)-1].Count
+	if total <= 0 {
+		return 0
+	}
+
+	rank := q * total
+	lowerBound, lowerCount := 0.0, 0.0
+	for _, bucket := range buckets {
+		if bucket.Count >= rank {
+			if math.IsInf(bucket.UpperBound, 1) {
+				return lowerBound
+			}
+			if bucket.Count == lowerCount {
+				return bucket.UpperBound
+			}
+			return lowerBound + (bucket.UpperBound-lowerBound)*(rank-lowerCount)/(bucket.Count-lowerCount)
+		}
+		lowerBound, lowerCount = bucket.UpperBound, bucket.Count
+	}
+	return lowerBound
+}
+
+// secondsToDuration converts float seconds into duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// parsePrometheusText parses Prometheus text exposition format
+func parsePrometheusText(r io.Reader) ([]MetricSample, error) {
+	var samples []MetricSample
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sample, err := parseMetricLine(line)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+// parseMetricLine parses `name{label="value",...} value [timestamp]`
+func parseMetricLine(line string) (MetricSample, error) {
+	sample := MetricSample{Labels: make(map[string]string)}
+
+	rest := line
+	if brace := strings.IndexByte(line, '{'); brace >= 0 {
+		sample.Name = line[:brace]
+		end := strings.LastIndexByte(line, '}')
+		if end < brace {
+			return sample, fmt.Errorf("invalid metric line: %s", line)
+		}
+		if err := parseLabels(line[brace+1:end], sample.Labels); err != nil {
+			return sample, fmt.Errorf("invalid labels in line %q: %v", line, err)
+		}
+		rest = line[end+1:]
+	} else {
+		space := strings.IndexAny(line, " \t")
+		if space < 0 {
+			return sample, fmt.Errorf("invalid metric line: %s", line)
+		}
+		sample.Name = line[:space]
+		rest = line[space:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return sample, fmt.Errorf("metric line without value: %s", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, fmt.Errorf("invalid value in line %q: %v", line, err)
+	}
+	sample.Value = value
+
+	return sample, nil
+}
+
+// parseLabels parses `label="value",label2="value2"` with escaped quotes
+func parseLabels(text string, labels map[string]string) error {
+	for len(strings.TrimSpace(text)) > 0 {
+		text = strings.TrimLeft(text, " ,")
+		if text == "" {
+			// Trailing comma is allowed by the exposition format
+			break
+		}
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return fmt.Errorf("missing '='")
+		}
+		name := strings.TrimSpace(text[:eq])
+		text = strings.TrimSpace(text[eq+1:])
+		if !strings.HasPrefix(text, `"`) {
+			return fmt.Errorf("label %s value is not quoted", name)
+		}
+
+		var value strings.Builder
+		i := 1
+		for ; i < len(text); i++ {
+			c := text[i]
+			if c == '\\' && i+1 < len(text) {
+				i++
+				switch text[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(text[i])
+				}
+				continue
+			}
+			if c == '"' {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if i >= len(text) {
+			return fmt.Errorf("label %s value is not terminated", name)
+		}
+
+		labels[name] = value.String()
+		text = text[i+1:]
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMetricLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		name   string
+		labels map[string]string
+		value  float64
+		err    bool
+	}{
+		{line: "process_cpu_seconds_total 12.5", name: "process_cpu_seconds_total", value: 12.5},
+		{line: "up\t1", name: "up", value: 1},
+		{line: "requests_total 3 1700000000000", name: "requests_total", value: 3},
+		{line: "gauge +Inf", name: "gauge", value: math.Inf(1)},
+		{line: "small 1.5e-3", name: "small", value: 0.0015},
+		{
+			line:   `http_requests_total{method="GET",status="200"} 1027`,
+			name:   "http_requests_total",
+			labels: map[string]string{"method": "GET", "status": "200"},
+			value:  1027,
+		},
+		{
+			line:   `http_server_requests_seconds_bucket{uri="/api/products", le="0.005",} 42`,
+			name:   "http_server_requests_seconds_bucket",
+			labels: map[string]string{"uri": "/api/products", "le": "0.005"},
+			value:  42,
+		},
+		{
+			line:   `msg{text="say \"hi\"\nbye",path="C:\\tmp",brace="}"} 1`,
+			name:   "msg",
+			labels: map[string]string{"text": "say \"hi\"\nbye", "path": `C:\tmp`, "brace": "}"},
+			value:  1,
+		},
+		{line: "no_value", err: true},
+		{line: `labels_only{a="b"}`, err: true},
+		{line: "bad_value abc", err: true},
+		{line: `unquoted{a=b} 1`, err: true},
+		{line: `no_equals{a} 1`, err: true},
+		{line: `unterminated{a="b} 1`, err: true},
+		{line: `unclosed{a="b" 1`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			sample, err := parseMetricLine(tt.line)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				return
+			}
+			if sample.Name != tt.name || sample.Value != tt.value {
+				t.Errorf("sample = %s %v, want %s %v", sample.Name, sample.Value, tt.name, tt.value)
+			}
+			if len(sample.Labels) != len(tt.labels) {
+				t.Fatalf("labels = %v, want %v", sample.Labels, tt.labels)
+			}
+			for name, value := range tt.labels {
+				if sample.Labels[name] != value {
+					t.Errorf("label %s = %q, want %q", name, sample.Labels[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePrometheusText(t *testing.T) {
+	text := `# HELP http_requests_total Total requests
+# TYPE http_requests_total counter
+http_requests_total{status="200"} 10
+
+http_requests_total{status="500"} 2
+   process_resident_memory_bytes 1.048576e+06
+`
+	samples, err := parsePrometheusText(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("samples = %d, want 3", len(samples))
+	}
+	snapshot := &MetricsSnapshot{Samples: samples}
+	if got := snapshot.sum([]string{"http_requests_total"}, nil); got != 12 {
+		t.Errorf("sum = %v, want 12", got)
+	}
+	if got := snapshot.sum([]string{"http_requests_total"}, isServerError); got != 2 {
+		t.Errorf("5xx sum = %v, want 2", got)
+	}
+
+	if _, err := parsePrometheusText(strings.NewReader("ok 1\nbroken\n")); err == nil {
+		t.Error("invalid line parsed without error")
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := []histogramBucket{
+		{UpperBound: 0.1, Count: 50},
+		{UpperBound: 0.2, Count: 90},
+		{UpperBound: 0.5, Count: 100},
+		{UpperBound: math.Inf(1), Count: 100},
+	}
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.25, 0.05},
+		{0.5, 0.1},
+		{0.7, 0.15},
+		{0.95, 0.35},
+		{1, 0.5},
+	}
+	for _, tt := range tests {
+		if got := histogramQuantile(tt.q, buckets); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("histogramQuantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	// Observations above the largest finite bucket are reported at its bound
+	overflow := []histogramBucket{{UpperBound: 0.1, Count: 1}, {UpperBound: math.Inf(1), Count: 10}}
+	if got := histogramQuantile(0.99, overflow); got != 0.1 {
+		t.Errorf("overflow quantile = %v, want 0.1", got)
+	}
+	if got := histogramQuantile(0.5, nil); got != 0 {
+		t.Errorf("empty quantile = %v, want 0", got)
+	}
+	if got := histogramQuantile(0.5, []histogramBucket{{UpperBound: 1, Count: 0}}); got != 0 {
+		t.Errorf("zero count quantile = %v, want 0", got)
+	}
+}
+
+func TestHistogramDelta(t *testing.T) {
+	buckets := histogramDelta(map[float64]float64{0.1: 5, 1: 8}, map[float64]float64{1: 20, 0.1: 10, math.Inf(1): 21})
+	want := []histogramBucket{{0.1, 5}, {1, 12}, {math.Inf(1), 21}}
+	if len(buckets) != len(want) {
+		t.Fatalf("buckets = %v, want %v", buckets, want)
+	}
+	for i := range want {
+		if buckets[i] != want[i] {
+			t.Errorf("bucket %d = %v, want %v", i, buckets[i], want[i])
+		}
+	}
+}
+
+// snapshotAt parses metrics text into a snapshot taken at offset
+func snapshotAt(t *testing.T, offset time.Duration, text string) *MetricsSnapshot {
+	t.Helper()
+	samples, err := parsePrometheusText(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &MetricsSnapshot{Time: time.Unix(0, 0).Add(offset), Samples: samples}
+}
+
+func TestComputeServerStatsGin(t *testing.T) {
+	snapshots := []*MetricsSnapshot{
+		snapshotAt(t, 0, `
+http_requests_total{path="/api/products",status="200"} 100
+http_requests_total{path="/api/products",status="500"} 1
+http_request_duration_seconds_sum 1
+http_request_duration_seconds_count 101
+http_request_duration_seconds_bucket{le="0.01"} 100
+http_request_duration_seconds_bucket{le="0.1"} 101
+http_request_duration_seconds_bucket{le="+Inf"} 101
+process_cpu_seconds_total 10
+process_resident_memory_bytes 10485760
+gorm_dbstats_max_open_connections 25
+gorm_dbstats_in_use 1
+gorm_dbstats_open_connections 2
+gorm_dbstats_wait_count 3
+`),
+		snapshotAt(t, 5*time.Second, `
+http_requests_total{path="/api/products",status="200"} 500
+http_requests_total{path="/api/products",status="500"} 1
+process_cpu_seconds_total 15
+process_resident_memory_bytes 31457280
+gorm_dbstats_max_open_connections 25
+gorm_dbstats_in_use 9
+gorm_dbstats_open_connections 10
+gorm_dbstats_wait_count 5
+`),
+		snapshotAt(t, 10*time.Second, `
+http_requests_total{path="/api/products",status="200"} 1000
+http_requests_total{path="/api/products",status="500"} 11
+http_request_duration_seconds_sum 19.2
+http_request_duration_seconds_count 1011
+http_request_duration_seconds_bucket{le="0.01"} 600
+http_request_duration_seconds_bucket{le="0.1"} 1011
+http_request_duration_seconds_bucket{le="+Inf"} 1011
+process_cpu_seconds_total 20
+process_resident_memory_bytes 20971520
+gorm_dbstats_max_open_connections 25
+gorm_dbstats_in_use 2
+gorm_dbstats_open_connections 10
+gorm_dbstats_wait_count 10
+`),
+	}
+
+	stats := &ServerStats{}
+	computeServerStats(stats, snapshots)
+
+	if stats.Requests != 910 || stats.ErrorRequests != 10 {
+		t.Errorf("requests = %v (%v 5xx), want 910 (10)", stats.Requests, stats.ErrorRequests)
+	}
+	if stats.AvgLatency != 20*time.Millisecond {
+		t.Errorf("avg latency = %s, want 20ms", stats.AvgLatency)
+	}
+	// 500 of 910 new observations fell into the first bucket
+	if stats.P50Latency <= 0 || stats.P50Latency > 10*time.Millisecond || stats.P99Latency <= 10*time.Millisecond {
+		t.Errorf("percentiles = p50 %s, p99 %s", stats.P50Latency, stats.P99Latency)
+	}
+	if stats.CPUSeconds != 10 || stats.AvgCPUCores != 1 {
+		t.Errorf("cpu = %vs, %v cores, want 10s, 1 core", stats.CPUSeconds, stats.AvgCPUCores)
+	}
+	if stats.MemoryStartMB != 10 || stats.MemoryEndMB != 20 || stats.MemoryMaxMB != 30 {
+		t.Errorf("memory = %v -> %v, max %v", stats.MemoryStartMB, stats.MemoryEndMB, stats.MemoryMaxMB)
+	}
+	if stats.DBPoolMax != 25 || stats.DBPoolMaxUsed != 0 || stats.DBPoolInUseMax != 9 || stats.DBPoolOpenMax != 10 || stats.DBPoolWaits != 7 {
+		t.Errorf("pool = %+v", stats)
+	}
+}
+
+func TestComputeServerStatsQuarkus(t *testing.T) {
+	snapshots := []*MetricsSnapshot{
+		snapshotAt(t, 0, `
+http_server_requests_seconds_count{uri="/api/products",status="200"} 10
+http_server_requests_seconds_count{uri="/q/metrics",status="200"} 1
+http_server_requests_seconds_sum{uri="/api/products",status="200"} 0.1
+process_cpu_time_ns_total 1e9
+jvm_memory_used_bytes{area="heap"} 1048576
+jvm_memory_used_bytes{area="nonheap"} 1048576
+agroal_max_used_count{datasource="default"} 20
+agroal_active_count{datasource="default"} 0
+agroal_awaiting_count{datasource="default"} 0
+`),
+		snapshotAt(t, 2*time.Second, `
+http_server_requests_seconds_count{uri="/api/products",status="200"} 110
+http_server_requests_seconds_count{uri="/q/metrics",status="200"} 2
+http_server_requests_seconds_sum{uri="/api/products",status="200"} 0.6
+process_cpu_time_ns_total 3e9
+jvm_memory_used_bytes{area="heap"} 2097152
+jvm_memory_used_bytes{area="nonheap"} 1048576
+agroal_max_used_count{datasource="default"} 20
+agroal_active_count{datasource="default"} 6
+agroal_awaiting_count{datasource="default"} 4
+`),
+	}
+
+	stats := &ServerStats{}
+	computeServerStats(stats, snapshots)
+
+	if stats.Requests != 100 {
+		t.Errorf("requests = %v, want 100 without management endpoints", stats.Requests)
+	}
+	if stats.AvgLatency != 5*time.Millisecond {
+		t.Errorf("avg latency = %s, want 5ms", stats.AvgLatency)
+	}
+	if stats.CPUSeconds != 2 || stats.AvgCPUCores != 1 {
+		t.Errorf("cpu = %vs, %v cores, want 2s, 1 core", stats.CPUSeconds, stats.AvgCPUCores)
+	}
+	if stats.MemoryStartMB != 2 || stats.MemoryEndMB != 3 {
+		t.Errorf("memory = %v -> %v, want 2 -> 3", stats.MemoryStartMB, stats.MemoryEndMB)
+	}
+	// agroal_max_used_count is a high-water mark, not the pool size limit
+	if stats.DBPoolMax != 0 || stats.DBPoolMaxUsed != 20 || stats.DBPoolInUseMax != 6 || stats.DBPoolWaits != 4 {
+		t.Errorf("pool = %+v", stats)
+	}
+}
+
+func TestMetricsScraperDetect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/q/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("http_server_requests_seconds_count 1\n"))
+	}))
+	defer server.Close()
+
+	scraper := newMetricsScraper("auto", server.URL+"/api/products", time.Second)
+	if scraper == nil || scraper.url != server.URL+"/q/metrics" {
+		t.Fatalf("scraper = %+v, want /q/metrics detected", scraper)
+	}
+	if newMetricsScraper("", server.URL, time.Second) != nil {
+		t.Error("scraper created without metrics URL")
+	}
+	if newMetricsScraper("auto", "not a url", time.Second) != nil {
+		t.Error("scraper created for invalid target")
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// scriptMaxSteps limits Starlark execution steps of one call, so a runaway loop fails the request
+// instead of hanging the worker
+const scriptMaxSteps = 1_000_000
+
+// scriptMaxRandString limits the length of rand_string, so a bad argument fails the call
+// instead of allocating gigabytes
+const scriptMaxRandString = 1 << 20
+
+// scriptFileOptions are Starlark dialect options of scripts: while loops and top-level statements are allowed
+var scriptFileOptions = &syntax.FileOptions{
+	Set:             true,
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+}
+
+// errSkipStep is returned by BuildRequest of a script step whose request function returned None
+var errSkipStep = errors.New("step skipped")
+
+// ScriptError is returned when a script function fails or returns an unexpected value
+type ScriptError struct {
+	Message string
+}
+
+func (e *ScriptError) Error() string {
+	return "script error: " + e.Message
+}
+
+// Script is a Starlark script building requests and inspecting responses (script).
+//
+// A script defines functions of a single step at the top level:
+//
+//	def request(state): ...        # returns dict(method, url, headers, body or json), None skips the step
+//	def check(response): ...       # optional: returns None/True, or False/a message if the response failed
+//	def extract(state, response):  # optional: stores values in state for the following steps
+//
+// or several steps as steps = [{"name": ..., "request": ..., "check": ..., "extract": ...}].
+// state is a dict shared by the steps of one iteration
+type Script struct {
+	Name  string
+	steps []scriptStep
+
+	calls  int64 // Function calls, atomic
+	nanos  int64 // Time spent in calls, atomic
+	errors int64 // Failed calls, atomic
+}
+
+// scriptStep is a step of a script
+type scriptStep struct {
+	name    string
+	request starlark.Callable
+	check   starlark.Callable // Optional
+	extract starlark.Callable // Optional
+}
+
+// ScriptStats is the time spent in script functions during the run
+type ScriptStats struct {
+	Name    string
+	Calls   int64
+	Errors  int64
+	AvgTime time.Duration
+}
+
+// scriptBuiltins are functions available in scripts in addition to the Starlark built-ins
+var scriptBuiltins = starlark.StringDict{
+	"json": json.Module,
+	"rand_int": starlark.NewBuiltin("rand_int", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var min, max int
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &min, &max); err != nil {
+			return nil, err
+		}
+		if max <= min {
+			return starlark.MakeInt(min), nil
+		}
+		span := uint64(max) - uint64(min)
+		if span >= math.MaxInt64 {
+			return nil, fmt.Errorf("%s: range %d-%d is too large", b.Name(), min, max)
+		}
+		return starlark.MakeInt64(int64(min) + mathrand.Int63n(int64(span)+1)), nil
+	}),
+	"rand_float": starlark.NewBuiltin("rand_float", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var min, max float64
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &min, &max); err != nil {
+			return nil, err
+		}
+		return starlark.Float(min + mathrand.Float64()*(max-min)), nil
+	}),
+	"rand_string": starlark.NewBuiltin("rand_string", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var n int
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &n); err != nil {
+			return nil, err
+		}
+		if n < 0 || n > scriptMaxRandString {
+			return nil, fmt.Errorf("%s: length %d is out of range 0-%d", b.Name(), n, scriptMaxRandString)
+		}
+		const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+		text := make([]byte, n)
+		for i := range text {
+			text[i] = letters[mathrand.Intn(len(letters))]
+		}
+		return starlark.String(text), nil
+	}),
+	"uuid": starlark.NewBuiltin("uuid", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return starlark.String(newUUID()), nil
+	}),
+	"seq": starlark.NewBuiltin("seq", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return starlark.MakeInt64(atomic.AddInt64(&requestSequence, 1)), nil
+	}),
+	"timestamp": starlark.NewBuiltin("timestamp", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return starlark.MakeInt64(time.Now().UnixMilli()), nil
+	}),
+}
+
+// loadScript reads and validates a script
+func loadScript(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseScript(data, path)
+}
+
+// parseScript executes the script's top level and collects its steps. source names it in errors
+func parseScript(data []byte, source string) (*Script, error) {
+	script := &Script{Name: strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))}
+
+	thread := script.thread()
+	globals, err := starlark.ExecFileOptions(scriptFileOptions, thread, source, data, scriptBuiltins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load script %s: %v", source, scriptErrorMessage(err))
+	}
+
+	steps, ok := globals["steps"]
+	if !ok {
+		step, err := newScriptStep("script", globals)
+		if err != nil {
+			return nil, fmt.Errorf("script %s: %v", source, err)
+		}
+		script.steps = []scriptStep{step}
+		return script, nil
+	}
+
+	list, ok := steps.(*starlark.List)
+	if !ok || list.Len() == 0 {
+		return nil, fmt.Errorf("script %s: steps must be a non-empty list of dicts", source)
+	}
+	seen := make(map[string]bool, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		dict, ok := list.Index(i).(*starlark.Dict)
+		if !ok {
+			return nil, fmt.Errorf("script %s: step %d is %s, not a dict", source, i+1, list.Index(i).Type())
+		}
+		fields := make(starlark.StringDict, dict.Len())
+		for _, item := range dict.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("script %s: step %d has a non-string key %s", source, i+1, item[0])
+			}
+			fields[key] = item[1]
+		}
+
+		name := fmt.Sprintf("step-%d", i+1)
+		if value, ok := fields["name"]; ok {
+			if name, ok = starlark.AsString(value); !ok {
+				return nil, fmt.Errorf("script %s: step %d: name must be a string", source, i+1)
+			}
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("script %s: duplicate step name %q", source, name)
+		}
+		seen[name] = true
+
+		step, err := newScriptStep(name, fields)
+		if err != nil {
+			return nil, fmt.Errorf("script %s: step %q: %v", source, name, err)
+		}
+		script.steps = append(script.steps, step)
+	}
+	return script, nil
+}
+
+// newScriptStep creates step from request, check and extract functions of fields
+func newScriptStep(name string, fields starlark.StringDict) (scriptStep, error) {
+	step := scriptStep{name: name}
+	for _, fn := range []struct {
+		name     string
+		target   *starlark.Callable
+		required bool
+	}{{"request", &step.request, true}, {"check", &step.check, false}, {"extract", &step.extract, false}} {
+		value, ok := fields[fn.name]
+		if !ok || value == starlark.None {
+			if fn.required {
+				return step, fmt.Errorf("no %s function", fn.name)
+			}
+			continue
+		}
+		callable, ok := value.(starlark.Callable)
+		if !ok {
+			return step, fmt.Errorf("%s is %s, not a function", fn.name, value.Type())
+		}
+		*fn.target = callable
+	}
+	return step, nil
+}
+
+// thread creates Starlark thread of one call. print() goes to the log
+func (s *Script) thread() *starlark.Thread {
+	thread := &starlark.Thread{
+		Name:  s.Name,
+		Print: func(_ *starlark.Thread, msg string) { log.Printf("%s: %s", s.Name, msg) },
+	}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	return thread
+}
+
+// call calls a script function and records the time spent in it
+func (s *Script) call(fn starlark.Callable, args ...starlark.Value) (starlark.Value, error) {
+	start := time.Now()
+	value, err := starlark.Call(s.thread(), fn, args, nil)
+	atomic.AddInt64(&s.nanos, int64(time.Since(start)))
+	atomic.AddInt64(&s.calls, 1)
+	if err != nil {
+		atomic.AddInt64(&s.errors, 1)
+		return nil, &ScriptError{Message: scriptErrorMessage(err)}
+	}
+	return value, nil
+}
+
+// Stats returns the time spent in script functions so far
+func (s *Script) Stats() *ScriptStats {
+	stats := &ScriptStats{
+		Name:   s.Name,
+		Calls:  atomic.LoadInt64(&s.calls),
+		Errors: atomic.LoadInt64(&s.errors),
+	}
+	if stats.Calls > 0 {
+		stats.AvgTime = time.Duration(atomic.LoadInt64(&s.nanos) / stats.Calls)
+	}
+	return stats
+}
+
+// Operations returns an operation per script step. Request paths starting with "/" are relative to baseURL
+func (s *Script) Operations(baseURL string) []Operation {
+	ops := make([]Operation, 0, len(s.steps))
+	for _, step := range s.steps {
+		ops = append(ops, scriptOperation{script: s, step: step, baseURL: strings.TrimSuffix(baseURL, "/")})
+	}
+	return ops
+}
+
+// scriptErrorMessage returns error message with the position in the script, without the backtrace
+func scriptErrorMessage(err error) string {
+	var evalErr *starlark.EvalError
+	if !errors.As(err, &evalErr) {
+		return err.Error()
+	}
+	for i := 0; i < len(evalErr.CallStack); i++ {
+		frame := evalErr.CallStack.At(i)
+		if frame.Pos.IsValid() {
+			return fmt.Sprintf("%s: in %s: %s", frame.Pos, frame.Name, evalErr.Msg)
+		}
+	}
+	return evalErr.Msg
+}
+
+// scriptOperation is a step of a script
+type scriptOperation struct {
+	script  *Script
+	step    scriptStep
+	baseURL string
+}
+
+func (op scriptOperation) Name() string {
+	return op.step.name
+}
+
+func (op scriptOperation) BuildRequest(ctx *RequestContext, state *State) (*http.Request, error) {
+	value, err := op.script.call(op.step.request, scriptState(state))
+	if err != nil {
+		return nil, err
+	}
+	if value == starlark.None {
+		return nil, errSkipStep
+	}
+	dict, ok := value.(*starlark.Dict)
+	if !ok {
+		return nil, &ScriptError{Message: fmt.Sprintf("%s: request must return a dict or None, got %s", op.step.name, value.Type())}
+	}
+
+	fields := make(map[string]starlark.Value, dict.Len())
+	for _, item := range dict.Items() {
+		key, _ := starlark.AsString(item[0])
+		fields[key] = item[1]
+	}
+	field := func(name string) (string, error) {
+		value, ok := fields[name]
+		if !ok || value == starlark.None {
+			return "", nil
+		}
+		text, ok := starlark.AsString(value)
+		if !ok {
+			return "", &ScriptError{Message: fmt.Sprintf("%s: request %s must be a string, got %s", op.step.name, name, value.Type())}
+		}
+		return text, nil
+	}
+
+	method, err := field("method")
+	if err != nil {
+		return nil, err
+	}
+	if method == "" {
+		method = "GET"
+	}
+	url, err := field("url")
+	if err != nil {
+		return nil, err
+	}
+	if url == "" || strings.HasPrefix(url, "/") {
+		url = op.baseURL + url
+	}
+
+	var body []byte
+	text, err := field("body")
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		body = []byte(text)
+	}
+	if value, ok := fields["json"]; ok {
+		encoded, err := op.script.call(json.Module.Members["encode"].(starlark.Callable), value)
+		if err != nil {
+			return nil, err
+		}
+		body = []byte(encoded.(starlark.String))
+	}
+
+	req, err := newRequest(strings.ToUpper(method), url, body)
+	if err != nil {
+		return nil, err
+	}
+	if headers, ok := fields["headers"]; ok && headers != starlark.None {
+		dict, ok := headers.(*starlark.Dict)
+		if !ok {
+			return nil, &ScriptError{Message: fmt.Sprintf("%s: request headers must be a dict, got %s", op.step.name, headers.Type())}
+		}
+		for _, item := range dict.Items() {
+			name, _ := starlark.AsString(item[0])
+			value, ok := starlark.AsString(item[1])
+			if !ok {
+				value = item[1].String()
+			}
+			req.Header.Set(name, value)
+		}
+	}
+	return req, nil
+}
+
+func (op scriptOperation) ClassifyResponse(resp *Response) error {
+	if op.step.check == nil {
+		return classifyServerErrors(resp)
+	}
+	value, err := op.script.call(op.step.check, scriptResponse(resp))
+	if err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil
+	case starlark.Bool:
+		if v {
+			return nil
+		}
+		return &AssertionError{Message: fmt.Sprintf("%s: check failed, status %d", op.step.name, resp.StatusCode)}
+	case starlark.String:
+		return &AssertionError{Message: string(v)}
+	default:
+		return &ScriptError{Message: fmt.Sprintf("%s: check must return None, a bool or a message, got %s", op.step.name, value.Type())}
+	}
+}
+
+func (op scriptOperation) ExtractState(resp *Response, state *State) error {
+	if op.step.extract == nil {
+		return nil
+	}
+	_, err := op.script.call(op.step.extract, scriptState(state), scriptResponse(resp))
+	return err
+}
+
+func (op scriptOperation) ExtractsState() bool {
+	return op.step.extract != nil
+}
+
+// scriptState returns the state dict of the iteration, shared by its script steps
+func scriptState(state *State) *starlark.Dict {
+	if state.script == nil {
+		state.script = starlark.NewDict(4)
+	}
+	return state.script
+}
+
+// scriptResponse converts response into a Starlark struct: status, headers (first values) and body
+func scriptResponse(resp *Response) starlark.Value {
+	headers := starlark.NewDict(len(resp.Header))
+	for name := range resp.Header {
+		_ = headers.SetKey(starlark.String(name), starlark.String(resp.Header.Get(name)))
+	}
+	return starlarkstruct.FromStringDict(starlark.String("response"), starlark.StringDict{
+		"status":  starlark.MakeInt(resp.StatusCode),
+		"headers": headers,
+		"body":    starlark.String(resp.Body),
+	})
+}
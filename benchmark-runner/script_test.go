@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		steps   []string
+		wantErr string
+	}{
+		{name: "single step", source: "def request(state):\n    return {}\n", steps: []string{"script"}},
+		{name: "steps", source: `
+def a(state):
+    return {}
+def b(state):
+    return {}
+steps = [{"name": "create", "request": a}, {"request": b}]
+`, steps: []string{"create", "step-2"}},
+		{name: "syntax error", source: "def request(state)\n", wantErr: "failed to load script"},
+		{name: "no request", source: "def check(resp):\n    pass\n", wantErr: "no request function"},
+		{name: "request not a function", source: "request = 1\n", wantErr: "request is int, not a function"},
+		{name: "empty steps", source: "steps = []\n", wantErr: "steps must be a non-empty list"},
+		{name: "steps not a list", source: "steps = {}\n", wantErr: "steps must be a non-empty list"},
+		{name: "step not a dict", source: "steps = [1]\n", wantErr: "step 1 is int, not a dict"},
+		{name: "name not a string", source: "def a(state):\n    return {}\nsteps = [{\"name\": 1, \"request\": a}]\n", wantErr: "name must be a string"},
+		{name: "duplicate name", source: `
+def a(state):
+    return {}
+steps = [{"name": "x", "request": a}, {"name": "x", "request": a}]
+`, wantErr: `duplicate step name "x"`},
+	}
+	for _, tt := range tests {
+		script, err := parseScript([]byte(tt.source), "scripts/test.star")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if script.Name != "test" {
+			t.Errorf("%s: name = %q, want test", tt.name, script.Name)
+		}
+		var steps []string
+		for _, op := range script.Operations("http://api") {
+			steps = append(steps, op.Name())
+		}
+		if strings.Join(steps, ",") != strings.Join(tt.steps, ",") {
+			t.Errorf("%s: steps = %v, want %v", tt.name, steps, tt.steps)
+		}
+	}
+}
+
+// scriptOperations parses the script and returns its operations against http://api
+func scriptOperations(t *testing.T, source string) []Operation {
+	t.Helper()
+	script, err := parseScript([]byte(source), "test.star")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script.Operations("http://api/")
+}
+
+func TestScriptOperationBuildRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		method  string
+		url     string
+		body    string
+		header  string
+		wantErr string
+	}{
+		{name: "defaults", request: `{}`, method: "GET", url: "http://api"},
+		{name: "relative url", request: `{"method": "delete", "url": "/api/products/1"}`, method: "DELETE", url: "http://api/api/products/1"},
+		{name: "absolute url", request: `{"url": "http://other/health"}`, method: "GET", url: "http://other/health"},
+		{name: "body", request: `{"method": "POST", "url": "/x", "body": "raw"}`, method: "POST", url: "http://api/x", body: "raw"},
+		{name: "json", request: `{"method": "POST", "url": "/x", "json": {"n": 1}}`, method: "POST", url: "http://api/x", body: `{"n":1}`},
+		{name: "headers", request: `{"headers": {"X-Token": "abc", "X-Retry": 2}}`, method: "GET", url: "http://api", header: "abc/2"},
+		{name: "not a dict", request: `[]`, wantErr: "request must return a dict or None, got list"},
+		{name: "method not a string", request: `{"method": 1}`, wantErr: "request method must be a string, got int"},
+		{name: "headers not a dict", request: `{"headers": "x"}`, wantErr: "request headers must be a dict, got string"},
+		{name: "runtime error", request: `{"url": state["missing"]}`, wantErr: "test.star:2"},
+	}
+	for _, tt := range tests {
+		op := scriptOperations(t, "def request(state):\n    return "+tt.request+"\n")[0]
+		req, err := op.BuildRequest(nil, &State{})
+		if tt.wantErr != "" {
+			var scriptErr *ScriptError
+			if !errors.As(err, &scriptErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want a script error %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if req.Method != tt.method || req.URL.String() != tt.url {
+			t.Errorf("%s: request = %s %s, want %s %s", tt.name, req.Method, req.URL, tt.method, tt.url)
+		}
+		var body []byte
+		if req.Body != nil {
+			body, _ = io.ReadAll(req.Body)
+		}
+		if string(body) != tt.body {
+			t.Errorf("%s: body = %q, want %q", tt.name, body, tt.body)
+		}
+		if tt.header != "" {
+			if got := req.Header.Get("X-Token") + "/" + req.Header.Get("X-Retry"); got != tt.header {
+				t.Errorf("%s: headers = %q, want %q", tt.name, got, tt.header)
+			}
+		}
+	}
+}
+
+func TestScriptOperationSkipsStep(t *testing.T) {
+	op := scriptOperations(t, "def request(state):\n    return None\n")[0]
+	if _, err := op.BuildRequest(nil, &State{}); !errors.Is(err, errSkipStep) {
+		t.Errorf("error = %v, want errSkipStep", err)
+	}
+}
+
+func TestScriptOperationClassifyResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   string
+		status  int
+		wantErr string // "assertion_error", "script_error" or empty
+	}{
+		{name: "none", check: "None", status: 500},
+		{name: "true", check: "True", status: 404},
+		{name: "false", check: "False", status: 200, wantErr: "assertion_error"},
+		{name: "message", check: `"status %d" % resp.status`, status: 200, wantErr: "assertion_error"},
+		{name: "wrong type", check: "1", status: 200, wantErr: "script_error"},
+		{name: "runtime error", check: "resp.missing", status: 200, wantErr: "script_error"},
+	}
+	for _, tt := range tests {
+		op := scriptOperations(t, "def request(state):\n    return {}\ndef check(resp):\n    return "+tt.check+"\n")[0]
+		err := op.ClassifyResponse(&Response{StatusCode: tt.status, Header: http.Header{}})
+		if got := errorType(err, ""); err != nil && got != tt.wantErr || err == nil && tt.wantErr != "" {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	op := scriptOperations(t, "def request(state):\n    return {}\n")[0]
+	if err := op.ClassifyResponse(&Response{StatusCode: 503}); err == nil {
+		t.Error("5xx without check: no error")
+	}
+	if err := op.ClassifyResponse(&Response{StatusCode: 404}); err != nil {
+		t.Errorf("4xx without check: %v", err)
+	}
+}
+
+func TestScriptStateIsSharedBetweenSteps(t *testing.T) {
+	ops := scriptOperations(t, `
+def create(state):
+    return {"method": "POST", "url": "/api/products"}
+
+def save_id(state, resp):
+    state["id"] = json.decode(resp.body)["id"] + int(resp.headers["X-Offset"])
+
+def read(state):
+    return {"url": "/api/products/%d" % state["id"]}
+
+steps = [
+    {"name": "create", "request": create, "extract": save_id},
+    {"name": "read", "request": read},
+]
+`)
+	state := &State{}
+	resp := &Response{StatusCode: 201, Header: http.Header{"X-Offset": {"2"}}, Body: []byte(`{"id": 40}`)}
+	if err := ops[0].ExtractState(resp, state); err != nil {
+		t.Fatal(err)
+	}
+	req, err := ops[1].BuildRequest(nil, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.Path != "/api/products/42" {
+		t.Errorf("path = %s, want /api/products/42", req.URL.Path)
+	}
+
+	// A new iteration starts with an empty state
+	if _, err := ops[1].BuildRequest(nil, &State{}); err == nil {
+		t.Error("read without create: no error")
+	}
+}
+
+func TestScriptGlobalsAreFrozen(t *testing.T) {
+	ops := scriptOperations(t, `
+seen = []
+counts = {}
+
+def append(state):
+    seen.append(1)
+    return {}
+
+def count(state):
+    counts["n"] = 1
+    return {}
+
+def read(state):
+    return {"url": "/%d" % len(seen)}
+
+steps = [
+    {"name": "append", "request": append},
+    {"name": "count", "request": count},
+    {"name": "read", "request": read},
+]
+`)
+	for _, op := range ops[:2] {
+		_, err := op.BuildRequest(nil, &State{})
+		var scriptErr *ScriptError
+		if !errors.As(err, &scriptErr) || !strings.Contains(err.Error(), "frozen") {
+			t.Errorf("%s: error = %v, want a frozen script error", op.Name(), err)
+		}
+	}
+	if _, err := ops[2].BuildRequest(nil, &State{}); err != nil {
+		t.Errorf("reading a global: %v", err)
+	}
+}
+
+func TestScriptStats(t *testing.T) {
+	source := "def request(state):\n    return {\"url\": state[\"missing\"]}\n"
+	script, err := parseScript([]byte(source), "stats.star")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := script.Operations("http://api")[0]
+	for i := 0; i < 3; i++ {
+		op.BuildRequest(nil, &State{})
+	}
+	stats := script.Stats()
+	if stats.Name != "stats" || stats.Calls != 3 || stats.Errors != 3 {
+		t.Errorf("stats = %+v, want 3 calls and 3 errors of stats", stats)
+	}
+}
+
+func TestScriptBuiltins(t *testing.T) {
+	ops := scriptOperations(t, `
+def request(state):
+    n = rand_int(5, 7)
+    f = rand_float(1.0, 2.0)
+    s = rand_string(8)
+    if n < 5 or n > 7 or f < 1.0 or f > 2.0 or len(s) != 8:
+        fail("out of range: %d %f %s" % (n, f, s))
+    if len(uuid()) != 36 or seq() >= seq() or timestamp() <= 0:
+        fail("bad uuid, seq or timestamp")
+    return {"json": json.decode(json.encode({"ok": True}))}
+`)
+	for i := 0; i < 20; i++ {
+		if _, err := ops[0].BuildRequest(nil, &State{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScriptBuiltinsRejectBadArguments(t *testing.T) {
+	calls := map[string]string{
+		"rand_string(-1)":                   "rand_string: length -1 is out of range",
+		"rand_string(10000000)":             "rand_string: length 10000000 is out of range",
+		"rand_int(0, 9223372036854775807)":  "rand_int: range 0-9223372036854775807 is too large",
+		"rand_int(-9223372036854775808, 0)": "rand_int: range -9223372036854775808-0 is too large",
+	}
+	for call, want := range calls {
+		ops := scriptOperations(t, "def request(state):\n    return {\"url\": \"/\" + str("+call+")}\n")
+		_, err := ops[0].BuildRequest(nil, &State{})
+		var scriptErr *ScriptError
+		if !errors.As(err, &scriptErr) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want a script error %q", call, err, want)
+		}
+	}
+
+	// Wide ranges that fit are fine
+	ops := scriptOperations(t, "def request(state):\n    return {\"url\": \"/\" + str(rand_int(-4000000000000000000, 4000000000000000000))}\n")
+	if _, err := ops[0].BuildRequest(nil, &State{}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,580 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Run statuses of the serve mode
+const (
+	runRunning   = "running"
+	runCompleted = "completed"
+	runAborted   = "aborted"
+	runFailed    = "failed"
+)
+
+// RunRequest is a run definition accepted by POST /runs. Empty fields take defaults of the command line flags
+type RunRequest struct {
+	URL            string            `json:"url"`
+	Type           string            `json:"type,omitempty"`
+	RPS            int               `json:"rps,omitempty"`
+	Duration       string            `json:"duration,omitempty"`
+	Profile        string            `json:"profile,omitempty"`
+	Concurrency    int               `json:"concurrency,omitempty"`
+	MaxConcurrency int               `json:"max_concurrency,omitempty"`
+	VirtualUsers   int               `json:"vus,omitempty"`
+	Think          string            `json:"think,omitempty"`
+	SessionViews   int               `json:"session_views,omitempty"`
+	Method         string            `json:"method,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Body           string            `json:"body,omitempty"`
+	Captures       []string          `json:"captures,omitempty"`
+	ExpectStatus   string            `json:"expect_status,omitempty"`
+	Journey        json.RawMessage   `json:"journey,omitempty"`   // Inline journey definition, implies type journey
+	Scenarios      json.RawMessage   `json:"scenarios,omitempty"` // Inline scenario definitions, as in -scenarios files
+	Script         string            `json:"script,omitempty"`    // Inline Starlark script, implies type script
+	MetricsURL     string            `json:"metrics_url,omitempty"`
+	Slowest        int               `json:"slowest,omitempty"`
+	KeepCreated    bool              `json:"keep_created,omitempty"` // Don't delete created resources after the run
+	Assert         []string          `json:"assert,omitempty"`
+}
+
+// serveRun is a run started by the control API
+type serveRun struct {
+	ID         string
+	Request    RunRequest
+	config     Config
+	assertions []Assertion
+	cancel     context.CancelFunc
+	done       chan struct{} // Closed when the run finished
+
+	mu          sync.Mutex
+	status      string
+	started     time.Time
+	finished    time.Time
+	err         string
+	progress    *ProgressUpdate
+	result      map[string]interface{}
+	passed      bool
+	subscribers map[chan ProgressUpdate]struct{}
+}
+
+// runServer is the control API: it starts one run at a time and keeps results of the last runs
+type runServer struct {
+	maxRuns int
+
+	mu     sync.Mutex
+	runs   map[string]*serveRun
+	order  []string // Run IDs, oldest first
+	active *serveRun
+}
+
+// runServeCommand implements "benchmark-runner serve [flags]"
+func runServeCommand(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s serve [flags]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "Listens for run definitions over HTTP, streams progress and keeps results by run ID.\n\n")
+		fs.PrintDefaults()
+	}
+	listen := fs.String("listen", ":8090", "Address of the control API")
+	maxRuns := fs.Int("max-runs", 100, "Number of finished runs whose results are kept")
+	fs.Parse(args)
+
+	server := &runServer{maxRuns: *maxRuns, runs: make(map[string]*serveRun)}
+	httpServer := &http.Server{Addr: *listen, Handler: server.handler()}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+	log.Printf("Control API listening on %s", *listen)
+
+	select {
+	case err := <-errCh:
+		log.Printf("Control API failed: %v", err)
+		return 1
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down")
+	server.abortActive()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down control API: %v", err)
+	}
+	return 0
+}
+
+// handler returns routes of the control API
+func (s *runServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.handleStart)
+	mux.HandleFunc("GET /runs", s.handleList)
+	mux.HandleFunc("GET /runs/{id}", s.handleGet)
+	mux.HandleFunc("GET /runs/{id}/events", s.handleEvents)
+	mux.HandleFunc("POST /runs/{id}/abort", s.handleAbort)
+	return mux
+}
+
+// handleStart starts a run. Only one run is active at a time, so runs don't skew each other
+func (s *runServer) handleStart(w http.ResponseWriter, r *http.Request) {
+	var request RunRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid run definition: %v", err))
+		return
+	}
+
+	config, assertions, err := request.config()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if s.active != nil {
+		id := s.active.ID
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %s is in progress", id))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &serveRun{
+		ID:          newRunID(),
+		Request:     request,
+		config:      config,
+		assertions:  assertions,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		status:      runRunning,
+		started:     time.Now(),
+		subscribers: make(map[chan ProgressUpdate]struct{}),
+	}
+	run.config.Progress = run.publish
+	s.active = run
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+	s.evict()
+	s.mu.Unlock()
+
+	log.Printf("Run %s started: %s %s, %s", run.ID, config.BenchmarkType, config.URL, config.Duration)
+	go s.execute(ctx, run)
+
+	w.Header().Set("Location", "/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, run.summary(false))
+}
+
+// execute runs the benchmark and stores its results
+func (s *runServer) execute(ctx context.Context, run *serveRun) {
+	result, err := runOnce(ctx, run.config)
+	aborted := ctx.Err() != nil
+	run.cancel()
+
+	run.mu.Lock()
+	run.finished = time.Now()
+	switch {
+	case err != nil:
+		run.status = runFailed
+		run.err = err.Error()
+	case aborted:
+		run.status = runAborted
+	default:
+		run.status = runCompleted
+	}
+	if result != nil {
+		result.Assertions, run.passed = evaluateAssertions(run.assertions, result)
+		run.result = resultJSON(result)
+	}
+	for ch := range run.subscribers {
+		close(ch)
+	}
+	run.subscribers = nil
+	status := run.status
+	run.mu.Unlock()
+	close(run.done)
+
+	s.mu.Lock()
+	s.active = nil
+	s.evict()
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Run %s failed: %v", run.ID, err)
+	} else {
+		log.Printf("Run %s %s: %d requests, %d failed, p99 %s", run.ID, status,
+			result.TotalRequests, result.FailedRequests, result.P99Latency.Round(time.Microsecond))
+	}
+}
+
+// evict drops the oldest finished runs above maxRuns. s.mu must be held
+func (s *runServer) evict() {
+	for len(s.order) > s.maxRuns {
+		i := 0
+		for i < len(s.order) && s.runs[s.order[i]] == s.active {
+			i++
+		}
+		if i == len(s.order) {
+			return
+		}
+		delete(s.runs, s.order[i])
+		s.order = append(s.order[:i], s.order[i+1:]...)
+	}
+}
+
+// abortActive aborts the active run and waits for it to finish
+func (s *runServer) abortActive() {
+	s.mu.Lock()
+	run := s.active
+	s.mu.Unlock()
+	if run != nil {
+		run.cancel()
+		<-run.done
+	}
+}
+
+// lookup returns the run of the request path, writing 404 if it doesn't exist
+func (s *runServer) lookup(w http.ResponseWriter, r *http.Request) *serveRun {
+	s.mu.Lock()
+	run, ok := s.runs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("run %s not found", r.PathValue("id")))
+		return nil
+	}
+	return run
+}
+
+// handleList lists kept runs without results, newest first
+func (s *runServer) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	runs := make([]*serveRun, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		runs = append(runs, s.runs[s.order[i]])
+	}
+	s.mu.Unlock()
+
+	list := make([]map[string]interface{}, 0, len(runs))
+	for _, run := range runs {
+		list = append(list, run.summary(false))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runs": list})
+}
+
+// handleGet returns status of the run and its results once finished
+func (s *runServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	if run := s.lookup(w, r); run != nil {
+		writeJSON(w, http.StatusOK, run.summary(true))
+	}
+}
+
+// handleAbort stops the run early. Results of the requests made so far are kept
+func (s *runServer) handleAbort(w http.ResponseWriter, r *http.Request) {
+	run := s.lookup(w, r)
+	if run == nil {
+		return
+	}
+
+	run.mu.Lock()
+	status := run.status
+	run.mu.Unlock()
+	if status != runRunning {
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %s is %s", run.ID, status))
+		return
+	}
+
+	log.Printf("Run %s aborted", run.ID)
+	run.cancel()
+	<-run.done
+	writeJSON(w, http.StatusOK, run.summary(false))
+}
+
+// handleEvents streams progress of the run as Server-Sent Events: a "progress" event
+// every second and a final "done" event with status and results
+func (s *runServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	run := s.lookup(w, r)
+	if run == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	updates := run.subscribe()
+	for {
+		select {
+		case <-r.Context().Done():
+			run.unsubscribe(updates)
+			return
+		case update, ok := <-updates:
+			if !ok {
+				writeEvent(w, "done", run.summary(true))
+				flusher.Flush()
+				return
+			}
+			writeEvent(w, "progress", progressJSON(update))
+			flusher.Flush()
+		}
+	}
+}
+
+// publish passes progress of the run to the subscribers. Slow subscribers miss updates
+func (run *serveRun) publish(update ProgressUpdate) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	run.progress = &update
+	for ch := range run.subscribers {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// subscribe returns channel of progress updates, closed when the run finished
+func (run *serveRun) subscribe() chan ProgressUpdate {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	ch := make(chan ProgressUpdate, 16)
+	if run.subscribers == nil {
+		close(ch) // Already finished
+		return ch
+	}
+	run.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (run *serveRun) unsubscribe(ch chan ProgressUpdate) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	delete(run.subscribers, ch)
+}
+
+// summary returns JSON state of the run. Results are included only if withResult is set
+func (run *serveRun) summary(withResult bool) map[string]interface{} {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	summary := map[string]interface{}{
+		"id":         run.ID,
+		"status":     run.status,
+		"request":    run.Request,
+		"started_at": run.started.Format(time.RFC3339),
+	}
+	if !run.finished.IsZero() {
+		summary["finished_at"] = run.finished.Format(time.RFC3339)
+	}
+	if run.err != "" {
+		summary["error"] = run.err
+	}
+	if run.progress != nil && run.status == runRunning {
+		summary["progress"] = progressJSON(*run.progress)
+	}
+	if run.result != nil {
+		if len(run.assertions) > 0 {
+			summary["passed"] = run.passed
+		}
+		if withResult {
+			summary["result"] = run.result
+		}
+	}
+	return summary
+}
+
+// progressJSON builds JSON of a progress update
+func progressJSON(update ProgressUpdate) map[string]interface{} {
+	return map[string]interface{}{
+		"elapsed_seconds": update.Elapsed.Seconds(),
+		"total_requests":  update.TotalRequests,
+		"total_errors":    update.TotalErrors,
+		"rps":             update.Last.Requests,
+		"errors":          update.Last.Errors,
+		"in_flight":       update.Last.InFlight,
+		"avg_ms":          durationMs(update.Last.AvgLatency),
+		"p50_ms":          durationMs(update.Last.P50Latency),
+		"p95_ms":          durationMs(update.Last.P95Latency),
+		"p99_ms":          durationMs(update.Last.P99Latency),
+		"max_ms":          durationMs(update.Last.MaxLatency),
+	}
+}
+
+// config validates the run definition and converts it into the run configuration
+func (req RunRequest) config() (Config, []Assertion, error) {
+	config := Config{
+		URL:            req.URL,
+		RPS:            valueOr(req.RPS, 100),
+		BenchmarkType:  BenchmarkType(req.Type),
+		Concurrency:    valueOr(req.Concurrency, 10),
+		MaxConcurrency: valueOr(req.MaxConcurrency, 1000),
+		Slowest:        valueOr(req.Slowest, 10),
+		VirtualUsers:   valueOr(req.VirtualUsers, 10),
+		SessionViews:   valueOr(req.SessionViews, 3),
+		MetricsURL:     req.MetricsURL,
+		ScrapeInterval: 5 * time.Second,
+		Cleanup:        !req.KeepCreated,
+		WarmUp:         defaultWarmUpConfig,
+	}
+	if config.URL == "" {
+		return config, nil, errors.New("url is required")
+	}
+	positive := []struct {
+		name  string
+		value int
+	}{
+		{"rps", config.RPS},
+		{"concurrency", config.Concurrency},
+		{"max_concurrency", config.MaxConcurrency},
+		{"vus", config.VirtualUsers},
+	}
+	for _, field := range positive {
+		if field.value <= 0 {
+			return config, nil, fmt.Errorf("%s must be positive, got %d", field.name, field.value)
+		}
+	}
+	if config.SessionViews < 0 {
+		return config, nil, fmt.Errorf("session_views must not be negative, got %d", config.SessionViews)
+	}
+	if config.BenchmarkType == "" {
+		config.BenchmarkType = GetProducts
+	}
+
+	duration := req.Duration
+	if duration == "" {
+		duration = "30s"
+	}
+	var err error
+	if config.Duration, err = time.ParseDuration(duration); err != nil {
+		return config, nil, fmt.Errorf("invalid duration: %v", err)
+	}
+
+	think := req.Think
+	if think == "" {
+		think = "1s"
+	}
+	if config.ThinkTime, err = parseThinkTime(think); err != nil {
+		return config, nil, fmt.Errorf("invalid think time: %v", err)
+	}
+
+	config.Request.Method = req.Method
+	if config.Request.Method == "" {
+		config.Request.Method = "GET"
+	}
+	config.Request.Body = req.Body
+	config.Request.Headers = req.Headers
+	if config.Request.Headers == nil {
+		config.Request.Headers = make(map[string]string)
+	}
+	for _, capture := range req.Captures {
+		rule, err := parseCaptureRule(capture)
+		if err != nil {
+			return config, nil, err
+		}
+		config.Request.Captures = append(config.Request.Captures, rule)
+	}
+	if config.Request.ExpectStatus, err = parseStatusList(req.ExpectStatus); err != nil {
+		return config, nil, err
+	}
+
+	if len(req.Journey) > 0 {
+		if config.Journey, err = parseJourney(req.Journey, "(inline)"); err != nil {
+			return config, nil, err
+		}
+		config.BenchmarkType = JourneyType
+	}
+	if req.Script != "" {
+		if config.Script, err = parseScript([]byte(req.Script), "script.star"); err != nil {
+			return config, nil, err
+		}
+		config.BenchmarkType = ScriptType
+	}
+
+	if config.Profile, err = parseRateProfile(req.Profile); err != nil {
+		return config, nil, err
+	}
+
+	if len(req.Scenarios) > 0 {
+		file := append(append([]byte(`{"scenarios":`), req.Scenarios...), '}')
+		if config.Scenarios, err = parseScenarios(file, "(inline)", ".", config); err != nil {
+			return config, nil, err
+		}
+		config.BenchmarkType = ScenariosType
+	} else if config.BenchmarkType != UserSession {
+		if _, err := resolveOperations(config); err != nil {
+			return config, nil, err
+		}
+	}
+
+	var assertions []Assertion
+	for _, spec := range req.Assert {
+		assertion, err := parseAssertion(spec, scheduledRPS(config))
+		if err != nil {
+			return config, nil, err
+		}
+		assertions = append(assertions, assertion)
+	}
+	return config, assertions, nil
+}
+
+// valueOr returns value, or def if value is zero
+func valueOr(value, def int) int {
+	if value == 0 {
+		return def
+	}
+	return value
+}
+
+// newRunID returns a random run ID
+func newRunID() string {
+	var id [6]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// writeJSON writes value as JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+}
+
+// writeError writes JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeEvent writes a Server-Sent Event with JSON data
+func writeEvent(w http.ResponseWriter, event string, value interface{}) {
+	data, _ := json.Marshal(value)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
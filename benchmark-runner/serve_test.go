@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunRequestConfig(t *testing.T) {
+	config, assertions, err := RunRequest{URL: "http://a", Assert: []string{"rps>=0.9*target"}}.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.RPS != 100 || config.Concurrency != 10 || config.MaxConcurrency != 1000 || config.VirtualUsers != 10 ||
+		config.SessionViews != 3 || config.Slowest != 10 || config.Duration != 30*time.Second || config.ThinkTime.Min != time.Second {
+		t.Errorf("defaults = %+v", config)
+	}
+	if config.BenchmarkType != GetProducts || config.Request.Method != "GET" || !config.Cleanup {
+		t.Errorf("type %s, method %s, cleanup %v", config.BenchmarkType, config.Request.Method, config.Cleanup)
+	}
+	if len(assertions) != 1 || assertions[0].Threshold != 90 {
+		t.Errorf("assertions = %+v, want threshold of 90%% of the default rate", assertions)
+	}
+}
+
+func TestRunRequestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request RunRequest
+		err     string
+	}{
+		{name: "no url", request: RunRequest{}, err: "url is required"},
+		{name: "negative rps", request: RunRequest{URL: "http://a", RPS: -1}, err: "rps must be positive, got -1"},
+		{name: "negative concurrency", request: RunRequest{URL: "http://a", Concurrency: -5}, err: "concurrency must be positive"},
+		{name: "negative max concurrency", request: RunRequest{URL: "http://a", MaxConcurrency: -1}, err: "max_concurrency must be positive"},
+		{name: "negative vus", request: RunRequest{URL: "http://a", Type: string(UserSession), VirtualUsers: -2}, err: "vus must be positive"},
+		{name: "negative session views", request: RunRequest{URL: "http://a", SessionViews: -1}, err: "session_views must not be negative"},
+		{name: "duration", request: RunRequest{URL: "http://a", Duration: "ten seconds"}, err: "invalid duration"},
+		{name: "think time", request: RunRequest{URL: "http://a", Think: "uniform:2s-1s"}, err: "invalid think time"},
+		{name: "capture", request: RunRequest{URL: "http://a", Type: "http", Captures: []string{"id"}}, err: `invalid capture "id"`},
+		{name: "expect status", request: RunRequest{URL: "http://a", ExpectStatus: "ok"}, err: `invalid status code "ok"`},
+		{name: "profile", request: RunRequest{URL: "http://a", Profile: "zigzag"}, err: "unknown rate profile"},
+		{name: "type", request: RunRequest{URL: "http://a", Type: "get-everything"}, err: "unknown benchmark type"},
+		{name: "journey", request: RunRequest{URL: "http://a", Journey: json.RawMessage(`{"steps": []}`)}, err: "has no steps"},
+		{name: "script", request: RunRequest{URL: "http://a", Script: "steps = 1"}, err: "steps must be a non-empty list"},
+		{name: "scenarios", request: RunRequest{URL: "http://a", Scenarios: json.RawMessage(`[]`)}, err: "no scenarios"},
+		{name: "assertion", request: RunRequest{URL: "http://a", Assert: []string{"p42<1ms"}}, err: `unknown metric "p42"`},
+		{name: "target of virtual users", request: RunRequest{URL: "http://a", Type: string(UserSession), Assert: []string{"rps>=target"}}, err: "no target rate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.request.config()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRunRequestConfigTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		request RunRequest
+		want    BenchmarkType
+	}{
+		{name: "operation", request: RunRequest{URL: "http://a", Type: string(DeleteProduct)}, want: DeleteProduct},
+		{name: "virtual users", request: RunRequest{URL: "http://a", Type: string(UserSession), VirtualUsers: 5}, want: UserSession},
+		{name: "journey", request: RunRequest{URL: "http://a", Journey: json.RawMessage(`{"steps": [{"name": "list", "url": "/api/products"}]}`)}, want: JourneyType},
+		{name: "script", request: RunRequest{URL: "http://a", Script: `steps = [{"name": "list", "request": lambda state: {"url": "/"}}]`}, want: ScriptType},
+		{name: "scenarios", request: RunRequest{URL: "http://a", Scenarios: json.RawMessage(`[{"name": "read", "type": "get-products", "rps": 5}]`)}, want: ScenariosType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _, err := tt.request.config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.BenchmarkType != tt.want {
+				t.Errorf("type = %s, want %s", config.BenchmarkType, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueOr(t *testing.T) {
+	tests := []struct{ value, def, want int }{{0, 10, 10}, {5, 10, 5}, {-1, 10, -1}}
+	for _, tt := range tests {
+		if got := valueOr(tt.value, tt.def); got != tt.want {
+			t.Errorf("valueOr(%d, %d) = %d, want %d", tt.value, tt.def, got, tt.want)
+		}
+	}
+}
+
+// serveRequest sends a request to the control API and decodes the JSON response
+func serveRequest(t *testing.T, handler http.Handler, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var response map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: invalid response %q: %v", method, path, recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+func TestServeRejectsInvalidRuns(t *testing.T) {
+	handler := (&runServer{maxRuns: 10, runs: make(map[string]*serveRun)}).handler()
+
+	tests := []struct {
+		body string
+		err  string
+	}{
+		{body: `{"url": "http://a", "rps": -1}`, err: "rps must be positive"},
+		{body: `{"url": "http://a", "vus": -10, "type": "user-session"}`, err: "vus must be positive"},
+		{body: `{"url": "http://a", "workers": 5}`, err: `unknown field "workers"`},
+		{body: `{"url": `, err: "invalid run definition"},
+	}
+	for _, tt := range tests {
+		code, response := serveRequest(t, handler, http.MethodPost, "/runs", tt.body)
+		if code != http.StatusBadRequest || !strings.Contains(fmt.Sprint(response["error"]), tt.err) {
+			t.Errorf("POST %s = %d %v, want 400 with %q", tt.body, code, response, tt.err)
+		}
+	}
+
+	for _, path := range []string{"/runs/missing", "/runs/missing/events"} {
+		if code, _ := serveRequest(t, handler, http.MethodGet, path, ""); code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, code)
+		}
+	}
+	if code, response := serveRequest(t, handler, http.MethodGet, "/runs", ""); code != http.StatusOK || len(response["runs"].([]interface{})) != 0 {
+		t.Errorf("GET /runs = %d %v, want no runs", code, response)
+	}
+}
+
+func TestServeRunLifecycle(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer target.Close()
+
+	server := &runServer{maxRuns: 1, runs: make(map[string]*serveRun)}
+	handler := server.handler()
+
+	body := fmt.Sprintf(`{"url": %q, "rps": 50, "duration": "1m", "assert": ["errors==0"]}`, target.URL)
+	code, started := serveRequest(t, handler, http.MethodPost, "/runs", body)
+	if code != http.StatusAccepted || started["status"] != runRunning {
+		t.Fatalf("POST /runs = %d %v", code, started)
+	}
+	id := started["id"].(string)
+
+	// One run at a time
+	if code, _ := serveRequest(t, handler, http.MethodPost, "/runs", body); code != http.StatusConflict {
+		t.Errorf("second run = %d, want 409", code)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if code, aborted := serveRequest(t, handler, http.MethodPost, "/runs/"+id+"/abort", ""); code != http.StatusOK || aborted["status"] != runAborted {
+		t.Fatalf("abort = %d %v", code, aborted)
+	}
+	if code, _ := serveRequest(t, handler, http.MethodPost, "/runs/"+id+"/abort", ""); code != http.StatusConflict {
+		t.Errorf("second abort = %d, want 409", code)
+	}
+
+	code, run := serveRequest(t, handler, http.MethodGet, "/runs/"+id, "")
+	if code != http.StatusOK || run["result"] == nil || run["passed"] != true || run["finished_at"] == nil {
+		t.Errorf("GET /runs/%s = %d %v, want the aborted run with results", id, code, run)
+	}
+
+	// Only the last maxRuns finished runs are kept
+	code, next := serveRequest(t, handler, http.MethodPost, "/runs", body)
+	if code != http.StatusAccepted {
+		t.Fatalf("next run = %d %v", code, next)
+	}
+	server.abortActive()
+	if code, _ := serveRequest(t, handler, http.MethodGet, "/runs/"+id, ""); code != http.StatusNotFound {
+		t.Errorf("evicted run = %d, want 404", code)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ThinkTime describes the pause distribution between steps of a virtual user session
+type ThinkTime struct {
+	Kind string        // none, const, uniform, exp
+	Min  time.Duration // Lower bound (uniform) or fixed value (const)
+	Max  time.Duration // Upper bound (uniform)
+	Mean time.Duration // Mean value (exp)
+}
+
+// parseThinkTime parses think time specification.
+// Supported formats: "0", "1s", "const:1s", "uniform:500ms-2s", "exp:1s"
+func parseThinkTime(spec string) (ThinkTime, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "0" || spec == "none" {
+		return ThinkTime{Kind: "none"}, nil
+	}
+
+	kind, value, found := strings.Cut(spec, ":")
+	if !found {
+		kind, value = "const", spec
+	}
+
+	switch kind {
+	case "const":
+		d, err := parseThinkDuration(spec, value)
+		if err != nil {
+			return ThinkTime{}, err
+		}
+		return ThinkTime{Kind: "const", Min: d, Max: d, Mean: d}, nil
+	case "uniform":
+		minStr, maxStr, ok := strings.Cut(value, "-")
+		if !ok {
+			return ThinkTime{}, fmt.Errorf("invalid think time %q: expected uniform:MIN-MAX", spec)
+		}
+		minD, err := parseThinkDuration(spec, minStr)
+		if err != nil {
+			return ThinkTime{}, err
+		}
+		maxD, err := parseThinkDuration(spec, maxStr)
+		if err != nil {
+			return ThinkTime{}, err
+		}
+		if maxD < minD {
+			return ThinkTime{}, fmt.Errorf("invalid think time %q: max is less than min", spec)
+		}
+		return ThinkTime{Kind: "uniform", Min: minD, Max: maxD, Mean: (minD + maxD) / 2}, nil
+	case "exp":
+		d, err := parseThinkDuration(spec, value)
+		if err != nil {
+			return ThinkTime{}, err
+		}
+		return ThinkTime{Kind: "exp", Mean: d}, nil
+	default:
+		return ThinkTime{}, fmt.Errorf("unknown think time distribution: %s", kind)
+	}
+}
+
+// parseThinkDuration parses a duration of the think time spec, rejecting negative ones
+func parseThinkDuration(spec, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid think time %q: %v", spec, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid think time %q: %s is negative", spec, d)
+	}
+	return d, nil
+}
+
+// Next returns the next think time sampled from the distribution
+func (t ThinkTime) Next(rng *rand.Rand) time.Duration {
+	switch t.Kind {
+	case "const":
+		return t.Min
+	case "uniform":
+		if t.Max == t.Min {
+			return t.Min
+		}
+		return t.Min + time.Duration(rng.Int63n(int64(t.Max-t.Min)))
+	case "exp":
+		return time.Duration(rng.ExpFloat64() * float64(t.Mean))
+	default:
+		return 0
+	}
+}
+
+// String returns human readable representation of the distribution
+func (t ThinkTime) String() string {
+	switch t.Kind {
+	case "const":
+		return t.Min.String()
+	case "uniform":
+		return fmt.Sprintf("uniform %s-%s", t.Min, t.Max)
+	case "exp":
+		return fmt.Sprintf("exponential, mean %s", t.Mean)
+	default:
+		return "none"
+	}
+}
+
+// stepRecorder collects per-step statistics, preserving the order steps were first seen
+type stepRecorder struct {
+	mu    sync.Mutex
+	order []string
+	steps map[string]*StepStats
+}
+
+func newStepRecorder() *stepRecorder {
+	return &stepRecorder{
+		steps: make(map[string]*StepStats),
+	}
+}
+
+// record records latency and outcome of a single step execution
+func (sr *stepRecorder) record(name string, latency time.Duration, err error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	step, ok := sr.steps[name]
+	if !ok {
+		step = &StepStats{Name: name}
+		sr.steps[name] = step
+		sr.order = append(sr.order, name)
+	}
+
+	step.Requests++
+	if err != nil {
+		step.Failed++
+	}
+	step.Latencies = append(step.Latencies, latency)
+}
+
+// results returns step statistics in the order steps were first seen
+func (sr *stepRecorder) results() []*StepStats {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	steps := make([]*StepStats, 0, len(sr.order))
+	for _, name := range sr.order {
+		step := sr.steps[name]
+		calculateStepStats(step)
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// runVirtualUsers runs the closed-model benchmark: each virtual user executes
+// sessions in a loop, pausing between steps according to the think time.
+// Cancelling runCtx stops the run early
+func runVirtualUsers(runCtx context.Context, config Config) (*Result, error) {
+	var (
+		totalRequests   int64
+		successRequests int64
+		failedRequests  int64
+		sessions        int64
+		failedSessions  int64
+		latencies       []time.Duration
+		sessionTime     time.Duration
+		latenciesMutex  sync.Mutex
+	)
+
+	errorStats := NewErrorStats()
+	steps := newStepRecorder()
+
+	ctx := newRequestContext(config, errorStats)
+	if err := ctx.Events.Open(); err != nil {
+		return nil, err
+	}
+
+	benchmarkCtx, cancel := context.WithTimeout(runCtx, config.Duration)
+	defer cancel()
+
+	// recordStep is called by sessions after every HTTP request
+	recordStep := func(name string, latency time.Duration, err error) {
+		atomic.AddInt64(&totalRequests, 1)
+		if err != nil {
+			atomic.AddInt64(&failedRequests, 1)
+		} else {
+			atomic.AddInt64(&successRequests, 1)
+		}
+
+		latenciesMutex.Lock()
+		latencies = append(latencies, latency)
+		latenciesMutex.Unlock()
+
+		steps.record(name, latency, err)
+	}
+
+	scraper := newMetricsScraper(config.MetricsURL, config.URL, config.ScrapeInterval)
+	scraper.Start()
+	startTime := time.Now()
+	errorStats.Start = startTime
+	ctx.Live.Start()
+	ctx.BodySizes.Start()
+	ctx.Events.Start(startTime)
+	monitor := newRunnerMonitor(nil, nil)
+	monitor.Start()
+
+	var wg sync.WaitGroup
+	for i := 0; i < config.VirtualUsers; i++ {
+		wg.Add(1)
+		go func(user int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(user)))
+
+			// Spread session starts so users do not hit the target at the same instant
+			if !sleepContext(benchmarkCtx, config.ThinkTime.Next(rng)) {
+				return
+			}
+
+			for benchmarkCtx.Err() == nil {
+				sessionStart := time.Now()
+				completed, ok := runSession(benchmarkCtx, ctx, rng, recordStep)
+				if !completed {
+					return
+				}
+
+				atomic.AddInt64(&sessions, 1)
+				if !ok {
+					atomic.AddInt64(&failedSessions, 1)
+				}
+
+				latenciesMutex.Lock()
+				sessionTime += time.Since(sessionStart)
+				latenciesMutex.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	duration := time.Since(startTime)
+	timeSeries := ctx.Live.Stop()
+	events := ctx.Events.Close(duration)
+	runnerStats := monitor.Stop()
+	slowestTraces := ctx.Tracer.Shutdown()
+	serverStats := scraper.Stop()
+
+	result := &Result{
+		TotalRequests:   totalRequests,
+		SuccessRequests: successRequests,
+		FailedRequests:  failedRequests,
+		TotalDuration:   duration,
+		StartTime:       startTime,
+		Latencies:       latencies,
+		Errors:          errorStats,
+		BenchmarkType:   config.BenchmarkType,
+		VirtualUsers:    config.VirtualUsers,
+		Sessions:        sessions,
+		FailedSessions:  failedSessions,
+		Steps:           steps.results(),
+		Runner:          runnerStats,
+		Server:          serverStats,
+		TimeSeries:      timeSeries,
+		SlowestRequests: ctx.Slowest.Slowest(),
+		SlowestTraces:   slowestTraces,
+		BodySizes:       ctx.BodySizes.Results(),
+		Events:          events,
+	}
+	if sessions > 0 {
+		result.AvgSessionDuration = sessionTime / time.Duration(sessions)
+	}
+
+	calculateLatencyStats(result)
+
+	return result, nil
+}
+
+// runSession executes a single user session: list products, view several of them
+// and update one. Returns whether the session ran to the end and whether all steps succeeded
+func runSession(benchmarkCtx context.Context, ctx *RequestContext, rng *rand.Rand, record func(string, time.Duration, error)) (bool, bool) {
+	think := func() bool {
+		return sleepContext(benchmarkCtx, ctx.Config.ThinkTime.Next(rng))
+	}
+	listOp, _ := LookupOperation(string(GetProducts))
+	viewOp, _ := LookupOperation(string(GetProductByID))
+	updateOp, _ := LookupOperation(string(UpdateProduct))
+
+	state := newState()
+	sessionSuccess := true
+
+	state.span = ctx.Tracer.StartSpan(string(UserSession), spanKindInternal, nil)
+	defer func() {
+		var err error
+		if !sessionSuccess {
+			err = fmt.Errorf("session failed")
+		}
+		state.span.Finish(err)
+	}()
+
+	// Step 1: list products
+	resp, latency, err := executeRequest(ctx, listOp, state)
+	record("list", latency, err)
+	if err != nil {
+		sessionSuccess = false
+	}
+	var ids []int64
+	if resp != nil {
+		ids = productIDsFromList(resp.Body)
+	}
+
+	// Step 2: view a few products
+	viewed := make([]int64, 0, ctx.Config.SessionViews)
+	for i := 0; i < ctx.Config.SessionViews; i++ {
+		if !think() {
+			return false, sessionSuccess
+		}
+
+		state.ProductID = FixedProductID
+		if len(ids) > 0 {
+			state.ProductID = ids[rng.Intn(len(ids))]
+		}
+		viewed = append(viewed, state.ProductID)
+
+		_, latency, err = executeRequest(ctx, viewOp, state)
+		record("view", latency, err)
+		if err != nil {
+			sessionSuccess = false
+		}
+	}
+
+	// Step 3: update one of the viewed products
+	if !think() {
+		return false, sessionSuccess
+	}
+
+	state.ProductID = FixedProductID
+	if len(viewed) > 0 {
+		state.ProductID = viewed[rng.Intn(len(viewed))]
+	}
+
+	_, latency, err = executeRequest(ctx, updateOp, state)
+	record("update", latency, err)
+	if err != nil {
+		sessionSuccess = false
+	}
+
+	// Pause before the next session starts
+	think()
+
+	return true, sessionSuccess
+}
+
+// productIDsFromList extracts product IDs from GET /api/products response body
+func productIDsFromList(body []byte) []int64 {
+	var products []Product
+	if err := json.Unmarshal(body, &products); err != nil {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(products))
+	for _, p := range products {
+		if p.ID > 0 {
+			ids = append(ids, p.ID)
+		}
+	}
+	return ids
+}
+
+// sleepContext sleeps for the given duration. Returns false if context was cancelled
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
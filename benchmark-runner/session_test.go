@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseThinkTime(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    ThinkTime
+		wantErr bool
+	}{
+		{spec: "", want: ThinkTime{Kind: "none"}},
+		{spec: "0", want: ThinkTime{Kind: "none"}},
+		{spec: "none", want: ThinkTime{Kind: "none"}},
+		{spec: "1s", want: ThinkTime{Kind: "const", Min: time.Second, Max: time.Second, Mean: time.Second}},
+		{spec: "const:250ms", want: ThinkTime{Kind: "const", Min: 250 * time.Millisecond, Max: 250 * time.Millisecond, Mean: 250 * time.Millisecond}},
+		{spec: "uniform:500ms-2s", want: ThinkTime{Kind: "uniform", Min: 500 * time.Millisecond, Max: 2 * time.Second, Mean: 1250 * time.Millisecond}},
+		{spec: "exp:1s", want: ThinkTime{Kind: "exp", Mean: time.Second}},
+		{spec: "uniform:2s-1s", wantErr: true},
+		{spec: "uniform:1s", wantErr: true},
+		{spec: "exp:soon", wantErr: true},
+		{spec: "gauss:1s", wantErr: true},
+		{spec: "fast", wantErr: true},
+		{spec: "-1s", wantErr: true},
+		{spec: "const:-1s", wantErr: true},
+		{spec: "exp:-1s", wantErr: true},
+		{spec: "uniform:-2s--1s", wantErr: true},
+		{spec: "uniform:-1s-1s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseThinkTime(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseThinkTime(%q) = %+v, want error", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseThinkTime(%q): %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseThinkTime(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestThinkTimeNext(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	uniform := ThinkTime{Kind: "uniform", Min: 100 * time.Millisecond, Max: 200 * time.Millisecond}
+	for i := 0; i < 1000; i++ {
+		if d := uniform.Next(rng); d < uniform.Min || d >= uniform.Max {
+			t.Fatalf("uniform think time %s out of [%s, %s)", d, uniform.Min, uniform.Max)
+		}
+	}
+
+	if d := (ThinkTime{Kind: "const", Min: time.Second}).Next(rng); d != time.Second {
+		t.Errorf("const think time = %s, want 1s", d)
+	}
+	if d := (ThinkTime{Kind: "none"}).Next(rng); d != 0 {
+		t.Errorf("no think time = %s, want 0", d)
+	}
+
+	exp := ThinkTime{Kind: "exp", Mean: 100 * time.Millisecond}
+	var sum time.Duration
+	const samples = 20000
+	for i := 0; i < samples; i++ {
+		sum += exp.Next(rng)
+	}
+	if mean := sum / samples; mean < 90*time.Millisecond || mean > 110*time.Millisecond {
+		t.Errorf("exponential think time mean = %s, want about 100ms", mean)
+	}
+}
+
+func TestRunVirtualUsers(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if r.Method == http.MethodGet && r.URL.Path == "/api/products" {
+			w.Write([]byte(`[{"id": 5}, {"id": 6}]`))
+			return
+		}
+		w.Write([]byte(`{"id": 5}`))
+	}))
+	defer server.Close()
+
+	config := Config{
+		URL:           server.URL,
+		BenchmarkType: UserSession,
+		Duration:      500 * time.Millisecond,
+		VirtualUsers:  3,
+		ThinkTime:     ThinkTime{Kind: "const", Min: 10 * time.Millisecond, Max: 10 * time.Millisecond, Mean: 10 * time.Millisecond},
+		SessionViews:  2,
+	}
+	result, err := runVirtualUsers(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Sessions == 0 || result.FailedSessions != 0 || result.VirtualUsers != 3 {
+		t.Errorf("sessions = %d, failed %d, users %d", result.Sessions, result.FailedSessions, result.VirtualUsers)
+	}
+	if result.TotalRequests != atomic.LoadInt64(&requests) || result.FailedRequests != 0 {
+		t.Errorf("requests = %d, failed %d, want %d sent, 0 failed", result.TotalRequests, result.FailedRequests, requests)
+	}
+	// A session has 4 think times of 10ms, so a user can't run more than 12 sessions in 500ms
+	if result.Sessions > 36 || result.AvgSessionDuration < 40*time.Millisecond {
+		t.Errorf("sessions = %d, avg duration %s, want think time between steps", result.Sessions, result.AvgSessionDuration)
+	}
+
+	if len(result.Steps) != 3 {
+		t.Fatalf("steps = %+v, want list, view and update", result.Steps)
+	}
+	list, view, update := result.Steps[0], result.Steps[1], result.Steps[2]
+	if list.Name != "list" || view.Name != "view" || update.Name != "update" {
+		t.Fatalf("steps = %s, %s, %s, want list, view, update", list.Name, view.Name, update.Name)
+	}
+	if list.Requests+view.Requests+update.Requests != result.TotalRequests {
+		t.Errorf("step requests %d + %d + %d != %d", list.Requests, view.Requests, update.Requests, result.TotalRequests)
+	}
+	// Every completed session updates once; sessions cut by the end of the run may stop earlier
+	if update.Requests != result.Sessions || list.Requests < result.Sessions || view.Requests < 2*result.Sessions || view.Requests > 2*list.Requests {
+		t.Errorf("list %d, view %d, update %d requests for %d sessions", list.Requests, view.Requests, update.Requests, result.Sessions)
+	}
+}
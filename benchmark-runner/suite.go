@@ -0,0 +1,523 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Suite is a suite definition: targets, readiness wait and an ordered list of benchmarks
+// run against every target
+type Suite struct {
+	Name          string        `json:"name"`
+	Targets       []SuiteTarget `json:"targets"`
+	ReadyTimeout  string        `json:"ready_timeout"`  // How long to wait for targets' health paths (default 1m)
+	ReadyInterval string        `json:"ready_interval"` // Interval of health checks (default 2s)
+	Cooldown      string        `json:"cooldown"`       // Pause between runs (default 10s)
+
+	// Benchmarks are run definitions of the control API (RunRequest) without url, plus
+	// "name", "cooldown" and "targets": target name to fields overriding the benchmark for that target.
+	// "journey" may also be a journey file and "script" is a script file, relative to the suite file
+	Benchmarks []map[string]json.RawMessage `json:"benchmarks"`
+}
+
+// SuiteTarget is a service the benchmarks run against
+type SuiteTarget struct {
+	Name      string                     `json:"name"`
+	URL       string                     `json:"url"`
+	Health    string                     `json:"health"`    // Path polled until it returns 2xx, e.g. /health
+	Overrides map[string]json.RawMessage `json:"overrides"` // Run fields applied to every benchmark of the target
+}
+
+// suiteRun is a benchmark planned against a target
+type suiteRun struct {
+	Benchmark  string
+	Target     string
+	Cooldown   time.Duration
+	Request    RunRequest
+	config     Config
+	assertions []Assertion
+
+	Result *Result
+	Passed bool
+	Err    error
+}
+
+// suitePlan is a validated suite
+type suitePlan struct {
+	Name          string
+	Targets       []SuiteTarget
+	ReadyTimeout  time.Duration
+	ReadyInterval time.Duration
+	Runs          []*suiteRun
+}
+
+// runSuiteCommand implements "benchmark-runner suite [flags] FILE"
+func runSuiteCommand(args []string) int {
+	fs := flag.NewFlagSet("suite", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s suite [flags] FILE\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(fs.Output(), "Waits for the targets to become ready, runs the benchmarks of the suite file in order\n")
+		fmt.Fprintf(fs.Output(), "against every target and prints one aggregated report.\n\n")
+		fs.PrintDefaults()
+	}
+	var targetURLs stringList
+	fs.Var(&targetURLs, "target", "Override URL of a suite target: NAME=URL, can be repeated")
+	reports := fs.Bool("reports", false, "Print the full report of every run, not only the aggregated one")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	urls := make(map[string]string, len(targetURLs))
+	for _, spec := range targetURLs {
+		name, url, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || url == "" {
+			log.Printf("Invalid -target %q: expected NAME=URL", spec)
+			return 2
+		}
+		urls[name] = url
+	}
+
+	plan, err := loadSuite(fs.Arg(0), urls)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+		log.Printf("Interrupted: stopping the current run and skipping the rest, press Ctrl+C again to exit immediately")
+	}()
+
+	log.Printf("Suite %s: %d targets, %d runs", plan.Name, len(plan.Targets), len(plan.Runs))
+	for _, target := range plan.Targets {
+		if err := waitReady(ctx, target, plan.ReadyTimeout, plan.ReadyInterval); err != nil {
+			log.Print(err)
+			return 1
+		}
+	}
+
+	runSuite(ctx, plan, *reports)
+	printSuiteResults(plan)
+
+	exitCode := 0
+	for _, run := range plan.Runs {
+		if run.Err != nil || run.Result == nil {
+			return 1
+		}
+		if !run.Passed {
+			exitCode = exitAssertionFailed
+		}
+	}
+	return exitCode
+}
+
+// loadSuite reads and validates the suite file. urls override URLs of targets by name.
+// Environment variables ($VAR, ${VAR}) are expanded in target urls and health paths
+func loadSuite(path string, urls map[string]string) (*suitePlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suite Suite
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&suite); err != nil {
+		return nil, fmt.Errorf("failed to parse suite %s: %v", path, err)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(suite.Targets) == 0 {
+		return nil, fmt.Errorf("suite %s: no targets", path)
+	}
+	if len(suite.Benchmarks) == 0 {
+		return nil, fmt.Errorf("suite %s: no benchmarks", path)
+	}
+
+	plan := &suitePlan{Name: suite.Name}
+	if plan.ReadyTimeout, err = parseDurationOr(suite.ReadyTimeout, time.Minute); err != nil {
+		return nil, fmt.Errorf("suite %s: invalid ready_timeout: %v", path, err)
+	}
+	if plan.ReadyInterval, err = parseDurationOr(suite.ReadyInterval, 2*time.Second); err != nil {
+		return nil, fmt.Errorf("suite %s: invalid ready_interval: %v", path, err)
+	}
+	cooldown, err := parseDurationOr(suite.Cooldown, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("suite %s: invalid cooldown: %v", path, err)
+	}
+
+	targetNames := make(map[string]bool, len(suite.Targets))
+	for i, target := range suite.Targets {
+		if target.Name == "" {
+			return nil, fmt.Errorf("suite %s: target %d has no name", path, i+1)
+		}
+		if targetNames[target.Name] {
+			return nil, fmt.Errorf("suite %s: duplicate target name %q", path, target.Name)
+		}
+		targetNames[target.Name] = true
+		if url, ok := urls[target.Name]; ok {
+			suite.Targets[i].URL = url
+			delete(urls, target.Name)
+		} else if suite.Targets[i].URL, err = expandEnv(target.URL); err != nil {
+			return nil, fmt.Errorf("suite %s: target %q: url: %v", path, target.Name, err)
+		}
+		if suite.Targets[i].URL == "" {
+			return nil, fmt.Errorf("suite %s: target %q has no url", path, target.Name)
+		}
+		if suite.Targets[i].Health, err = expandEnv(target.Health); err != nil {
+			return nil, fmt.Errorf("suite %s: target %q: health: %v", path, target.Name, err)
+		}
+	}
+	for name := range urls {
+		return nil, fmt.Errorf("suite %s: -target %q is not a target of the suite", path, name)
+	}
+	plan.Targets = suite.Targets
+
+	for i, fields := range suite.Benchmarks {
+		name := fmt.Sprintf("benchmark-%d", i+1)
+		if raw, ok := fields["name"]; ok {
+			if err := json.Unmarshal(raw, &name); err != nil {
+				return nil, fmt.Errorf("suite %s: benchmark %d: invalid name: %v", path, i+1, err)
+			}
+		}
+		runs, err := planBenchmark(name, fields, suite.Targets, cooldown, filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("suite %s: benchmark %q: %v", path, name, err)
+		}
+		plan.Runs = append(plan.Runs, runs...)
+	}
+	return plan, nil
+}
+
+// expandEnv expands ${VAR} and $VAR references, failing on variables that are not set
+func expandEnv(value string) (string, error) {
+	var missing []string
+	expanded := os.Expand(value, func(name string) string {
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return env
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// planBenchmark builds runs of a benchmark against every target. Fields of the target's
+// "overrides" and of the benchmark's "targets" entry replace fields of the benchmark
+func planBenchmark(name string, fields map[string]json.RawMessage, targets []SuiteTarget, cooldown time.Duration, dir string) ([]*suiteRun, error) {
+	base := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		base[key] = value
+	}
+	delete(base, "name")
+
+	if raw, ok := base["cooldown"]; ok {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("invalid cooldown: %v", err)
+		}
+		var err error
+		if cooldown, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid cooldown: %v", err)
+		}
+		delete(base, "cooldown")
+	}
+
+	perTarget := make(map[string]map[string]json.RawMessage)
+	if raw, ok := base["targets"]; ok {
+		if err := json.Unmarshal(raw, &perTarget); err != nil {
+			return nil, fmt.Errorf("invalid targets: %v", err)
+		}
+		delete(base, "targets")
+	}
+	for targetName := range perTarget {
+		found := false
+		for _, target := range targets {
+			found = found || target.Name == targetName
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown target %q", targetName)
+		}
+	}
+
+	runs := make([]*suiteRun, 0, len(targets))
+	for _, target := range targets {
+		merged := make(map[string]json.RawMessage, len(base))
+		for _, layer := range []map[string]json.RawMessage{base, target.Overrides, perTarget[target.Name]} {
+			for key, value := range layer {
+				merged[key] = value
+			}
+		}
+		url, _ := json.Marshal(target.URL)
+		merged["url"] = url
+
+		// A journey may be a file and a script is a file relative to the suite file
+		if raw, ok := merged["journey"]; ok {
+			var journeyPath string
+			if json.Unmarshal(raw, &journeyPath) == nil {
+				data, err := os.ReadFile(suitePath(dir, journeyPath))
+				if err != nil {
+					return nil, err
+				}
+				merged["journey"] = data
+			}
+		}
+		if raw, ok := merged["script"]; ok {
+			var scriptPath string
+			if err := json.Unmarshal(raw, &scriptPath); err != nil {
+				return nil, fmt.Errorf("invalid script: %v", err)
+			}
+			data, err := os.ReadFile(suitePath(dir, scriptPath))
+			if err != nil {
+				return nil, err
+			}
+			merged["script"], _ = json.Marshal(string(data))
+		}
+
+		data, err := json.Marshal(merged)
+		if err != nil {
+			return nil, err
+		}
+		var request RunRequest
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil {
+			return nil, fmt.Errorf("target %q: %v", target.Name, err)
+		}
+		config, assertions, err := request.config()
+		if err != nil {
+			return nil, fmt.Errorf("target %q: %v", target.Name, err)
+		}
+		runs = append(runs, &suiteRun{
+			Benchmark:  name,
+			Target:     target.Name,
+			Cooldown:   cooldown,
+			Request:    request,
+			config:     config,
+			assertions: assertions,
+		})
+	}
+	return runs, nil
+}
+
+// suitePath resolves path relative to the suite file directory
+func suitePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// parseDurationOr parses duration, or returns def if value is empty
+func parseDurationOr(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// waitReady polls the target's health path until it returns 2xx
+func waitReady(ctx context.Context, target SuiteTarget, timeout, interval time.Duration) error {
+	if target.Health == "" {
+		return nil
+	}
+	url := strings.TrimSuffix(target.URL, "/") + target.Health
+	client := &http.Client{Timeout: interval}
+	deadline := time.Now().Add(timeout)
+
+	log.Printf("Waiting for %s to be ready (%s)", target.Name, url)
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				log.Printf("%s is ready", target.Name)
+				return nil
+			}
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("target %s did not become ready in %s: %v", target.Name, timeout, err)
+		}
+		log.Printf("Attempt %d: %s not ready yet: %v", attempt, target.Name, err)
+		if !sleepContext(ctx, interval) {
+			return errors.New("interrupted")
+		}
+	}
+}
+
+// runSuite runs the planned runs in order with cooldowns between them.
+// After an interrupt the remaining runs are skipped
+func runSuite(ctx context.Context, plan *suitePlan, reports bool) {
+	for i, run := range plan.Runs {
+		if i > 0 && run.Cooldown > 0 && !sleepContext(ctx, run.Cooldown) {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		log.Printf("Run %d/%d: %s against %s (%s)", i+1, len(plan.Runs), run.Benchmark, run.Target, run.config.URL)
+		run.Result, run.Err = runOnce(ctx, run.config)
+		if run.Err != nil {
+			log.Printf("  failed: %v", run.Err)
+			continue
+		}
+		run.Result.Assertions, run.Passed = evaluateAssertions(run.assertions, run.Result)
+
+		metrics := runMetrics(run.Result)
+		log.Printf("  %.1f RPS, avg %.2fms, p99 %.2fms, errors %.2f%%",
+			metrics["rps"], metrics["avg_ms"], metrics["p99_ms"], metrics["error_rate"])
+		if reports {
+			printResults(run.Result, run.config.Verbose)
+		}
+	}
+}
+
+// printSuiteResults prints the aggregated report of a suite: one row per benchmark and target
+func printSuiteResults(plan *suitePlan) {
+	fmt.Println("")
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Printf("                      SUITE %s\n", plan.Name)
+	fmt.Println("════════════════════════════════════════════════════════════════")
+	fmt.Println("┌──────────────────────┬────────────────┬──────────┬────────────┬────────────┬────────────┬──────────┬────────┐")
+	fmt.Println("│      BENCHMARK       │     TARGET     │  REQ/S   │    AVG     │    P95     │    P99     │  ERRORS  │  SLO   │")
+	fmt.Println("├──────────────────────┼────────────────┼──────────┼────────────┼────────────┼────────────┼──────────┼────────┤")
+
+	runList := make([]map[string]interface{}, 0, len(plan.Runs))
+	for _, run := range plan.Runs {
+		runJSON := map[string]interface{}{"benchmark": run.Benchmark, "target": run.Target, "url": run.config.URL}
+		runList = append(runList, runJSON)
+
+		if run.Result == nil {
+			status := "skipped"
+			if run.Err != nil {
+				status = "failed: " + run.Err.Error()
+				runJSON["error"] = run.Err.Error()
+			}
+			runJSON["status"] = status
+			fmt.Printf("│ %-20s │ %-14s │ %-67s │\n", truncateString(run.Benchmark, 17), truncateString(run.Target, 11), truncateString(status, 64))
+			continue
+		}
+
+		r := run.Result
+		slo := "-"
+		if len(r.Assertions) > 0 {
+			slo = "PASS"
+			if !run.Passed {
+				slo = "FAIL"
+			}
+		}
+		metrics := runMetrics(r)
+		fmt.Printf("│ %-20s │ %-14s │ %8.1f │ %10s │ %10s │ %10s │ %7.2f%% │ %-6s │\n",
+			truncateString(run.Benchmark, 17), truncateString(run.Target, 11), metrics["rps"],
+			r.AvgLatency.Round(time.Microsecond), r.P95Latency.Round(time.Microsecond), r.P99Latency.Round(time.Microsecond),
+			metrics["error_rate"], slo)
+		runJSON["status"] = "completed"
+		runJSON["passed"] = run.Passed
+		runJSON["metrics"] = metrics
+		runJSON["result"] = resultJSON(r)
+	}
+	fmt.Println("└──────────────────────┴────────────────┴──────────┴────────────┴────────────┴────────────┴──────────┴────────┘")
+
+	printSuiteWarmUp(plan)
+
+	// Every benchmark is compared with its run against the first target
+	if len(plan.Targets) > 1 {
+		fmt.Println("")
+		fmt.Printf("Compared with %s:\n", plan.Targets[0].Name)
+		var reference *suiteRun
+		for _, run := range plan.Runs {
+			if run.Target == plan.Targets[0].Name {
+				reference = run
+				continue
+			}
+			if reference == nil || reference.Benchmark != run.Benchmark || reference.Result == nil || run.Result == nil {
+				continue
+			}
+			ref, cur := runMetrics(reference.Result), runMetrics(run.Result)
+			fmt.Printf("  %-20s %-14s RPS %s, avg %s, p99 %s", truncateString(run.Benchmark, 17), truncateString(run.Target, 11),
+				ratio(cur["rps"], ref["rps"]), ratio(cur["avg_ms"], ref["avg_ms"]), ratio(cur["p99_ms"], ref["p99_ms"]))
+			if reference.Result.WarmUp != nil && run.Result.WarmUp != nil {
+				fmt.Printf(", steady state %s vs %s", run.Result.WarmUp.SteadyState, reference.Result.WarmUp.SteadyState)
+			}
+			fmt.Println("")
+		}
+	}
+
+	jsonData := map[string]interface{}{
+		"suite": plan.Name,
+		"runs":  runList,
+	}
+	jsonResult, _ := json.MarshalIndent(jsonData, "", "  ")
+	fmt.Println("")
+	fmt.Println("JSON Results:")
+	fmt.Println(string(jsonResult))
+}
+
+// ratio formats value relative to the reference, e.g. "x1.25"
+func ratio(value, reference float64) string {
+	if reference == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("x%.2f", value/reference)
+}
+
+// printSuiteWarmUp prints time to steady state and the warm-up penalty of every run, to compare how targets warm up
+func printSuiteWarmUp(plan *suitePlan) {
+	var runs []*suiteRun
+	for _, run := range plan.Runs {
+		if run.Result != nil && run.Result.WarmUp != nil {
+			runs = append(runs, run)
+		}
+	}
+	if len(runs) == 0 {
+		return
+	}
+
+	fmt.Println("")
+	fmt.Println("Warm-up:")
+	fmt.Println("┌──────────────────────┬────────────────┬──────────────┬──────────────┬────────────┬────────────┬────────────┐")
+	fmt.Println("│      BENCHMARK       │     TARGET     │ STEADY STATE │ WARM-UP AVG  │ STEADY AVG │  PENALTY   │ EXTRA LAT  │")
+	fmt.Println("├──────────────────────┼────────────────┼──────────────┼──────────────┼────────────┼────────────┼────────────┤")
+	for _, run := range runs {
+		w := run.Result.WarmUp
+		steady := w.SteadyState.String()
+		if !w.Reached {
+			steady = "not reached"
+		}
+		warmUpAvg, penalty, extra := "-", "-", "-"
+		if w.PenaltyWindow > 0 {
+			warmUpAvg = fmt.Sprintf("%s (%s)", w.WarmUpAvg.Round(10*time.Microsecond), w.PenaltyWindow)
+			penalty = fmt.Sprintf("%+.1f%%", w.PenaltyPercent())
+			extra = formatSignedDuration(w.ExtraLatency.Round(time.Millisecond))
+		}
+		fmt.Printf("│ %-20s │ %-14s │ %-12s │ %-12s │ %10s │ %10s │ %10s │\n",
+			truncateString(run.Benchmark, 17), truncateString(run.Target, 11), steady, truncateString(warmUpAvg, 12),
+			w.SteadyAvg.Round(time.Microsecond), penalty, extra)
+	}
+	fmt.Println("└──────────────────────┴────────────────┴──────────────┴──────────────┴────────────┴────────────┴────────────┘")
+	fmt.Println("  PENALTY is the average latency of the first seconds over the steady-state average (the last third of the run).")
+}
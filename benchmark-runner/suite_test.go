@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeSuite writes a suite file and the files it references into a temporary directory
+func writeSuite(t *testing.T, suite string, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "compare.json")
+	if err := os.WriteFile(path, []byte(suite), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSuite(t *testing.T) {
+	t.Setenv("SUITE_QUARKUS_URL", "http://quarkus:8080")
+	t.Setenv("SUITE_HEALTH", "/q/health")
+	path := writeSuite(t, `{
+		"ready_interval": "1s",
+		"cooldown": "5s",
+		"targets": [
+			{"name": "quarkus", "url": "${SUITE_QUARKUS_URL}", "health": "$SUITE_HEALTH", "overrides": {"concurrency": 20}},
+			{"name": "golang", "url": "${SUITE_GOLANG_URL}"}
+		],
+		"benchmarks": [
+			{"name": "list", "type": "get-products", "rps": 50, "assert": ["p99<50ms"]},
+			{"type": "http", "body": "{\"price\": \"$5\"}", "captures": ["id=$.id"], "cooldown": "1s",
+			 "targets": {"golang": {"rps": 70, "concurrency": 30}}},
+			{"name": "crud", "journey": "crud.json", "script": "check.star"}
+		]
+	}`, map[string]string{
+		"crud.json":  `{"steps": [{"name": "list", "url": "/api/products"}]}`,
+		"check.star": `steps = [{"name": "list", "request": lambda state: {"url": "/"}}]`,
+	})
+
+	plan, err := loadSuite(path, map[string]string{"golang": "http://localhost:8081"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Name != "compare" || plan.ReadyTimeout != time.Minute || plan.ReadyInterval != time.Second {
+		t.Errorf("plan = %s, ready %s every %s", plan.Name, plan.ReadyTimeout, plan.ReadyInterval)
+	}
+	if plan.Targets[0].URL != "http://quarkus:8080" || plan.Targets[0].Health != "/q/health" || plan.Targets[1].URL != "http://localhost:8081" {
+		t.Errorf("targets = %+v", plan.Targets)
+	}
+	if len(plan.Runs) != 6 {
+		t.Fatalf("runs = %d, want 3 benchmarks against 2 targets", len(plan.Runs))
+	}
+
+	tests := []struct {
+		run         int
+		benchmark   string
+		target      string
+		url         string
+		rps         int
+		concurrency int
+		cooldown    time.Duration
+	}{
+		{run: 0, benchmark: "list", target: "quarkus", url: "http://quarkus:8080", rps: 50, concurrency: 20, cooldown: 5 * time.Second},
+		{run: 1, benchmark: "list", target: "golang", url: "http://localhost:8081", rps: 50, concurrency: 10, cooldown: 5 * time.Second},
+		{run: 2, benchmark: "benchmark-2", target: "quarkus", url: "http://quarkus:8080", rps: 100, concurrency: 20, cooldown: time.Second},
+		{run: 3, benchmark: "benchmark-2", target: "golang", url: "http://localhost:8081", rps: 70, concurrency: 30, cooldown: time.Second},
+	}
+	for _, tt := range tests {
+		run := plan.Runs[tt.run]
+		if run.Benchmark != tt.benchmark || run.Target != tt.target || run.config.URL != tt.url ||
+			run.config.RPS != tt.rps || run.config.Concurrency != tt.concurrency || run.Cooldown != tt.cooldown {
+			t.Errorf("run %d = %s against %s (%s), rps %d, concurrency %d, cooldown %s", tt.run,
+				run.Benchmark, run.Target, run.config.URL, run.config.RPS, run.config.Concurrency, run.Cooldown)
+		}
+	}
+	if len(plan.Runs[0].assertions) != 1 {
+		t.Errorf("assertions = %+v", plan.Runs[0].assertions)
+	}
+	// Fields other than target urls and health paths are not expanded
+	if body := plan.Runs[2].config.Request.Body; body != `{"price": "$5"}` {
+		t.Errorf("body = %q", body)
+	}
+	if capture := plan.Runs[2].config.Request.Captures[0]; capture.Source != "$.id" {
+		t.Errorf("capture = %+v", capture)
+	}
+	// Journey and script files are relative to the suite file
+	if crud := plan.Runs[4].config; crud.Journey == nil || crud.Script == nil || crud.BenchmarkType != ScriptType {
+		t.Errorf("crud = type %s, journey %v, script %v", crud.BenchmarkType, crud.Journey != nil, crud.Script != nil)
+	}
+}
+
+func TestLoadSuiteErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		suite string
+		urls  map[string]string
+		err   string
+	}{
+		{name: "invalid json", suite: `{"targets": `, err: "failed to parse suite"},
+		{name: "unknown field", suite: `{"target": []}`, err: `unknown field "target"`},
+		{name: "no targets", suite: `{"benchmarks": [{}]}`, err: "no targets"},
+		{name: "no benchmarks", suite: `{"targets": [{"name": "a", "url": "http://a"}]}`, err: "no benchmarks"},
+		{name: "ready timeout", suite: `{"ready_timeout": "soon", "targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{}]}`, err: "invalid ready_timeout"},
+		{name: "cooldown", suite: `{"cooldown": "-", "targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{}]}`, err: "invalid cooldown"},
+		{name: "target name", suite: `{"targets": [{"url": "http://a"}], "benchmarks": [{}]}`, err: "target 1 has no name"},
+		{name: "duplicate target", suite: `{"targets": [{"name": "a", "url": "http://a"}, {"name": "a", "url": "http://b"}], "benchmarks": [{}]}`, err: `duplicate target name "a"`},
+		{name: "no url", suite: `{"targets": [{"name": "a"}], "benchmarks": [{}]}`, err: `target "a" has no url`},
+		{
+			name:  "unset variable",
+			suite: `{"targets": [{"name": "a", "url": "${SUITE_MISSING_URL}"}], "benchmarks": [{}]}`,
+			err:   `target "a": url: environment variable SUITE_MISSING_URL is not set`,
+		},
+		{
+			name:  "unset variables",
+			suite: `{"targets": [{"name": "a", "url": "http://$SUITE_MISSING_HOST:${SUITE_MISSING_PORT}"}], "benchmarks": [{}]}`,
+			err:   "environment variable SUITE_MISSING_HOST, SUITE_MISSING_PORT is not set",
+		},
+		{
+			name:  "unset health variable",
+			suite: `{"targets": [{"name": "a", "url": "http://a", "health": "${SUITE_MISSING_URL}"}], "benchmarks": [{}]}`,
+			err:   `target "a": health: environment variable SUITE_MISSING_URL is not set`,
+		},
+		{name: "unknown -target", suite: `{"targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{}]}`, urls: map[string]string{"b": "http://b"}, err: `-target "b" is not a target`},
+		{name: "benchmark name", suite: `{"targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{"name": 1}]}`, err: "benchmark 1: invalid name"},
+		{name: "benchmark cooldown", suite: `{"targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{"name": "b", "cooldown": "x"}]}`, err: `benchmark "b": invalid cooldown`},
+		{name: "benchmark targets", suite: `{"targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{"name": "b", "targets": {"c": {}}}]}`, err: `benchmark "b": unknown target "c"`},
+		{name: "benchmark field", suite: `{"targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{"name": "b", "workers": 5}]}`, err: `target "a": json: unknown field "workers"`},
+		{name: "benchmark config", suite: `{"targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{"name": "b", "rps": -5}]}`, err: `target "a": rps must be positive`},
+		{name: "missing journey", suite: `{"targets": [{"name": "a", "url": "http://a"}], "benchmarks": [{"name": "b", "journey": "missing.json"}]}`, err: "missing.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadSuite(writeSuite(t, tt.suite, nil), tt.urls)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadSuiteTargetOverridesUnsetVariable(t *testing.T) {
+	path := writeSuite(t, `{"targets": [{"name": "a", "url": "${SUITE_MISSING_URL}"}], "benchmarks": [{}]}`, nil)
+	plan, err := loadSuite(path, map[string]string{"a": "http://a"})
+	if err != nil || plan.Targets[0].URL != "http://a" {
+		t.Errorf("plan = %+v, %v, want the -target URL", plan, err)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("SUITE_HOST", "localhost")
+	t.Setenv("SUITE_EMPTY", "")
+	tests := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{value: "http://${SUITE_HOST}:8080", want: "http://localhost:8080"},
+		{value: "http://$SUITE_HOST/", want: "http://localhost/"},
+		{value: "${SUITE_EMPTY}", want: ""},
+		{value: "http://a", want: "http://a"},
+		{value: "${SUITE_UNSET_VAR}", err: "environment variable SUITE_UNSET_VAR is not set"},
+	}
+	for _, tt := range tests {
+		got, err := expandEnv(tt.value)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("expandEnv(%q) error = %v, want %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("expandEnv(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseDurationOr(t *testing.T) {
+	if d, err := parseDurationOr("", time.Minute); d != time.Minute || err != nil {
+		t.Errorf("default = %s, %v", d, err)
+	}
+	if d, err := parseDurationOr("5s", time.Minute); d != 5*time.Second || err != nil {
+		t.Errorf("value = %s, %v", d, err)
+	}
+	if _, err := parseDurationOr("soon", time.Minute); err == nil {
+		t.Error("invalid duration accepted")
+	}
+}
+
+func TestSuitePath(t *testing.T) {
+	if got := suitePath("suites", "../journeys/crud.json"); got != "journeys/crud.json" {
+		t.Errorf("relative = %s", got)
+	}
+	if got := suitePath("suites", "/etc/crud.json"); got != "/etc/crud.json" {
+		t.Errorf("absolute = %s", got)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	var checks int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if atomic.AddInt32(&checks, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	target := SuiteTarget{Name: "app", URL: server.URL + "/", Health: "/health"}
+	if err := waitReady(context.Background(), target, time.Second, 10*time.Millisecond); err != nil || checks != 3 {
+		t.Errorf("waitReady = %v after %d checks, want ready after 3", err, checks)
+	}
+
+	atomic.StoreInt32(&checks, -1000)
+	err := waitReady(context.Background(), target, 30*time.Millisecond, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "target app did not become ready in 30ms: status 503") {
+		t.Errorf("error = %v, want timeout", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := waitReady(ctx, target, time.Minute, 10*time.Millisecond); err == nil || err.Error() != "interrupted" {
+		t.Errorf("error = %v, want interrupted", err)
+	}
+
+	if err := waitReady(context.Background(), SuiteTarget{Name: "no health", URL: "http://invalid"}, 0, 0); err != nil {
+		t.Errorf("target without health path: %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+const (
+	// maxCapturedValues is how many recent values of each captured variable are kept
+	maxCapturedValues = 1000
+)
+
+// requestSequence is a global sequence number available in templates as {{seq}}
+var requestSequence int64
+
+// templateFuncs are functions available in request templates
+var templateFuncs = template.FuncMap{
+	"randInt": func(min, max int) int {
+		if max <= min {
+			return min
+		}
+		return min + mathrand.Intn(max-min+1)
+	},
+	"randFloat": func(min, max float64) float64 {
+		return min + mathrand.Float64()*(max-min)
+	},
+	"randString": func(n int) string {
+		const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = letters[mathrand.Intn(len(letters))]
+		}
+		return string(b)
+	},
+	"uuid":      newUUID,
+	"seq":       func() int64 { return atomic.AddInt64(&requestSequence, 1) },
+	"timestamp": func() int64 { return time.Now().UnixMilli() },
+}
+
+// newUUID returns random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// parseRequestTemplate parses template with request template functions
+func parseRequestTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// VarStore keeps values captured from responses and shares them between workers
+type VarStore struct {
+	mu     sync.RWMutex
+	values map[string][]string
+	next   map[string]int // Ring buffer position
+}
+
+func NewVarStore() *VarStore {
+	return &VarStore{
+		values: make(map[string][]string),
+		next:   make(map[string]int),
+	}
+}
+
+// Set stores captured value, keeping the last maxCapturedValues values per name
+func (vs *VarStore) Set(name, value string) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	values := vs.values[name]
+	if len(values) < maxCapturedValues {
+		vs.values[name] = append(values, value)
+		return
+	}
+	values[vs.next[name]] = value
+	vs.next[name] = (vs.next[name] + 1) % maxCapturedValues
+}
+
+// Get returns a random recently captured value
+func (vs *VarStore) Get(name string) (string, bool) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	values := vs.values[name]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[mathrand.Intn(len(values))], true
+}
+
+// templateData is the data passed to request templates
+type templateData struct {
+	state *State
+	store *VarStore
+}
+
+// Var returns variable value: first from the current iteration state,
+// then from values captured by earlier responses. Used as {{.Var "id"}}
+func (d templateData) Var(name string) string {
+	if d.state != nil {
+		if value, ok := d.state.Vars[name]; ok {
+			return value
+		}
+	}
+	if d.store != nil {
+		if value, ok := d.store.Get(name); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// ProductID returns product ID of the current iteration. Used as {{.ProductID}}
+func (d templateData) ProductID() int64 {
+	if d.state == nil {
+		return 0
+	}
+	return d.state.ProductID
+}
+
+// CaptureRule describes value to capture from response into a variable
+type CaptureRule struct {
+	Name   string // Variable name
+	Source string // JSONPath-like path in JSON body, or "header:<Name>"
+}
+
+// parseCaptureRule parses "name=$.path" or "name=header:Location"
+func parseCaptureRule(spec string) (CaptureRule, error) {
+	name, source, ok := strings.Cut(spec, "=")
+	if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(source) == "" {
+		return CaptureRule{}, fmt.Errorf("invalid capture %q: expected name=path", spec)
+	}
+	return CaptureRule{Name: strings.TrimSpace(name), Source: strings.TrimSpace(source)}, nil
+}
+
+// Extract returns captured value from response
+func (c CaptureRule) Extract(resp *Response) (string, error) {
+	if header, ok := strings.CutPrefix(c.Source, "header:"); ok {
+		value := resp.Header.Get(header)
+		if value == "" {
+			return "", fmt.Errorf("header %s not found", header)
+		}
+		return value, nil
+	}
+	return extractJSONPath(resp.Body, c.Source)
+}
+
+// TemplateRequest is the definition of a templated HTTP request
+type TemplateRequest struct {
+	Method       string
+	URL          string            // URL template
+	Headers      map[string]string // Header value templates
+	Body         string            // Body template
+	Captures     []CaptureRule
+	ExpectStatus []int           // Successful status codes. Empty means anything but 5xx
+	Assertions   *StepAssertions // Additional response checks (optional)
+}
+
+// templateOperation is an operation built from a request template
+type templateOperation struct {
+	name     string
+	def      TemplateRequest
+	url      *template.Template
+	body     *template.Template
+	headers  map[string]*template.Template
+	captures []CaptureRule
+	product  bool // Templates reference {{.ProductID}}
+}
+
+// newTemplateOperation parses templates of the request definition
+func newTemplateOperation(name string, def TemplateRequest) (*templateOperation, error) {
+	if def.Method == "" {
+		def.Method = http.MethodGet
+	}
+	op := &templateOperation{
+		name:     name,
+		def:      def,
+		headers:  make(map[string]*template.Template, len(def.Headers)),
+		captures: def.Captures,
+	}
+
+	var err error
+	if op.url, err = parseRequestTemplate(name+":url", def.URL); err != nil {
+		return nil, fmt.Errorf("invalid URL template: %v", err)
+	}
+	if def.Body != "" {
+		if op.body, err = parseRequestTemplate(name+":body", def.Body); err != nil {
+			return nil, fmt.Errorf("invalid body template: %v", err)
+		}
+	}
+	for header, value := range def.Headers {
+		if op.headers[header], err = parseRequestTemplate(name+":"+header, value); err != nil {
+			return nil, fmt.Errorf("invalid template of header %s: %v", header, err)
+		}
+	}
+
+	op.product = strings.Contains(def.URL+def.Body, ".ProductID")
+	for _, value := range def.Headers {
+		op.product = op.product || strings.Contains(value, ".ProductID")
+	}
+
+	return op, nil
+}
+
+func (op *templateOperation) Name() string {
+	return op.name
+}
+
+func (op *templateOperation) UsesProductID() bool {
+	return op.product
+}
+
+func (op *templateOperation) BuildRequest(ctx *RequestContext, state *State) (*http.Request, error) {
+	data := templateData{state: state, store: ctx.Vars}
+
+	url, err := renderTemplate(op.url, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if op.body != nil {
+		rendered, err := renderTemplate(op.body, data)
+		if err != nil {
+			return nil, err
+		}
+		body = []byte(rendered)
+	}
+
+	req, err := newRequest(op.def.Method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for header, tmpl := range op.headers {
+		value, err := renderTemplate(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(header, "Host") {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(header, value)
+	}
+
+	return req, nil
+}
+
+func (op *templateOperation) ClassifyResponse(resp *Response) error {
+	if len(op.def.ExpectStatus) == 0 {
+		if err := classifyServerErrors(resp); err != nil {
+			return err
+		}
+	} else if !containsStatus(op.def.ExpectStatus, resp.StatusCode) {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return op.def.Assertions.Check(resp)
+}
+
+// containsStatus reports whether status is in the list
+func containsStatus(statuses []int, status int) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractState stores captured values in the iteration state
+func (op *templateOperation) ExtractState(resp *Response, state *State) error {
+	for _, capture := range op.captures {
+		value, err := capture.Extract(resp)
+		if err != nil {
+			return fmt.Errorf("capture %s: %v", capture.Name, err)
+		}
+		state.SetVar(capture.Name, value)
+	}
+	return nil
+}
+
+func (op *templateOperation) ExtractsState() bool {
+	return len(op.captures) > 0
+}
+
+// renderTemplate executes template into string
+func renderTemplate(tmpl *template.Template, data templateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// parseHeader parses "Name: value" header specification
+func parseHeader(spec string) (string, string, error) {
+	name, value, ok := strings.Cut(spec, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return "", "", fmt.Errorf("invalid header %q: expected 'Name: value'", spec)
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(value), nil
+}
+
+// parseStatusList parses comma separated list of status codes
+func parseStatusList(spec string) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	var statuses []int
+	for _, part := range strings.Split(spec, ",") {
+		status, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || status < 100 || status > 599 {
+			return nil, fmt.Errorf("invalid status code %q: expected 100-599", part)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseCaptureRule(t *testing.T) {
+	tests := []struct {
+		spec string
+		want CaptureRule
+		err  bool
+	}{
+		{spec: "id=$.id", want: CaptureRule{Name: "id", Source: "$.id"}},
+		{spec: " location = header:Location ", want: CaptureRule{Name: "location", Source: "header:Location"}},
+		{spec: "token=$.data.token=x", want: CaptureRule{Name: "token", Source: "$.data.token=x"}},
+		{spec: "id", err: true},
+		{spec: "=$.id", err: true},
+		{spec: "id= ", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseCaptureRule(tt.spec)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaptureRuleExtract(t *testing.T) {
+	resp := &Response{
+		Header: http.Header{"Location": {"/api/products/7"}},
+		Body:   []byte(`{"id": 7}`),
+	}
+
+	tests := []struct {
+		source string
+		want   string
+		err    bool
+	}{
+		{source: "$.id", want: "7"},
+		{source: "header:Location", want: "/api/products/7"},
+		{source: "header:location", want: "/api/products/7"},
+		{source: "header:X-Missing", err: true},
+		{source: "$.name", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := CaptureRule{Name: "v", Source: tt.source}.Extract(resp)
+			if (err != nil) != tt.err {
+				t.Fatalf("error = %v, want error %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		spec, name, value string
+		err               bool
+	}{
+		{spec: "Authorization: Bearer abc", name: "Authorization", value: "Bearer abc"},
+		{spec: "X-Time:12:30", name: "X-Time", value: "12:30"},
+		{spec: "X-Empty:", name: "X-Empty", value: ""},
+		{spec: "Authorization", err: true},
+		{spec: " : value", err: true},
+	}
+
+	for _, tt := range tests {
+		name, value, err := parseHeader(tt.spec)
+		if (err != nil) != tt.err {
+			t.Errorf("parseHeader(%q) error = %v, want error %v", tt.spec, err, tt.err)
+			continue
+		}
+		if name != tt.name || value != tt.value {
+			t.Errorf("parseHeader(%q) = %q, %q, want %q, %q", tt.spec, name, value, tt.name, tt.value)
+		}
+	}
+}
+
+func TestParseStatusList(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []int
+		err  bool
+	}{
+		{spec: "", want: nil},
+		{spec: "200", want: []int{200}},
+		{spec: "200, 201,404", want: []int{200, 201, 404}},
+		{spec: "200,ok", err: true},
+		{spec: "200,", err: true},
+		{spec: "0", err: true},
+		{spec: "-1", err: true},
+		{spec: "99", err: true},
+		{spec: "2000", err: true},
+		{spec: "100,599", want: []int{100, 599}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseStatusList(tt.spec)
+		if (err != nil) != tt.err {
+			t.Errorf("parseStatusList(%q) error = %v, want error %v", tt.spec, err, tt.err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseStatusList(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestVarStoreKeepsRecentValues(t *testing.T) {
+	store := NewVarStore()
+	if _, ok := store.Get("id"); ok {
+		t.Fatal("Get of an unknown variable succeeded")
+	}
+
+	for i := 0; i < maxCapturedValues+10; i++ {
+		store.Set("id", strconv.Itoa(i))
+	}
+	if got := len(store.values["id"]); got != maxCapturedValues {
+		t.Fatalf("kept %d values, want %d", got, maxCapturedValues)
+	}
+	// The oldest values are overwritten first
+	for _, value := range store.values["id"] {
+		if n, _ := strconv.Atoi(value); n < 10 {
+			t.Fatalf("value %s was not overwritten", value)
+		}
+	}
+	if value, ok := store.Get("id"); !ok || value == "" {
+		t.Errorf("Get = %q, %v", value, ok)
+	}
+}
+
+func TestTemplateDataVar(t *testing.T) {
+	store := NewVarStore()
+	store.Set("id", "shared")
+	store.Set("other", "from-store")
+	state := newState()
+	state.SetVar("id", "own")
+
+	data := templateData{state: state, store: store}
+	tests := map[string]string{"id": "own", "other": "from-store", "missing": ""}
+	for name, want := range tests {
+		if got := data.Var(name); got != want {
+			t.Errorf("Var(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := (templateData{}).Var("id"); got != "" {
+		t.Errorf("Var without state and store = %q", got)
+	}
+	if got := (templateData{}).ProductID(); got != 0 {
+		t.Errorf("ProductID without state = %d", got)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		text    string
+		pattern string
+	}{
+		{`{{randInt 5 5}}`, `^5$`},
+		{`{{randInt 10 1}}`, `^10$`},
+		{`{{randInt 1 3}}`, `^[1-3]$`},
+		{`{{randString 12}}`, `^[a-z0-9]{12}$`},
+		{`{{uuid}}`, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{`{{seq}}`, `^[0-9]+$`},
+		{`{{timestamp}}`, `^[0-9]{13}$`},
+		{`{{printf "%.1f" (randFloat 2 2)}}`, `^2\.0$`},
+		{`{{.ProductID}}`, `^1$`},
+		{`{{or (.Var "missing") "default"}}`, `^default$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tmpl, err := parseRequestTemplate("test", tt.text)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			for i := 0; i < 20; i++ {
+				got, err := renderTemplate(tmpl, templateData{state: newState(), store: NewVarStore()})
+				if err != nil {
+					t.Fatalf("render: %v", err)
+				}
+				if !regexp.MustCompile(tt.pattern).MatchString(got) {
+					t.Fatalf("got %q, want %s", got, tt.pattern)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateSeqIncreases(t *testing.T) {
+	tmpl, _ := parseRequestTemplate("seq", "{{seq}}")
+	first, _ := renderTemplate(tmpl, templateData{})
+	second, _ := renderTemplate(tmpl, templateData{})
+	a, _ := strconv.Atoi(first)
+	b, _ := strconv.Atoi(second)
+	if b <= a {
+		t.Errorf("seq went from %d to %d", a, b)
+	}
+}
+
+func TestTemplateOperation(t *testing.T) {
+	op, err := newTemplateOperation("update", TemplateRequest{
+		Method:       http.MethodPut,
+		URL:          `http://localhost/api/products/{{.Var "id"}}`,
+		Headers:      map[string]string{"X-Request": "r-{{.ProductID}}", "Host": "api.local"},
+		Body:         `{"id": {{.Var "id"}}}`,
+		Captures:     []CaptureRule{{Name: "version", Source: "$.version"}},
+		ExpectStatus: []int{200, 204},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := newRequestContext(Config{}, NewErrorStats())
+	state := newState()
+	state.Vars["id"] = "7"
+	req, err := op.BuildRequest(ctx, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.Method != http.MethodPut || req.URL.String() != "http://localhost/api/products/7" || string(body) != `{"id": 7}` {
+		t.Errorf("request = %s %s %s", req.Method, req.URL, body)
+	}
+	if req.Header.Get("X-Request") != "r-1" || req.Host != "api.local" {
+		t.Errorf("headers = %v, host = %s", req.Header, req.Host)
+	}
+
+	for status, failed := range map[int]bool{200: false, 204: false, 404: true, 500: true} {
+		if err := op.ClassifyResponse(&Response{StatusCode: status}); (err != nil) != failed {
+			t.Errorf("status %d: error = %v, want failed %v", status, err, failed)
+		}
+	}
+
+	if err := op.ExtractState(&Response{Body: []byte(`{"version": 3}`)}, state); err != nil || state.Vars["version"] != "3" {
+		t.Errorf("ExtractState = %v, vars = %v", err, state.Vars)
+	}
+	if err := op.ExtractState(&Response{Body: []byte(`{}`)}, state); err == nil || !strings.HasPrefix(err.Error(), "capture version:") {
+		t.Errorf("ExtractState error = %v, want capture version", err)
+	}
+}
+
+func TestTemplateOperationDefaults(t *testing.T) {
+	op, err := newTemplateOperation("get", TemplateRequest{URL: "http://localhost/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.def.Method != http.MethodGet {
+		t.Errorf("method = %s, want GET", op.def.Method)
+	}
+	for status, failed := range map[int]bool{200: false, 404: false, 503: true} {
+		if err := op.ClassifyResponse(&Response{StatusCode: status}); (err != nil) != failed {
+			t.Errorf("status %d: error = %v, want failed %v", status, err, failed)
+		}
+	}
+
+	if _, err := newTemplateOperation("bad", TemplateRequest{URL: "{{.Var"}); err == nil || !strings.Contains(err.Error(), "invalid URL template") {
+		t.Errorf("error = %v, want invalid URL template", err)
+	}
+	if _, err := newTemplateOperation("bad", TemplateRequest{URL: "/", Body: "{{end}}"}); err == nil || !strings.Contains(err.Error(), "invalid body template") {
+		t.Errorf("error = %v, want invalid body template", err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+	"time"
+)
+
+// topN keeps the size items with the longest duration. Not safe for concurrent use
+type topN[T any] struct {
+	size  int
+	items topNHeap[T]
+}
+
+// newTopN creates top-N of items ordered by duration
+func newTopN[T any](size int, duration func(T) time.Duration) *topN[T] {
+	return &topN[T]{size: size, items: topNHeap[T]{duration: duration}}
+}
+
+// Add keeps item if it is one of the longest so far
+func (t *topN[T]) Add(item T) {
+	if t.size <= 0 {
+		return
+	}
+	if len(t.items.list) < t.size {
+		heap.Push(&t.items, item)
+	} else if t.items.duration(item) > t.items.duration(t.items.list[0]) {
+		t.items.list[0] = item
+		heap.Fix(&t.items, 0)
+	}
+}
+
+// Threshold returns duration an item must exceed to be kept, 0 until size items are kept
+func (t *topN[T]) Threshold() time.Duration {
+	if t.size <= 0 || len(t.items.list) < t.size {
+		return 0
+	}
+	return t.items.duration(t.items.list[0])
+}
+
+// Sorted returns kept items, longest first
+func (t *topN[T]) Sorted() []T {
+	result := slices.Clone(t.items.list)
+	slices.SortStableFunc(result, func(a, b T) int {
+		return cmp.Compare(t.items.duration(b), t.items.duration(a))
+	})
+	return result
+}
+
+// topNHeap is a min-heap of items by duration
+type topNHeap[T any] struct {
+	list     []T
+	duration func(T) time.Duration
+}
+
+func (h topNHeap[T]) Len() int            { return len(h.list) }
+func (h topNHeap[T]) Less(i, j int) bool  { return h.duration(h.list[i]) < h.duration(h.list[j]) }
+func (h topNHeap[T]) Swap(i, j int)       { h.list[i], h.list[j] = h.list[j], h.list[i] }
+func (h *topNHeap[T]) Push(x interface{}) { h.list = append(h.list, x.(T)) }
+func (h *topNHeap[T]) Pop() interface{} {
+	old := h.list
+	n := len(old)
+	item := old[n-1]
+	h.list = old[:n-1]
+	return item
+}
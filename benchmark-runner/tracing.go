@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// spanBufferSize is the number of finished spans waiting for export; spans are dropped when full
+	spanBufferSize = 10000
+
+	// spanBatchSize is the maximum number of spans in one OTLP export request
+	spanBatchSize = 512
+
+	// spanExportInterval is how often incomplete batches are exported
+	spanExportInterval = 2 * time.Second
+
+	// slowestTracesCount is how many slowest traced requests are listed in the report
+	slowestTracesCount = 10
+
+	// OTLP span kinds and status codes
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+// TracingConfig configures W3C trace context propagation and span export
+type TracingConfig struct {
+	Enabled      bool    // Inject traceparent header and record client spans
+	OTLPEndpoint string  // OTLP/HTTP collector base URL, e.g. http://otel-collector:4318 (optional)
+	SampleRatio  float64 // Share of traces marked as sampled and exported
+	ServiceName  string  // service.name resource attribute
+}
+
+// Span is a client-side span of a request or a scenario iteration
+type Span struct {
+	tracer   *Tracer
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte
+	Name     string
+	Kind     int
+	Sampled  bool
+	Start    time.Time
+	End      time.Time
+	Attrs    map[string]interface{}
+	Err      string
+}
+
+// TraceSample is a traced request listed in the report
+type TraceSample struct {
+	TraceID    string
+	Name       string
+	Duration   time.Duration
+	StatusCode int
+	Start      time.Time
+}
+
+// Tracer creates spans and exports them to an OTLP collector in background.
+// All methods are safe to call on a nil *Tracer (tracing disabled)
+type Tracer struct {
+	config  TracingConfig
+	client  *http.Client
+	spans   chan *Span
+	done    chan struct{}
+	dropped int64
+	failed  int64
+
+	mu      sync.Mutex
+	slowest *topN[TraceSample]
+}
+
+// NewTracer creates tracer and starts exporter. Returns nil if tracing is disabled
+func NewTracer(config TracingConfig) *Tracer {
+	if !config.Enabled {
+		return nil
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = "benchmark-runner"
+	}
+
+	t := &Tracer{
+		config:  config,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		spans:   make(chan *Span, spanBufferSize),
+		done:    make(chan struct{}),
+		slowest: newTopN(slowestTracesCount, func(s TraceSample) time.Duration { return s.Duration }),
+	}
+	go t.exportLoop()
+	return t
+}
+
+// StartSpan starts a new span. Child spans share trace ID and sampling decision with the parent
+func (t *Tracer) StartSpan(name string, kind int, parent *Span) *Span {
+	if t == nil {
+		return nil
+	}
+
+	span := &Span{
+		tracer: t,
+		Name:   name,
+		Kind:   kind,
+		Start:  time.Now(),
+		Attrs:  make(map[string]interface{}),
+	}
+	if parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+		span.Sampled = parent.Sampled
+	} else {
+		_, _ = rand.Read(span.TraceID[:])
+		span.Sampled = mathrand.Float64() < t.config.SampleRatio
+	}
+	_, _ = rand.Read(span.SpanID[:])
+	return span
+}
+
+// Traceparent returns W3C traceparent header value
+func (s *Span) Traceparent() string {
+	flags := "00"
+	if s.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(s.TraceID[:]) + "-" + hex.EncodeToString(s.SpanID[:]) + "-" + flags
+}
+
+// Inject sets traceparent header of the request
+func (s *Span) Inject(req *http.Request) {
+	if s == nil {
+		return
+	}
+	req.Header.Set("traceparent", s.Traceparent())
+}
+
+// SetAttr sets span attribute
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.Attrs[key] = value
+}
+
+// Finish ends the span and queues it for export
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.End = time.Now()
+	if err != nil {
+		s.Err = err.Error()
+	}
+	s.tracer.finish(s)
+}
+
+// finish records sampled span: tracks the slowest client spans and queues span for export
+func (t *Tracer) finish(span *Span) {
+	if !span.Sampled {
+		return
+	}
+
+	if span.Kind == spanKindClient {
+		statusCode, _ := span.Attrs["http.response.status_code"].(int)
+		sample := TraceSample{
+			TraceID:    hex.EncodeToString(span.TraceID[:]),
+			Name:       span.Name,
+			Duration:   span.End.Sub(span.Start),
+			StatusCode: statusCode,
+			Start:      span.Start,
+		}
+		t.mu.Lock()
+		t.slowest.Add(sample)
+		t.mu.Unlock()
+	}
+
+	if t.config.OTLPEndpoint == "" {
+		return
+	}
+	select {
+	case t.spans <- span:
+	default:
+		atomic.AddInt64(&t.dropped, 1)
+	}
+}
+
+// Shutdown flushes queued spans and returns the slowest traced requests, slowest first
+func (t *Tracer) Shutdown() []TraceSample {
+	if t == nil {
+		return nil
+	}
+
+	close(t.spans)
+	<-t.done
+
+	if dropped := atomic.LoadInt64(&t.dropped); dropped > 0 {
+		log.Printf("Tracing: %d spans dropped (export buffer full)", dropped)
+	}
+	if failed := atomic.LoadInt64(&t.failed); failed > 0 {
+		log.Printf("Tracing: %d spans failed to export to %s", failed, t.config.OTLPEndpoint)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.slowest.Sorted()
+}
+
+// exportLoop batches spans and sends them to the collector
+func (t *Tracer) exportLoop() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(spanExportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, spanBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.export(batch); err != nil {
+			if atomic.AddInt64(&t.failed, int64(len(batch))) == int64(len(batch)) {
+				log.Printf("Tracing: failed to export spans: %v", err)
+			}
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span, ok := <-t.spans:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= spanBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// export sends spans using OTLP/HTTP with JSON encoding
+func (t *Tracer) export(spans []*Span) error {
+	otlpSpans := make([]map[string]interface{}, 0, len(spans))
+	for _, span := range spans {
+		otlpSpan := map[string]interface{}{
+			"traceId":           hex.EncodeToString(span.TraceID[:]),
+			"spanId":            hex.EncodeToString(span.SpanID[:]),
+			"name":              span.Name,
+			"kind":              span.Kind,
+			"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attrs),
+			"status":            map[string]interface{}{"code": statusCodeOK},
+		}
+		if span.ParentID != ([8]byte{}) {
+			otlpSpan["parentSpanId"] = hex.EncodeToString(span.ParentID[:])
+		}
+		if span.Err != "" {
+			otlpSpan["status"] = map[string]interface{}{"code": statusCodeError, "message": span.Err}
+		}
+		otlpSpans = append(otlpSpans, otlpSpan)
+	}
+
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{
+						"service.name": t.config.ServiceName,
+					}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "benchmark-runner"},
+						"spans": otlpSpans,
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(t.config.OTLPEndpoint, "/") + "/v1/traces"
+	resp, err := t.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// otlpAttributes converts attributes map to OTLP key-value list
+func otlpAttributes(attrs map[string]interface{}) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(attrs))
+	for key, value := range attrs {
+		var otlpValue map[string]interface{}
+		switch v := value.(type) {
+		case int:
+			otlpValue = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			otlpValue = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case bool:
+			otlpValue = map[string]interface{}{"boolValue": v}
+		case float64:
+			otlpValue = map[string]interface{}{"doubleValue": v}
+		default:
+			otlpValue = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		result = append(result, map[string]interface{}{"key": key, "value": otlpValue})
+	}
+	return result
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+var traceparentPattern = regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-0[01]$`)
+
+func TestTraceparent(t *testing.T) {
+	span := &Span{
+		TraceID: [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	}
+	if got, want := span.Traceparent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"; got != want {
+		t.Errorf("unsampled traceparent = %s, want %s", got, want)
+	}
+	span.Sampled = true
+	if got, want := span.Traceparent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"; got != want {
+		t.Errorf("sampled traceparent = %s, want %s", got, want)
+	}
+}
+
+func TestStartSpan(t *testing.T) {
+	tracer := NewTracer(TracingConfig{Enabled: true, SampleRatio: 1})
+	defer tracer.Shutdown()
+
+	root := tracer.StartSpan("iteration", spanKindInternal, nil)
+	child := tracer.StartSpan("get-products", spanKindClient, root)
+
+	if !root.Sampled || root.ParentID != ([8]byte{}) {
+		t.Errorf("root = %+v, want sampled span without parent", root)
+	}
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID || child.SpanID == root.SpanID || !child.Sampled {
+		t.Errorf("child = %+v does not continue root %+v", child, root)
+	}
+	if !traceparentPattern.MatchString(child.Traceparent()) {
+		t.Errorf("traceparent %s is malformed", child.Traceparent())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	child.Inject(req)
+	if req.Header.Get("traceparent") != child.Traceparent() {
+		t.Errorf("injected header = %q", req.Header.Get("traceparent"))
+	}
+}
+
+func TestSampleRatio(t *testing.T) {
+	tracer := NewTracer(TracingConfig{Enabled: true, SampleRatio: 0})
+	defer tracer.Shutdown()
+
+	root := tracer.StartSpan("iteration", spanKindInternal, nil)
+	if root.Sampled || tracer.StartSpan("step", spanKindClient, root).Sampled {
+		t.Error("span sampled with ratio 0")
+	}
+	if got := root.Traceparent(); got[len(got)-2:] != "00" {
+		t.Errorf("traceparent %s has sampled flag", got)
+	}
+}
+
+func TestDisabledTracer(t *testing.T) {
+	tracer := NewTracer(TracingConfig{})
+	if tracer != nil {
+		t.Fatal("NewTracer returned tracer with tracing disabled")
+	}
+
+	span := tracer.StartSpan("request", spanKindClient, nil)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	span.Inject(req)
+	span.SetAttr("http.request.method", "GET")
+	span.Finish(nil)
+	if req.Header.Get("traceparent") != "" {
+		t.Error("nil span injected traceparent")
+	}
+	if samples := tracer.Shutdown(); samples != nil {
+		t.Errorf("Shutdown = %v, want nil", samples)
+	}
+}
+
+func TestTracerKeepsSlowestClientSpans(t *testing.T) {
+	tracer := NewTracer(TracingConfig{Enabled: true, SampleRatio: 1})
+	start := time.Now()
+	for i := 1; i <= slowestTracesCount+5; i++ {
+		span := tracer.StartSpan("request", spanKindClient, nil)
+		span.Start = start
+		span.SetAttr("http.response.status_code", 200+i)
+		span.End = start.Add(time.Duration(i) * time.Millisecond)
+		tracer.finish(span)
+	}
+	// Internal spans are never listed
+	internal := tracer.StartSpan("iteration", spanKindInternal, nil)
+	internal.Start, internal.End = start, start.Add(time.Second)
+	tracer.finish(internal)
+
+	samples := tracer.Shutdown()
+	if len(samples) != slowestTracesCount {
+		t.Fatalf("samples = %d, want %d", len(samples), slowestTracesCount)
+	}
+	for i, sample := range samples {
+		want := time.Duration(slowestTracesCount+5-i) * time.Millisecond
+		if sample.Duration != want || sample.StatusCode != 200+slowestTracesCount+5-i || sample.Name != "request" {
+			t.Errorf("sample %d = %+v, want duration %s", i, sample, want)
+		}
+	}
+}
+
+func TestTracerExportsOTLP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		payloads []map[string]interface{}
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("export to %s with content type %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer(TracingConfig{Enabled: true, SampleRatio: 1, OTLPEndpoint: collector.URL + "/"})
+	root := tracer.StartSpan("iteration", spanKindInternal, nil)
+	child := tracer.StartSpan("get-products", spanKindClient, root)
+	child.SetAttr("http.response.status_code", 500)
+	child.Finish(errors.New("server error: 500"))
+	root.Finish(nil)
+	tracer.Shutdown()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(payloads) != 1 {
+		t.Fatalf("exports = %d, want 1", len(payloads))
+	}
+	resourceSpans := payloads[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	scopeSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})
+	spans := scopeSpans["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	exportedChild := spans[0].(map[string]interface{})
+	if exportedChild["parentSpanId"] == nil || exportedChild["name"] != "get-products" {
+		t.Errorf("child span = %v", exportedChild)
+	}
+	status := exportedChild["status"].(map[string]interface{})
+	if status["code"] != float64(statusCodeError) || status["message"] != "server error: 500" {
+		t.Errorf("child status = %v", status)
+	}
+	if _, ok := spans[1].(map[string]interface{})["parentSpanId"]; ok {
+		t.Error("root span has parent")
+	}
+	resource := resourceSpans["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	if resource["key"] != "service.name" || resource["value"].(map[string]interface{})["stringValue"] != "benchmark-runner" {
+		t.Errorf("resource attribute = %v", resource)
+	}
+}
+
+func TestOTLPAttributes(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{200, `{"intValue":"200"}`},
+		{int64(1 << 40), `{"intValue":"1099511627776"}`},
+		{true, `{"boolValue":true}`},
+		{0.5, `{"doubleValue":0.5}`},
+		{"GET", `{"stringValue":"GET"}`},
+		{time.Second, `{"stringValue":"1s"}`},
+	}
+
+	for _, tt := range tests {
+		attrs := otlpAttributes(map[string]interface{}{"key": tt.value})
+		got, _ := json.Marshal(attrs[0]["value"])
+		if attrs[0]["key"] != "key" || string(got) != tt.want {
+			t.Errorf("otlpAttributes(%v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -8,12 +8,17 @@ import (
 type BenchmarkType string
 
 const (
-	GetProducts      BenchmarkType = "get-products"
-	CreateProduct    BenchmarkType = "create-product"
-	GetProductByID   BenchmarkType = "get-product-by-id"
-	UpdateProduct    BenchmarkType = "update-product"
-	DeleteProduct    BenchmarkType = "delete-product"
-	MixedOperations  BenchmarkType = "mixed-operations"
+	GetProducts     BenchmarkType = "get-products"
+	CreateProduct   BenchmarkType = "create-product"
+	GetProductByID  BenchmarkType = "get-product-by-id"
+	UpdateProduct   BenchmarkType = "update-product"
+	DeleteProduct   BenchmarkType = "delete-product"
+	MixedOperations BenchmarkType = "mixed-operations"
+	UserSession     BenchmarkType = "user-session"
+	HTTPTemplate    BenchmarkType = "http"
+	JourneyType     BenchmarkType = "journey"
+	ScriptType      BenchmarkType = "script"
+	ScenariosType   BenchmarkType = "scenarios" // Combined result of concurrent scenarios
 )
 
 type Config struct {
@@ -23,6 +28,56 @@ type Config struct {
 	BenchmarkType BenchmarkType
 	Concurrency   int
 	Verbose       bool
+	Live          bool // Show live dashboard (progress log lines if stdout is not a terminal)
+	Slowest       int  // Number of the slowest requests listed in the report
+
+	// Progress is called every second with statistics of the run so far (optional, serve mode)
+	Progress func(ProgressUpdate)
+
+	// MaxConcurrency is the upper limit the worker pool may grow to
+	// when workers can't keep up with the target RPS
+	MaxConcurrency int
+
+	// Profile changes the request rate over the run (open model only)
+	Profile RateProfile
+
+	// Concurrent scenarios loaded from -scenarios file, each with its own rate and worker pool
+	Scenarios []LoadScenario
+
+	// Generic HTTP (http) settings, URL is a template
+	Request TemplateRequest
+
+	// Journey loaded from -journey file (journey)
+	Journey *Journey
+
+	// Starlark script loaded from -script file (script)
+	Script *Script
+
+	Tracing TracingConfig
+
+	// Target metrics scraping: URL of the Prometheus endpoint, "auto" or empty (disabled)
+	MetricsURL     string
+	ScrapeInterval time.Duration
+
+	// Container resource usage queries (optional)
+	Prometheus PrometheusConfig
+
+	// Cleanup deletes resources created during the run in the teardown phase
+	Cleanup bool
+
+	// Fault-injection TCP proxy started for the run (optional)
+	Proxy ProxyConfig
+
+	// Per-request event log file (optional): NDJSON for .ndjson and .jsonl files, compact binary otherwise
+	EventLog string
+
+	// Detection of the steady state and the warm-up penalty in the time-series
+	WarmUp WarmUpConfig
+
+	// Closed-model (user-session) settings
+	VirtualUsers int       // Number of concurrent virtual users
+	ThinkTime    ThinkTime // Pause between session steps
+	SessionViews int       // Number of product views per session
 }
 
 type Result struct {
@@ -30,6 +85,7 @@ type Result struct {
 	SuccessRequests int64
 	FailedRequests  int64
 	TotalDuration   time.Duration
+	StartTime       time.Time
 	MinLatency      time.Duration
 	MaxLatency      time.Duration
 	AvgLatency      time.Duration
@@ -38,7 +94,71 @@ type Result struct {
 	P99Latency      time.Duration
 	Latencies       []time.Duration
 	Errors          *ErrorStats
-	BenchmarkType   BenchmarkType // To know if it was mixed-operations
+	BenchmarkType   BenchmarkType // Scenario name
+	StepsPerCycle   int           // HTTP requests per cycle for multi-step scenarios (e.g. mixed-operations)
+	TargetRPS       int           // Requested RPS at the full rate of the profile (open model only)
+	Profile         RateProfile
+
+	// Worker pool statistics (open model only)
+	Concurrency     int   // Initial number of workers
+	MaxConcurrency  int   // Upper limit of the worker pool
+	WorkerPoolSize  int64 // Number of workers at the end of the run
+	PeakConcurrency int64 // Maximum number of simultaneously busy workers
+	SaturatedTicks  int64 // Ticks when queue backed up but the pool was already at its limit
+	Dispatched      int64 // Tasks the generator managed to put into the queue
+
+	Runner *RunnerStats // Runner's own resource usage during the run
+
+	Server *ServerStats // Target's own metrics scraped during the run (optional)
+
+	Resources *ResourceStats // Target containers' resource usage from Prometheus (optional)
+
+	Proxy *ProxyStats // Fault windows of the fault-injection proxy (optional)
+
+	Cleanup *CleanupStats // Resources created during the run and the teardown phase
+
+	BodySizes []BodySizeStats // Response sizes per operation over the run
+
+	Scripts []*ScriptStats // Time spent in script functions, per script
+
+	Events *EventLogStats // Per-request event log (optional)
+
+	Assertions []AssertionResult // Outcome of -assert expressions
+
+	TimeSeries []TimeSeriesPoint // Per-second statistics of HTTP requests
+
+	WarmUp *WarmUpStats // Time to steady state and the warm-up penalty (runs of at least three windows)
+
+	SlowestRequests []SlowRequest // Slowest HTTP requests, slowest first
+
+	SlowestTraces []TraceSample // Slowest sampled requests with trace IDs (tracing only)
+
+	// Concurrent scenarios: results of every scenario. The result itself combines them,
+	// counting HTTP requests rather than cycles
+	Scenario  string // Name of the scenario (per-scenario results only)
+	Scenarios []*Result
+
+	// Closed-model (user-session) results
+	VirtualUsers       int
+	Sessions           int64
+	FailedSessions     int64
+	AvgSessionDuration time.Duration // Including think time
+
+	Steps []*StepStats // Per-step statistics of sessions and multi-step scenarios
+}
+
+// StepStats holds latency statistics of a single session or scenario step
+type StepStats struct {
+	Name       string
+	Requests   int64
+	Failed     int64
+	Latencies  []time.Duration
+	MinLatency time.Duration
+	AvgLatency time.Duration
+	MaxLatency time.Duration
+	P50Latency time.Duration
+	P95Latency time.Duration
+	P99Latency time.Duration
 }
 
 type Product struct {
@@ -53,9 +173,16 @@ type RequestContext struct {
 	Client     *http.Client
 	Config     Config
 	ErrorStats *ErrorStats
-	ProductID  int64 // ID of the created product to use in operations
+	Vars       *VarStore  // Values captured from responses, shared between workers
+	Tracer     *Tracer    // Trace context propagation, nil if tracing is disabled
+	Live       *LiveStats // Per-second statistics and live dashboard
+	Slowest    *slowestRecorder
+	Created    *resourceTracker  // Resources created and not deleted yet, deleted in the teardown
+	BodySizes  *bodySizeRecorder // Response sizes per operation over the run
+	Events     *eventLog         // Per-request event log, nil if disabled
+	Scenario   string            // Name of the concurrent scenario, "" for a single one
 }
 
 type RequestTask struct {
 	Type BenchmarkType
-}
\ No newline at end of file
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	defaultSteadyWindow    = 5 * time.Second
+	defaultSteadyTolerance = 0.2
+	defaultWarmUpWindow    = 30 * time.Second
+
+	// steadyLatencySlack is latency jitter tolerated at any level, sub-millisecond latencies are noisy
+	steadyLatencySlack = 500 * time.Microsecond
+)
+
+// defaultWarmUpConfig is the warm-up analysis of runs started without flags (control API, suites)
+var defaultWarmUpConfig = WarmUpConfig{Window: defaultSteadyWindow, Tolerance: defaultSteadyTolerance, Penalty: defaultWarmUpWindow}
+
+// WarmUpConfig configures detection of the steady state in the time-series of a run
+type WarmUpConfig struct {
+	Window    time.Duration // Rolling window smoothing per-second values
+	Tolerance float64       // Allowed deviation from the steady-state level, 0.2 - 20%
+	Penalty   time.Duration // First part of the run the warm-up penalty is measured over
+}
+
+// warmUpFlags registers flags of the warm-up analysis
+func warmUpFlags(fs *flag.FlagSet, config *WarmUpConfig) {
+	fs.DurationVar(&config.Window, "steady-window", defaultSteadyWindow, "Rolling window of the steady-state detection")
+	fs.Float64Var(&config.Tolerance, "steady-tolerance", defaultSteadyTolerance, "Allowed deviation of rolling p95 and throughput from the steady-state level (0.2 = 20%)")
+	fs.DurationVar(&config.Penalty, "warmup", defaultWarmUpWindow, "First part of the run the warm-up penalty (extra latency compared to the steady state) is measured over")
+}
+
+// validate rejects settings the steady state can't be detected with
+func (c WarmUpConfig) validate() error {
+	if c.Tolerance <= 0 || c.Tolerance >= 1 {
+		return fmt.Errorf("-steady-tolerance must be between 0 and 1, got %g", c.Tolerance)
+	}
+	if c.Window < time.Second {
+		return fmt.Errorf("-steady-window must be at least 1s (the time-series is per second), got %s", c.Window)
+	}
+	return nil
+}
+
+// WarmUpStats describes how long the target took to reach steady-state performance.
+// The steady-state level is the median of the last third of the run
+type WarmUpStats struct {
+	Window    time.Duration
+	Tolerance float64
+
+	SteadyP95 time.Duration // Steady-state level of per-second p95
+	SteadyRPS float64       // Steady-state level of requests per second
+
+	LatencySteady    time.Duration // Offset from which the rolling p95 stays within the tolerance
+	ThroughputSteady time.Duration // Offset from which the rolling throughput stays within the tolerance
+	SteadyState      time.Duration // Later of the two: time to steady state
+	Reached          bool          // The steady state was reached before the last third of the run
+
+	PenaltyWindow  time.Duration // First part of the run compared with the steady state, 0 - too short run
+	WarmUpRequests int64
+	WarmUpAvg      time.Duration // Average latency of the penalty window
+	SteadyAvg      time.Duration // Average latency of the last third
+	WarmUpP99      time.Duration // Average of per-second p99 of the penalty window
+	SteadyP99      time.Duration // Average of per-second p99 of the last third
+	Penalty        time.Duration // WarmUpAvg - SteadyAvg
+	ExtraLatency   time.Duration // Latency added by the warm-up to all requests of the penalty window
+}
+
+// PenaltyPercent returns the warm-up penalty relative to the steady-state average latency
+func (w *WarmUpStats) PenaltyPercent() float64 {
+	if w.SteadyAvg == 0 {
+		return 0
+	}
+	return float64(w.Penalty) / float64(w.SteadyAvg) * 100
+}
+
+// analyzeWarmUp detects when rolling p95 latency and throughput settle at their steady-state levels and measures
+// the extra latency of the first seconds. Returns nil for runs shorter than three windows or without requests
+// in the last third
+func analyzeWarmUp(series []TimeSeriesPoint, duration time.Duration, config WarmUpConfig) *WarmUpStats {
+	window := int(config.Window / time.Second)
+	if window < 1 {
+		window = 1
+	}
+	// The last second is usually incomplete
+	seconds := min(int(duration/time.Second), len(series))
+	if seconds < 3*window {
+		return nil
+	}
+	series = series[:seconds]
+	reference := seconds - seconds/3
+	steady := series[reference:]
+
+	stats := &WarmUpStats{
+		Window:    time.Duration(window) * time.Second,
+		Tolerance: config.Tolerance,
+		SteadyP95: medianP95(steady),
+		SteadyRPS: medianRequests(steady),
+	}
+	if stats.SteadyP95 == 0 || stats.SteadyRPS == 0 {
+		return nil
+	}
+
+	latencyLimit := max(time.Duration(float64(stats.SteadyP95)*(1+config.Tolerance)), stats.SteadyP95+steadyLatencySlack)
+	rpsLow, rpsHigh := stats.SteadyRPS*(1-config.Tolerance), stats.SteadyRPS*(1+config.Tolerance)
+	var latencyFrom, throughputFrom int
+	for start := 0; start+window <= seconds; start++ {
+		points := series[start : start+window]
+		if medianP95(points) > latencyLimit {
+			latencyFrom = start + 1
+		}
+		var requests int64
+		for _, point := range points {
+			requests += point.Requests
+		}
+		if rps := float64(requests) / float64(window); rps < rpsLow || rps > rpsHigh {
+			throughputFrom = start + 1
+		}
+	}
+	stats.LatencySteady = time.Duration(latencyFrom) * time.Second
+	stats.ThroughputSteady = time.Duration(throughputFrom) * time.Second
+	stats.SteadyState = max(stats.LatencySteady, stats.ThroughputSteady)
+	stats.Reached = max(latencyFrom, throughputFrom) <= reference
+
+	penalty := min(int(config.Penalty/time.Second), reference)
+	if penalty < 1 {
+		return stats
+	}
+	stats.PenaltyWindow = time.Duration(penalty) * time.Second
+	stats.WarmUpAvg, stats.WarmUpP99, stats.WarmUpRequests = averageLatency(series[:penalty])
+	stats.SteadyAvg, stats.SteadyP99, _ = averageLatency(steady)
+	stats.Penalty = stats.WarmUpAvg - stats.SteadyAvg
+	stats.ExtraLatency = stats.Penalty * time.Duration(stats.WarmUpRequests)
+	return stats
+}
+
+// averageLatency returns the average latency of the requests of the seconds, the average of per-second p99
+// and the number of requests
+func averageLatency(points []TimeSeriesPoint) (time.Duration, time.Duration, int64) {
+	var requests int64
+	var total, p99Sum time.Duration
+	var seconds int
+	for _, point := range points {
+		if point.Requests == 0 {
+			continue
+		}
+		requests += point.Requests
+		total += point.AvgLatency * time.Duration(point.Requests)
+		p99Sum += point.P99Latency
+		seconds++
+	}
+	if requests == 0 {
+		return 0, 0, 0
+	}
+	return total / time.Duration(requests), p99Sum / time.Duration(seconds), requests
+}
+
+// medianP95 returns the median of per-second p95 of the seconds with requests
+func medianP95(points []TimeSeriesPoint) time.Duration {
+	values := make([]time.Duration, 0, len(points))
+	for _, point := range points {
+		if point.Requests > 0 {
+			values = append(values, point.P95Latency)
+		}
+	}
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values[len(values)/2]
+}
+
+// medianRequests returns the median of requests per second
+func medianRequests(points []TimeSeriesPoint) float64 {
+	values := make([]float64, 0, len(points))
+	for _, point := range points {
+		values = append(values, float64(point.Requests))
+	}
+	sort.Float64s(values)
+	return values[len(values)/2]
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// warmUpSeries builds a time-series of seconds seconds; point returns requests and average latency
+// of the second, p95 is twice and p99 three times the average
+func warmUpSeries(seconds int, point func(second int) (int64, time.Duration)) []TimeSeriesPoint {
+	series := make([]TimeSeriesPoint, seconds)
+	for i := range series {
+		requests, latency := point(i)
+		series[i] = TimeSeriesPoint{Second: i, Requests: requests, AvgLatency: latency, P95Latency: 2 * latency, P99Latency: 3 * latency}
+	}
+	return series
+}
+
+func TestAnalyzeWarmUp(t *testing.T) {
+	config := WarmUpConfig{Window: 5 * time.Second, Tolerance: 0.2, Penalty: 10 * time.Second}
+	slowUntil := func(slow int) func(int) (int64, time.Duration) {
+		return func(second int) (int64, time.Duration) {
+			if second < slow {
+				return 100, 20 * time.Millisecond
+			}
+			return 100, 5 * time.Millisecond
+		}
+	}
+
+	tests := []struct {
+		name       string
+		point      func(second int) (int64, time.Duration)
+		latency    time.Duration
+		throughput time.Duration
+		reached    bool
+	}{
+		{name: "steady", point: slowUntil(0), reached: true},
+		// Windows of 5 seconds are slow while 3 of them are: the window starting at 3s is the last one
+		{name: "slow start", point: slowUntil(6), latency: 4 * time.Second, reached: true},
+		{name: "ramp-up", point: func(second int) (int64, time.Duration) {
+			return int64(min(10*(second+1), 100)), 5 * time.Millisecond
+		}, throughput: 5 * time.Second, reached: true},
+		// The steady state is only reached after 22s, past the start of the last third at 20s
+		{name: "not reached", point: slowUntil(24), latency: 22 * time.Second, reached: false},
+	}
+	for _, tt := range tests {
+		stats := analyzeWarmUp(warmUpSeries(30, tt.point), 30*time.Second, config)
+		if stats == nil {
+			t.Errorf("%s: no stats", tt.name)
+			continue
+		}
+		if stats.SteadyP95 != 10*time.Millisecond || stats.SteadyRPS != 100 {
+			t.Errorf("%s: steady level = p95 %s, %.0f RPS, want 10ms and 100 RPS", tt.name, stats.SteadyP95, stats.SteadyRPS)
+		}
+		if stats.LatencySteady != tt.latency || stats.ThroughputSteady != tt.throughput || stats.SteadyState != max(tt.latency, tt.throughput) {
+			t.Errorf("%s: steady after latency %s, throughput %s, state %s, want %s and %s",
+				tt.name, stats.LatencySteady, stats.ThroughputSteady, stats.SteadyState, tt.latency, tt.throughput)
+		}
+		if stats.Reached != tt.reached {
+			t.Errorf("%s: reached = %v, want %v", tt.name, stats.Reached, tt.reached)
+		}
+	}
+}
+
+func TestAnalyzeWarmUpPenalty(t *testing.T) {
+	series := warmUpSeries(30, func(second int) (int64, time.Duration) {
+		if second < 6 {
+			return 100, 20 * time.Millisecond
+		}
+		return 100, 5 * time.Millisecond
+	})
+	stats := analyzeWarmUp(series, 30*time.Second, WarmUpConfig{Window: 5 * time.Second, Tolerance: 0.2, Penalty: 10 * time.Second})
+
+	// The first 10 seconds: 6 at 20ms and 4 at 5ms
+	if stats.PenaltyWindow != 10*time.Second || stats.WarmUpRequests != 1000 {
+		t.Errorf("penalty window = %s of %d requests, want 10s of 1000", stats.PenaltyWindow, stats.WarmUpRequests)
+	}
+	if stats.WarmUpAvg != 14*time.Millisecond || stats.WarmUpP99 != 42*time.Millisecond {
+		t.Errorf("warm-up = avg %s, p99 %s, want 14ms and 42ms", stats.WarmUpAvg, stats.WarmUpP99)
+	}
+	if stats.SteadyAvg != 5*time.Millisecond || stats.SteadyP99 != 15*time.Millisecond {
+		t.Errorf("steady = avg %s, p99 %s, want 5ms and 15ms", stats.SteadyAvg, stats.SteadyP99)
+	}
+	if stats.Penalty != 9*time.Millisecond || stats.ExtraLatency != 9*time.Second || stats.PenaltyPercent() != 180 {
+		t.Errorf("penalty = %s (%.0f%%), extra latency %s, want 9ms (180%%) and 9s", stats.Penalty, stats.PenaltyPercent(), stats.ExtraLatency)
+	}
+
+	// The penalty window ends at the last third at most
+	stats = analyzeWarmUp(series, 30*time.Second, WarmUpConfig{Window: 5 * time.Second, Tolerance: 0.2, Penalty: time.Minute})
+	if stats.PenaltyWindow != 20*time.Second {
+		t.Errorf("penalty window = %s, want 20s", stats.PenaltyWindow)
+	}
+	stats = analyzeWarmUp(series, 30*time.Second, WarmUpConfig{Window: 5 * time.Second, Tolerance: 0.2})
+	if stats.PenaltyWindow != 0 || stats.Penalty != 0 {
+		t.Errorf("without penalty window: window %s, penalty %s", stats.PenaltyWindow, stats.Penalty)
+	}
+}
+
+func TestAnalyzeWarmUpSkipsShortRuns(t *testing.T) {
+	config := WarmUpConfig{Window: 5 * time.Second, Tolerance: 0.2, Penalty: 10 * time.Second}
+	busy := warmUpSeries(30, func(int) (int64, time.Duration) { return 100, 5 * time.Millisecond })
+	idleEnd := warmUpSeries(30, func(second int) (int64, time.Duration) {
+		if second >= 20 {
+			return 0, 0
+		}
+		return 100, 5 * time.Millisecond
+	})
+
+	tests := []struct {
+		name     string
+		series   []TimeSeriesPoint
+		duration time.Duration
+		config   WarmUpConfig
+		want     bool
+	}{
+		{name: "three windows", series: busy, duration: 15 * time.Second, config: config, want: true},
+		{name: "under three windows", series: busy, duration: 14 * time.Second, config: config, want: false},
+		{name: "short series", series: busy[:14], duration: 30 * time.Second, config: config, want: false},
+		{name: "no requests in the last third", series: idleEnd, duration: 30 * time.Second, config: config, want: false},
+		{name: "sub-second window", series: busy[:3], duration: 3 * time.Second, config: WarmUpConfig{Window: time.Millisecond}, want: true},
+	}
+	for _, tt := range tests {
+		stats := analyzeWarmUp(tt.series, tt.duration, tt.config)
+		if (stats != nil) != tt.want {
+			t.Errorf("%s: stats = %+v, want stats %v", tt.name, stats, tt.want)
+		}
+	}
+}
+
+func TestAverageLatency(t *testing.T) {
+	tests := []struct {
+		name     string
+		points   []TimeSeriesPoint
+		avg, p99 time.Duration
+		requests int64
+	}{
+		{name: "empty"},
+		{name: "idle seconds", points: []TimeSeriesPoint{{}, {}}},
+		{name: "weighted by requests", points: []TimeSeriesPoint{
+			{Requests: 30, AvgLatency: 10 * time.Millisecond, P99Latency: 40 * time.Millisecond},
+			{},
+			{Requests: 10, AvgLatency: 2 * time.Millisecond, P99Latency: 20 * time.Millisecond},
+		}, avg: 8 * time.Millisecond, p99: 30 * time.Millisecond, requests: 40},
+	}
+	for _, tt := range tests {
+		avg, p99, requests := averageLatency(tt.points)
+		if avg != tt.avg || p99 != tt.p99 || requests != tt.requests {
+			t.Errorf("%s: averageLatency = %s, %s, %d, want %s, %s, %d", tt.name, avg, p99, requests, tt.avg, tt.p99, tt.requests)
+		}
+	}
+}
+
+func TestMedianP95(t *testing.T) {
+	p95 := func(requests int64, ms int) TimeSeriesPoint {
+		return TimeSeriesPoint{Requests: requests, P95Latency: time.Duration(ms) * time.Millisecond}
+	}
+	tests := []struct {
+		name   string
+		points []TimeSeriesPoint
+		want   time.Duration
+	}{
+		{name: "empty", want: 0},
+		{name: "idle seconds", points: []TimeSeriesPoint{p95(0, 0), p95(0, 0)}, want: 0},
+		{name: "odd", points: []TimeSeriesPoint{p95(1, 30), p95(1, 10), p95(1, 20)}, want: 20 * time.Millisecond},
+		{name: "even takes the upper", points: []TimeSeriesPoint{p95(1, 40), p95(1, 10), p95(1, 30), p95(1, 20)}, want: 30 * time.Millisecond},
+		{name: "idle seconds ignored", points: []TimeSeriesPoint{p95(0, 0), p95(0, 0), p95(1, 50), p95(1, 7), p95(1, 9)}, want: 9 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := medianP95(tt.points); got != tt.want {
+			t.Errorf("%s: medianP95 = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMedianRequests(t *testing.T) {
+	tests := []struct {
+		requests []int64
+		want     float64
+	}{
+		{requests: []int64{5}, want: 5},
+		{requests: []int64{100, 0, 90}, want: 90},
+		{requests: []int64{0, 0, 100, 100}, want: 100},
+		{requests: []int64{3, 1, 2, 0, 0}, want: 1},
+	}
+	for _, tt := range tests {
+		points := make([]TimeSeriesPoint, len(tt.requests))
+		for i, requests := range tt.requests {
+			points[i].Requests = requests
+		}
+		if got := medianRequests(points); got != tt.want {
+			t.Errorf("medianRequests(%v) = %v, want %v", tt.requests, got, tt.want)
+		}
+	}
+}
+
+func TestPenaltyPercent(t *testing.T) {
+	tests := []struct {
+		penalty, steady time.Duration
+		want            float64
+	}{
+		{penalty: 5 * time.Millisecond, steady: 10 * time.Millisecond, want: 50},
+		{penalty: -time.Millisecond, steady: 4 * time.Millisecond, want: -25},
+		{penalty: time.Millisecond, steady: 0, want: 0},
+	}
+	for _, tt := range tests {
+		stats := &WarmUpStats{Penalty: tt.penalty, SteadyAvg: tt.steady}
+		if got := stats.PenaltyPercent(); got != tt.want {
+			t.Errorf("PenaltyPercent of %s over %s = %v, want %v", tt.penalty, tt.steady, got, tt.want)
+		}
+	}
+}
+
+func TestWarmUpConfigValidate(t *testing.T) {
+	if err := defaultWarmUpConfig.validate(); err != nil {
+		t.Errorf("default config: %v", err)
+	}
+	invalid := []WarmUpConfig{
+		{Window: 5 * time.Second, Tolerance: 0},
+		{Window: 5 * time.Second, Tolerance: -0.1},
+		{Window: 5 * time.Second, Tolerance: 1},
+		{Window: 500 * time.Millisecond, Tolerance: 0.2},
+	}
+	for _, config := range invalid {
+		if err := config.validate(); err == nil {
+			t.Errorf("%+v is valid", config)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"sort"
 	"sync"
@@ -10,143 +12,415 @@ import (
 )
 
 // runBenchmark runs the benchmark with honest RPS counting
-// It creates a queue of requests and workers that process them
-func runBenchmark(config Config) *Result {
-	var (
-		totalRequests   int64
-		successRequests int64
-		failedRequests  int64
-		latencies       []time.Duration
-		latenciesMutex  sync.Mutex
-	)
+// It creates a queue of requests and workers that process them, one queue and
+// worker pool per scenario. Cancelling runCtx stops the run early, results of
+// the requests made so far are returned
+func runBenchmark(runCtx context.Context, config Config) (*Result, error) {
+	scenarios := config.Scenarios
+	if len(scenarios) == 0 {
+		scenarios = []LoadScenario{{Config: config}}
+	}
 
 	errorStats := NewErrorStats()
 
 	// Create context for request execution
-	ctx := &RequestContext{
-		Client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		Config:     config,
-		ErrorStats: errorStats,
-	}
+	ctx := newRequestContext(config, errorStats)
 
-	// Create request queue channel
-	requestQueue := make(chan RequestTask, config.Concurrency*2)
-
-	// Start worker goroutines
-	var wg sync.WaitGroup
-	for i := 0; i < config.Concurrency; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for task := range requestQueue {
-				// For mixed operations, execute full CRUD cycle
-				if task.Type == MixedOperations {
-					executeCRUDCycle(ctx, &totalRequests, &successRequests, &failedRequests, &latencies, &latenciesMutex)
-				} else {
-					// Single operation mode
-					atomic.AddInt64(&totalRequests, 1)
-
-					latency, err := executeRequest(ctx, task)
-
-					latenciesMutex.Lock()
-					latencies = append(latencies, latency)
-					latenciesMutex.Unlock()
-
-					if err != nil {
-						atomic.AddInt64(&failedRequests, 1)
-					} else {
-						atomic.AddInt64(&successRequests, 1)
-					}
-				}
-			}
-		}()
+	pools := make([]*workerPool, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		ops, err := resolveOperations(scenario.Config)
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, newWorkerPool(ctx, scenario, ops))
+	}
+	if err := ctx.Events.Open(); err != nil {
+		return nil, err
 	}
 
 	// Start request generator
+	scraper := newMetricsScraper(config.MetricsURL, config.URL, config.ScrapeInterval)
+	scraper.Start()
 	startTime := time.Now()
+	errorStats.Start = startTime
+	ctx.Live.Start()
+	ctx.BodySizes.Start()
+	ctx.Events.Start(startTime)
+
+	benchmarkCtx, cancel := context.WithTimeout(runCtx, config.Duration)
+	defer cancel()
+
+	monitor := newRunnerMonitor(
+		func() int {
+			depth := 0
+			for _, pool := range pools {
+				depth += len(pool.queue)
+			}
+			return depth
+		},
+		func() int64 {
+			var busy int64
+			for _, pool := range pools {
+				busy += atomic.LoadInt64(&pool.busyWorkers)
+			}
+			return busy
+		},
+	)
+	monitor.Start()
+
+	for _, pool := range pools {
+		pool.start(benchmarkCtx, startTime)
+	}
+
+	// Wait for generators to stop (context expired) and workers to finish remaining requests
+	for _, pool := range pools {
+		pool.wait()
+	}
+
+	duration := time.Since(startTime)
+	timeSeries := ctx.Live.Stop()
+	events := ctx.Events.Close(duration)
+	runnerStats := monitor.Stop()
+	slowestTraces := ctx.Tracer.Shutdown()
+	serverStats := scraper.Stop()
+
+	// Calculate statistics
+	results := make([]*Result, 0, len(pools))
+	for _, pool := range pools {
+		result := pool.result(duration)
+		result.Runner = runnerStats
+		detectClientBottleneck(result, pool.targetRate(duration))
+		results = append(results, result)
+	}
 
-	// For mixed operations, each cycle contains 4 HTTP requests
-	// So we need to divide RPS by 4 to get the correct number of cycles
-	rps := config.RPS
-	if config.BenchmarkType == MixedOperations {
-		rps = config.RPS / 4
-		if rps == 0 {
-			rps = 1 // Minimum 1 cycle per second
+	result := results[0]
+	if len(results) > 1 {
+		result = combineResults(results)
+		result.Scenarios = results
+	}
+	result.StartTime = startTime
+	result.Errors = errorStats
+	result.Runner = runnerStats
+	result.Server = serverStats
+	result.TimeSeries = timeSeries
+	result.SlowestRequests = ctx.Slowest.Slowest()
+	result.SlowestTraces = slowestTraces
+	result.BodySizes = ctx.BodySizes.Results()
+	result.Events = events
+	for _, scenario := range scenarios {
+		if scenario.Config.Script != nil {
+			result.Scripts = append(result.Scripts, scenario.Config.Script.Stats())
 		}
 	}
 
-	ticker := time.NewTicker(time.Second / time.Duration(rps))
-	defer ticker.Stop()
+	// Teardown is not measured: it runs after the statistics are collected
+	result.Cleanup = ctx.Created.teardown(ctx.Client, config.Concurrency, config.Cleanup)
 
-	benchmarkCtx, cancel := context.WithTimeout(context.Background(), config.Duration)
-	defer cancel()
+	return result, nil
+}
+
+// workerPool is the request generator and worker pool of one scenario
+type workerPool struct {
+	ctx      *RequestContext // Shared context with the scenario's config
+	scenario LoadScenario
+	ops      []Operation
+	steps    *stepRecorder // Per-step latency of multi-step scenarios, nil otherwise
+	rps      int           // Iterations per second at full rate
+
+	queue chan RequestTask
+	wg    sync.WaitGroup
+	done  chan struct{} // Closed when the generator stopped
+
+	totalRequests   int64
+	successRequests int64
+	failedRequests  int64
+	latencies       []time.Duration
+	latenciesMutex  sync.Mutex
+
+	// Worker pool starts with Concurrency workers and grows up to
+	// MaxConcurrency when the queue backs up
+	workerCount     int64
+	maxWorkers      int64
+	busyWorkers     int64
+	peakConcurrency int64
+	saturatedTicks  int64
+	dispatched      int64
+}
+
+// newWorkerPool creates worker pool of the scenario. Workers share ctx, but see the scenario's config
+func newWorkerPool(ctx *RequestContext, scenario LoadScenario, ops []Operation) *workerPool {
+	config := scenario.Config
+
+	scenarioCtx := *ctx
+	scenarioCtx.Config = config
+	scenarioCtx.Scenario = scenario.Name
+
+	pool := &workerPool{
+		ctx:        &scenarioCtx,
+		scenario:   scenario,
+		ops:        ops,
+		queue:      make(chan RequestTask, config.Concurrency*2),
+		done:       make(chan struct{}),
+		maxWorkers: int64(config.MaxConcurrency),
+	}
+	if pool.maxWorkers < int64(config.Concurrency) {
+		pool.maxWorkers = int64(config.Concurrency)
+	}
+
+	// Multi-step scenarios also report latency of every step
+	if len(ops) > 1 {
+		pool.steps = newStepRecorder()
+	}
+
+	// For multi-step scenarios, each cycle contains several HTTP requests
+	// So we need to divide RPS by number of steps to get the correct number of cycles
+	pool.rps = config.RPS / len(ops)
+	if pool.rps == 0 {
+		pool.rps = 1 // Minimum 1 cycle per second
+	}
+	return pool
+}
+
+// startWorker starts a worker goroutine processing the queue
+func (p *workerPool) startWorker() {
+	p.workerCount++
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for range p.queue {
+			updateMax(&p.peakConcurrency, atomic.AddInt64(&p.busyWorkers, 1))
+
+			// For multi-step scenarios one task is a full cycle (e.g. CRUD)
+			atomic.AddInt64(&p.totalRequests, 1)
+
+			latency, ok := executeIteration(p.ctx, p.ops, p.steps)
+
+			p.latenciesMutex.Lock()
+			p.latencies = append(p.latencies, latency)
+			p.latenciesMutex.Unlock()
+
+			if ok {
+				atomic.AddInt64(&p.successRequests, 1)
+			} else {
+				atomic.AddInt64(&p.failedRequests, 1)
+			}
+
+			atomic.AddInt64(&p.busyWorkers, -1)
+		}
+	}()
+}
+
+// start starts workers and the request generator. The generator stops when benchmarkCtx is done
+func (p *workerPool) start(benchmarkCtx context.Context, startTime time.Time) {
+	for i := 0; i < p.scenario.Config.Concurrency; i++ {
+		p.startWorker()
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(p.rps))
+	profile := p.scenario.Config.Profile
+
+	// Only the generator starts new workers, so workerCount needs no synchronization
+	growPool := func() {
+		if p.workerCount < p.maxWorkers {
+			p.startWorker()
+		} else {
+			p.saturatedTicks++
+		}
+	}
 
 	// Generate requests at specified RPS
+	// Ticks come at the full rate, the profile decides which of them send a request
 	go func() {
-		defer close(requestQueue)
+		defer close(p.done)
+		defer close(p.queue)
+		defer ticker.Stop()
+		var credit float64
 		for {
 			select {
 			case <-benchmarkCtx.Done():
 				return
 			case <-ticker.C:
-				// For mixed operations, pass MixedOperations type
-				// The worker will execute full CRUD cycle
-				requestQueue <- RequestTask{Type: config.BenchmarkType}
+				credit += profile.Factor(time.Since(startTime))
+				if credit < 1 {
+					continue
+				}
+				credit--
+
+				task := RequestTask{Type: p.scenario.Config.BenchmarkType}
+
+				// Queue backs up while every worker is busy: grow the pool
+				if len(p.queue) > 0 && atomic.LoadInt64(&p.busyWorkers) >= p.workerCount {
+					growPool()
+				}
+
+				p.dispatched++
+				select {
+				case p.queue <- task:
+					continue
+				default:
+				}
+
+				// Queue is full: add a worker before blocking so the generator keeps up
+				growPool()
+				select {
+				case p.queue <- task:
+				case <-benchmarkCtx.Done():
+					return
+				}
 			}
 		}
 	}()
+}
 
-	// Wait for context to expire
-	<-benchmarkCtx.Done()
-
-	// Wait for all workers to finish processing remaining requests
-	wg.Wait()
+// wait waits for the generator to stop and workers to process remaining requests
+func (p *workerPool) wait() {
+	<-p.done
+	p.wg.Wait()
+}
 
-	duration := time.Since(startTime)
+// targetRate returns iterations per second the profile asked for on average over the run
+func (p *workerPool) targetRate(duration time.Duration) float64 {
+	return float64(p.rps) * p.scenario.Config.Profile.Average(duration)
+}
 
-	// Calculate statistics
+// result returns statistics of the scenario
+func (p *workerPool) result(duration time.Duration) *Result {
+	config := p.scenario.Config
 	result := &Result{
-		TotalRequests:   totalRequests,
-		SuccessRequests: successRequests,
-		FailedRequests:  failedRequests,
+		Scenario:        p.scenario.Name,
+		TotalRequests:   p.totalRequests,
+		SuccessRequests: p.successRequests,
+		FailedRequests:  p.failedRequests,
 		TotalDuration:   duration,
-		Latencies:       latencies,
-		Errors:          errorStats,
+		Latencies:       p.latencies,
 		BenchmarkType:   config.BenchmarkType,
+		TargetRPS:       config.RPS,
+		Profile:         config.Profile,
+		Concurrency:     config.Concurrency,
+		MaxConcurrency:  int(p.maxWorkers),
+		WorkerPoolSize:  p.workerCount,
+		PeakConcurrency: p.peakConcurrency,
+		SaturatedTicks:  p.saturatedTicks,
+		Dispatched:      p.dispatched,
+	}
+	if p.steps != nil {
+		result.StepsPerCycle = len(p.ops)
+		result.Steps = p.steps.results()
 	}
 
 	calculateLatencyStats(result)
-
 	return result
 }
 
+// combineResults merges results of concurrent scenarios. Combined statistics count
+// HTTP requests: multi-step scenarios contribute their steps rather than cycles
+func combineResults(results []*Result) *Result {
+	combined := &Result{
+		BenchmarkType: ScenariosType,
+		TotalDuration: results[0].TotalDuration,
+	}
+	for _, r := range results {
+		combined.TargetRPS += r.TargetRPS
+		combined.Concurrency += r.Concurrency
+		combined.MaxConcurrency += r.MaxConcurrency
+		combined.WorkerPoolSize += r.WorkerPoolSize
+		combined.PeakConcurrency += r.PeakConcurrency
+		combined.SaturatedTicks += r.SaturatedTicks
+		combined.Dispatched += r.Dispatched
+
+		if r.StepsPerCycle <= 1 {
+			combined.TotalRequests += r.TotalRequests
+			combined.FailedRequests += r.FailedRequests
+			combined.Latencies = append(combined.Latencies, r.Latencies...)
+			continue
+		}
+		for _, step := range r.Steps {
+			combined.TotalRequests += step.Requests
+			combined.FailedRequests += step.Failed
+			combined.Latencies = append(combined.Latencies, step.Latencies...)
+		}
+	}
+	combined.SuccessRequests = combined.TotalRequests - combined.FailedRequests
+
+	calculateLatencyStats(combined)
+	return combined
+}
+
+// newRequestContext creates context shared by all workers of a benchmark run
+func newRequestContext(config Config, errorStats *ErrorStats) *RequestContext {
+	return &RequestContext{
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		Config:     config,
+		ErrorStats: errorStats,
+		Vars:       NewVarStore(),
+		Tracer:     NewTracer(config.Tracing),
+		Live:       newLiveStats(config, errorStats),
+		Slowest:    newSlowestRecorder(config.Slowest),
+		Created:    newResourceTracker(),
+		BodySizes:  newBodySizeRecorder(),
+		Events:     newEventLog(config.EventLog, config),
+	}
+}
+
+// updateMax atomically stores value into addr if it is greater than the current value
+func updateMax(addr *int64, value int64) {
+	for {
+		current := atomic.LoadInt64(addr)
+		if value <= current || atomic.CompareAndSwapInt64(addr, current, value) {
+			return
+		}
+	}
+}
+
 // calculateLatencyStats calculates latency statistics
 func calculateLatencyStats(result *Result) {
 	if len(result.Latencies) == 0 {
 		return
 	}
+	stats := summarizeLatencies(result.Latencies)
+	result.MinLatency, result.AvgLatency, result.MaxLatency = stats.Min, stats.Avg, stats.Max
+	result.P50Latency, result.P95Latency, result.P99Latency = stats.P50, stats.P95, stats.P99
+}
+
+// calculateStepStats calculates latency statistics of a session step
+func calculateStepStats(step *StepStats) {
+	if len(step.Latencies) == 0 {
+		return
+	}
+	stats := summarizeLatencies(step.Latencies)
+	step.MinLatency, step.AvgLatency, step.MaxLatency = stats.Min, stats.Avg, stats.Max
+	step.P50Latency, step.P95Latency, step.P99Latency = stats.P50, stats.P95, stats.P99
+}
 
-	// Sort latencies for percentile calculation
-	sort.Slice(result.Latencies, func(i, j int) bool {
-		return result.Latencies[i] < result.Latencies[j]
-	})
+// latencySummary is min, avg, max and percentiles of latencies
+type latencySummary struct {
+	Min, Avg, Max time.Duration
+	P50, P95, P99 time.Duration
+}
 
-	result.MinLatency = result.Latencies[0]
-	result.MaxLatency = result.Latencies[len(result.Latencies)-1]
+// summarizeLatencies sorts latencies in place and calculates their summary, zero for no latencies
+func summarizeLatencies(latencies []time.Duration) latencySummary {
+	if len(latencies) == 0 {
+		return latencySummary{}
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
 
-	// Calculate average
 	var sum time.Duration
-	for _, lat := range result.Latencies {
+	for _, lat := range latencies {
 		sum += lat
 	}
-	result.AvgLatency = sum / time.Duration(len(result.Latencies))
-
-	// Calculate percentiles
-	result.P50Latency = percentile(result.Latencies, 0.50)
-	result.P95Latency = percentile(result.Latencies, 0.95)
-	result.P99Latency = percentile(result.Latencies, 0.99)
+	return latencySummary{
+		Min: latencies[0],
+		Avg: sum / time.Duration(len(latencies)),
+		Max: latencies[len(latencies)-1],
+		P50: percentile(latencies, 0.50),
+		P95: percentile(latencies, 0.95),
+		P99: percentile(latencies, 0.99),
+	}
 }
 
 // percentile calculates the percentile value from sorted latencies
@@ -161,53 +435,79 @@ func percentile(sortedLatencies []time.Duration, p float64) time.Duration {
 	return sortedLatencies[index]
 }
 
-// executeCRUDCycle executes full CRUD cycle: CREATE -> GET -> UPDATE -> DELETE
-// This counts as ONE request iteration
-func executeCRUDCycle(ctx *RequestContext, totalRequests, successRequests, failedRequests *int64, latencies *[]time.Duration, mutex *sync.Mutex) {
-	atomic.AddInt64(totalRequests, 1)
-
+// executeIteration executes all operations of a scenario as ONE request iteration.
+// Per-step latencies are recorded in steps (optional)
+func executeIteration(ctx *RequestContext, ops []Operation, steps *stepRecorder) (time.Duration, bool) {
 	start := time.Now()
-	var cycleSuccess = true
-
-	// Step 1: CREATE product and get ID
-	productID, err := createProductAndGetID(ctx)
-	if err != nil {
-		atomic.AddInt64(failedRequests, 1)
-		cycleSuccess = false
-		// Record latency even for failed cycle
-		mutex.Lock()
-		*latencies = append(*latencies, time.Since(start))
-		mutex.Unlock()
-		return
+	state := newState()
+	success := true
+
+	// Multi-step iterations get their own span, parent of the request spans
+	if len(ops) > 1 {
+		state.span = ctx.Tracer.StartSpan(string(ctx.Config.BenchmarkType), spanKindInternal, nil)
+		defer func() {
+			var err error
+			if !success {
+				err = fmt.Errorf("iteration failed")
+			}
+			state.span.Finish(err)
+		}()
 	}
 
-	// Step 2: GET product by ID
-	_, _, err = getProductByID(ctx, int(productID))
-	if err != nil {
-		cycleSuccess = false
+	for i, op := range ops {
+		latency, proceed, err := executeStep(ctx, op, state, i == len(ops)-1)
+		if errors.Is(err, errSkipStep) {
+			continue
+		}
+
+		if steps != nil {
+			steps.record(op.Name(), latency, err)
+		}
+		if err != nil {
+			success = false
+		}
+		if !proceed {
+			break
+		}
 	}
 
-	// Step 3: UPDATE product
-	_, _, err = updateProduct(ctx, int(productID))
-	if err != nil {
-		cycleSuccess = false
+	return time.Since(start), success
+}
+
+// resolveOperations returns operations of the configured benchmark type:
+// the templated request for http, journey or script steps, or the registered scenario
+func resolveOperations(config Config) ([]Operation, error) {
+	if config.BenchmarkType == HTTPTemplate {
+		def := config.Request
+		def.URL = config.URL
+		op, err := newTemplateOperation(string(HTTPTemplate), def)
+		if err != nil {
+			return nil, err
+		}
+		return []Operation{op}, nil
+	}
+	if config.BenchmarkType == ScriptType {
+		if config.Script == nil {
+			return nil, fmt.Errorf("script benchmark requires -script file")
+		}
+		return config.Script.Operations(config.URL), nil
+	}
+	if config.BenchmarkType == JourneyType {
+		if config.Journey == nil {
+			return nil, fmt.Errorf("journey benchmark requires -journey file")
+		}
+		return config.Journey.Operations(config.URL)
 	}
 
-	// Step 4: DELETE product
-	_, _, err = deleteProduct(ctx, int(productID))
+	scenario, err := LookupScenario(string(config.BenchmarkType))
 	if err != nil {
-		cycleSuccess = false
+		return nil, err
 	}
 
-	latency := time.Since(start)
-
-	mutex.Lock()
-	*latencies = append(*latencies, latency)
-	mutex.Unlock()
-
-	if cycleSuccess {
-		atomic.AddInt64(successRequests, 1)
-	} else {
-		atomic.AddInt64(failedRequests, 1)
+	ops := make([]Operation, 0, len(scenario.Steps))
+	for _, step := range scenario.Steps {
+		op, _ := LookupOperation(step)
+		ops = append(ops, op)
 	}
+	return ops, nil
 }
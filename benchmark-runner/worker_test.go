@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSummarizeLatencies(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		latencies := make([]time.Duration, len(values))
+		for i, v := range values {
+			latencies[i] = time.Duration(v) * time.Millisecond
+		}
+		return latencies
+	}
+	hundred := make([]int, 100)
+	for i := range hundred {
+		hundred[i] = 100 - i
+	}
+
+	tests := []struct {
+		name      string
+		latencies []time.Duration
+		want      latencySummary
+	}{
+		{name: "empty", latencies: nil, want: latencySummary{}},
+		{name: "single", latencies: ms(7), want: latencySummary{
+			Min: 7 * time.Millisecond, Avg: 7 * time.Millisecond, Max: 7 * time.Millisecond,
+			P50: 7 * time.Millisecond, P95: 7 * time.Millisecond, P99: 7 * time.Millisecond,
+		}},
+		{name: "unsorted", latencies: ms(30, 10, 20, 40), want: latencySummary{
+			Min: 10 * time.Millisecond, Avg: 25 * time.Millisecond, Max: 40 * time.Millisecond,
+			P50: 30 * time.Millisecond, P95: 40 * time.Millisecond, P99: 40 * time.Millisecond,
+		}},
+		{name: "hundred", latencies: ms(hundred...), want: latencySummary{
+			Min: 1 * time.Millisecond, Avg: 50500 * time.Microsecond, Max: 100 * time.Millisecond,
+			P50: 51 * time.Millisecond, P95: 96 * time.Millisecond, P99: 100 * time.Millisecond,
+		}},
+	}
+	for _, tt := range tests {
+		if got := summarizeLatencies(tt.latencies); got != tt.want {
+			t.Errorf("%s: summarizeLatencies = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateStepStatsMatchesResult(t *testing.T) {
+	latencies := []time.Duration{5 * time.Millisecond, time.Millisecond, 3 * time.Millisecond}
+	result := &Result{Latencies: append([]time.Duration(nil), latencies...)}
+	step := &StepStats{Latencies: append([]time.Duration(nil), latencies...)}
+	calculateLatencyStats(result)
+	calculateStepStats(step)
+
+	if result.MinLatency != step.MinLatency || result.AvgLatency != step.AvgLatency || result.MaxLatency != step.MaxLatency ||
+		result.P50Latency != step.P50Latency || result.P95Latency != step.P95Latency || result.P99Latency != step.P99Latency {
+		t.Errorf("step stats %+v differ from result stats %+v", step, result)
+	}
+	if result.AvgLatency != 3*time.Millisecond {
+		t.Errorf("avg = %s, want 3ms", result.AvgLatency)
+	}
+}
+
+// getOperation sends GET requests to a test server
+type getOperation struct{ url string }
+
+func (op getOperation) Name() string { return "get" }
+
+func (op getOperation) BuildRequest(ctx *RequestContext, state *State) (*http.Request, error) {
+	return newRequest(http.MethodGet, op.url, nil)
+}
+
+func (op getOperation) ClassifyResponse(resp *Response) error { return classifyServerErrors(resp) }
+
+func (op getOperation) ExtractState(resp *Response, state *State) error { return nil }
+
+// slowServer answers every request after the delay
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Write([]byte("[]"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// runPool runs a worker pool of the configuration against the server for the duration
+func runPool(server *httptest.Server, config Config, duration time.Duration) *Result {
+	ctx := newRequestContext(config, NewErrorStats())
+	pool := newWorkerPool(ctx, LoadScenario{Config: config}, []Operation{getOperation{url: server.URL}})
+	runCtx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	pool.start(runCtx, time.Now())
+	pool.wait()
+	return pool.result(duration)
+}
+
+func TestWorkerPoolGrowsToSustainRate(t *testing.T) {
+	// 50 RPS of 100ms requests need about 5 busy workers
+	server := slowServer(t, 100*time.Millisecond)
+	result := runPool(server, Config{RPS: 50, Concurrency: 1, MaxConcurrency: 20, BenchmarkType: "test"}, time.Second)
+
+	if result.WorkerPoolSize <= 1 {
+		t.Errorf("worker pool didn't grow: %d workers", result.WorkerPoolSize)
+	}
+	if result.WorkerPoolSize > 20 {
+		t.Errorf("worker pool grew to %d workers, over the limit of 20", result.WorkerPoolSize)
+	}
+	if result.PeakConcurrency < 3 {
+		t.Errorf("peak concurrency = %d, want at least 3", result.PeakConcurrency)
+	}
+	if result.TotalRequests < 40 {
+		t.Errorf("sent %d requests in 1s at 50 RPS, want at least 40", result.TotalRequests)
+	}
+	if result.SaturatedTicks != 0 {
+		t.Errorf("saturated ticks = %d, want 0 below the limit", result.SaturatedTicks)
+	}
+}
+
+func TestWorkerPoolStopsAtMaxConcurrency(t *testing.T) {
+	server := slowServer(t, 100*time.Millisecond)
+	result := runPool(server, Config{RPS: 50, Concurrency: 1, MaxConcurrency: 2, BenchmarkType: "test"}, time.Second)
+
+	if result.WorkerPoolSize != 2 {
+		t.Errorf("worker pool size = %d, want the limit of 2", result.WorkerPoolSize)
+	}
+	if result.SaturatedTicks == 0 {
+		t.Error("saturated ticks = 0, want the queue to back up at the limit")
+	}
+}
+
+func TestUpdateMax(t *testing.T) {
+	var max int64
+	var wg sync.WaitGroup
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func(value int64) {
+			defer wg.Done()
+			updateMax(&max, value)
+		}(int64(i))
+	}
+	wg.Wait()
+	if max != 100 {
+		t.Errorf("max = %d, want 100", max)
+	}
+	updateMax(&max, 50)
+	if max != 100 {
+		t.Errorf("max = %d after a smaller value, want 100", max)
+	}
+}
+
+func TestExecuteIterationRecordsFailedRequestOnce(t *testing.T) {
+	tests := []struct {
+		name     string
+		failing  string // Method answered with 500
+		requests int64
+	}{
+		// Update and delete need the product ID the failed create didn't return
+		{name: "create", failing: http.MethodPost, requests: 1},
+		// Get extracts nothing, so the rest of the cycle still runs
+		{name: "get", failing: http.MethodGet, requests: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var requests int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests++
+				mu.Unlock()
+				if r.Method == tt.failing {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id": 7}`))
+			}))
+			defer server.Close()
+
+			config := Config{URL: server.URL, BenchmarkType: MixedOperations}
+			ops, err := resolveOperations(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			errorStats := NewErrorStats()
+			if _, success := executeIteration(newRequestContext(config, errorStats), ops, nil); success {
+				t.Error("iteration succeeded")
+			}
+			if requests != tt.requests {
+				t.Errorf("requests = %d, want %d", requests, tt.requests)
+			}
+			if total := errorStats.GetTotalCount(); total != 1 {
+				t.Errorf("errors = %d, want 1: %+v", total, errorStats.GetSortedErrors())
+			}
+		})
+	}
+}
+
+func TestExecuteStepReportsLastStepCaptureErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	defer server.Close()
+
+	// The only step of -type=http captures values for other workers, so a wrong path is an error
+	op, err := newTemplateOperation("get", TemplateRequest{URL: server.URL, Captures: []CaptureRule{{Name: "id", Source: "$.missing"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorStats := NewErrorStats()
+	ctx := newRequestContext(Config{URL: server.URL}, errorStats)
+	if _, _, err := executeStep(ctx, op, newState(), true); err == nil {
+		t.Error("failed capture of the last step returned no error")
+	}
+	errs := errorStats.GetSortedErrors()
+	if len(errs) != 1 || errs[0].ErrorType != "extract_error" {
+		t.Errorf("errors = %+v, want one extract_error", errs)
+	}
+}